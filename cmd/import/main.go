@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"messanger/internal/app"
+	"os"
+)
+
+// Импорт истории из выгрузок Telegram и Slack:
+//
+//	go run ./cmd/import -source telegram -archive ChatExport/result.json -mapping users.json
+func main() {
+	if err := app.Import(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
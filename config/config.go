@@ -5,13 +5,23 @@ import (
 	"github.com/joho/godotenv"
 	"log"
 	"sync"
+	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	WSServer WSConfig
-	PG       PGConfig
-	TokenKey string `env:"TOKEN_KEY,required"`
+	Server      ServerConfig
+	WSServer    WSConfig
+	PG          PGConfig
+	Messages    MessagesConfig
+	Attachments AttachmentsConfig
+	Previews    LinkPreviewsConfig
+	Scheduled   ScheduledConfig
+	Retention   RetentionConfig
+	Filters     FiltersConfig
+	Sync        SyncConfig
+	TokenKey    string `env:"TOKEN_KEY,required"`
+	// AdminUserIDs пользователи с доступом к административному API
+	AdminUserIDs []int64 `env:"ADMIN_USER_IDS" envSeparator:","`
 }
 
 type ServerConfig struct {
@@ -31,6 +41,70 @@ type PGConfig struct {
 	SSLMode  string `env:"DB_SSLMODE,required"`
 }
 
+type MessagesConfig struct {
+	EditWindow         time.Duration `env:"MESSAGE_EDIT_WINDOW" envDefault:"0"`
+	ReactionsPerMinute int           `env:"REACTIONS_PER_MINUTE" envDefault:"30"`
+	LargeRoomSize      int           `env:"LARGE_ROOM_SIZE" envDefault:"50"`
+	PurgeInterval      time.Duration `env:"MESSAGE_PURGE_INTERVAL" envDefault:"10s"`
+	PollCloseInterval  time.Duration `env:"POLL_CLOSE_INTERVAL" envDefault:"5s"`
+}
+
+type AttachmentsConfig struct {
+	MaxSize int64 `env:"ATTACHMENT_MAX_SIZE" envDefault:"20971520"`
+	// Storage тип хранилища файлов: local или s3
+	Storage  string `env:"BLOB_STORAGE" envDefault:"local"`
+	LocalDir string `env:"BLOB_LOCAL_DIR" envDefault:"./data/blobs"`
+	S3       S3Config
+}
+
+type LinkPreviewsConfig struct {
+	Enabled  bool          `env:"LINK_PREVIEWS_ENABLED" envDefault:"true"`
+	Timeout  time.Duration `env:"LINK_PREVIEW_TIMEOUT" envDefault:"5s"`
+	MaxBytes int64         `env:"LINK_PREVIEW_MAX_BYTES" envDefault:"1048576"`
+	Workers  int           `env:"LINK_PREVIEW_WORKERS" envDefault:"4"`
+	CacheTTL time.Duration `env:"LINK_PREVIEW_CACHE_TTL" envDefault:"24h"`
+}
+
+type ScheduledConfig struct {
+	PollInterval time.Duration `env:"SCHEDULED_POLL_INTERVAL" envDefault:"5s"`
+	BatchSize    int           `env:"SCHEDULED_BATCH_SIZE" envDefault:"50"`
+	Lease        time.Duration `env:"SCHEDULED_LEASE" envDefault:"1m"`
+}
+
+// RetentionConfig глобальные сроки хранения в днях; переписка может их переопределить. 0 — хранить бессрочно
+type RetentionConfig struct {
+	DeletedDays   int           `env:"RETENTION_DELETED_DAYS" envDefault:"30"`
+	MessageDays   int           `env:"RETENTION_MESSAGE_DAYS" envDefault:"0"`
+	PurgeInterval time.Duration `env:"RETENTION_PURGE_INTERVAL" envDefault:"1m"`
+	BatchSize     int           `env:"RETENTION_BATCH_SIZE" envDefault:"500"`
+}
+
+// FiltersConfig настройки фильтрации сообщений по умолчанию; комната может их переопределить. 0 отключает фильтр
+type FiltersConfig struct {
+	Words        []string      `env:"FILTER_WORDS" envSeparator:","`
+	WordAction   string        `env:"FILTER_WORD_ACTION" envDefault:"mask"`
+	RepeatLimit  int           `env:"FILTER_REPEAT_LIMIT" envDefault:"3"`
+	RepeatWindow time.Duration `env:"FILTER_REPEAT_WINDOW" envDefault:"1m"`
+	LinkSpam     bool          `env:"FILTER_LINK_SPAM" envDefault:"true"`
+	MaxLinks     int           `env:"FILTER_MAX_LINKS" envDefault:"10"`
+}
+
+// SyncConfig журнал событий для синхронизации после переподключения. Клиент, отставший больше чем на Retention,
+// выполняет полную синхронизацию
+type SyncConfig struct {
+	Retention       time.Duration `env:"SYNC_LOG_RETENTION" envDefault:"168h"`
+	CompactInterval time.Duration `env:"SYNC_COMPACT_INTERVAL" envDefault:"10m"`
+	CompactBatch    int           `env:"SYNC_COMPACT_BATCH_SIZE" envDefault:"1000"`
+}
+
+type S3Config struct {
+	Endpoint  string `env:"S3_ENDPOINT"`
+	Bucket    string `env:"S3_BUCKET"`
+	Region    string `env:"S3_REGION" envDefault:"us-east-1"`
+	AccessKey string `env:"S3_ACCESS_KEY"`
+	SecretKey string `env:"S3_SECRET_KEY"`
+}
+
 var (
 	config Config
 	once   sync.Once
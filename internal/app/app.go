@@ -1,12 +1,17 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"messanger/config"
+	"messanger/internal/events"
+	"messanger/internal/models"
 	"messanger/internal/repo"
 	"messanger/internal/services"
 	"messanger/internal/transport/http"
 	"messanger/internal/transport/ws"
+	"messanger/pkg/blobstore"
+	"messanger/pkg/linkpreview"
 	"messanger/pkg/logger"
 	"os"
 	"os/signal"
@@ -23,15 +28,153 @@ func Run() {
 
 	log.Info("Staring messanger-app...")
 
-	messageRepo := repo.NewMessageRepo(cfg)
-	messageService := services.NewMessageService(messageRepo)
+	eventBus := events.NewBus()
+	presence := events.NewPresence()
+
+	db := repo.NewPostgresDB(cfg)
+
+	messageRepo := repo.NewMessageRepo(db)
+	eventRepo := repo.NewEventRepo(db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Сервисы публикуют события через журнал: он нумерует их для каждого получателя и передаёт в шину
+	eventLog := services.NewEventLog(services.EventLogConfig{
+		Repo: eventRepo,
+		Next: eventBus,
+		Log:  log,
+	})
+	compactor := services.NewEventLogCompactor(services.EventLogCompactorConfig{
+		Repo:      eventRepo,
+		Log:       log,
+		Retention: cfg.Sync.Retention,
+		Interval:  cfg.Sync.CompactInterval,
+		BatchSize: cfg.Sync.CompactBatch,
+	})
+	go compactor.Run(ctx)
+
+	var linkPreviewer *services.LinkPreviewer
+	if cfg.Previews.Enabled {
+		linkPreviewer = services.NewLinkPreviewer(services.LinkPreviewerConfig{
+			Repo:     repo.NewLinkPreviewRepo(db),
+			Messages: messageRepo,
+			Fetcher: linkpreview.NewHTTPFetcher(linkpreview.HTTPFetcherConfig{
+				Timeout:  cfg.Previews.Timeout,
+				MaxBytes: cfg.Previews.MaxBytes,
+			}),
+			Events:   eventLog,
+			Log:      log,
+			Workers:  cfg.Previews.Workers,
+			CacheTTL: cfg.Previews.CacheTTL,
+		})
+		go linkPreviewer.Run(ctx)
+	}
+
+	pipeline := services.NewMessagePipeline(services.MessagePipelineConfig{
+		Stages: services.BuiltinStages(messageRepo),
+		Defaults: models.MessageFilters{
+			Words:        cfg.Filters.Words,
+			WordAction:   cfg.Filters.WordAction,
+			RepeatLimit:  cfg.Filters.RepeatLimit,
+			RepeatWindow: int(cfg.Filters.RepeatWindow.Seconds()),
+			LinkSpam:     cfg.Filters.LinkSpam,
+			MaxLinks:     cfg.Filters.MaxLinks,
+		},
+	})
+
+	blobStore, err := newBlobStore(cfg.Attachments)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("failed to init blob storage: %v", err))
+	}
+
+	messageService := services.NewMessageService(services.MessageServiceConfig{
+		Repo:               messageRepo,
+		Users:              repo.NewUserRepo(db),
+		Events:             eventLog,
+		Presence:           presence,
+		Store:              blobStore,
+		Log:                log,
+		EditWindow:         cfg.Messages.EditWindow,
+		ReactionsPerMinute: cfg.Messages.ReactionsPerMinute,
+		LargeRoomSize:      cfg.Messages.LargeRoomSize,
+		LinkPreviews:       linkPreviewer,
+		Pipeline:           pipeline,
+	})
+	searchService := services.NewSearchService(repo.NewSearchRepo(db))
+
+	scheduledRepo := repo.NewScheduledMessageRepo(db)
+	scheduledService := services.NewScheduledService(scheduledRepo, messageRepo)
+	scheduledSender := services.NewScheduledSender(services.ScheduledSenderConfig{
+		Repo:      scheduledRepo,
+		Messages:  messageService,
+		Log:       log,
+		Interval:  cfg.Scheduled.PollInterval,
+		BatchSize: cfg.Scheduled.BatchSize,
+		Lease:     cfg.Scheduled.Lease,
+	})
+	go scheduledSender.Run(ctx)
+
+	purger := services.NewMessagePurger(services.MessagePurgerConfig{
+		Repo:     messageRepo,
+		Store:    blobStore,
+		Events:   eventLog,
+		Log:      log,
+		Interval: cfg.Messages.PurgeInterval,
+	})
+	go purger.Run(ctx)
+
+	pollCloser := services.NewPollCloser(services.PollCloserConfig{
+		Repo:     messageRepo,
+		Events:   eventLog,
+		Log:      log,
+		Interval: cfg.Messages.PollCloseInterval,
+	})
+	go pollCloser.Run(ctx)
+
+	retentionPurger := services.NewRetentionPurger(services.RetentionPurgerConfig{
+		Repo:        messageRepo,
+		Store:       blobStore,
+		Events:      eventLog,
+		Log:         log,
+		Interval:    cfg.Retention.PurgeInterval,
+		BatchSize:   cfg.Retention.BatchSize,
+		DeletedDays: cfg.Retention.DeletedDays,
+		MessageDays: cfg.Retention.MessageDays,
+	})
+	go retentionPurger.Run(ctx)
+
+	attachmentService := services.NewAttachmentService(services.AttachmentServiceConfig{
+		Repo:     repo.NewAttachmentRepo(db),
+		Messages: messageRepo,
+		Store:    blobStore,
+		MaxSize:  cfg.Attachments.MaxSize,
+	})
+	importService := services.NewImportService(services.ImportServiceConfig{
+		Repo:        repo.NewImportRepo(db),
+		Attachments: attachmentService,
+		Log:         log,
+	})
+
+	syncService := services.NewSyncService(eventRepo)
+
 	httpServer := http.NewServer(http.ServerConfig{
 		Addr:           cfg.Server.Addr,
 		MessageService: messageService,
-		Log:            log,
+		SearchService:  searchService,
+		Attachments:    attachmentService,
+		Scheduled:      scheduledService,
+		Exports:        services.NewExportService(messageRepo),
+		Imports:        importService,
+		Sync:           syncService,
+		// Запас на заголовки multipart и остальные поля формы
+		BodyLimit: int(cfg.Attachments.MaxSize) + 1<<20,
+		Log:       log,
+		TokenKey:  cfg.TokenKey,
+		AdminIDs:  cfg.AdminUserIDs,
 	})
 
-	websocketServer := ws.NewWebSocketServer(messageService, log, cfg.TokenKey)
+	websocketServer := ws.NewWebSocketServer(messageService, syncService, eventBus, presence, log, cfg.TokenKey)
 
 	go func() {
 		if err := httpServer.Run(); err != nil {
@@ -61,3 +204,20 @@ func Run() {
 		log.Info("HTTP server successfully shutdown")
 	}
 }
+
+func newBlobStore(cfg config.AttachmentsConfig) (blobstore.BlobStore, error) {
+	switch cfg.Storage {
+	case "local":
+		return blobstore.NewLocalStore(cfg.LocalDir)
+	case "s3":
+		return blobstore.NewS3Store(blobstore.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Bucket:    cfg.S3.Bucket,
+			Region:    cfg.S3.Region,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob storage %q", cfg.Storage)
+	}
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"messanger/config"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+	"messanger/pkg/chatimport"
+	"messanger/pkg/logger"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Import переносит историю из выгрузки Telegram или Slack и печатает итоги в stdout в формате JSON.
+// Повторный запуск с той же выгрузкой переносит только то, что не было перенесено раньше
+func Import(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	source := flags.String("source", "", "export source: telegram or slack")
+	archivePath := flags.String("archive", "", "Telegram export directory, result.json or zip; Slack export zip")
+	mappingPath := flags.String("mapping", "", `user mapping JSON: {"telegram": {"user123": 1}, "slack": {"U024BE7LH": 2}}`)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *source == "" || *archivePath == "" || *mappingPath == "" {
+		flags.Usage()
+		return errors.New("-source, -archive and -mapping are required")
+	}
+
+	mappingFile, err := os.Open(*mappingPath)
+	if err != nil {
+		return fmt.Errorf("failed to open mapping: %w", err)
+	}
+	defer mappingFile.Close()
+	users, err := chatimport.ReadUserMap(mappingFile, *source)
+	if err != nil {
+		return err
+	}
+
+	archive, closer, err := chatimport.Open(*archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer closer.Close()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// stdout занят итогами импорта
+	log := logger.GetLogger()
+	log.SetOutput(os.Stderr)
+
+	db := repo.NewPostgresDB(cfg)
+	defer db.Close()
+
+	blobStore, err := newBlobStore(cfg.Attachments)
+	if err != nil {
+		return fmt.Errorf("failed to init blob storage: %w", err)
+	}
+	importService := services.NewImportService(services.ImportServiceConfig{
+		Repo: repo.NewImportRepo(db),
+		Attachments: services.NewAttachmentService(services.AttachmentServiceConfig{
+			Repo:     repo.NewAttachmentRepo(db),
+			Messages: repo.NewMessageRepo(db),
+			Store:    blobStore,
+			MaxSize:  cfg.Attachments.MaxSize,
+		}),
+		Log: log,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	report, err := importService.Import(ctx, services.ImportParams{
+		Source:  *source,
+		Archive: archive,
+		Users:   users,
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,32 @@
+// Package apperr ошибки предметной области. Сервисы возвращают их, а транспорт переводит
+// в HTTP-статусы и коды кадров WebSocket, не завися от сервисного слоя
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrForbidden   = errors.New("forbidden")
+	ErrValidation  = errors.New("validation failed")
+	ErrRateLimited = errors.New("rate limit exceeded")
+	ErrConflict    = errors.New("conflict")
+	// ErrMessageRejected сообщение отклонено фильтром; подробности в *RejectionError
+	ErrMessageRejected = errors.New("message rejected")
+)
+
+// RejectionError сообщение отклонено этапом конвейера. Отправитель получает её как структурированную ошибку
+type RejectionError struct {
+	Stage  string `json:"stage"`
+	Reason string `json:"reason"`
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("message rejected by %s: %s", e.Stage, e.Reason)
+}
+
+func (e *RejectionError) Unwrap() error {
+	return ErrMessageRejected
+}
@@ -0,0 +1,87 @@
+package events
+
+import "sync"
+
+// Типы событий, которые рассылаются участникам переписки
+const (
+	MessageNew     = "message.new"
+	MessageEdited  = "message.edited"
+	MessageDeleted = "message.deleted"
+	// MessageLinkPreview приходит, когда к уже отправленному сообщению прикреплено превью ссылки
+	MessageLinkPreview = "message.link_preview"
+	// MessageExpired приходит, когда исчезающее сообщение удалено окончательно
+	MessageExpired = "message.expired"
+
+	ReactionAdded   = "reaction.added"
+	ReactionRemoved = "reaction.removed"
+
+	MessagePinned   = "message.pinned"
+	MessageUnpinned = "message.unpinned"
+
+	MessageReceipt = "message.receipt"
+
+	// DraftUpdated доставляется всем устройствам автора черновика; пустой content — черновик удалён
+	DraftUpdated = "draft.updated"
+
+	// PollUpdated несёт новые итоги опроса после каждого голоса; PollClosed — итоги закрытого опроса
+	PollUpdated = "poll.updated"
+	PollClosed  = "poll.closed"
+
+	// Mention доставляется упомянутым пользователям отдельно от message.new,
+	// чтобы клиент мог уведомить о нём независимо от настроек переписки
+	Mention = "mention"
+
+	// MemberJoined и MemberLeft получают участники комнаты и сам добавленный или исключённый пользователь
+	MemberJoined = "member.joined"
+	MemberLeft   = "member.left"
+
+	// Typing сообщает, что пользователь набирает сообщение
+	Typing = "typing"
+)
+
+// Ephemeral сообщает, что событие актуально только в момент доставки: такие события
+// не записываются в журнал для синхронизации после переподключения
+func Ephemeral(eventType string) bool {
+	return eventType == Typing
+}
+
+// Event событие, доставляемое клиентам в реальном времени
+type Event struct {
+	Type    string `json:"type"`
+	Payload any    `json:"payload"`
+	// Seq номер события в журнале получателя; задаётся, когда событие адресовано одному пользователю
+	// и записано в журнал для синхронизации после переподключения
+	Seq int64 `json:"seq,omitempty"`
+}
+
+type Publisher interface {
+	Publish(userIDs []int64, event Event)
+}
+
+type Handler func(userIDs []int64, event Event)
+
+// Bus простая in-process шина событий: сервисы публикуют, транспорт (WebSocket) подписывается
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *Bus) Publish(userIDs []int64, event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, handler := range b.handlers {
+		handler(userIDs, event)
+	}
+}
@@ -0,0 +1,50 @@
+package events
+
+import "sync"
+
+// Presence отслеживает пользователей, у которых есть хотя бы одно активное соединение
+type Presence struct {
+	mu          sync.RWMutex
+	connections map[int64]int
+}
+
+func NewPresence() *Presence {
+	return &Presence{connections: make(map[int64]int)}
+}
+
+func (p *Presence) Connect(userID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.connections[userID]++
+}
+
+func (p *Presence) Disconnect(userID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.connections[userID] <= 1 {
+		delete(p.connections, userID)
+		return
+	}
+	p.connections[userID]--
+}
+
+// Online возвращает подмножество userIDs, находящихся в сети
+func (p *Presence) Online(userIDs []int64) []int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var online []int64
+	for _, id := range userIDs {
+		if p.connections[id] > 0 {
+			online = append(online, id)
+		}
+	}
+	return online
+}
+
+// OnlineChecker сообщает, кто из пользователей сейчас в сети
+type OnlineChecker interface {
+	Online(userIDs []int64) []int64
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type Attachment struct {
+	ID         int64     `json:"id"`
+	MessageID  int64     `json:"message_id,omitempty"`
+	UploaderID int64     `json:"uploader_id"`
+	FileName   string    `json:"file_name"`
+	MimeType   string    `json:"mime_type"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256"`
+	StorageKey string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Width, Height и Blurhash заполняются только для изображений
+	Width      int         `json:"width,omitempty"`
+	Height     int         `json:"height,omitempty"`
+	Blurhash   string      `json:"blurhash,omitempty"`
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty"`
+}
+
+// Thumbnail уменьшенная копия изображения, вписанная в квадрат Size×Size
+type Thumbnail struct {
+	Size       int    `json:"size"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	MimeType   string `json:"mime_type"`
+	StorageKey string `json:"-"`
+}
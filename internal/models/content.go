@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// ContentVersion текущая версия схемы структурированного содержимого
+const ContentVersion = 1
+
+// Виды структурированного содержимого
+const (
+	ContentKindText   = "text"
+	ContentKindSystem = "system"
+	ContentKindPoll   = "poll"
+)
+
+// Типы разметки текста
+const (
+	EntityBold      = "bold"
+	EntityItalic    = "italic"
+	EntityStrike    = "strikethrough"
+	EntityCode      = "code"
+	EntityPre       = "pre"
+	EntityLink      = "link"
+	EntityMention   = "mention"
+	EntitySpoiler   = "spoiler"
+	EntityUnderline = "underline"
+
+	// EntityRoomMention (@room) упоминает всех участников комнаты, EntityHereMention (@here) — тех, кто в сети
+	EntityRoomMention = "room_mention"
+	EntityHereMention = "here_mention"
+)
+
+// RichContent версионированное структурированное содержимое сообщения. Хранится в JSONB,
+// а текстовое представление для старых клиентов и поиска сохраняется в Message.Content
+type RichContent struct {
+	Version int    `json:"v"`
+	Kind    string `json:"kind"`
+
+	// Text и Entities используются видом text, а также как подпись к вложениям
+	Text     string          `json:"text,omitempty"`
+	Entities []ContentEntity `json:"entities,omitempty"`
+	// Attachments ID вложений в порядке отображения
+	Attachments []int64 `json:"attachments,omitempty"`
+
+	System *SystemContent `json:"system,omitempty"`
+	Poll   *PollContent   `json:"poll,omitempty"`
+}
+
+// ContentEntity разметка фрагмента текста. Offset и Length считаются в символах Unicode
+type ContentEntity struct {
+	Type   string `json:"type"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	// URL задаётся для ссылок
+	URL string `json:"url,omitempty"`
+	// UserID задаётся для упоминаний
+	UserID int64 `json:"user_id,omitempty"`
+	// Language необязательный язык блока кода
+	Language string `json:"language,omitempty"`
+}
+
+// SystemContent служебное сообщение о событии в переписке; создаётся только сервером
+type SystemContent struct {
+	Event   string  `json:"event"`
+	ActorID int64   `json:"actor_id,omitempty"`
+	UserIDs []int64 `json:"user_ids,omitempty"`
+	// MessageTTL новое время жизни сообщений в секундах для события message_ttl_changed
+	MessageTTL int64 `json:"message_ttl,omitempty"`
+}
+
+// События служебных сообщений
+const (
+	SystemEventMessageTTLChanged = "message_ttl_changed"
+)
+
+// PollContent опрос. Параметры задаются при создании и больше не меняются
+type PollContent struct {
+	Question      string   `json:"question"`
+	Options       []string `json:"options"`
+	MultipleVotes bool     `json:"multiple_votes,omitempty"`
+	// Anonymous скрывает, кто за какой вариант проголосовал; видно только число голосов
+	Anonymous bool `json:"anonymous,omitempty"`
+	// AllowVoteChange разрешает менять и отзывать голос, пока опрос открыт
+	AllowVoteChange bool `json:"allow_vote_change,omitempty"`
+	// ClosesAt время автоматического закрытия опроса; без него опрос закрывает автор или администратор
+	ClosesAt *time.Time `json:"closes_at,omitempty"`
+}
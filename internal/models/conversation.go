@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Draft неотправленный текст пользователя в переписке. Пустой Content в событии означает, что черновик удалён
+type Draft struct {
+	ReceiverID int64     `json:"receiver_id,omitempty"`
+	RoomID     int64     `json:"room_id,omitempty"`
+	Content    string    `json:"content"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Conversation переписка в списке переписок пользователя: личная (ReceiverID) или комната (RoomID)
+type Conversation struct {
+	ReceiverID  int64    `json:"receiver_id,omitempty"`
+	RoomID      int64    `json:"room_id,omitempty"`
+	LastMessage *Message `json:"last_message,omitempty"`
+	UnreadCount int      `json:"unread_count"`
+	Draft       *Draft   `json:"draft,omitempty"`
+}
+
+// Typing пользователь UserID набирает сообщение в комнате RoomID или, если RoomID не задан,
+// в личной переписке с получателем события
+type Typing struct {
+	UserID int64 `json:"user_id"`
+	RoomID int64 `json:"room_id,omitempty"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// ExportedMessage сообщение в выгрузке переписки вместе с историей правок
+type ExportedMessage struct {
+	Message
+	Revisions []MessageRevision `json:"revisions,omitempty"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Действия фильтра запрещённых слов
+const (
+	// FilterActionReject отклоняет сообщение
+	FilterActionReject = "reject"
+	// FilterActionMask заменяет найденные слова звёздочками
+	FilterActionMask = "mask"
+	// FilterActionFlag сохраняет сообщение без изменений и помечает его для модераторов
+	FilterActionFlag = "flag"
+)
+
+// MessageFilters настройки фильтрации сообщений перед сохранением. Нулевое значение поля отключает
+// соответствующий фильтр; комната без собственных настроек использует настройки сервера
+type MessageFilters struct {
+	// Words запрещённые слова; сравниваются целиком и без учёта регистра
+	Words []string `json:"words"`
+	// WordAction что делать с сообщением, содержащим запрещённое слово: reject, mask или flag
+	WordAction string `json:"word_action"`
+	// RepeatLimit сколько одинаковых сообщений отправитель может отправить за RepeatWindow секунд
+	RepeatLimit  int `json:"repeat_limit"`
+	RepeatWindow int `json:"repeat_window"`
+	// LinkSpam помечает сообщения, похожие на рассылку ссылок
+	LinkSpam bool `json:"link_spam"`
+	// MaxLinks наибольшее число ссылок в одном сообщении
+	MaxLinks int `json:"max_links"`
+}
+
+// MessageFlag пометка фильтра: сообщение сохранено, но вызвало подозрение
+type MessageFlag struct {
+	Stage  string `json:"stage"`
+	Reason string `json:"reason"`
+}
+
+// FlaggedMessage сообщение с пометками фильтров для модераторов комнаты
+type FlaggedMessage struct {
+	Message   Message       `json:"message"`
+	Flags     []MessageFlag `json:"flags"`
+	FlaggedAt time.Time     `json:"flagged_at"`
+}
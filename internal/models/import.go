@@ -0,0 +1,28 @@
+package models
+
+// ImportReport итоги импорта истории из выгрузки стороннего мессенджера
+type ImportReport struct {
+	Source string         `json:"source"`
+	Chats  []ImportedChat `json:"chats"`
+	// UnmappedUsers внешние ID пользователей без соответствия в файле сопоставления;
+	// их сообщения пропущены и будут перенесены повторным импортом после дополнения файла
+	UnmappedUsers []string `json:"unmapped_users,omitempty"`
+}
+
+// ImportedChat итоги импорта одной переписки
+type ImportedChat struct {
+	ExternalID string `json:"external_id"`
+	Name       string `json:"name,omitempty"`
+	// RoomID задаётся для комнаты, UserIDs — для личной переписки
+	RoomID  int64   `json:"room_id,omitempty"`
+	UserIDs []int64 `json:"user_ids,omitempty"`
+	// Imported перенесено в этот запуск, AlreadyImported — в предыдущие
+	Imported        int `json:"imported"`
+	AlreadyImported int `json:"already_imported"`
+	// Skipped сообщения, которые перенести нельзя: автор не сопоставлен или текст не проходит проверку
+	Skipped int `json:"skipped"`
+	// MissingFiles вложения, содержимого которых нет в выгрузке или которые не удалось сохранить
+	MissingFiles int `json:"missing_files"`
+	// Error причина, по которой переписка не импортирована целиком
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// LinkPreview карточка первой ссылки в сообщении, собранная из OpenGraph и Twitter Cards
+type LinkPreview struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// MessageLinkPreview превью, прикреплённое к сообщению после отправки
+type MessageLinkPreview struct {
+	MessageID   int64        `json:"message_id"`
+	LinkPreview *LinkPreview `json:"link_preview"`
+}
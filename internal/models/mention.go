@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Виды упоминаний
+const (
+	MentionUser = "user"
+	MentionRoom = "room"
+	MentionHere = "here"
+)
+
+// Mention упоминание пользователя в сообщении; из упоминаний складывается его входящий список
+type Mention struct {
+	MessageID int64      `json:"message_id"`
+	RoomID    int64      `json:"room_id,omitempty"`
+	SenderID  int64      `json:"sender_id"`
+	Kind      string     `json:"kind"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	Message   *Message   `json:"message,omitempty"`
+}
+
+// MentionInbox страница входящих упоминаний
+type MentionInbox struct {
+	Mentions    []Mention `json:"mentions"`
+	UnreadCount int       `json:"unread_count"`
+	// NextBeforeID передаётся в before для получения следующей страницы; 0 — страниц больше нет
+	NextBeforeID int64 `json:"next_before_id,omitempty"`
+}
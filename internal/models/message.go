@@ -3,12 +3,104 @@ package models
 import "time"
 
 type Message struct {
-	ID         int64
-	SenderID   int64
-	ReceiverID int64
-	Content    string
-	SentAt     *time.Time
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
-	DeletedAt  *time.Time
+	ID          int64  `json:"id"`
+	SenderID    int64  `json:"sender_id"`
+	ReceiverID  int64  `json:"receiver_id"`
+	RoomID      int64  `json:"room_id,omitempty"`
+	ClientMsgID string `json:"client_msg_id,omitempty"`
+	// Seq номер сообщения в переписке: растёт на единицу с каждым сообщением комнаты или личной переписки.
+	// Пропуск номера в событиях означает, что клиент пропустил сообщения и должен догрузить историю
+	Seq         int64        `json:"seq"`
+	Content     string       `json:"content"`
+	RichContent *RichContent `json:"rich_content,omitempty"`
+	SentAt      *time.Time   `json:"sent_at,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	DeletedAt   *time.Time   `json:"deleted_at,omitempty"`
+	DeletedBy   int64        `json:"deleted_by,omitempty"`
+	Edited      bool         `json:"edited"`
+	EditedAt    *time.Time   `json:"edited_at,omitempty"`
+	ExpiresAt   *time.Time   `json:"expires_at,omitempty"`
+	ViewOnce    bool         `json:"view_once,omitempty"`
+	// ReplyToID сообщение той же переписки, на которое дан ответ
+	ReplyToID int64 `json:"reply_to_id,omitempty"`
+	// ForwardedFrom задаётся для пересланных сообщений
+	ForwardedFrom *ForwardedFrom `json:"forwarded_from,omitempty"`
+
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	LinkPreview *LinkPreview      `json:"link_preview,omitempty"`
+	Pinned      bool              `json:"pinned,omitempty"`
+	PollResults *PollResults      `json:"poll_results,omitempty"`
+	Reactions   []ReactionSummary `json:"reactions,omitempty"`
+	// Status и SeenBy заполняются только для собственных сообщений пользователя, запросившего историю
+	Status string   `json:"status,omitempty"`
+	SeenBy []SeenBy `json:"seen_by,omitempty"`
+}
+
+// ForwardedFrom происхождение пересланного сообщения. При повторной пересылке указывает на самый первый оригинал
+type ForwardedFrom struct {
+	MessageID int64 `json:"message_id"`
+	SenderID  int64 `json:"sender_id"`
+	// ReceiverID задаётся для личной переписки, RoomID — для комнаты
+	ReceiverID int64     `json:"receiver_id,omitempty"`
+	RoomID     int64     `json:"room_id,omitempty"`
+	SentAt     time.Time `json:"sent_at"`
+}
+
+// MessageRevision предыдущая версия отредактированного сообщения
+type MessageRevision struct {
+	ID          int64        `json:"id"`
+	MessageID   int64        `json:"message_id"`
+	EditorID    int64        `json:"editor_id"`
+	Content     string       `json:"content"`
+	RichContent *RichContent `json:"rich_content,omitempty"`
+	EditedAt    time.Time    `json:"edited_at"`
+}
+
+// MessageDeletion описывает удаление сообщения для событий в реальном времени
+type MessageDeletion struct {
+	MessageID int64  `json:"message_id"`
+	Scope     string `json:"scope"`
+	DeletedBy int64  `json:"deleted_by"`
+}
+
+// MessageExpiration окончательное удаление сообщения по истечении времени жизни
+type MessageExpiration struct {
+	MessageID int64 `json:"message_id"`
+}
+
+// Reaction реакция пользователя на сообщение
+type Reaction struct {
+	MessageID int64  `json:"message_id"`
+	UserID    int64  `json:"user_id"`
+	Emoji     string `json:"emoji"`
+}
+
+// ReactionSummary количество реакций одного вида на сообщение
+type ReactionSummary struct {
+	Emoji       string `json:"emoji"`
+	Count       int    `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
+// Статусы доставки сообщения получателю в порядке возрастания
+const (
+	ReceiptSent      = "sent"
+	ReceiptDelivered = "delivered"
+	ReceiptRead      = "read"
+)
+
+// Receipt изменение статуса доставки сообщения конкретному получателю
+type Receipt struct {
+	MessageID int64     `json:"message_id"`
+	SenderID  int64     `json:"sender_id"`
+	UserID    int64     `json:"user_id"`
+	Status    string    `json:"status"`
+	At        time.Time `json:"at"`
+}
+
+// SeenBy участник комнаты, прочитавший сообщение
+type SeenBy struct {
+	UserID int64     `json:"user_id"`
+	ReadAt time.Time `json:"read_at"`
 }
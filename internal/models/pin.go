@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Pin закреплённое в переписке сообщение
+type Pin struct {
+	MessageID int64     `json:"message_id"`
+	RoomID    int64     `json:"room_id,omitempty"`
+	PinnedBy  int64     `json:"pinned_by"`
+	PinnedAt  time.Time `json:"pinned_at"`
+}
+
+// PinnedMessage закрепление вместе с самим сообщением
+type PinnedMessage struct {
+	Pin
+	Message Message `json:"message"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// PollResults текущие итоги опроса
+type PollResults struct {
+	MessageID   int64              `json:"message_id"`
+	Options     []PollOptionResult `json:"options"`
+	TotalVoters int                `json:"total_voters"`
+	// MyVotes варианты, выбранные пользователем, запросившим итоги; в событиях не заполняется
+	MyVotes  []int      `json:"my_votes,omitempty"`
+	ClosesAt *time.Time `json:"closes_at,omitempty"`
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+}
+
+// PollOptionResult голоса за вариант опроса
+type PollOptionResult struct {
+	Option int `json:"option"`
+	Votes  int `json:"votes"`
+	// Voters проголосовавшие за вариант; в анонимных опросах не заполняется
+	Voters []int64 `json:"voters,omitempty"`
+}
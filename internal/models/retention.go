@@ -0,0 +1,12 @@
+package models
+
+// RetentionPolicy сроки хранения сообщений переписки в днях. nil — действует глобальная настройка,
+// 0 — сообщения хранятся бессрочно
+type RetentionPolicy struct {
+	// DeletedDays через сколько дней после удаления сообщение стирается окончательно
+	DeletedDays *int `json:"deleted_retention_days"`
+	// MessageDays через сколько дней после отправки стирается любое сообщение
+	MessageDays *int `json:"message_retention_days"`
+	// LegalHold переписка исключена из очистки по срокам хранения
+	LegalHold bool `json:"legal_hold"`
+}
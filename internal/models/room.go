@@ -17,6 +17,14 @@ type RoomMember struct {
 	IsAdmin  bool      `json:"is_admin"`
 }
 
+// MembershipChange полезная нагрузка событий member.joined и member.left
+type MembershipChange struct {
+	RoomID int64 `json:"room_id"`
+	UserID int64 `json:"user_id"`
+	// ChangedBy кто добавил или исключил участника; совпадает с UserID, если тот вышел сам
+	ChangedBy int64 `json:"changed_by"`
+}
+
 type UserRoom struct {
 	Room        Room     `json:"room"`
 	UnreadCount int      `json:"unread_count"`
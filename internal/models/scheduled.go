@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Статусы отложенного сообщения; отправленные сообщения из списка удаляются
+const (
+	ScheduledPending = "pending"
+	ScheduledFailed  = "failed"
+)
+
+// ScheduledMessage сообщение, которое будет отправлено в указанное время
+type ScheduledMessage struct {
+	ID            int64        `json:"id"`
+	SenderID      int64        `json:"sender_id"`
+	ReceiverID    int64        `json:"receiver_id,omitempty"`
+	RoomID        int64        `json:"room_id,omitempty"`
+	Content       string       `json:"content"`
+	RichContent   *RichContent `json:"rich_content,omitempty"`
+	AttachmentIDs []int64      `json:"attachment_ids,omitempty"`
+	SendAt        time.Time    `json:"send_at"`
+	Status        string       `json:"status"`
+	// Error причина, по которой сообщение не удалось отправить
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
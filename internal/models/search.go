@@ -0,0 +1,15 @@
+package models
+
+// SearchResult найденное сообщение с подсвеченным фрагментом
+type SearchResult struct {
+	Message Message `json:"message"`
+	// Snippet фрагмент в HTML: текст экранирован, совпадения обёрнуты в <mark>
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+// SearchPage страница результатов поиска; NextCursor пуст на последней странице
+type SearchPage struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
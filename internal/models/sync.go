@@ -0,0 +1,26 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SyncEvent событие из журнала пользователя
+type SyncEvent struct {
+	Seq       int64           `json:"seq"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// SyncBatch события, пропущенные клиентом, в порядке возрастания seq
+type SyncBatch struct {
+	Events []SyncEvent `json:"events"`
+	// LastSeq номер последнего события пользователя на момент запроса
+	LastSeq int64 `json:"last_seq"`
+	// HasMore после последнего события пачки есть ещё события; следующий запрос — с since = seq последнего
+	HasMore bool `json:"has_more"`
+	// ResyncRequired часть пропущенных событий уже удалена из журнала: клиент должен заново загрузить
+	// переписки и продолжить синхронизацию с LastSeq
+	ResyncRequired bool `json:"resync_required"`
+}
@@ -0,0 +1,276 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"messanger/internal/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type AttachmentRepo interface {
+	CreateAttachment(ctx context.Context, params CreateAttachmentParams) (*models.Attachment, error)
+	GetAttachment(ctx context.Context, attachmentID int64) (*models.Attachment, error)
+}
+
+type attachmentRepo struct {
+	db *sqlx.DB
+}
+
+func NewAttachmentRepo(db *sqlx.DB) AttachmentRepo {
+	return &attachmentRepo{db: db}
+}
+
+type attachment struct {
+	ID         int64     `db:"id"`
+	MessageID  *int64    `db:"message_id"`
+	UploaderID int64     `db:"uploader_id"`
+	FileName   string    `db:"file_name"`
+	MimeType   string    `db:"mime_type"`
+	Size       int64     `db:"size"`
+	SHA256     string    `db:"sha256"`
+	StorageKey string    `db:"storage_key"`
+	CreatedAt  time.Time `db:"created_at"`
+	Width      *int      `db:"width"`
+	Height     *int      `db:"height"`
+	Blurhash   *string   `db:"blurhash"`
+}
+
+const attachmentColumns = `id, message_id, uploader_id, file_name, mime_type, size, sha256, storage_key, created_at, width, height, blurhash`
+
+func (a attachment) toModel() models.Attachment {
+	result := models.Attachment{
+		ID:         a.ID,
+		UploaderID: a.UploaderID,
+		FileName:   a.FileName,
+		MimeType:   a.MimeType,
+		Size:       a.Size,
+		SHA256:     a.SHA256,
+		StorageKey: a.StorageKey,
+		CreatedAt:  a.CreatedAt,
+	}
+	if a.MessageID != nil {
+		result.MessageID = *a.MessageID
+	}
+	if a.Width != nil && a.Height != nil {
+		result.Width, result.Height = *a.Width, *a.Height
+	}
+	if a.Blurhash != nil {
+		result.Blurhash = *a.Blurhash
+	}
+	return result
+}
+
+type thumbnail struct {
+	AttachmentID int64  `db:"attachment_id"`
+	Size         int    `db:"size"`
+	Width        int    `db:"width"`
+	Height       int    `db:"height"`
+	MimeType     string `db:"mime_type"`
+	StorageKey   string `db:"storage_key"`
+}
+
+func (t thumbnail) toModel() models.Thumbnail {
+	return models.Thumbnail{
+		Size:       t.Size,
+		Width:      t.Width,
+		Height:     t.Height,
+		MimeType:   t.MimeType,
+		StorageKey: t.StorageKey,
+	}
+}
+
+type CreateAttachmentParams struct {
+	UploaderID int64
+	FileName   string
+	MimeType   string
+	Size       int64
+	SHA256     string
+	StorageKey string
+	// Width, Height и Blurhash задаются для изображений, 0 и пустая строка сохраняются как NULL
+	Width      int
+	Height     int
+	Blurhash   string
+	Thumbnails []models.Thumbnail
+}
+
+const createAttachmentQuery = `
+INSERT INTO attachments (uploader_id, file_name, mime_type, size, sha256, storage_key, width, height, blurhash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING ` + attachmentColumns
+
+const createThumbnailQuery = `
+INSERT INTO attachment_thumbnails (attachment_id, size, width, height, mime_type, storage_key)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+func (r *attachmentRepo) CreateAttachment(ctx context.Context, params CreateAttachmentParams) (*models.Attachment, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var a attachment
+	err = tx.GetContext(ctx, &a, createAttachmentQuery,
+		params.UploaderID,
+		params.FileName,
+		params.MimeType,
+		params.Size,
+		params.SHA256,
+		params.StorageKey,
+		nullableInt(params.Width),
+		nullableInt(params.Height),
+		nullableString(params.Blurhash),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	for _, t := range params.Thumbnails {
+		if _, err = tx.ExecContext(ctx, createThumbnailQuery, a.ID, t.Size, t.Width, t.Height, t.MimeType, t.StorageKey); err != nil {
+			return nil, fmt.Errorf("failed to create thumbnail: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result := a.toModel()
+	result.Thumbnails = params.Thumbnails
+	return &result, nil
+}
+
+// nullableInt превращает нулевое значение в NULL
+func nullableInt(v int) *int {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+const getAttachmentQuery = `
+SELECT ` + attachmentColumns + ` FROM attachments
+WHERE id = $1
+`
+
+func (r *attachmentRepo) GetAttachment(ctx context.Context, attachmentID int64) (*models.Attachment, error) {
+	var a attachment
+	if err := r.db.GetContext(ctx, &a, getAttachmentQuery, attachmentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	result := []models.Attachment{a.toModel()}
+	if err := attachThumbnails(ctx, r.db, result); err != nil {
+		return nil, err
+	}
+	return &result[0], nil
+}
+
+// Привязать можно только собственные вложения, ещё не отправленные ни с одним сообщением
+const linkAttachmentsQuery = `
+UPDATE attachments
+SET message_id = $1
+WHERE id = ANY($2) AND uploader_id = $3 AND message_id IS NULL
+`
+
+func linkAttachments(ctx context.Context, tx *sqlx.Tx, messageID, uploaderID int64, attachmentIDs []int64) error {
+	res, err := tx.ExecContext(ctx, linkAttachmentsQuery, messageID, pq.Array(attachmentIDs), uploaderID)
+	if err != nil {
+		return fmt.Errorf("failed to link attachments: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected != int64(len(attachmentIDs)) {
+		return ErrAttachmentUnavailable
+	}
+	return nil
+}
+
+const getMessagesAttachmentsQuery = `
+SELECT ` + attachmentColumns + ` FROM attachments
+WHERE message_id = ANY($1)
+ORDER BY id
+`
+
+// attachAttachments дополняет сообщения их вложениями
+func attachAttachments(ctx context.Context, q sqlx.QueryerContext, messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(messages))
+	byID := make(map[int64]*models.Message, len(messages))
+	for i := range messages {
+		// Вложения удалённого сообщения остаются только под legal hold и участникам не показываются
+		if messages[i].DeletedAt != nil {
+			continue
+		}
+		ids = append(ids, messages[i].ID)
+		byID[messages[i].ID] = &messages[i]
+	}
+
+	var rows []attachment
+	if err := sqlx.SelectContext(ctx, q, &rows, getMessagesAttachmentsQuery, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to get attachments: %w", err)
+	}
+
+	attachments := make([]models.Attachment, len(rows))
+	for i, a := range rows {
+		attachments[i] = a.toModel()
+	}
+	if err := attachThumbnails(ctx, q, attachments); err != nil {
+		return err
+	}
+
+	for _, a := range attachments {
+		msg := byID[a.MessageID]
+		msg.Attachments = append(msg.Attachments, a)
+	}
+
+	return nil
+}
+
+const getThumbnailsQuery = `
+SELECT attachment_id, size, width, height, mime_type, storage_key FROM attachment_thumbnails
+WHERE attachment_id = ANY($1)
+ORDER BY size
+`
+
+// attachThumbnails дополняет вложения их уменьшенными копиями
+func attachThumbnails(ctx context.Context, q sqlx.QueryerContext, attachments []models.Attachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(attachments))
+	byID := make(map[int64]*models.Attachment, len(attachments))
+	for i := range attachments {
+		ids[i] = attachments[i].ID
+		byID[attachments[i].ID] = &attachments[i]
+	}
+
+	var thumbnails []thumbnail
+	if err := sqlx.SelectContext(ctx, q, &thumbnails, getThumbnailsQuery, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to get thumbnails: %w", err)
+	}
+
+	for _, t := range thumbnails {
+		a := byID[t.AttachmentID]
+		a.Thumbnails = append(a.Thumbnails, t.toModel())
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+const lockBlobQuery = `SELECT pg_advisory_lock(hashtextextended('blob:' || $1, 0))`
+
+const unlockBlobsQuery = `SELECT pg_advisory_unlock_all()`
+
+// LockBlobs блокирует объекты хранилища с заданным содержимым до вызова возвращённой функции.
+// Загрузка держит блокировку от проверки наличия объекта до записи вложения, очистка — от поиска
+// осиротевших ключей до удаления объектов, поэтому очистка не удалит объект, который только что
+// переиспользовала загрузка. Блокировки сеансовые, поэтому под них выделяется отдельное соединение
+func (m messageRepo) LockBlobs(ctx context.Context, hashes []string) (func(), error) {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	release := func() {
+		// Контекст запроса мог быть уже отменён, а блокировки нужно снять в любом случае
+		if _, err := conn.ExecContext(context.Background(), unlockBlobsQuery); err != nil {
+			// Соединение с неснятыми блокировками нельзя возвращать в пул: ErrBadConn закрывает его
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+
+	// Единый порядок захвата исключает взаимные блокировки
+	for _, hash := range slices.Compact(slices.Sorted(slices.Values(hashes))) {
+		if _, err = conn.ExecContext(ctx, lockBlobQuery, hash); err != nil {
+			release()
+			return nil, fmt.Errorf("failed to lock blob: %w", err)
+		}
+	}
+	return release, nil
+}
+
+// Из-за дедупликации один объект может принадлежать нескольким вложениям
+const getOrphanedStorageKeysQuery = `
+SELECT key FROM UNNEST($1::TEXT[]) AS key
+WHERE NOT EXISTS (SELECT 1 FROM attachments WHERE storage_key = key)
+AND NOT EXISTS (SELECT 1 FROM attachment_thumbnails WHERE storage_key = key)
+`
+
+// GetOrphanedStorageKeys возвращает ключи, на которые не ссылается ни одно вложение.
+// Вызывается под LockBlobs, иначе результат может устареть к моменту удаления объектов
+func (m messageRepo) GetOrphanedStorageKeys(ctx context.Context, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	var orphaned []string
+	if err := m.db.SelectContext(ctx, &orphaned, getOrphanedStorageKeysQuery, pq.Array(keys)); err != nil {
+		return nil, fmt.Errorf("failed to get orphaned storage keys: %w", err)
+	}
+	return orphaned, nil
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"messanger/internal/models"
+)
+
+type conversationRow struct {
+	message
+	ConversationRoomID     int64 `db:"conversation_room_id"`
+	ConversationReceiverID int64 `db:"conversation_receiver_id"`
+	UnreadCount            int   `db:"unread_count"`
+}
+
+// Собеседник личной переписки вычисляется относительно пользователя; для комнат он равен 0
+const getConversationsQuery = `
+WITH visible AS (
+    SELECT ` + messageColumns + `,
+        COALESCE(room_id, 0) AS conversation_room_id,
+        CASE WHEN room_id IS NOT NULL THEN 0 WHEN sender_id = $1 THEN receiver_id ELSE sender_id END AS conversation_receiver_id
+    FROM messages
+    WHERE (room_id IN (SELECT room_id FROM room_members WHERE user_id = $1)
+        OR (room_id IS NULL AND (sender_id = $1 OR receiver_id = $1)))
+    AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = messages.id AND h.user_id = $1)
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+),
+unread AS (
+    SELECT conversation_room_id, conversation_receiver_id, COUNT(*) AS unread_count
+    FROM visible
+    JOIN message_receipts r ON r.message_id = visible.id AND r.user_id = $1 AND r.status <> 'read'
+    WHERE visible.deleted_at IS NULL
+    GROUP BY conversation_room_id, conversation_receiver_id
+)
+SELECT * FROM (
+    SELECT DISTINCT ON (conversation_room_id, conversation_receiver_id)
+        visible.*, COALESCE(unread.unread_count, 0) AS unread_count
+    FROM visible
+    LEFT JOIN unread USING (conversation_room_id, conversation_receiver_id)
+    ORDER BY conversation_room_id, conversation_receiver_id, visible.id DESC
+) last
+ORDER BY last.id DESC
+`
+
+// GetConversations возвращает переписки пользователя с последним видимым ему сообщением
+// и числом непрочитанных, начиная с переписки с самым новым сообщением
+func (m messageRepo) GetConversations(ctx context.Context, userID int64) ([]models.Conversation, error) {
+	var rows []conversationRow
+	if err := m.db.SelectContext(ctx, &rows, getConversationsQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to get conversations: %w", err)
+	}
+
+	messages := make([]models.Message, len(rows))
+	for i, row := range rows {
+		messages[i] = row.message.toModel()
+	}
+	if err := attachAttachments(ctx, m.db, messages); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Conversation, len(rows))
+	for i, row := range rows {
+		result[i] = models.Conversation{
+			ReceiverID:  row.ConversationReceiverID,
+			RoomID:      row.ConversationRoomID,
+			LastMessage: &messages[i],
+			UnreadCount: row.UnreadCount,
+		}
+	}
+	return result, nil
+}
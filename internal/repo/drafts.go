@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"messanger/internal/models"
+)
+
+type DraftParams struct {
+	UserID int64
+	// RoomID задаётся для комнаты; иначе черновик относится к личной переписке с ReceiverID
+	RoomID     int64
+	ReceiverID int64
+}
+
+type draft struct {
+	RoomID     int64     `db:"room_id"`
+	ReceiverID int64     `db:"receiver_id"`
+	Content    string    `db:"content"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+func (d draft) toModel() models.Draft {
+	return models.Draft{
+		ReceiverID: d.ReceiverID,
+		RoomID:     d.RoomID,
+		Content:    d.Content,
+		UpdatedAt:  d.UpdatedAt,
+	}
+}
+
+const saveDraftQuery = `
+INSERT INTO drafts (user_id, room_id, receiver_id, content)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, room_id, receiver_id) DO UPDATE
+SET content = EXCLUDED.content, updated_at = CURRENT_TIMESTAMP
+RETURNING room_id, receiver_id, content, updated_at
+`
+
+// SaveDraft создаёт или заменяет черновик пользователя в переписке
+func (m messageRepo) SaveDraft(ctx context.Context, params DraftParams, content string) (*models.Draft, error) {
+	var d draft
+	if err := m.db.GetContext(ctx, &d, saveDraftQuery, params.UserID, params.RoomID, params.ReceiverID, content); err != nil {
+		return nil, fmt.Errorf("failed to save draft: %w", err)
+	}
+
+	result := d.toModel()
+	return &result, nil
+}
+
+const deleteDraftQuery = `
+DELETE FROM drafts
+WHERE user_id = $1 AND room_id = $2 AND receiver_id = $3
+`
+
+// DeleteDraft удаляет черновик и сообщает, был ли он
+func (m messageRepo) DeleteDraft(ctx context.Context, params DraftParams) (bool, error) {
+	res, err := m.db.ExecContext(ctx, deleteDraftQuery, params.UserID, params.RoomID, params.ReceiverID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete draft: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+const getDraftsQuery = `
+SELECT room_id, receiver_id, content, updated_at FROM drafts
+WHERE user_id = $1
+ORDER BY updated_at DESC
+`
+
+// GetDrafts возвращает все черновики пользователя, начиная с последнего изменённого
+func (m messageRepo) GetDrafts(ctx context.Context, userID int64) ([]models.Draft, error) {
+	var rows []draft
+	if err := m.db.SelectContext(ctx, &rows, getDraftsQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to get drafts: %w", err)
+	}
+
+	result := make([]models.Draft, len(rows))
+	for i, row := range rows {
+		result[i] = row.toModel()
+	}
+	return result, nil
+}
@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"messanger/internal/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// EventRepo журнал событий пользователей. Каждый пользователь получает собственную копию события
+// со своим номером, поэтому клиент может запросить всё, что произошло после известного ему номера
+type EventRepo interface {
+	AppendEvent(ctx context.Context, userIDs []int64, eventType string, payload []byte) (map[int64]int64, error)
+	GetEventLogState(ctx context.Context, userID int64) (*EventLogState, error)
+	GetEvents(ctx context.Context, params GetEventsParams) ([]models.SyncEvent, error)
+	CompactEvents(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+type eventRepo struct {
+	db *sqlx.DB
+}
+
+func NewEventRepo(db *sqlx.DB) EventRepo {
+	return &eventRepo{db: db}
+}
+
+// Строка user_event_seqs блокируется до конца запроса, поэтому номера одного пользователя не повторяются
+// и не пропускаются даже при одновременной записи
+const appendEventQuery = `
+WITH seqs AS (
+    INSERT INTO user_event_seqs (user_id, last_seq)
+    SELECT UNNEST($1::BIGINT[]), 1
+    ON CONFLICT (user_id) DO UPDATE SET last_seq = user_event_seqs.last_seq + 1
+    RETURNING user_id, last_seq
+)
+INSERT INTO user_events (user_id, seq, type, payload)
+SELECT user_id, last_seq, $2, $3 FROM seqs
+RETURNING user_id, seq
+`
+
+// AppendEvent записывает событие в журналы пользователей и возвращает присвоенные номера.
+// userIDs не должны повторяться
+func (r *eventRepo) AppendEvent(ctx context.Context, userIDs []int64, eventType string, payload []byte) (map[int64]int64, error) {
+	var rows []struct {
+		UserID int64 `db:"user_id"`
+		Seq    int64 `db:"seq"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, appendEventQuery, pq.Array(userIDs), eventType, string(payload)); err != nil {
+		return nil, fmt.Errorf("failed to append event: %w", err)
+	}
+
+	seqs := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		seqs[row.UserID] = row.Seq
+	}
+	return seqs, nil
+}
+
+// EventLogState положение журнала пользователя
+type EventLogState struct {
+	// LastSeq номер последнего записанного события
+	LastSeq int64 `db:"last_seq"`
+	// CompactedSeq номер последнего события, удалённого при сжатии; более ранние события недоступны
+	CompactedSeq int64 `db:"compacted_seq"`
+}
+
+const getEventLogStateQuery = `
+SELECT last_seq, compacted_seq FROM user_event_seqs
+WHERE user_id = $1
+`
+
+// GetEventLogState возвращает положение журнала; у пользователя без событий оно нулевое
+func (r *eventRepo) GetEventLogState(ctx context.Context, userID int64) (*EventLogState, error) {
+	var state EventLogState
+	if err := r.db.GetContext(ctx, &state, getEventLogStateQuery, userID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get event log state: %w", err)
+	}
+	return &state, nil
+}
+
+type GetEventsParams struct {
+	UserID int64
+	// Since возвращаются события с номером больше указанного
+	Since int64
+	Limit int
+}
+
+type eventRow struct {
+	Seq       int64     `db:"seq"`
+	Type      string    `db:"type"`
+	Payload   []byte    `db:"payload"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+const getEventsQuery = `
+SELECT seq, type, payload, created_at FROM user_events
+WHERE user_id = $1 AND seq > $2
+ORDER BY seq
+LIMIT $3
+`
+
+// GetEvents возвращает события пользователя после Since в порядке номеров
+func (r *eventRepo) GetEvents(ctx context.Context, params GetEventsParams) ([]models.SyncEvent, error) {
+	var rows []eventRow
+	if err := r.db.SelectContext(ctx, &rows, getEventsQuery, params.UserID, params.Since, params.Limit); err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	result := make([]models.SyncEvent, len(rows))
+	for i, row := range rows {
+		result[i] = models.SyncEvent{
+			Seq:       row.Seq,
+			Type:      row.Type,
+			Payload:   row.Payload,
+			CreatedAt: row.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+// Номер последнего удалённого события запоминается, чтобы отстающий клиент узнал о пропуске
+const compactEventsQuery = `
+WITH deleted AS (
+    DELETE FROM user_events
+    WHERE (user_id, seq) IN (
+        SELECT user_id, seq FROM user_events
+        WHERE created_at < $1
+        LIMIT $2
+    )
+    RETURNING user_id, seq
+), compacted AS (
+    UPDATE user_event_seqs s SET compacted_seq = GREATEST(s.compacted_seq, d.max_seq)
+    FROM (SELECT user_id, MAX(seq) AS max_seq FROM deleted GROUP BY user_id) d
+    WHERE s.user_id = d.user_id
+)
+SELECT COUNT(*) FROM deleted
+`
+
+// CompactEvents удаляет до limit событий, записанных раньше before, и возвращает их число
+func (r *eventRepo) CompactEvents(ctx context.Context, before time.Time, limit int) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, compactEventsQuery, before, limit); err != nil {
+		return 0, fmt.Errorf("failed to compact events: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"messanger/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type SetMessageTTLParams struct {
+	// RoomID задаётся для комнаты; иначе настройка относится к личной переписке UserIDs
+	RoomID  int64
+	UserIDs [2]int64
+	// TTL время жизни новых сообщений; 0 отключает исчезающие сообщения
+	TTL time.Duration
+}
+
+const setRoomMessageTTLQuery = `
+UPDATE rooms SET message_ttl = $2
+WHERE id = $1
+`
+
+const setDirectMessageTTLQuery = `
+INSERT INTO direct_chat_settings (user_low, user_high, message_ttl)
+VALUES (LEAST($1::BIGINT, $2::BIGINT), GREATEST($1::BIGINT, $2::BIGINT), $3)
+ON CONFLICT (user_low, user_high) DO UPDATE SET message_ttl = EXCLUDED.message_ttl
+`
+
+// SetMessageTTL задаёт время жизни сообщений, отправленных в переписку после изменения
+func (m messageRepo) SetMessageTTL(ctx context.Context, params SetMessageTTLParams) error {
+	ttl := nullableInt(int(params.TTL.Seconds()))
+
+	if params.RoomID != 0 {
+		res, err := m.db.ExecContext(ctx, setRoomMessageTTLQuery, params.RoomID, ttl)
+		if err != nil {
+			return fmt.Errorf("failed to set room message ttl: %w", err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if affected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	}
+
+	if _, err := m.db.ExecContext(ctx, setDirectMessageTTLQuery, params.UserIDs[0], params.UserIDs[1], ttl); err != nil {
+		return fmt.Errorf("failed to set direct message ttl: %w", err)
+	}
+	return nil
+}
+
+// PurgeResult удалённые сообщения и ключи объектов хранилища, на которые они ссылались.
+// Объекты могут быть нужны другим вложениям: удалять можно только те, что вернёт GetOrphanedStorageKeys
+type PurgeResult struct {
+	Messages    []models.Message
+	StorageKeys []string
+}
+
+// SKIP LOCKED позволяет нескольким экземплярам очищать сообщения параллельно. Переписки под legal hold
+// пропускаются: истёкшие сообщения в них скрыты от участников, но хранятся до снятия удержания
+const lockExpiredMessagesQuery = `
+SELECT m.id FROM messages m
+` + conversationSettingsJoin + `
+WHERE m.expires_at <= CURRENT_TIMESTAMP
+AND NOT ` + legalHold + `
+ORDER BY m.expires_at
+LIMIT $1
+FOR UPDATE OF m SKIP LOCKED
+`
+
+const getMessageStorageKeysQuery = `
+SELECT a.storage_key FROM attachments a
+WHERE a.message_id = ANY($1)
+UNION
+SELECT t.storage_key FROM attachment_thumbnails t
+JOIN attachments a ON a.id = t.attachment_id
+WHERE a.message_id = ANY($1)
+`
+
+const purgeMessagesQuery = `
+DELETE FROM messages
+WHERE id = ANY($1)
+RETURNING ` + messageColumns
+
+// PurgeExpiredMessages окончательно удаляет истёкшие сообщения. Правки, реакции, статусы доставки,
+// упоминания и вложения удаляются каскадно; объекты хранилища удаляет вызывающий по StorageKeys
+func (m messageRepo) PurgeExpiredMessages(ctx context.Context, limit int) (*PurgeResult, error) {
+	return m.purgeMessages(ctx, lockExpiredMessagesQuery, limit)
+}
+
+// purgeMessages удаляет пачку сообщений, которые выбирает и блокирует lockQuery
+func (m messageRepo) purgeMessages(ctx context.Context, lockQuery string, args ...any) (*PurgeResult, error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ids []int64
+	if err = tx.SelectContext(ctx, &ids, lockQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to lock messages: %w", err)
+	}
+	if len(ids) == 0 {
+		return &PurgeResult{}, nil
+	}
+
+	var keys []string
+	if err = tx.SelectContext(ctx, &keys, getMessageStorageKeysQuery, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("failed to get storage keys: %w", err)
+	}
+
+	var rows []message
+	if err = tx.SelectContext(ctx, &rows, purgeMessagesQuery, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("failed to purge messages: %w", err)
+	}
+
+	result := &PurgeResult{Messages: make([]models.Message, len(rows)), StorageKeys: keys}
+	for i, row := range rows {
+		result.Messages[i] = row.toModel()
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeExpiredMessages_LegalHold(t *testing.T) {
+	db := newTestDB(t)
+	r := messageRepo{db: db}
+	ctx := context.Background()
+
+	insertExpired := func(roomID int64) int64 {
+		var id int64
+		err := db.Get(&id, `
+			INSERT INTO messages (sender_id, receiver_id, room_id, content, seq, expires_at)
+			VALUES (1, 0, $1, 'secret', 1, NOW() - INTERVAL '1 minute')
+			RETURNING id`, roomID)
+		require.NoError(t, err)
+		return id
+	}
+	heldID := insertExpired(createTestRoom(t, db, true))
+	freeID := insertExpired(createTestRoom(t, db, false))
+
+	for {
+		result, err := r.PurgeExpiredMessages(ctx, 100)
+		require.NoError(t, err)
+		if len(result.Messages) == 0 {
+			break
+		}
+	}
+
+	var content string
+	require.NoError(t, db.Get(&content, `SELECT content FROM messages WHERE id = $1`, heldID))
+	assert.Equal(t, "secret", content)
+
+	_, err := r.GetMessageByID(ctx, freeID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDeleteMessageForEveryone_LegalHold(t *testing.T) {
+	db := newTestDB(t)
+	r := messageRepo{db: db}
+	ctx := context.Background()
+
+	var id int64
+	err := db.Get(&id, `
+		INSERT INTO messages (sender_id, receiver_id, room_id, content, seq)
+		VALUES (1, 0, $1, 'secret', 1)
+		RETURNING id`, createTestRoom(t, db, true))
+	require.NoError(t, err)
+
+	deleted, err := r.DeleteMessageForEveryone(ctx, DeleteMessageParams{MessageID: id, DeletedBy: 1})
+	require.NoError(t, err)
+	assert.NotNil(t, deleted.Message.DeletedAt)
+	assert.Empty(t, deleted.Message.Content)
+	assert.Empty(t, deleted.StorageKeys)
+
+	var content string
+	require.NoError(t, db.Get(&content, `SELECT content FROM messages WHERE id = $1`, id))
+	assert.Equal(t, "secret", content)
+}
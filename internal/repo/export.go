@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"messanger/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type ExportMessagesParams struct {
+	// RoomID задаётся для комнаты; иначе выгружается личная переписка UserIDs
+	RoomID  int64
+	UserIDs [2]int64
+	// ViewerID пользователь, для которого строится выгрузка: скрытые им сообщения пропускаются,
+	// реакции и итоги опросов отмечаются относительно него. 0 — выгрузка без учёта пользователя
+	ViewerID int64
+	// AfterID выгрузка продолжается с сообщения, следующего за AfterID
+	AfterID int64
+	Limit   int
+}
+
+// Выгрузка идёт по возрастанию id, чтобы её можно было читать частями без смещений
+const exportMessagesQuery = `
+SELECT ` + messageColumns + ` FROM messages
+WHERE id > $1
+AND (($2::INT <> 0 AND room_id = $2)
+    OR ($2::INT = 0 AND room_id IS NULL AND ((sender_id = $3 AND receiver_id = $4) OR (sender_id = $4 AND receiver_id = $3))))
+AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = messages.id AND h.user_id = $5)
+AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+ORDER BY id
+LIMIT $6
+`
+
+// ExportMessages возвращает очередную часть переписки с вложениями, реакциями, итогами опросов и правками
+func (m messageRepo) ExportMessages(ctx context.Context, params ExportMessagesParams) ([]models.ExportedMessage, error) {
+	var rows []message
+	if err := m.db.SelectContext(ctx, &rows, exportMessagesQuery,
+		params.AfterID,
+		params.RoomID,
+		params.UserIDs[0],
+		params.UserIDs[1],
+		params.ViewerID,
+		params.Limit,
+	); err != nil {
+		return nil, fmt.Errorf("failed to export messages: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	messages := make([]models.Message, len(rows))
+	for i, row := range rows {
+		messages[i] = row.toModel()
+	}
+	if err := attachAttachments(ctx, m.db, messages); err != nil {
+		return nil, err
+	}
+	if err := m.attachReactions(ctx, messages, params.ViewerID); err != nil {
+		return nil, err
+	}
+	if err := m.attachPolls(ctx, messages, params.ViewerID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := m.getRevisions(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.ExportedMessage, len(messages))
+	for i, msg := range messages {
+		result[i] = models.ExportedMessage{Message: msg, Revisions: revisions[msg.ID]}
+	}
+	return result, nil
+}
+
+const getRevisionsByMessagesQuery = `
+SELECT id, message_id, editor_id, content, rich_content, created_at FROM message_edits
+WHERE message_id = ANY($1)
+ORDER BY id
+`
+
+// getRevisions возвращает правки сообщений, сгруппированные по ID сообщения
+func (m messageRepo) getRevisions(ctx context.Context, messages []models.Message) (map[int64][]models.MessageRevision, error) {
+	var ids []int64
+	for _, msg := range messages {
+		if msg.Edited {
+			ids = append(ids, msg.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var rows []messageRevision
+	if err := m.db.SelectContext(ctx, &rows, getRevisionsByMessagesQuery, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("failed to get message revisions: %w", err)
+	}
+
+	result := make(map[int64][]models.MessageRevision, len(ids))
+	for _, r := range rows {
+		result[r.MessageID] = append(result[r.MessageID], r.toModel())
+	}
+	return result, nil
+}
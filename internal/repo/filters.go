@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"messanger/internal/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const setMessageFiltersQuery = `
+UPDATE rooms SET message_filters = $2
+WHERE id = $1
+`
+
+// SetMessageFilters задаёт настройки фильтрации комнаты; nil возвращает комнату к настройкам сервера
+func (m messageRepo) SetMessageFilters(ctx context.Context, roomID int64, filters *models.MessageFilters) error {
+	var data *string
+	if filters != nil {
+		encoded, err := json.Marshal(filters)
+		if err != nil {
+			return fmt.Errorf("failed to encode message filters: %w", err)
+		}
+		s := string(encoded)
+		data = &s
+	}
+
+	res, err := m.db.ExecContext(ctx, setMessageFiltersQuery, roomID, data)
+	if err != nil {
+		return fmt.Errorf("failed to set message filters: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+const getMessageFiltersQuery = `
+SELECT message_filters FROM rooms
+WHERE id = $1
+`
+
+// GetMessageFilters возвращает собственные настройки фильтрации комнаты; nil — комната их не задавала
+func (m messageRepo) GetMessageFilters(ctx context.Context, roomID int64) (*models.MessageFilters, error) {
+	var data []byte
+	if err := m.db.GetContext(ctx, &data, getMessageFiltersQuery, roomID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get message filters: %w", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var filters models.MessageFilters
+	if err := json.Unmarshal(data, &filters); err != nil {
+		return nil, fmt.Errorf("failed to decode message filters: %w", err)
+	}
+	return &filters, nil
+}
+
+type CountRecentMessagesParams struct {
+	SenderID int64
+	Content  string
+	Since    time.Time
+}
+
+const countRecentMessagesQuery = `
+SELECT COUNT(*) FROM messages
+WHERE sender_id = $1 AND content = $2 AND created_at >= $3 AND deleted_at IS NULL
+`
+
+// CountRecentMessages считает сообщения отправителя с тем же текстом во всех переписках начиная с Since
+func (m messageRepo) CountRecentMessages(ctx context.Context, params CountRecentMessagesParams) (int, error) {
+	var count int
+	if err := m.db.GetContext(ctx, &count, countRecentMessagesQuery, params.SenderID, params.Content, params.Since); err != nil {
+		return 0, fmt.Errorf("failed to count recent messages: %w", err)
+	}
+	return count, nil
+}
+
+const createFlagsQuery = `
+INSERT INTO message_flags (message_id, stage, reason)
+SELECT $1, UNNEST($2::TEXT[]), UNNEST($3::TEXT[])
+`
+
+func createFlags(ctx context.Context, tx *sqlx.Tx, messageID int64, flags []models.MessageFlag) error {
+	stages := make([]string, len(flags))
+	reasons := make([]string, len(flags))
+	for i, flag := range flags {
+		stages[i] = flag.Stage
+		reasons[i] = flag.Reason
+	}
+
+	if _, err := tx.ExecContext(ctx, createFlagsQuery, messageID, pq.Array(stages), pq.Array(reasons)); err != nil {
+		return fmt.Errorf("failed to create message flags: %w", err)
+	}
+	return nil
+}
+
+type GetFlaggedMessagesParams struct {
+	RoomID int64
+	// BeforeID курсор: возвращаются сообщения с ID меньше указанного; 0 — с самого нового
+	BeforeID int64
+	Limit    int
+}
+
+type flaggedMessageRow struct {
+	message
+	FlaggedAt time.Time `db:"flagged_at"`
+}
+
+const getFlaggedMessagesQuery = `
+WITH fl AS (
+    SELECT message_id, MAX(created_at) AS flagged_at FROM message_flags
+    GROUP BY message_id
+)
+SELECT fl.flagged_at, ` + messageColumns + ` FROM messages
+JOIN fl ON fl.message_id = messages.id
+WHERE messages.room_id = $1 AND messages.deleted_at IS NULL
+AND ($2 = 0 OR messages.id < $2)
+ORDER BY messages.id DESC
+LIMIT $3
+`
+
+type messageFlagRow struct {
+	MessageID int64  `db:"message_id"`
+	Stage     string `db:"stage"`
+	Reason    string `db:"reason"`
+}
+
+const getMessageFlagsQuery = `
+SELECT message_id, stage, reason FROM message_flags
+WHERE message_id = ANY($1)
+ORDER BY id
+`
+
+// GetFlaggedMessages возвращает помеченные фильтрами сообщения комнаты, от новых к старым
+func (m messageRepo) GetFlaggedMessages(ctx context.Context, params GetFlaggedMessagesParams) ([]models.FlaggedMessage, error) {
+	var rows []flaggedMessageRow
+	if err := m.db.SelectContext(ctx, &rows, getFlaggedMessagesQuery, params.RoomID, params.BeforeID, params.Limit); err != nil {
+		return nil, fmt.Errorf("failed to get flagged messages: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	messages := make([]models.Message, len(rows))
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		messages[i] = row.message.toModel()
+		ids[i] = row.ID
+	}
+	if err := attachAttachments(ctx, m.db, messages); err != nil {
+		return nil, err
+	}
+
+	var flagRows []messageFlagRow
+	if err := m.db.SelectContext(ctx, &flagRows, getMessageFlagsQuery, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("failed to get message flags: %w", err)
+	}
+	flags := make(map[int64][]models.MessageFlag, len(rows))
+	for _, row := range flagRows {
+		flags[row.MessageID] = append(flags[row.MessageID], models.MessageFlag{Stage: row.Stage, Reason: row.Reason})
+	}
+
+	result := make([]models.FlaggedMessage, len(rows))
+	for i, row := range rows {
+		result[i] = models.FlaggedMessage{
+			Message:   messages[i],
+			Flags:     flags[row.ID],
+			FlaggedAt: row.FlaggedAt,
+		}
+	}
+	return result, nil
+}
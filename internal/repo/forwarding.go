@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type SetForwardingParams struct {
+	// RoomID задаётся для комнаты; иначе настройка относится к личной переписке UserIDs
+	RoomID   int64
+	UserIDs  [2]int64
+	Disabled bool
+}
+
+const setRoomForwardingQuery = `
+UPDATE rooms SET forwarding_disabled = $2
+WHERE id = $1
+`
+
+const setDirectForwardingQuery = `
+INSERT INTO direct_chat_settings (user_low, user_high, forwarding_disabled)
+VALUES (LEAST($1::BIGINT, $2::BIGINT), GREATEST($1::BIGINT, $2::BIGINT), $3)
+ON CONFLICT (user_low, user_high) DO UPDATE SET forwarding_disabled = EXCLUDED.forwarding_disabled
+`
+
+// SetForwarding разрешает или запрещает пересылать сообщения из переписки
+func (m messageRepo) SetForwarding(ctx context.Context, params SetForwardingParams) error {
+	if params.RoomID != 0 {
+		res, err := m.db.ExecContext(ctx, setRoomForwardingQuery, params.RoomID, params.Disabled)
+		if err != nil {
+			return fmt.Errorf("failed to set room forwarding: %w", err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if affected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	}
+
+	if _, err := m.db.ExecContext(ctx, setDirectForwardingQuery, params.UserIDs[0], params.UserIDs[1], params.Disabled); err != nil {
+		return fmt.Errorf("failed to set direct forwarding: %w", err)
+	}
+	return nil
+}
+
+const getRoomForwardingQuery = `
+SELECT forwarding_disabled FROM rooms
+WHERE id = $1
+`
+
+const getDirectForwardingQuery = `
+SELECT forwarding_disabled FROM direct_chat_settings
+WHERE user_low = LEAST($1::BIGINT, $2::BIGINT) AND user_high = GREATEST($1::BIGINT, $2::BIGINT)
+`
+
+// ForwardingDisabled сообщает, запрещена ли пересылка из переписки. Личная переписка без настроек пересылку разрешает
+func (m messageRepo) ForwardingDisabled(ctx context.Context, roomID int64, userIDs [2]int64) (bool, error) {
+	var disabled bool
+	var err error
+	if roomID != 0 {
+		err = m.db.GetContext(ctx, &disabled, getRoomForwardingQuery, roomID)
+	} else {
+		err = m.db.GetContext(ctx, &disabled, getDirectForwardingQuery, userIDs[0], userIDs[1])
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		if roomID != 0 {
+			return false, ErrNotFound
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get forwarding setting: %w", err)
+	}
+	return disabled, nil
+}
+
+// Копируются только записи о вложениях: новые записи ссылаются на те же объекты хранилища
+const copyAttachmentQuery = `
+INSERT INTO attachments (message_id, uploader_id, file_name, mime_type, size, sha256, storage_key, width, height, blurhash)
+SELECT $1, uploader_id, file_name, mime_type, size, sha256, storage_key, width, height, blurhash
+FROM attachments
+WHERE id = $2
+RETURNING id
+`
+
+const copyThumbnailsQuery = `
+INSERT INTO attachment_thumbnails (attachment_id, size, width, height, mime_type, storage_key)
+SELECT $1, size, width, height, mime_type, storage_key
+FROM attachment_thumbnails
+WHERE attachment_id = $2
+`
+
+const getMessageAttachmentIDsQuery = `
+SELECT id FROM attachments
+WHERE message_id = $1
+ORDER BY id
+`
+
+const setRichContentQuery = `
+UPDATE messages SET rich_content = $2
+WHERE id = $1
+`
+
+// copyAttachments привязывает к сообщению копии вложений исходного сообщения
+// и заменяет ID вложений в его структурированном содержимом на ID копий
+func copyAttachments(ctx context.Context, tx *sqlx.Tx, msg *message, sourceID int64) error {
+	var sourceIDs []int64
+	if err := tx.SelectContext(ctx, &sourceIDs, getMessageAttachmentIDsQuery, sourceID); err != nil {
+		return fmt.Errorf("failed to get source attachments: %w", err)
+	}
+	if len(sourceIDs) == 0 {
+		return nil
+	}
+
+	copies := make(map[int64]int64, len(sourceIDs))
+	for _, id := range sourceIDs {
+		var copyID int64
+		if err := tx.GetContext(ctx, &copyID, copyAttachmentQuery, msg.ID, id); err != nil {
+			return fmt.Errorf("failed to copy attachment: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, copyThumbnailsQuery, copyID, id); err != nil {
+			return fmt.Errorf("failed to copy thumbnails: %w", err)
+		}
+		copies[id] = copyID
+	}
+
+	content := decodeRichContent(msg.RichContent)
+	if content == nil || len(content.Attachments) == 0 {
+		return nil
+	}
+	for i, id := range content.Attachments {
+		content.Attachments[i] = copies[id]
+	}
+
+	richContent, err := encodeRichContent(content)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, setRichContentQuery, msg.ID, richContent); err != nil {
+		return fmt.Errorf("failed to update rich content: %w", err)
+	}
+	msg.RichContent = []byte(*richContent)
+	return nil
+}
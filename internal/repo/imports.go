@@ -0,0 +1,255 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ImportRepo переносит историю из выгрузок сторонних мессенджеров. Созданные комнаты и сообщения
+// запоминаются по их внешним идентификаторам, поэтому импорт можно безопасно запускать повторно
+type ImportRepo interface {
+	ImportRoom(ctx context.Context, params ImportRoomParams) (int64, error)
+	GetImportedMessageIDs(ctx context.Context, source, externalChatID string) (map[string]int64, error)
+	ImportMessage(ctx context.Context, params ImportMessageParams) (int64, error)
+}
+
+type importRepo struct {
+	db *sqlx.DB
+}
+
+func NewImportRepo(db *sqlx.DB) ImportRepo {
+	return &importRepo{db: db}
+}
+
+type ImportRoomParams struct {
+	Source     string
+	ExternalID string
+	Name       string
+	CreatorID  int64
+	CreatedAt  time.Time
+	// MemberIDs участники комнаты; создатель становится её администратором
+	MemberIDs []int64
+}
+
+const lockImportQuery = `SELECT pg_advisory_xact_lock(hashtext($1))`
+
+const getImportedRoomQuery = `
+SELECT room_id FROM imported_rooms
+WHERE source = $1 AND external_id = $2
+`
+
+const createImportedRoomQuery = `
+INSERT INTO rooms (name, created_at, creator_id)
+VALUES ($1, $2, $3)
+RETURNING id
+`
+
+const saveImportedRoomQuery = `
+INSERT INTO imported_rooms (source, external_id, room_id)
+VALUES ($1, $2, $3)
+`
+
+const addImportedMembersQuery = `
+INSERT INTO room_members (room_id, user_id, joined_at, is_admin)
+SELECT $1, u, $3, u = $4 FROM UNNEST($2::BIGINT[]) AS u
+ON CONFLICT (room_id, user_id) DO NOTHING
+`
+
+// ImportRoom возвращает комнату, ранее созданную для внешней переписки, или создаёт её.
+// Недостающие участники добавляются в обоих случаях
+func (r *importRepo) ImportRoom(ctx context.Context, params ImportRoomParams) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Параллельные импорты одной выгрузки не должны создать две комнаты
+	if _, err = tx.ExecContext(ctx, lockImportQuery, params.Source+":"+params.ExternalID); err != nil {
+		return 0, fmt.Errorf("failed to lock imported room: %w", err)
+	}
+
+	var roomID int64
+	err = tx.GetContext(ctx, &roomID, getImportedRoomQuery, params.Source, params.ExternalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if err = tx.GetContext(ctx, &roomID, createImportedRoomQuery, params.Name, params.CreatedAt, params.CreatorID); err != nil {
+			return 0, fmt.Errorf("failed to create room: %w", err)
+		}
+		if _, err = tx.ExecContext(ctx, saveImportedRoomQuery, params.Source, params.ExternalID, roomID); err != nil {
+			return 0, fmt.Errorf("failed to save imported room: %w", err)
+		}
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to get imported room: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, addImportedMembersQuery, roomID, pq.Array(params.MemberIDs), params.CreatedAt, params.CreatorID); err != nil {
+		return 0, fmt.Errorf("failed to add room members: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return roomID, nil
+}
+
+const getImportedMessagesQuery = `
+SELECT external_id, message_id FROM imported_messages
+WHERE source = $1 AND external_chat_id = $2
+`
+
+// GetImportedMessageIDs возвращает уже перенесённые сообщения внешней переписки по их внешним ID
+func (r *importRepo) GetImportedMessageIDs(ctx context.Context, source, externalChatID string) (map[string]int64, error) {
+	var rows []struct {
+		ExternalID string `db:"external_id"`
+		MessageID  int64  `db:"message_id"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, getImportedMessagesQuery, source, externalChatID); err != nil {
+		return nil, fmt.Errorf("failed to get imported messages: %w", err)
+	}
+
+	result := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		result[row.ExternalID] = row.MessageID
+	}
+	return result, nil
+}
+
+type ImportMessageParams struct {
+	Source         string
+	ExternalChatID string
+	ExternalID     string
+	SenderID       int64
+	ReceiverID     int64
+	// RoomID задаётся для комнаты; ReceiverID в этом случае не используется
+	RoomID  int64
+	Content string
+	// SentAt исходное время отправки; им же становятся created_at и updated_at
+	SentAt time.Time
+	// ReplyToExternalID сообщение той же внешней переписки, на которое дан ответ.
+	// Если оно не было импортировано, ссылка на ответ не сохраняется
+	ReplyToExternalID string
+	// AttachmentIDs ранее загруженные от имени отправителя вложения
+	AttachmentIDs []int64
+}
+
+// Статусы доставки для импортированных сообщений не заводятся: история не должна стать непрочитанной
+const importMessageQuery = `
+INSERT INTO messages (sender_id, receiver_id, room_id, content, sent_at, created_at, updated_at, seq, reply_to_id)
+VALUES ($1, $2, $3, $4, $5, $5, $5, $9, (
+    SELECT message_id FROM imported_messages
+    WHERE source = $6 AND external_chat_id = $7 AND external_id = $8
+))
+RETURNING id
+`
+
+// importedConversation условие на сообщения переписки импортируемого сообщения: $1 — комната,
+// $2 и $3 — участники личной переписки по возрастанию ID
+const importedConversation = `
+CASE WHEN $1::INT IS NULL
+    THEN room_id IS NULL AND LEAST(sender_id, receiver_id) = $2::BIGINT AND GREATEST(sender_id, receiver_id) = $3::BIGINT
+    ELSE room_id = $1
+END
+`
+
+// Импортированное сообщение встаёт сразу за последним сообщением, отправленным не позже него
+const importedMessageSeqQuery = `
+SELECT COALESCE(MAX(seq), 0) + 1 FROM messages
+WHERE ` + importedConversation + `AND created_at <= $4::TIMESTAMP
+`
+
+// Более поздние сообщения сдвигаются на один номер. Уникальный индекс проверяется на каждой строке,
+// поэтому сдвиг идёт через отрицательные номера, которые не пересекаются с настоящими
+const shiftLaterMessagesQuery = `
+UPDATE messages SET seq = -(seq + 1)
+WHERE ` + importedConversation + `AND created_at > $4::TIMESTAMP
+`
+
+const restoreShiftedMessagesQuery = `
+UPDATE messages SET seq = -seq
+WHERE ` + importedConversation + `AND seq < 0
+`
+
+const saveImportedMessageQuery = `
+INSERT INTO imported_messages (source, external_chat_id, external_id, message_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (source, external_chat_id, external_id) DO NOTHING
+`
+
+// ImportMessage сохраняет сообщение из выгрузки. Если оно уже импортировано, возвращает ErrDuplicate.
+// Номер в переписке соответствует исходному времени отправки: если в переписке уже есть более поздние
+// сообщения, их номера увеличиваются на один, и клиентам стоит заново загрузить её историю
+func (r *importRepo) ImportMessage(ctx context.Context, params ImportMessageParams) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Счётчик увеличивается ради блокировки переписки: одновременные сообщения ждут окончания перенумерации
+	if _, err = nextMessageSeq(ctx, tx, params.SenderID, params.ReceiverID, params.RoomID); err != nil {
+		return 0, err
+	}
+
+	var userLow, userHigh int64
+	if params.RoomID == 0 {
+		userLow, userHigh = min(params.SenderID, params.ReceiverID), max(params.SenderID, params.ReceiverID)
+	}
+	conversation := []any{nullableID(params.RoomID), userLow, userHigh, params.SentAt}
+
+	var seq int64
+	if err = tx.GetContext(ctx, &seq, importedMessageSeqQuery, conversation...); err != nil {
+		return 0, fmt.Errorf("failed to get imported message seq: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, shiftLaterMessagesQuery, conversation...); err != nil {
+		return 0, fmt.Errorf("failed to shift later messages: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, restoreShiftedMessagesQuery, conversation[:3]...); err != nil {
+		return 0, fmt.Errorf("failed to shift later messages: %w", err)
+	}
+
+	var messageID int64
+	err = tx.GetContext(ctx, &messageID, importMessageQuery,
+		params.SenderID,
+		params.ReceiverID,
+		nullableID(params.RoomID),
+		params.Content,
+		params.SentAt,
+		params.Source,
+		params.ExternalChatID,
+		params.ReplyToExternalID,
+		seq,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to import message: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, saveImportedMessageQuery, params.Source, params.ExternalChatID, params.ExternalID, messageID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save imported message: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	// Сообщение успел импортировать параллельный запуск; вставка выше откатывается
+	if affected == 0 {
+		return 0, ErrDuplicate
+	}
+
+	if len(params.AttachmentIDs) > 0 {
+		if err = linkAttachments(ctx, tx, messageID, params.SenderID, params.AttachmentIDs); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return messageID, nil
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportMessage_HistoryOrder(t *testing.T) {
+	db := newTestDB(t)
+	messages := messageRepo{db: db}
+	imports := importRepo{db: db}
+	ctx := context.Background()
+	roomID := createTestRoom(t, db, false)
+
+	_, err := messages.SaveMessage(ctx, SaveMessageParams{SenderID: 1, RoomID: roomID, Content: "live"})
+	require.NoError(t, err)
+
+	// Выгрузка может прийти не по порядку и позже сообщений, написанных уже у нас
+	now := time.Now().UTC()
+	for _, imported := range []struct {
+		id     string
+		sentAt time.Time
+	}{
+		{"2", now.Add(-48 * time.Hour)},
+		{"1", now.Add(-72 * time.Hour)},
+	} {
+		_, err = imports.ImportMessage(ctx, ImportMessageParams{
+			Source:         "telegram",
+			ExternalChatID: strconv.FormatInt(roomID, 10),
+			ExternalID:     imported.id,
+			SenderID:       2,
+			RoomID:         roomID,
+			Content:        "imported " + imported.id,
+			SentAt:         imported.sentAt,
+		})
+		require.NoError(t, err)
+	}
+
+	history, err := messages.GetHistory(ctx, GetHistoryParams{SenderID: 1, RoomID: roomID, Limit: 10})
+	require.NoError(t, err)
+
+	var contents []string
+	var seqs []int64
+	for _, msg := range history {
+		contents = append(contents, msg.Content)
+		seqs = append(seqs, msg.Seq)
+	}
+	assert.Equal(t, []string{"live", "imported 2", "imported 1"}, contents)
+	assert.Equal(t, []int64{3, 2, 1}, seqs)
+}
@@ -0,0 +1,126 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"messanger/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type LinkPreviewRepo interface {
+	GetCachedLinkPreview(ctx context.Context, url string, notBefore time.Time) (*CachedLinkPreview, error)
+	SaveCachedLinkPreview(ctx context.Context, url string, preview *models.LinkPreview) error
+	SetMessageLinkPreview(ctx context.Context, params SetMessageLinkPreviewParams) (*models.Message, error)
+}
+
+type linkPreviewRepo struct {
+	db *sqlx.DB
+}
+
+func NewLinkPreviewRepo(db *sqlx.DB) LinkPreviewRepo {
+	return &linkPreviewRepo{db: db}
+}
+
+// CachedLinkPreview запись кэша; Preview равен nil, если страницу получить не удалось
+type CachedLinkPreview struct {
+	Preview *models.LinkPreview
+}
+
+// decodeLinkPreview разбирает JSONB-колонку с превью; повреждённое значение игнорируется
+func decodeLinkPreview(data []byte) *models.LinkPreview {
+	if len(data) == 0 {
+		return nil
+	}
+	var preview models.LinkPreview
+	if err := json.Unmarshal(data, &preview); err != nil {
+		return nil
+	}
+	return &preview
+}
+
+// encodeLinkPreview готовит превью к записи в JSONB; nil сохраняется как NULL
+func encodeLinkPreview(preview *models.LinkPreview) (*string, error) {
+	if preview == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(preview)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode link preview: %w", err)
+	}
+	return nullableString(string(data)), nil
+}
+
+const getCachedLinkPreviewQuery = `
+SELECT preview FROM link_previews
+WHERE url = $1 AND fetched_at >= $2
+`
+
+// GetCachedLinkPreview возвращает запись кэша не старше notBefore или ErrNotFound
+func (r linkPreviewRepo) GetCachedLinkPreview(ctx context.Context, url string, notBefore time.Time) (*CachedLinkPreview, error) {
+	var data []byte
+	if err := r.db.GetContext(ctx, &data, getCachedLinkPreviewQuery, url, notBefore); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get cached link preview: %w", err)
+	}
+	return &CachedLinkPreview{Preview: decodeLinkPreview(data)}, nil
+}
+
+const saveCachedLinkPreviewQuery = `
+INSERT INTO link_previews (url, preview, fetched_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+ON CONFLICT (url) DO UPDATE SET preview = EXCLUDED.preview, fetched_at = EXCLUDED.fetched_at
+`
+
+// SaveCachedLinkPreview сохраняет результат загрузки страницы; nil запоминает неудачу,
+// чтобы не обращаться к недоступному сайту при каждом сообщении
+func (r linkPreviewRepo) SaveCachedLinkPreview(ctx context.Context, url string, preview *models.LinkPreview) error {
+	data, err := encodeLinkPreview(preview)
+	if err != nil {
+		return err
+	}
+	if _, err = r.db.ExecContext(ctx, saveCachedLinkPreviewQuery, url, data); err != nil {
+		return fmt.Errorf("failed to save cached link preview: %w", err)
+	}
+	return nil
+}
+
+type SetMessageLinkPreviewParams struct {
+	MessageID int64
+	// EditedAt время последней правки, которую видел обработчик; если сообщение с тех пор
+	// отредактировали или удалили, превью устарело и не сохраняется
+	EditedAt *time.Time
+	Preview  *models.LinkPreview
+}
+
+const setMessageLinkPreviewQuery = `
+UPDATE messages
+SET link_preview = $2
+WHERE id = $1 AND deleted_at IS NULL AND edited_at IS NOT DISTINCT FROM $3
+RETURNING ` + messageColumns
+
+// SetMessageLinkPreview прикрепляет превью к сообщению; ErrNotFound означает, что сообщение удалено или изменено
+func (r linkPreviewRepo) SetMessageLinkPreview(ctx context.Context, params SetMessageLinkPreviewParams) (*models.Message, error) {
+	data, err := encodeLinkPreview(params.Preview)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg message
+	if err = r.db.GetContext(ctx, &msg, setMessageLinkPreviewQuery, params.MessageID, data, params.EditedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to set message link preview: %w", err)
+	}
+
+	result := msg.toModel()
+	return &result, nil
+}
@@ -0,0 +1,54 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"messanger/internal/models"
+)
+
+const addRoomMemberQuery = `
+INSERT INTO room_members (room_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (room_id, user_id) DO NOTHING
+RETURNING room_id, user_id, joined_at, is_admin
+`
+
+// AddRoomMember добавляет пользователя в комнату; если он уже участник, возвращает ErrDuplicate
+func (m messageRepo) AddRoomMember(ctx context.Context, roomID, userID int64) (*models.RoomMember, error) {
+	var member roomMember
+	if err := m.db.GetContext(ctx, &member, addRoomMemberQuery, roomID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDuplicate
+		}
+		return nil, fmt.Errorf("failed to add room member: %w", err)
+	}
+
+	return &models.RoomMember{
+		RoomID:   member.RoomID,
+		UserID:   member.UserID,
+		JoinedAt: member.JoinedAt,
+		IsAdmin:  member.IsAdmin,
+	}, nil
+}
+
+const removeRoomMemberQuery = `
+DELETE FROM room_members
+WHERE room_id = $1 AND user_id = $2
+`
+
+// RemoveRoomMember исключает пользователя из комнаты; false — он не был её участником
+func (m messageRepo) RemoveRoomMember(ctx context.Context, roomID, userID int64) (bool, error) {
+	res, err := m.db.ExecContext(ctx, removeRoomMemberQuery, roomID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove room member: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"messanger/internal/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// MentionParams упоминание, сохраняемое вместе с сообщением
+type MentionParams struct {
+	UserID int64
+	Kind   string
+}
+
+const createMentionsQuery = `
+INSERT INTO mentions (message_id, user_id, kind)
+SELECT $1, UNNEST($2::BIGINT[]), UNNEST($3::TEXT[])
+ON CONFLICT (message_id, user_id) DO NOTHING
+`
+
+func createMentions(ctx context.Context, tx *sqlx.Tx, messageID int64, mentions []MentionParams) error {
+	userIDs := make([]int64, len(mentions))
+	kinds := make([]string, len(mentions))
+	for i, mention := range mentions {
+		userIDs[i] = mention.UserID
+		kinds[i] = mention.Kind
+	}
+
+	if _, err := tx.ExecContext(ctx, createMentionsQuery, messageID, pq.Array(userIDs), pq.Array(kinds)); err != nil {
+		return fmt.Errorf("failed to create mentions: %w", err)
+	}
+	return nil
+}
+
+type GetMentionsParams struct {
+	UserID     int64
+	UnreadOnly bool
+	// BeforeID курсор: возвращаются упоминания в сообщениях с ID меньше указанного; 0 — с самого нового
+	BeforeID int64
+	Limit    int
+}
+
+type mentionRow struct {
+	message
+	Kind   string     `db:"mention_kind"`
+	ReadAt *time.Time `db:"mention_read_at"`
+}
+
+const getMentionsQuery = `
+WITH mn AS (
+    SELECT message_id, kind AS mention_kind, read_at AS mention_read_at FROM mentions
+    WHERE user_id = $1
+    AND ($2 = FALSE OR read_at IS NULL)
+    AND ($3 = 0 OR message_id < $3)
+)
+SELECT mn.mention_kind, mn.mention_read_at, ` + messageColumns + ` FROM messages
+JOIN mn ON mn.message_id = messages.id
+WHERE NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = messages.id AND h.user_id = $1)
+AND (messages.expires_at IS NULL OR messages.expires_at > CURRENT_TIMESTAMP)
+ORDER BY messages.id DESC
+LIMIT $4
+`
+
+// GetMentions возвращает упоминания пользователя вместе с сообщениями, от новых к старым
+func (m messageRepo) GetMentions(ctx context.Context, params GetMentionsParams) ([]models.Mention, error) {
+	var rows []mentionRow
+	if err := m.db.SelectContext(ctx, &rows, getMentionsQuery,
+		params.UserID,
+		params.UnreadOnly,
+		params.BeforeID,
+		params.Limit,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get mentions: %w", err)
+	}
+
+	messages := make([]models.Message, len(rows))
+	for i, row := range rows {
+		messages[i] = row.message.toModel()
+	}
+	if err := attachAttachments(ctx, m.db, messages); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Mention, len(rows))
+	for i, row := range rows {
+		result[i] = models.Mention{
+			MessageID: messages[i].ID,
+			RoomID:    messages[i].RoomID,
+			SenderID:  messages[i].SenderID,
+			Kind:      row.Kind,
+			CreatedAt: messages[i].CreatedAt,
+			ReadAt:    row.ReadAt,
+			Message:   &messages[i],
+		}
+	}
+
+	return result, nil
+}
+
+const countUnreadMentionsQuery = `
+SELECT COUNT(*) FROM mentions
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (m messageRepo) CountUnreadMentions(ctx context.Context, userID int64) (int, error) {
+	var count int
+	if err := m.db.GetContext(ctx, &count, countUnreadMentionsQuery, userID); err != nil {
+		return 0, fmt.Errorf("failed to count unread mentions: %w", err)
+	}
+	return count, nil
+}
+
+const markMentionsReadQuery = `
+UPDATE mentions
+SET read_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND message_id = ANY($2) AND read_at IS NULL
+`
+
+func (m messageRepo) MarkMentionsRead(ctx context.Context, userID int64, messageIDs []int64) error {
+	if _, err := m.db.ExecContext(ctx, markMentionsReadQuery, userID, pq.Array(messageIDs)); err != nil {
+		return fmt.Errorf("failed to mark mentions read: %w", err)
+	}
+	return nil
+}
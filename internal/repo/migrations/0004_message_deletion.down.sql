@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS hidden_messages;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_by;
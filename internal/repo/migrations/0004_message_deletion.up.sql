@@ -0,0 +1,11 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_by BIGINT DEFAULT NULL;
+
+-- Сообщения, скрытые отдельными пользователями («удалить у себя»)
+CREATE TABLE IF NOT EXISTS hidden_messages (
+    message_id INT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    hidden_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_hidden_messages_user_id ON hidden_messages (user_id);
@@ -0,0 +1,11 @@
+-- Статус доставки сообщения каждому получателю
+CREATE TABLE IF NOT EXISTS message_receipts (
+    message_id INT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'sent' CHECK (status IN ('sent', 'delivered', 'read')),
+    delivered_at TIMESTAMP DEFAULT NULL,
+    read_at TIMESTAMP DEFAULT NULL,
+    PRIMARY KEY (message_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_receipts_user_status ON message_receipts (user_id, status);
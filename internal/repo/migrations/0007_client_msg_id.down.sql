@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_messages_sender_client_msg_id;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS client_msg_id;
@@ -0,0 +1,6 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS client_msg_id VARCHAR(64) DEFAULT NULL;
+
+-- Ключ идемпотентности уникален в пределах отправителя
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_sender_client_msg_id
+    ON messages (sender_id, client_msg_id)
+    WHERE client_msg_id IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Вложения загружаются до отправки сообщения и привязываются к нему при отправке.
+-- Содержимое хранится в BlobStore под ключом storage_key (SHA-256), поэтому одинаковые файлы
+-- разделяют один объект хранилища
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    message_id INT REFERENCES messages(id) ON DELETE CASCADE,
+    uploader_id BIGINT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_message_id ON attachments (message_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_sha256 ON attachments (sha256);
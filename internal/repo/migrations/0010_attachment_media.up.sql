@@ -0,0 +1,16 @@
+-- Метаданные изображений: размеры с учётом EXIF-ориентации и blurhash-заглушка
+ALTER TABLE attachments
+    ADD COLUMN IF NOT EXISTS width INT,
+    ADD COLUMN IF NOT EXISTS height INT,
+    ADD COLUMN IF NOT EXISTS blurhash VARCHAR(64);
+
+-- Уменьшенные копии изображений; size — ограничение большей стороны в пикселях
+CREATE TABLE IF NOT EXISTS attachment_thumbnails (
+    attachment_id INT NOT NULL REFERENCES attachments(id) ON DELETE CASCADE,
+    size INT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    mime_type VARCHAR(255) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    PRIMARY KEY (attachment_id, size)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE message_edits DROP COLUMN IF EXISTS rich_content;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS rich_content;
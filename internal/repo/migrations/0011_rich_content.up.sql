@@ -0,0 +1,4 @@
+-- Структурированное содержимое сообщения; content остаётся текстовым представлением для старых клиентов и поиска
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS rich_content JSONB;
+
+ALTER TABLE message_edits ADD COLUMN IF NOT EXISTS rich_content JSONB;
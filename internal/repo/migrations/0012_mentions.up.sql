@@ -0,0 +1,12 @@
+-- Упоминания пользователей; непрочитанные образуют входящий список упоминаний по всем перепискам
+CREATE TABLE IF NOT EXISTS mentions (
+    message_id INT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP,
+    PRIMARY KEY (message_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id, message_id DESC);
+CREATE INDEX IF NOT EXISTS idx_mentions_unread ON mentions (user_id) WHERE read_at IS NULL;
@@ -0,0 +1,9 @@
+-- Превью первой ссылки сообщения; заполняется асинхронно после отправки
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS link_preview JSONB;
+
+-- Кэш превью по URL; preview IS NULL означает, что страницу получить не удалось
+CREATE TABLE IF NOT EXISTS link_previews (
+    url TEXT PRIMARY KEY,
+    preview JSONB,
+    fetched_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
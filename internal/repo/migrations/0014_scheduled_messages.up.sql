@@ -0,0 +1,21 @@
+-- Отложенные сообщения. locked_until — аренда строки экземпляром, который её отправляет:
+-- пока она не истекла, другие экземпляры сообщение не берут, а пользователь не может его изменить
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id SERIAL PRIMARY KEY,
+    sender_id BIGINT NOT NULL,
+    receiver_id BIGINT NOT NULL DEFAULT 0,
+    room_id INT REFERENCES rooms(id) ON DELETE CASCADE,
+    content TEXT NOT NULL DEFAULT '',
+    rich_content JSONB,
+    attachment_ids BIGINT[] NOT NULL DEFAULT '{}',
+    send_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    error TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_sender_id ON scheduled_messages (sender_id, send_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due ON scheduled_messages (send_at) WHERE status = 'pending';
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS direct_chat_settings;
+
+ALTER TABLE rooms DROP COLUMN IF EXISTS message_ttl;
+
+DROP INDEX IF EXISTS idx_messages_expires_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS view_once;
+ALTER TABLE messages DROP COLUMN IF EXISTS expires_at;
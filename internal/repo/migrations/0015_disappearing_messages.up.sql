@@ -0,0 +1,18 @@
+-- Исчезающие сообщения: по наступлении expires_at сообщение удаляется целиком вместе со всеми связанными данными.
+-- view_once сообщения истекают сразу после первого прочтения
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS view_once BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_messages_expires_at ON messages (expires_at) WHERE expires_at IS NOT NULL;
+
+-- Время жизни новых сообщений в переписке, в секундах; NULL — сообщения не исчезают
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS message_ttl INT;
+
+-- Настройки личной переписки; пара пользователей хранится упорядоченной
+CREATE TABLE IF NOT EXISTS direct_chat_settings (
+    user_low BIGINT NOT NULL,
+    user_high BIGINT NOT NULL,
+    message_ttl INT,
+    PRIMARY KEY (user_low, user_high),
+    CHECK (user_low <= user_high)
+);
@@ -0,0 +1,6 @@
+-- Закреплённые сообщения; закрепление исчезает вместе с сообщением
+CREATE TABLE IF NOT EXISTS pinned_messages (
+    message_id INT PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    pinned_by BIGINT NOT NULL,
+    pinned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
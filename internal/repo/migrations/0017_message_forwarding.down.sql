@@ -0,0 +1,12 @@
+ALTER TABLE direct_chat_settings DROP COLUMN IF EXISTS forwarding_disabled;
+ALTER TABLE rooms DROP COLUMN IF EXISTS forwarding_disabled;
+
+DROP TRIGGER IF EXISTS keep_forwarded_from ON messages;
+DROP FUNCTION IF EXISTS keep_forwarded_from();
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS forwarded_sent_at,
+    DROP COLUMN IF EXISTS forwarded_room_id,
+    DROP COLUMN IF EXISTS forwarded_receiver_id,
+    DROP COLUMN IF EXISTS forwarded_sender_id,
+    DROP COLUMN IF EXISTS forwarded_message_id;
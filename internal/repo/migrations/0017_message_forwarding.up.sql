@@ -0,0 +1,30 @@
+-- Происхождение пересланного сообщения: исходное сообщение, его автор, переписка и время отправки.
+-- При повторной пересылке сохраняется самый первый оригинал. Внешнего ключа на исходное сообщение нет:
+-- оригинал может быть удалён, а ссылка на него должна остаться
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS forwarded_message_id INT,
+    ADD COLUMN IF NOT EXISTS forwarded_sender_id BIGINT,
+    ADD COLUMN IF NOT EXISTS forwarded_receiver_id BIGINT,
+    ADD COLUMN IF NOT EXISTS forwarded_room_id INT,
+    ADD COLUMN IF NOT EXISTS forwarded_sent_at TIMESTAMP;
+
+CREATE OR REPLACE FUNCTION keep_forwarded_from()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF (NEW.forwarded_message_id, NEW.forwarded_sender_id, NEW.forwarded_receiver_id, NEW.forwarded_room_id, NEW.forwarded_sent_at)
+        IS DISTINCT FROM
+       (OLD.forwarded_message_id, OLD.forwarded_sender_id, OLD.forwarded_receiver_id, OLD.forwarded_room_id, OLD.forwarded_sent_at) THEN
+        RAISE EXCEPTION 'forwarding provenance of message % is immutable', OLD.id;
+    END IF;
+RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER keep_forwarded_from
+    BEFORE UPDATE ON messages
+    FOR EACH ROW
+    EXECUTE FUNCTION keep_forwarded_from();
+
+-- Запрет пересылать сообщения из переписки
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS forwarding_disabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE direct_chat_settings ADD COLUMN IF NOT EXISTS forwarding_disabled BOOLEAN NOT NULL DEFAULT FALSE;
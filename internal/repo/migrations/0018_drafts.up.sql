@@ -0,0 +1,10 @@
+-- Неотправленный текст пользователя в переписке. Для комнаты задаётся room_id, для личной переписки — receiver_id;
+-- второй столбец равен 0, чтобы пара могла входить в первичный ключ
+CREATE TABLE IF NOT EXISTS drafts (
+    user_id BIGINT NOT NULL,
+    room_id BIGINT NOT NULL DEFAULT 0,
+    receiver_id BIGINT NOT NULL DEFAULT 0,
+    content TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, room_id, receiver_id)
+);
@@ -0,0 +1,22 @@
+-- Параметры опроса дублируют неизменяемое содержимое сообщения, чтобы голосование проверялось в одной транзакции.
+-- closes_at задаёт автор, closed_at проставляется при закрытии — вручную или по наступлении closes_at
+CREATE TABLE IF NOT EXISTS polls (
+    message_id INT PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    options INT NOT NULL,
+    multiple_votes BOOLEAN NOT NULL DEFAULT FALSE,
+    anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+    allow_vote_change BOOLEAN NOT NULL DEFAULT FALSE,
+    closes_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_polls_closes_at ON polls (closes_at) WHERE closed_at IS NULL AND closes_at IS NOT NULL;
+
+-- option_index — номер варианта в порядке PollContent.Options, начиная с 0
+CREATE TABLE IF NOT EXISTS poll_votes (
+    message_id INT NOT NULL REFERENCES polls(message_id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    option_index INT NOT NULL,
+    voted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, user_id, option_index)
+);
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_messages_created_at;
+DROP INDEX IF EXISTS idx_messages_deleted_at;
+
+ALTER TABLE direct_chat_settings
+    DROP COLUMN IF EXISTS legal_hold,
+    DROP COLUMN IF EXISTS message_retention_days,
+    DROP COLUMN IF EXISTS deleted_retention_days;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS legal_hold,
+    DROP COLUMN IF EXISTS message_retention_days,
+    DROP COLUMN IF EXISTS deleted_retention_days;
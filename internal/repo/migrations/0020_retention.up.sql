@@ -0,0 +1,15 @@
+-- Сроки хранения переписки в днях: deleted_retention_days — для удалённых сообщений,
+-- message_retention_days — для всех сообщений. NULL — действует глобальная настройка, 0 — хранить бессрочно.
+-- legal_hold исключает переписку из очистки по срокам хранения
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS deleted_retention_days INT,
+    ADD COLUMN IF NOT EXISTS message_retention_days INT,
+    ADD COLUMN IF NOT EXISTS legal_hold BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE direct_chat_settings
+    ADD COLUMN IF NOT EXISTS deleted_retention_days INT,
+    ADD COLUMN IF NOT EXISTS message_retention_days INT,
+    ADD COLUMN IF NOT EXISTS legal_hold BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_messages_deleted_at ON messages (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages (created_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS imported_messages;
+DROP TABLE IF EXISTS imported_rooms;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS reply_to_id;
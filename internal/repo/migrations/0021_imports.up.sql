@@ -0,0 +1,20 @@
+-- Ответ на сообщение той же переписки
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS reply_to_id INT REFERENCES messages(id) ON DELETE SET NULL;
+
+-- Соответствие переписок и сообщений из выгрузок сторонних мессенджеров созданным у нас.
+-- По нему повторный импорт той же выгрузки пропускает уже перенесённое
+CREATE TABLE IF NOT EXISTS imported_rooms (
+    source VARCHAR(32) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    room_id INT NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (source, external_id)
+);
+
+CREATE TABLE IF NOT EXISTS imported_messages (
+    source VARCHAR(32) NOT NULL,
+    external_chat_id VARCHAR(255) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    message_id INT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    PRIMARY KEY (source, external_chat_id, external_id)
+);
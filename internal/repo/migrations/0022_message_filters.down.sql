@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_messages_sender_created_at;
+
+DROP TABLE IF EXISTS message_flags;
+
+ALTER TABLE rooms DROP COLUMN IF EXISTS message_filters;
@@ -0,0 +1,16 @@
+-- Настройки фильтрации сообщений комнаты (models.MessageFilters). NULL — действуют настройки сервера
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS message_filters JSONB;
+
+-- Пометки фильтров для модераторов: сообщение сохранено, но вызвало подозрение
+CREATE TABLE IF NOT EXISTS message_flags (
+    id SERIAL PRIMARY KEY,
+    message_id INT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    stage VARCHAR(64) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_flags_message_id ON message_flags (message_id);
+
+-- Поиск повторов недавних сообщений отправителя
+CREATE INDEX IF NOT EXISTS idx_messages_sender_created_at ON messages (sender_id, created_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_events;
+
+DROP TABLE IF EXISTS user_event_seqs;
@@ -0,0 +1,18 @@
+-- Журнал событий пользователя для синхронизации после переподключения. seq растёт монотонно
+-- в пределах пользователя; last_seq выдаёт следующий номер, compacted_seq — последний удалённый при сжатии
+CREATE TABLE IF NOT EXISTS user_event_seqs (
+    user_id BIGINT PRIMARY KEY,
+    last_seq BIGINT NOT NULL DEFAULT 0,
+    compacted_seq BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS user_events (
+    user_id BIGINT NOT NULL,
+    seq BIGINT NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_events_created_at ON user_events (created_at);
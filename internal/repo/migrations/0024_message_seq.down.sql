@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_messages_direct_seq;
+
+DROP INDEX IF EXISTS idx_messages_room_seq;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS seq;
+
+DROP TABLE IF EXISTS conversation_seqs;
@@ -0,0 +1,42 @@
+-- Счётчики номеров сообщений переписок. Комната задаётся room_id, личная переписка — парой
+-- user_low/user_high при room_id = 0. last_seq — номер последнего сообщения переписки
+CREATE TABLE IF NOT EXISTS conversation_seqs (
+    room_id BIGINT NOT NULL DEFAULT 0,
+    user_low BIGINT NOT NULL DEFAULT 0,
+    user_high BIGINT NOT NULL DEFAULT 0,
+    last_seq BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (room_id, user_low, user_high),
+    CHECK (user_low <= user_high)
+);
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS seq BIGINT;
+
+-- Существующие сообщения нумеруются в порядке создания; одинаковое время различается по id
+UPDATE messages m SET seq = numbered.seq
+FROM (
+    SELECT id, ROW_NUMBER() OVER (
+        PARTITION BY
+            COALESCE(room_id, 0),
+            CASE WHEN room_id IS NULL THEN LEAST(sender_id, receiver_id) ELSE 0 END,
+            CASE WHEN room_id IS NULL THEN GREATEST(sender_id, receiver_id) ELSE 0 END
+        ORDER BY created_at, id
+    ) AS seq
+    FROM messages
+) numbered
+WHERE m.id = numbered.id;
+
+INSERT INTO conversation_seqs (room_id, user_low, user_high, last_seq)
+SELECT
+    COALESCE(room_id, 0),
+    CASE WHEN room_id IS NULL THEN LEAST(sender_id, receiver_id) ELSE 0 END,
+    CASE WHEN room_id IS NULL THEN GREATEST(sender_id, receiver_id) ELSE 0 END,
+    MAX(seq)
+FROM messages
+GROUP BY 1, 2, 3
+ON CONFLICT (room_id, user_low, user_high) DO UPDATE SET last_seq = GREATEST(conversation_seqs.last_seq, EXCLUDED.last_seq);
+
+ALTER TABLE messages ALTER COLUMN seq SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_room_seq ON messages (room_id, seq) WHERE room_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_direct_seq
+    ON messages (LEAST(sender_id, receiver_id), GREATEST(sender_id, receiver_id), seq) WHERE room_id IS NULL;
@@ -0,0 +1,15 @@
+-- Пользователи, известные сервису. Учётными записями владеет сервис авторизации, поэтому
+-- пользователь попадает сюда при подключении клиента; по таблице проверяются адресаты личных сообщений
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT PRIMARY KEY,
+    registered_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Участники существующих переписок и комнат считаются известными
+INSERT INTO users (id)
+SELECT sender_id FROM messages
+UNION
+SELECT receiver_id FROM messages WHERE receiver_id > 0
+UNION
+SELECT user_id FROM room_members
+ON CONFLICT (id) DO NOTHING;
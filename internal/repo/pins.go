@@ -0,0 +1,198 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"messanger/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type PinParams struct {
+	MessageID int64
+	UserID    int64
+	// RoomID и UserIDs задают переписку сообщения, как в GetPinnedMessagesParams
+	RoomID  int64
+	UserIDs [2]int64
+	// Limit сколько сообщений можно закрепить в переписке; 0 — без ограничений
+	Limit int
+}
+
+// Закрепления одной переписки выполняются по очереди, иначе одновременные запросы
+// могли бы превысить лимит. Блокировка снимается вместе с транзакцией
+const lockConversationPinsQuery = `
+SELECT pg_advisory_xact_lock(hashtextextended(format('pins:%s:%s:%s', $1::BIGINT, $2::BIGINT, $3::BIGINT), 0))
+`
+
+const pinMessageQuery = `
+INSERT INTO pinned_messages (message_id, pinned_by)
+VALUES ($1, $2)
+ON CONFLICT (message_id) DO NOTHING
+RETURNING pinned_at
+`
+
+// pinnedInConversation условие на закреплённые сообщения переписки: $1 — комната,
+// $2 и $3 — участники личной переписки
+const pinnedInConversation = `
+WHERE CASE WHEN $1::INT IS NULL
+    THEN room_id IS NULL AND ((sender_id = $2 AND receiver_id = $3) OR (sender_id = $3 AND receiver_id = $2))
+    ELSE room_id = $1
+END
+AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+`
+
+const countPinnedMessagesQuery = `
+SELECT COUNT(*) FROM pinned_messages p
+JOIN messages ON messages.id = p.message_id
+` + pinnedInConversation
+
+// PinMessage закрепляет сообщение; если оно уже закреплено, возвращает ErrDuplicate,
+// а если в переписке закреплено params.Limit сообщений — ErrLimitReached
+func (m messageRepo) PinMessage(ctx context.Context, params PinParams) (*models.Pin, error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	userLow, userHigh := min(params.UserIDs[0], params.UserIDs[1]), max(params.UserIDs[0], params.UserIDs[1])
+	if _, err = tx.ExecContext(ctx, lockConversationPinsQuery, params.RoomID, userLow, userHigh); err != nil {
+		return nil, fmt.Errorf("failed to lock pinned messages: %w", err)
+	}
+
+	var pinnedAt time.Time
+	if err = tx.GetContext(ctx, &pinnedAt, pinMessageQuery, params.MessageID, params.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDuplicate
+		}
+		return nil, fmt.Errorf("failed to pin message: %w", err)
+	}
+
+	if params.Limit > 0 {
+		var pinned int
+		if err = tx.GetContext(ctx, &pinned, countPinnedMessagesQuery,
+			nullableID(params.RoomID),
+			params.UserIDs[0],
+			params.UserIDs[1],
+		); err != nil {
+			return nil, fmt.Errorf("failed to count pinned messages: %w", err)
+		}
+		// Только что закреплённое сообщение уже учтено
+		if pinned > params.Limit {
+			return nil, ErrLimitReached
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &models.Pin{MessageID: params.MessageID, PinnedBy: params.UserID, PinnedAt: pinnedAt}, nil
+}
+
+const unpinMessageQuery = `
+DELETE FROM pinned_messages
+WHERE message_id = $1
+`
+
+// UnpinMessage открепляет сообщение и сообщает, было ли оно закреплено
+func (m messageRepo) UnpinMessage(ctx context.Context, messageID int64) (bool, error) {
+	res, err := m.db.ExecContext(ctx, unpinMessageQuery, messageID)
+	if err != nil {
+		return false, fmt.Errorf("failed to unpin message: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+type GetPinnedMessagesParams struct {
+	// RoomID задаётся для комнаты; иначе возвращаются закрепления личной переписки UserIDs
+	RoomID  int64
+	UserIDs [2]int64
+}
+
+type pinnedMessageRow struct {
+	message
+	PinnedBy int64     `db:"pinned_by"`
+	PinnedAt time.Time `db:"pinned_at"`
+}
+
+const getPinnedMessagesQuery = `
+SELECT p.pinned_by, p.pinned_at, ` + messageColumns + ` FROM pinned_messages p
+JOIN messages ON messages.id = p.message_id
+` + pinnedInConversation + `
+ORDER BY p.pinned_at DESC, p.message_id DESC
+`
+
+// GetPinnedMessages возвращает закреплённые сообщения переписки, начиная с последнего закреплённого
+func (m messageRepo) GetPinnedMessages(ctx context.Context, params GetPinnedMessagesParams) ([]models.PinnedMessage, error) {
+	var rows []pinnedMessageRow
+	if err := m.db.SelectContext(ctx, &rows, getPinnedMessagesQuery,
+		nullableID(params.RoomID),
+		params.UserIDs[0],
+		params.UserIDs[1],
+	); err != nil {
+		return nil, fmt.Errorf("failed to get pinned messages: %w", err)
+	}
+
+	messages := make([]models.Message, len(rows))
+	for i, row := range rows {
+		messages[i] = row.message.toModel()
+		messages[i].Pinned = true
+	}
+	if err := attachAttachments(ctx, m.db, messages); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.PinnedMessage, len(rows))
+	for i, row := range rows {
+		result[i] = models.PinnedMessage{
+			Pin: models.Pin{
+				MessageID: messages[i].ID,
+				RoomID:    messages[i].RoomID,
+				PinnedBy:  row.PinnedBy,
+				PinnedAt:  row.PinnedAt,
+			},
+			Message: messages[i],
+		}
+	}
+	return result, nil
+}
+
+const getPinnedMessageIDsQuery = `
+SELECT message_id FROM pinned_messages
+WHERE message_id = ANY($1)
+`
+
+// attachPins отмечает закреплённые сообщения
+func (m messageRepo) attachPins(ctx context.Context, messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+	}
+
+	var pinned []int64
+	if err := m.db.SelectContext(ctx, &pinned, getPinnedMessageIDsQuery, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to get pins: %w", err)
+	}
+
+	pinnedSet := make(map[int64]bool, len(pinned))
+	for _, id := range pinned {
+		pinnedSet[id] = true
+	}
+	for i := range messages {
+		messages[i].Pinned = pinnedSet[messages[i].ID]
+	}
+	return nil
+}
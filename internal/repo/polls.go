@@ -0,0 +1,255 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"messanger/internal/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const createPollQuery = `
+INSERT INTO polls (message_id, options, multiple_votes, anonymous, allow_vote_change, closes_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+// createPoll заводит опрос для только что сохранённого сообщения
+func createPoll(ctx context.Context, tx *sqlx.Tx, messageID int64, poll *models.PollContent) error {
+	if _, err := tx.ExecContext(ctx, createPollQuery,
+		messageID,
+		len(poll.Options),
+		poll.MultipleVotes,
+		poll.Anonymous,
+		poll.AllowVoteChange,
+		poll.ClosesAt,
+	); err != nil {
+		return fmt.Errorf("failed to create poll: %w", err)
+	}
+	return nil
+}
+
+type VoteParams struct {
+	MessageID int64
+	UserID    int64
+	// Options выбранные варианты; пустой список отзывает голос
+	Options []int
+	// Replace заменяет уже отданный голос; без него повторное голосование возвращает ErrDuplicate
+	Replace bool
+}
+
+const lockPollQuery = `
+SELECT closed_at IS NOT NULL OR closes_at <= CURRENT_TIMESTAMP AS closed FROM polls
+WHERE message_id = $1
+FOR UPDATE
+`
+
+const hasVotedQuery = `
+SELECT EXISTS (SELECT 1 FROM poll_votes WHERE message_id = $1 AND user_id = $2)
+`
+
+const deleteVotesQuery = `
+DELETE FROM poll_votes
+WHERE message_id = $1 AND user_id = $2
+`
+
+const createVotesQuery = `
+INSERT INTO poll_votes (message_id, user_id, option_index)
+SELECT $1, $2, UNNEST($3::INT[])
+`
+
+// Vote сохраняет голос пользователя. Строка опроса блокируется, поэтому голос не может быть принят
+// одновременно с закрытием опроса; в закрытом опросе возвращается ErrPollClosed
+func (m messageRepo) Vote(ctx context.Context, params VoteParams) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var closed sql.NullBool
+	if err = tx.GetContext(ctx, &closed, lockPollQuery, params.MessageID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to lock poll: %w", err)
+	}
+	if closed.Bool {
+		return ErrPollClosed
+	}
+
+	if !params.Replace {
+		var voted bool
+		if err = tx.GetContext(ctx, &voted, hasVotedQuery, params.MessageID, params.UserID); err != nil {
+			return fmt.Errorf("failed to check vote: %w", err)
+		}
+		if voted {
+			return ErrDuplicate
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteVotesQuery, params.MessageID, params.UserID); err != nil {
+		return fmt.Errorf("failed to delete votes: %w", err)
+	}
+	if len(params.Options) > 0 {
+		if _, err = tx.ExecContext(ctx, createVotesQuery, params.MessageID, params.UserID, pq.Array(params.Options)); err != nil {
+			return fmt.Errorf("failed to create votes: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+const closePollQuery = `
+UPDATE polls SET closed_at = CURRENT_TIMESTAMP
+WHERE message_id = $1 AND closed_at IS NULL
+`
+
+// ClosePoll закрывает опрос и сообщает, был ли он открыт
+func (m messageRepo) ClosePoll(ctx context.Context, messageID int64) (bool, error) {
+	res, err := m.db.ExecContext(ctx, closePollQuery, messageID)
+	if err != nil {
+		return false, fmt.Errorf("failed to close poll: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// Опрос считается закрытым в момент closes_at, даже если фоновая задача закрыла его позже
+const closeDuePollsQuery = `
+UPDATE polls SET closed_at = closes_at
+WHERE message_id IN (
+    SELECT message_id FROM polls
+    WHERE closed_at IS NULL AND closes_at <= CURRENT_TIMESTAMP
+    ORDER BY closes_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING message_id
+`
+
+// CloseDuePolls закрывает опросы, время которых истекло, и возвращает ID их сообщений
+func (m messageRepo) CloseDuePolls(ctx context.Context, limit int) ([]int64, error) {
+	var ids []int64
+	if err := m.db.SelectContext(ctx, &ids, closeDuePollsQuery, limit); err != nil {
+		return nil, fmt.Errorf("failed to close due polls: %w", err)
+	}
+	return ids, nil
+}
+
+type poll struct {
+	MessageID int64      `db:"message_id"`
+	Options   int        `db:"options"`
+	Anonymous bool       `db:"anonymous"`
+	ClosesAt  *time.Time `db:"closes_at"`
+	ClosedAt  *time.Time `db:"closed_at"`
+}
+
+type pollVote struct {
+	MessageID int64 `db:"message_id"`
+	UserID    int64 `db:"user_id"`
+	Option    int   `db:"option_index"`
+}
+
+const getPollsQuery = `
+SELECT message_id, options, anonymous, closes_at, closed_at FROM polls
+WHERE message_id = ANY($1)
+`
+
+const getPollVotesQuery = `
+SELECT message_id, user_id, option_index FROM poll_votes
+WHERE message_id = ANY($1)
+ORDER BY voted_at, user_id
+`
+
+// GetPollResults подсчитывает итоги опросов; MyVotes заполняется для пользователя userID
+func (m messageRepo) GetPollResults(ctx context.Context, messageIDs []int64, userID int64) (map[int64]*models.PollResults, error) {
+	var polls []poll
+	if err := m.db.SelectContext(ctx, &polls, getPollsQuery, pq.Array(messageIDs)); err != nil {
+		return nil, fmt.Errorf("failed to get polls: %w", err)
+	}
+	if len(polls) == 0 {
+		return map[int64]*models.PollResults{}, nil
+	}
+
+	var votes []pollVote
+	if err := m.db.SelectContext(ctx, &votes, getPollVotesQuery, pq.Array(messageIDs)); err != nil {
+		return nil, fmt.Errorf("failed to get poll votes: %w", err)
+	}
+
+	results := make(map[int64]*models.PollResults, len(polls))
+	anonymous := make(map[int64]bool, len(polls))
+	for _, p := range polls {
+		result := &models.PollResults{
+			MessageID: p.MessageID,
+			Options:   make([]models.PollOptionResult, p.Options),
+			ClosesAt:  p.ClosesAt,
+			ClosedAt:  p.ClosedAt,
+		}
+		for i := range result.Options {
+			result.Options[i].Option = i
+		}
+		results[p.MessageID] = result
+		anonymous[p.MessageID] = p.Anonymous
+	}
+
+	voters := make(map[int64]map[int64]bool, len(polls))
+	for _, v := range votes {
+		result := results[v.MessageID]
+		if result == nil || v.Option < 0 || v.Option >= len(result.Options) {
+			continue
+		}
+
+		option := &result.Options[v.Option]
+		option.Votes++
+		if !anonymous[v.MessageID] {
+			option.Voters = append(option.Voters, v.UserID)
+		}
+		if v.UserID == userID {
+			result.MyVotes = append(result.MyVotes, v.Option)
+		}
+
+		if voters[v.MessageID] == nil {
+			voters[v.MessageID] = make(map[int64]bool)
+		}
+		voters[v.MessageID][v.UserID] = true
+	}
+	for id, users := range voters {
+		results[id].TotalVoters = len(users)
+	}
+
+	return results, nil
+}
+
+// attachPolls дополняет сообщения-опросы итогами голосования
+func (m messageRepo) attachPolls(ctx context.Context, messages []models.Message, userID int64) error {
+	var ids []int64
+	for _, msg := range messages {
+		if msg.RichContent != nil && msg.RichContent.Kind == models.ContentKindPoll {
+			ids = append(ids, msg.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	results, err := m.GetPollResults(ctx, ids, userID)
+	if err != nil {
+		return err
+	}
+	for i := range messages {
+		messages[i].PollResults = results[messages[i].ID]
+	}
+	return nil
+}
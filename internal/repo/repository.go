@@ -2,7 +2,11 @@ package repo
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"messanger/config"
@@ -12,18 +16,74 @@ import (
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("duplicate")
+	// ErrAttachmentUnavailable вложение не существует, загружено другим пользователем или уже отправлено
+	ErrAttachmentUnavailable = errors.New("attachment unavailable")
+	// ErrPollClosed опрос закрыт и голоса больше не принимает
+	ErrPollClosed = errors.New("poll closed")
+	// ErrLimitReached в переписке уже столько записей, сколько разрешено
+	ErrLimitReached = errors.New("limit reached")
 )
 
 type MessageRepo interface {
-	SaveMessage(ctx context.Context, params SaveMessageParams) error
+	SaveMessage(ctx context.Context, params SaveMessageParams) (*models.Message, error)
+	SaveMessages(ctx context.Context, params []SaveMessageParams) ([]models.Message, error)
 	GetHistory(ctx context.Context, params GetHistoryParams) ([]models.Message, error)
+	GetMessageByID(ctx context.Context, messageID int64) (*models.Message, error)
+	GetMessageByClientMsgID(ctx context.Context, senderID int64, clientMsgID string) (*models.Message, error)
+	EditMessage(ctx context.Context, params EditMessageParams) (*models.Message, error)
+	GetMessageRevisions(ctx context.Context, messageID int64) ([]models.MessageRevision, error)
+	GetRoomMemberIDs(ctx context.Context, roomID int64) ([]int64, error)
+	GetRoomMember(ctx context.Context, roomID, userID int64) (*models.RoomMember, error)
+	AddRoomMember(ctx context.Context, roomID, userID int64) (*models.RoomMember, error)
+	RemoveRoomMember(ctx context.Context, roomID, userID int64) (bool, error)
+	DeleteMessageForEveryone(ctx context.Context, params DeleteMessageParams) (*DeletedMessage, error)
+	LockBlobs(ctx context.Context, hashes []string) (func(), error)
+	GetOrphanedStorageKeys(ctx context.Context, keys []string) ([]string, error)
+	HideMessage(ctx context.Context, params HideMessageParams) error
+	AddReaction(ctx context.Context, params ReactionParams) (bool, error)
+	RemoveReaction(ctx context.Context, params ReactionParams) (bool, error)
+	UpdateReceipts(ctx context.Context, params UpdateReceiptsParams) ([]models.Receipt, error)
+	GetMentions(ctx context.Context, params GetMentionsParams) ([]models.Mention, error)
+	CountUnreadMentions(ctx context.Context, userID int64) (int, error)
+	MarkMentionsRead(ctx context.Context, userID int64, messageIDs []int64) error
+	SetMessageTTL(ctx context.Context, params SetMessageTTLParams) error
+	PurgeExpiredMessages(ctx context.Context, limit int) (*PurgeResult, error)
+	PinMessage(ctx context.Context, params PinParams) (*models.Pin, error)
+	UnpinMessage(ctx context.Context, messageID int64) (bool, error)
+	GetPinnedMessages(ctx context.Context, params GetPinnedMessagesParams) ([]models.PinnedMessage, error)
+	SetForwarding(ctx context.Context, params SetForwardingParams) error
+	ForwardingDisabled(ctx context.Context, roomID int64, userIDs [2]int64) (bool, error)
+	SaveDraft(ctx context.Context, params DraftParams, content string) (*models.Draft, error)
+	DeleteDraft(ctx context.Context, params DraftParams) (bool, error)
+	GetDrafts(ctx context.Context, userID int64) ([]models.Draft, error)
+	GetConversations(ctx context.Context, userID int64) ([]models.Conversation, error)
+	ExportMessages(ctx context.Context, params ExportMessagesParams) ([]models.ExportedMessage, error)
+	SetRetention(ctx context.Context, params RetentionParams, policy models.RetentionPolicy) error
+	SetLegalHold(ctx context.Context, params RetentionParams, hold bool) error
+	GetRetention(ctx context.Context, params RetentionParams) (*models.RetentionPolicy, error)
+	PurgeRetainedMessages(ctx context.Context, params PurgeRetainedParams) (*PurgeResult, error)
+	Vote(ctx context.Context, params VoteParams) error
+	ClosePoll(ctx context.Context, messageID int64) (bool, error)
+	CloseDuePolls(ctx context.Context, limit int) ([]int64, error)
+	GetPollResults(ctx context.Context, messageIDs []int64, userID int64) (map[int64]*models.PollResults, error)
+	SetMessageFilters(ctx context.Context, roomID int64, filters *models.MessageFilters) error
+	GetMessageFilters(ctx context.Context, roomID int64) (*models.MessageFilters, error)
+	CountRecentMessages(ctx context.Context, params CountRecentMessagesParams) (int, error)
+	GetFlaggedMessages(ctx context.Context, params GetFlaggedMessagesParams) ([]models.FlaggedMessage, error)
 }
 
 type messageRepo struct {
 	db *sqlx.DB
 }
 
-func NewMessageRepo(cfg *config.Config) MessageRepo {
+// NewPostgresDB подключается к Postgres и применяет миграции; соединение разделяется всеми репозиториями
+func NewPostgresDB(cfg *config.Config) *sqlx.DB {
 	db := sqlx.MustConnect("postgres", fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		cfg.PG.Host,
@@ -42,6 +102,10 @@ func NewMessageRepo(cfg *config.Config) MessageRepo {
 		panic(fmt.Sprintf("failed to run migrations: %v", err))
 	}
 
+	return db
+}
+
+func NewMessageRepo(db *sqlx.DB) MessageRepo {
 	return &messageRepo{db: db}
 }
 
@@ -66,49 +130,394 @@ func Migration(db *sqlx.DB) error {
 }
 
 type message struct {
-	ID         int64      `db:"id"`
-	SenderID   int64      `db:"sender_id"`
-	ReceiverID int64      `db:"receiver_id"`
-	Content    string     `db:"content"`
-	SentAt     *time.Time `db:"sent_at"`
-	CreatedAt  time.Time  `db:"created_at"`
-	UpdatedAt  time.Time  `db:"updated_at"`
-	DeletedAt  *time.Time `db:"deleted_at"`
+	ID           int64      `db:"id"`
+	SenderID     int64      `db:"sender_id"`
+	ReceiverID   int64      `db:"receiver_id"`
+	RoomID       *int64     `db:"room_id"`
+	ClientMsgID  *string    `db:"client_msg_id"`
+	Content      string     `db:"content"`
+	RichContent  []byte     `db:"rich_content"`
+	LinkPreview  []byte     `db:"link_preview"`
+	SentAt       *time.Time `db:"sent_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at"`
+	DeletedAt    *time.Time `db:"deleted_at"`
+	DeletedBy    *int64     `db:"deleted_by"`
+	EditedAt     *time.Time `db:"edited_at"`
+	ErrorMessage *string    `db:"error_message"`
+	ExpiresAt    *time.Time `db:"expires_at"`
+	ViewOnce     bool       `db:"view_once"`
+	ReplyToID    *int64     `db:"reply_to_id"`
+	Seq          int64      `db:"seq"`
+
+	ForwardedMessageID  *int64     `db:"forwarded_message_id"`
+	ForwardedSenderID   *int64     `db:"forwarded_sender_id"`
+	ForwardedReceiverID *int64     `db:"forwarded_receiver_id"`
+	ForwardedRoomID     *int64     `db:"forwarded_room_id"`
+	ForwardedSentAt     *time.Time `db:"forwarded_sent_at"`
+}
+
+const messageColumns = `id, sender_id, receiver_id, room_id, client_msg_id, content, rich_content, link_preview, sent_at, created_at, updated_at, deleted_at, deleted_by, edited_at, error_message, expires_at, view_once, reply_to_id, seq,
+forwarded_message_id, forwarded_sender_id, forwarded_receiver_id, forwarded_room_id, forwarded_sent_at`
+
+func (m message) toModel() models.Message {
+	// Содержимое удалённого сообщения может храниться под legal hold, но участникам отдаётся только «надгробие»
+	if m.DeletedAt != nil {
+		m.Content, m.RichContent, m.LinkPreview = "", nil, nil
+	}
+	msg := models.Message{
+		ID:          m.ID,
+		SenderID:    m.SenderID,
+		ReceiverID:  m.ReceiverID,
+		Content:     m.Content,
+		RichContent: decodeRichContent(m.RichContent),
+		LinkPreview: decodeLinkPreview(m.LinkPreview),
+		SentAt:      m.SentAt,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+		DeletedAt:   m.DeletedAt,
+		Edited:      m.EditedAt != nil,
+		EditedAt:    m.EditedAt,
+		ExpiresAt:   m.ExpiresAt,
+		ViewOnce:    m.ViewOnce,
+		Seq:         m.Seq,
+	}
+	if m.RoomID != nil {
+		msg.RoomID = *m.RoomID
+	}
+	if m.DeletedBy != nil {
+		msg.DeletedBy = *m.DeletedBy
+	}
+	if m.ClientMsgID != nil {
+		msg.ClientMsgID = *m.ClientMsgID
+	}
+	if m.ReplyToID != nil {
+		msg.ReplyToID = *m.ReplyToID
+	}
+	if m.ForwardedMessageID != nil {
+		msg.ForwardedFrom = &models.ForwardedFrom{MessageID: *m.ForwardedMessageID}
+		if m.ForwardedSenderID != nil {
+			msg.ForwardedFrom.SenderID = *m.ForwardedSenderID
+		}
+		if m.ForwardedReceiverID != nil {
+			msg.ForwardedFrom.ReceiverID = *m.ForwardedReceiverID
+		}
+		if m.ForwardedRoomID != nil {
+			msg.ForwardedFrom.RoomID = *m.ForwardedRoomID
+		}
+		if m.ForwardedSentAt != nil {
+			msg.ForwardedFrom.SentAt = *m.ForwardedSentAt
+		}
+	}
+	return msg
+}
+
+// decodeRichContent разбирает JSONB-колонку; содержимое проверяется сервисом при записи,
+// поэтому повреждённое значение просто игнорируется, а клиенту остаётся текстовое представление
+func decodeRichContent(data []byte) *models.RichContent {
+	if len(data) == 0 {
+		return nil
+	}
+	var content models.RichContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil
+	}
+	return &content
+}
+
+// encodeRichContent готовит содержимое к записи в JSONB; nil сохраняется как NULL
+func encodeRichContent(content *models.RichContent) (*string, error) {
+	if content == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rich content: %w", err)
+	}
+	return nullableString(string(data)), nil
 }
 
 type SaveMessageParams struct {
 	SenderID   int64
 	ReceiverID int64
+	RoomID     int64
 	Content    string
+	// RichContent структурированное содержимое; Content при этом хранит его текстовое представление
+	RichContent *models.RichContent
+	// ClientMsgID необязательный ключ идемпотентности; повторная вставка с тем же ключом возвращает ErrDuplicate
+	ClientMsgID string
+	// AttachmentIDs ранее загруженные отправителем вложения
+	AttachmentIDs []int64
+	// Mentions упомянутые пользователи
+	Mentions []MentionParams
+	// RecipientIDs получатели, для которых заводятся статусы доставки
+	RecipientIDs []int64
+	// TTL время жизни сообщения; 0 — действует настройка переписки
+	TTL      time.Duration
+	ViewOnce bool
+	// ForwardedFrom происхождение пересланного сообщения
+	ForwardedFrom *models.ForwardedFrom
+	// CopyAttachmentsFrom сообщение, вложения которого переиспользуются без копирования содержимого
+	CopyAttachmentsFrom int64
+	// ClearDraft удаляет черновик отправителя в переписке
+	ClearDraft bool
+	// Flags пометки фильтров для модераторов
+	Flags []models.MessageFlag
 }
 
+// Если время жизни не задано для сообщения, берётся настройка комнаты или личной переписки
 const saveMessageQuery = `
-INSERT INTO messages (sender_id, receiver_id, content)
-VALUES ($1, $2, $3)
+INSERT INTO messages (sender_id, receiver_id, room_id, content, rich_content, client_msg_id, sent_at, expires_at, view_once,
+    forwarded_message_id, forwarded_sender_id, forwarded_receiver_id, forwarded_room_id, forwarded_sent_at, seq)
+VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP,
+    CURRENT_TIMESTAMP + make_interval(secs => COALESCE(
+        $7::INT,
+        (SELECT message_ttl FROM rooms WHERE id = $3),
+        (SELECT message_ttl FROM direct_chat_settings
+         WHERE $3::INT IS NULL AND user_low = LEAST($1, $2) AND user_high = GREATEST($1, $2))
+    )),
+    $8, $9, $10, $11, $12, $13, $14)
+ON CONFLICT (sender_id, client_msg_id) WHERE client_msg_id IS NOT NULL DO NOTHING
+RETURNING ` + messageColumns
+
+// Строка счётчика блокируется до конца транзакции: одновременные сообщения переписки получают
+// номера по очереди и фиксируются в том же порядке, а откат транзакции возвращает номер обратно
+const nextMessageSeqQuery = `
+INSERT INTO conversation_seqs (room_id, user_low, user_high, last_seq)
+VALUES ($1, $2, $3, 1)
+ON CONFLICT (room_id, user_low, user_high) DO UPDATE SET last_seq = conversation_seqs.last_seq + 1
+RETURNING last_seq
 `
 
-func (m messageRepo) SaveMessage(ctx context.Context, params SaveMessageParams) error {
-	_, err := m.db.ExecContext(ctx, saveMessageQuery, params.SenderID, params.ReceiverID, params.Content)
+// nextMessageSeq выдаёт следующий номер сообщения в комнате или личной переписке. Номера переписки
+// идут подряд без пропусков, пока сообщения не удаляются окончательно
+func nextMessageSeq(ctx context.Context, tx *sqlx.Tx, senderID, receiverID, roomID int64) (int64, error) {
+	var userLow, userHigh int64
+	if roomID == 0 {
+		userLow, userHigh = min(senderID, receiverID), max(senderID, receiverID)
+	}
+
+	var seq int64
+	if err := tx.GetContext(ctx, &seq, nextMessageSeqQuery, roomID, userLow, userHigh); err != nil {
+		return 0, fmt.Errorf("failed to assign message seq: %w", err)
+	}
+	return seq, nil
+}
+
+const createReceiptsQuery = `
+INSERT INTO message_receipts (message_id, user_id)
+SELECT $1, UNNEST($2::BIGINT[])
+ON CONFLICT (message_id, user_id) DO NOTHING
+`
+
+func (m messageRepo) SaveMessage(ctx context.Context, params SaveMessageParams) (*models.Message, error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to save message: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	return nil
+	defer tx.Rollback()
+
+	msg, err := saveMessage(ctx, tx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	msg.Status = models.ReceiptSent
+	return msg, nil
+}
+
+// SaveMessages сохраняет несколько сообщений в одной транзакции: либо все, либо ни одного
+func (m messageRepo) SaveMessages(ctx context.Context, params []SaveMessageParams) ([]models.Message, error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := make([]models.Message, len(params))
+	for i, p := range params {
+		msg, err := saveMessage(ctx, tx, p)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = *msg
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for i := range result {
+		result[i].Status = models.ReceiptSent
+	}
+	return result, nil
+}
+
+// saveMessage сохраняет сообщение вместе со статусами доставки, упоминаниями, опросом и вложениями в транзакции tx
+func saveMessage(ctx context.Context, tx *sqlx.Tx, params SaveMessageParams) (*models.Message, error) {
+	richContent, err := encodeRichContent(params.RichContent)
+	if err != nil {
+		return nil, err
+	}
+
+	var forwarded models.ForwardedFrom
+	var forwardedSentAt *time.Time
+	if params.ForwardedFrom != nil {
+		forwarded = *params.ForwardedFrom
+		forwardedSentAt = &forwarded.SentAt
+	}
+
+	seq, err := nextMessageSeq(ctx, tx, params.SenderID, params.ReceiverID, params.RoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg message
+	err = tx.GetContext(ctx, &msg, saveMessageQuery,
+		params.SenderID,
+		params.ReceiverID,
+		nullableID(params.RoomID),
+		params.Content,
+		richContent,
+		nullableString(params.ClientMsgID),
+		nullableInt(int(params.TTL.Seconds())),
+		params.ViewOnce,
+		nullableID(forwarded.MessageID),
+		nullableID(forwarded.SenderID),
+		nullableID(forwarded.ReceiverID),
+		nullableID(forwarded.RoomID),
+		forwardedSentAt,
+		seq,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDuplicate
+		}
+		return nil, fmt.Errorf("failed to save message: %w", err)
+	}
+
+	if len(params.RecipientIDs) > 0 {
+		if _, err = tx.ExecContext(ctx, createReceiptsQuery, msg.ID, pq.Array(params.RecipientIDs)); err != nil {
+			return nil, fmt.Errorf("failed to create receipts: %w", err)
+		}
+	}
+
+	if len(params.Mentions) > 0 {
+		if err = createMentions(ctx, tx, msg.ID, params.Mentions); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(params.Flags) > 0 {
+		if err = createFlags(ctx, tx, msg.ID, params.Flags); err != nil {
+			return nil, err
+		}
+	}
+
+	if params.RichContent != nil && params.RichContent.Kind == models.ContentKindPoll {
+		if err = createPoll(ctx, tx, msg.ID, params.RichContent.Poll); err != nil {
+			return nil, err
+		}
+	}
+
+	if params.ClearDraft {
+		draft := DraftParams{UserID: params.SenderID, RoomID: params.RoomID}
+		if params.RoomID == 0 {
+			draft.ReceiverID = params.ReceiverID
+		}
+		if _, err = tx.ExecContext(ctx, deleteDraftQuery, draft.UserID, draft.RoomID, draft.ReceiverID); err != nil {
+			return nil, fmt.Errorf("failed to delete draft: %w", err)
+		}
+	}
+
+	if params.CopyAttachmentsFrom != 0 {
+		if err = copyAttachments(ctx, tx, &msg, params.CopyAttachmentsFrom); err != nil {
+			return nil, err
+		}
+	}
+
+	result := []models.Message{msg.toModel()}
+	if len(params.AttachmentIDs) > 0 || params.CopyAttachmentsFrom != 0 {
+		if len(params.AttachmentIDs) > 0 {
+			if err = linkAttachments(ctx, tx, msg.ID, params.SenderID, params.AttachmentIDs); err != nil {
+				return nil, err
+			}
+		}
+		if err = attachAttachments(ctx, tx, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return &result[0], nil
+}
+
+// nullableID превращает нулевой идентификатор в NULL
+func nullableID(id int64) *int64 {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// nullableString превращает пустую строку в NULL
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
 
 type GetHistoryParams struct {
+	// SenderID пользователь, запросивший историю
 	SenderID   int64
 	ReceiverID int64
+	// RoomID задаётся для истории комнаты; ReceiverID в этом случае не используется
+	RoomID int64
+	// BeforeSeq возвращаются сообщения с меньшим номером; 0 — с последнего сообщения
+	BeforeSeq int64
+	// AfterSeq возвращаются ближайшие сообщения с большим номером; задаётся вместо BeforeSeq
+	AfterSeq int64
+	Limit    int
 }
 
+// Сообщения, удалённые для всех, возвращаются как «надгробия» с пустым текстом,
+// а скрытые пользователем для себя — не возвращаются вовсе
 const getHistoryQuery = `
-SELECT * FROM messages
-WHERE (sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1)
-ORDER BY created_at DESC
-LIMIT 100
+SELECT ` + messageColumns + ` FROM messages
+WHERE %s
+AND ($3::BIGINT = 0 OR seq < $3)
+AND seq > $4
+AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = messages.id AND h.user_id = $1)
+AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+ORDER BY seq %s
+LIMIT $5
 `
 
+// Условия переписки для getHistoryQuery: $1 — пользователь, $2 — собеседник или комната
+const (
+	directHistoryFilter = `room_id IS NULL
+AND LEAST(sender_id, receiver_id) = LEAST($1::BIGINT, $2::BIGINT) AND GREATEST(sender_id, receiver_id) = GREATEST($1::BIGINT, $2::BIGINT)`
+	roomHistoryFilter = `room_id = $2`
+)
+
+// GetHistory возвращает страницу истории личной переписки или комнаты от новых сообщений к старым
 func (m messageRepo) GetHistory(ctx context.Context, params GetHistoryParams) ([]models.Message, error) {
-	rows, err := m.db.QueryxContext(ctx, getHistoryQuery, params.SenderID, params.ReceiverID)
+	filter, target := directHistoryFilter, params.ReceiverID
+	if params.RoomID != 0 {
+		filter, target = roomHistoryFilter, params.RoomID
+	}
+	// После AfterSeq нужны ближайшие сообщения, поэтому они выбираются по возрастанию номера
+	order := "DESC"
+	if params.AfterSeq > 0 {
+		order = "ASC"
+	}
+
+	query := fmt.Sprintf(getHistoryQuery, filter, order)
+	rows, err := m.db.QueryxContext(ctx, query, params.SenderID, target, params.BeforeSeq, params.AfterSeq, params.Limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get message history: %w", err)
 	}
@@ -120,17 +529,574 @@ func (m messageRepo) GetHistory(ctx context.Context, params GetHistoryParams) ([
 		if err = rows.StructScan(&message); err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
-		messages = append(messages, models.Message{
-			ID:         message.ID,
-			SenderID:   message.SenderID,
-			ReceiverID: message.ReceiverID,
-			Content:    message.Content,
-			SentAt:     message.SentAt,
-			CreatedAt:  message.CreatedAt,
-			UpdatedAt:  message.UpdatedAt,
-			DeletedAt:  message.DeletedAt,
-		})
+		messages = append(messages, message.toModel())
+	}
+	if params.AfterSeq > 0 {
+		slices.Reverse(messages)
+	}
+
+	if err = attachAttachments(ctx, m.db, messages); err != nil {
+		return nil, err
+	}
+	if err = m.attachReactions(ctx, messages, params.SenderID); err != nil {
+		return nil, err
+	}
+	if err = m.attachPins(ctx, messages); err != nil {
+		return nil, err
+	}
+	if err = m.attachPolls(ctx, messages, params.SenderID); err != nil {
+		return nil, err
+	}
+	if err = m.attachReceipts(ctx, messages, params.SenderID); err != nil {
+		return nil, err
 	}
 
 	return messages, nil
 }
+
+const getMessageByIDQuery = `
+SELECT ` + messageColumns + ` FROM messages
+WHERE id = $1
+`
+
+func (m messageRepo) GetMessageByID(ctx context.Context, messageID int64) (*models.Message, error) {
+	var msg message
+	if err := m.db.GetContext(ctx, &msg, getMessageByIDQuery, messageID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	result := msg.toModel()
+	return &result, nil
+}
+
+const getMessageByClientMsgIDQuery = `
+SELECT ` + messageColumns + ` FROM messages
+WHERE sender_id = $1 AND client_msg_id = $2
+`
+
+func (m messageRepo) GetMessageByClientMsgID(ctx context.Context, senderID int64, clientMsgID string) (*models.Message, error) {
+	var msg message
+	if err := m.db.GetContext(ctx, &msg, getMessageByClientMsgIDQuery, senderID, clientMsgID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	result := msg.toModel()
+	return &result, nil
+}
+
+type EditMessageParams struct {
+	MessageID int64
+	EditorID  int64
+	Content   string
+	// RichContent новое структурированное содержимое; nil делает сообщение простым текстом
+	RichContent *models.RichContent
+	// Flags пометки фильтров, вызванные новой версией
+	Flags []models.MessageFlag
+}
+
+const lockMessageContentQuery = `
+SELECT content, rich_content FROM messages
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+const saveRevisionQuery = `
+INSERT INTO message_edits (message_id, editor_id, content, rich_content)
+VALUES ($1, $2, $3, $4)
+`
+
+const editMessageQuery = `
+UPDATE messages
+SET content = $2, rich_content = $3, link_preview = NULL, edited_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING ` + messageColumns
+
+// EditMessage сохраняет текущую версию сообщения в message_edits и заменяет её новой в одной транзакции
+func (m messageRepo) EditMessage(ctx context.Context, params EditMessageParams) (*models.Message, error) {
+	richContent, err := encodeRichContent(params.RichContent)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous struct {
+		Content     string  `db:"content"`
+		RichContent *string `db:"rich_content"`
+	}
+	if err = tx.GetContext(ctx, &previous, lockMessageContentQuery, params.MessageID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock message: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, saveRevisionQuery, params.MessageID, params.EditorID, previous.Content, previous.RichContent); err != nil {
+		return nil, fmt.Errorf("failed to save revision: %w", err)
+	}
+
+	var msg message
+	if err = tx.GetContext(ctx, &msg, editMessageQuery, params.MessageID, params.Content, richContent); err != nil {
+		return nil, fmt.Errorf("failed to edit message: %w", err)
+	}
+
+	if len(params.Flags) > 0 {
+		if err = createFlags(ctx, tx, msg.ID, params.Flags); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result := msg.toModel()
+	return &result, nil
+}
+
+type messageRevision struct {
+	ID          int64     `db:"id"`
+	MessageID   int64     `db:"message_id"`
+	EditorID    int64     `db:"editor_id"`
+	Content     string    `db:"content"`
+	RichContent []byte    `db:"rich_content"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+func (r messageRevision) toModel() models.MessageRevision {
+	return models.MessageRevision{
+		ID:          r.ID,
+		MessageID:   r.MessageID,
+		EditorID:    r.EditorID,
+		Content:     r.Content,
+		RichContent: decodeRichContent(r.RichContent),
+		EditedAt:    r.CreatedAt,
+	}
+}
+
+const getMessageRevisionsQuery = `
+SELECT id, message_id, editor_id, content, rich_content, created_at FROM message_edits
+WHERE message_id = $1
+ORDER BY id
+`
+
+func (m messageRepo) GetMessageRevisions(ctx context.Context, messageID int64) ([]models.MessageRevision, error) {
+	var revisions []messageRevision
+	if err := m.db.SelectContext(ctx, &revisions, getMessageRevisionsQuery, messageID); err != nil {
+		return nil, fmt.Errorf("failed to get message revisions: %w", err)
+	}
+
+	result := make([]models.MessageRevision, len(revisions))
+	for i, r := range revisions {
+		result[i] = r.toModel()
+	}
+
+	return result, nil
+}
+
+const getRoomMemberIDsQuery = `
+SELECT user_id FROM room_members
+WHERE room_id = $1
+`
+
+func (m messageRepo) GetRoomMemberIDs(ctx context.Context, roomID int64) ([]int64, error) {
+	var userIDs []int64
+	if err := m.db.SelectContext(ctx, &userIDs, getRoomMemberIDsQuery, roomID); err != nil {
+		return nil, fmt.Errorf("failed to get room members: %w", err)
+	}
+	return userIDs, nil
+}
+
+type roomMember struct {
+	RoomID   int64     `db:"room_id"`
+	UserID   int64     `db:"user_id"`
+	JoinedAt time.Time `db:"joined_at"`
+	IsAdmin  bool      `db:"is_admin"`
+}
+
+const getRoomMemberQuery = `
+SELECT room_id, user_id, joined_at, is_admin FROM room_members
+WHERE room_id = $1 AND user_id = $2
+`
+
+func (m messageRepo) GetRoomMember(ctx context.Context, roomID, userID int64) (*models.RoomMember, error) {
+	var member roomMember
+	if err := m.db.GetContext(ctx, &member, getRoomMemberQuery, roomID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get room member: %w", err)
+	}
+
+	return &models.RoomMember{
+		RoomID:   member.RoomID,
+		UserID:   member.UserID,
+		JoinedAt: member.JoinedAt,
+		IsAdmin:  member.IsAdmin,
+	}, nil
+}
+
+type DeleteMessageParams struct {
+	MessageID int64
+	DeletedBy int64
+}
+
+const deleteMessageForEveryoneQuery = `
+UPDATE messages
+SET content = '', rich_content = NULL, link_preview = NULL, deleted_at = CURRENT_TIMESTAMP, deleted_by = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING ` + messageColumns
+
+// Под legal hold содержимое удалённого сообщения сохраняется; участникам оно не показывается, см. message.toModel
+const deleteHeldMessageQuery = `
+UPDATE messages
+SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING ` + messageColumns
+
+const getMessageLegalHoldQuery = `
+SELECT ` + legalHold + ` FROM messages m
+` + conversationSettingsJoin + `
+WHERE m.id = $1
+`
+
+const deleteMessageRevisionsQuery = `
+DELETE FROM message_edits
+WHERE message_id = $1
+`
+
+const deleteMessageReactionsQuery = `
+DELETE FROM message_reactions
+WHERE message_id = $1
+`
+
+const deleteMessageAttachmentsQuery = `
+DELETE FROM attachments
+WHERE message_id = $1
+`
+
+const deleteMessageMentionsQuery = `
+DELETE FROM mentions
+WHERE message_id = $1
+`
+
+const deleteMessagePinQuery = `
+DELETE FROM pinned_messages
+WHERE message_id = $1
+`
+
+// DeletedMessage «надгробие» удалённого сообщения и ключи объектов хранилища его вложений
+type DeletedMessage struct {
+	Message     models.Message
+	StorageKeys []string
+}
+
+// DeleteMessageForEveryone стирает текст сообщения вместе с историей правок, реакциями, вложениями, упоминаниями и закреплением, оставляя «надгробие».
+// В переписке под legal hold текст, правки и вложения сохраняются, но участникам не показываются.
+// Из-за дедупликации на объекты хранилища могут ссылаться другие вложения, поэтому вызывающий
+// удаляет только те из StorageKeys, что вернёт GetOrphanedStorageKeys
+func (m messageRepo) DeleteMessageForEveryone(ctx context.Context, params DeleteMessageParams) (*DeletedMessage, error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var held bool
+	if err = tx.GetContext(ctx, &held, getMessageLegalHoldQuery, params.MessageID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+
+	query := deleteMessageForEveryoneQuery
+	if held {
+		query = deleteHeldMessageQuery
+	}
+	var msg message
+	if err = tx.GetContext(ctx, &msg, query, params.MessageID, params.DeletedBy); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteMessageReactionsQuery, params.MessageID); err != nil {
+		return nil, fmt.Errorf("failed to delete message reactions: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteMessageMentionsQuery, params.MessageID); err != nil {
+		return nil, fmt.Errorf("failed to delete message mentions: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteMessagePinQuery, params.MessageID); err != nil {
+		return nil, fmt.Errorf("failed to delete message pin: %w", err)
+	}
+
+	result := &DeletedMessage{Message: msg.toModel()}
+	// Правки и вложения — тоже содержимое сообщения, поэтому под legal hold остаются на месте
+	if !held {
+		if _, err = tx.ExecContext(ctx, deleteMessageRevisionsQuery, params.MessageID); err != nil {
+			return nil, fmt.Errorf("failed to delete message revisions: %w", err)
+		}
+
+		// Ключи собираются до удаления строк вложений: после него их уже не найти
+		if err = tx.SelectContext(ctx, &result.StorageKeys, getMessageStorageKeysQuery, pq.Array([]int64{params.MessageID})); err != nil {
+			return nil, fmt.Errorf("failed to get storage keys: %w", err)
+		}
+
+		if _, err = tx.ExecContext(ctx, deleteMessageAttachmentsQuery, params.MessageID); err != nil {
+			return nil, fmt.Errorf("failed to delete message attachments: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+type HideMessageParams struct {
+	MessageID int64
+	UserID    int64
+}
+
+const hideMessageQuery = `
+INSERT INTO hidden_messages (message_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (message_id, user_id) DO NOTHING
+`
+
+func (m messageRepo) HideMessage(ctx context.Context, params HideMessageParams) error {
+	if _, err := m.db.ExecContext(ctx, hideMessageQuery, params.MessageID, params.UserID); err != nil {
+		return fmt.Errorf("failed to hide message: %w", err)
+	}
+	return nil
+}
+
+type ReactionParams struct {
+	MessageID int64
+	UserID    int64
+	Emoji     string
+}
+
+const addReactionQuery = `
+INSERT INTO message_reactions (message_id, user_id, emoji)
+VALUES ($1, $2, $3)
+ON CONFLICT (message_id, user_id, emoji) DO NOTHING
+`
+
+// AddReaction добавляет реакцию и сообщает, была ли она новой
+func (m messageRepo) AddReaction(ctx context.Context, params ReactionParams) (bool, error) {
+	res, err := m.db.ExecContext(ctx, addReactionQuery, params.MessageID, params.UserID, params.Emoji)
+	if err != nil {
+		return false, fmt.Errorf("failed to add reaction: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+const removeReactionQuery = `
+DELETE FROM message_reactions
+WHERE message_id = $1 AND user_id = $2 AND emoji = $3
+`
+
+// RemoveReaction удаляет реакцию и сообщает, существовала ли она
+func (m messageRepo) RemoveReaction(ctx context.Context, params ReactionParams) (bool, error) {
+	res, err := m.db.ExecContext(ctx, removeReactionQuery, params.MessageID, params.UserID, params.Emoji)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove reaction: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+type reactionSummary struct {
+	MessageID   int64  `db:"message_id"`
+	Emoji       string `db:"emoji"`
+	Count       int    `db:"count"`
+	ReactedByMe bool   `db:"reacted_by_me"`
+}
+
+const getReactionSummariesQuery = `
+SELECT message_id, emoji, COUNT(*) AS count, BOOL_OR(user_id = $2) AS reacted_by_me
+FROM message_reactions
+WHERE message_id = ANY($1)
+GROUP BY message_id, emoji
+ORDER BY message_id, MIN(created_at)
+`
+
+// attachReactions дополняет сообщения агрегированными реакциями с точки зрения пользователя viewerID
+func (m messageRepo) attachReactions(ctx context.Context, messages []models.Message, viewerID int64) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(messages))
+	byID := make(map[int64]*models.Message, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+		byID[messages[i].ID] = &messages[i]
+	}
+
+	var summaries []reactionSummary
+	if err := m.db.SelectContext(ctx, &summaries, getReactionSummariesQuery, pq.Array(ids), viewerID); err != nil {
+		return fmt.Errorf("failed to get reactions: %w", err)
+	}
+
+	for _, r := range summaries {
+		msg := byID[r.MessageID]
+		msg.Reactions = append(msg.Reactions, models.ReactionSummary{
+			Emoji:       r.Emoji,
+			Count:       r.Count,
+			ReactedByMe: r.ReactedByMe,
+		})
+	}
+
+	return nil
+}
+
+type UpdateReceiptsParams struct {
+	UserID     int64
+	MessageIDs []int64
+	Status     string
+}
+
+// Статус меняется только вперёд: sent -> delivered -> read
+const markDeliveredQuery = `
+UPDATE message_receipts r
+SET status = 'delivered', delivered_at = CURRENT_TIMESTAMP
+FROM messages m
+WHERE m.id = r.message_id AND r.user_id = $1 AND r.message_id = ANY($2) AND r.status = 'sent'
+RETURNING r.message_id, m.sender_id, r.user_id, r.status, r.delivered_at AS at
+`
+
+// Первое прочтение view once сообщения сразу делает его истёкшим: оно пропадает из истории и удаляется очисткой
+const markReadQuery = `
+WITH updated AS (
+    UPDATE message_receipts r
+    SET status = 'read', read_at = CURRENT_TIMESTAMP, delivered_at = COALESCE(r.delivered_at, CURRENT_TIMESTAMP)
+    FROM messages m
+    WHERE m.id = r.message_id AND r.user_id = $1 AND r.message_id = ANY($2) AND r.status <> 'read'
+    RETURNING r.message_id, m.sender_id, r.user_id, r.status, r.read_at AS at
+), expired AS (
+    UPDATE messages
+    SET expires_at = CURRENT_TIMESTAMP
+    WHERE id IN (SELECT message_id FROM updated) AND view_once
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+)
+SELECT message_id, sender_id, user_id, status, at FROM updated
+`
+
+type receipt struct {
+	MessageID int64     `db:"message_id"`
+	SenderID  int64     `db:"sender_id"`
+	UserID    int64     `db:"user_id"`
+	Status    string    `db:"status"`
+	At        time.Time `db:"at"`
+}
+
+// UpdateReceipts продвигает статусы доставки получателя и возвращает фактически изменённые
+func (m messageRepo) UpdateReceipts(ctx context.Context, params UpdateReceiptsParams) ([]models.Receipt, error) {
+	var query string
+	switch params.Status {
+	case models.ReceiptDelivered:
+		query = markDeliveredQuery
+	case models.ReceiptRead:
+		query = markReadQuery
+	default:
+		return nil, fmt.Errorf("unsupported receipt status %q", params.Status)
+	}
+
+	var receipts []receipt
+	if err := m.db.SelectContext(ctx, &receipts, query, params.UserID, pq.Array(params.MessageIDs)); err != nil {
+		return nil, fmt.Errorf("failed to update receipts: %w", err)
+	}
+
+	result := make([]models.Receipt, len(receipts))
+	for i, r := range receipts {
+		result[i] = models.Receipt{
+			MessageID: r.MessageID,
+			SenderID:  r.SenderID,
+			UserID:    r.UserID,
+			Status:    r.Status,
+			At:        r.At,
+		}
+	}
+
+	return result, nil
+}
+
+type recipientReceipt struct {
+	MessageID int64      `db:"message_id"`
+	UserID    int64      `db:"user_id"`
+	Status    string     `db:"status"`
+	ReadAt    *time.Time `db:"read_at"`
+}
+
+const getOwnReceiptsQuery = `
+SELECT r.message_id, r.user_id, r.status, r.read_at
+FROM message_receipts r
+JOIN messages m ON m.id = r.message_id
+WHERE r.message_id = ANY($1) AND m.sender_id = $2
+ORDER BY r.read_at
+`
+
+var receiptRank = map[string]int{
+	models.ReceiptSent:      0,
+	models.ReceiptDelivered: 1,
+	models.ReceiptRead:      2,
+}
+
+// attachReceipts выставляет собственным сообщениям пользователя наименьший статус среди получателей,
+// а сообщениям в комнатах — список прочитавших
+func (m messageRepo) attachReceipts(ctx context.Context, messages []models.Message, viewerID int64) error {
+	ids := make([]int64, 0, len(messages))
+	byID := make(map[int64]*models.Message, len(messages))
+	for i := range messages {
+		if messages[i].SenderID != viewerID {
+			continue
+		}
+		ids = append(ids, messages[i].ID)
+		byID[messages[i].ID] = &messages[i]
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var receipts []recipientReceipt
+	if err := m.db.SelectContext(ctx, &receipts, getOwnReceiptsQuery, pq.Array(ids), viewerID); err != nil {
+		return fmt.Errorf("failed to get receipts: %w", err)
+	}
+
+	for _, r := range receipts {
+		msg := byID[r.MessageID]
+		if msg.Status == "" || receiptRank[r.Status] < receiptRank[msg.Status] {
+			msg.Status = r.Status
+		}
+		if msg.RoomID != 0 && r.ReadAt != nil {
+			msg.SeenBy = append(msg.SeenBy, models.SeenBy{UserID: r.UserID, ReadAt: *r.ReadAt})
+		}
+	}
+
+	return nil
+}
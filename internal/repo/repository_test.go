@@ -0,0 +1,46 @@
+package repo
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDB подключается к базе из TEST_PG_DSN и применяет миграции; без переменной тест пропускается
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_PG_DSN")
+	if dsn == "" {
+		t.Skip("TEST_PG_DSN is not set")
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
+	require.NoError(t, err)
+	m, err := migrate.NewWithDatabaseInstance("file://migrations", "postgres", driver)
+	require.NoError(t, err)
+	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		require.NoError(t, err)
+	}
+	return db
+}
+
+// createTestRoom создаёт комнату, которая удаляется вместе с сообщениями после теста
+func createTestRoom(t *testing.T, db *sqlx.DB, legalHold bool) int64 {
+	t.Helper()
+
+	var roomID int64
+	err := db.Get(&roomID, `INSERT INTO rooms (name, creator_id, legal_hold) VALUES ('test', 1, $1) RETURNING id`, legalHold)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Exec(`DELETE FROM rooms WHERE id = $1`, roomID) })
+	return roomID
+}
@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"messanger/internal/models"
+)
+
+type RetentionParams struct {
+	// RoomID задаётся для комнаты; иначе настройка относится к личной переписке UserIDs
+	RoomID  int64
+	UserIDs [2]int64
+}
+
+const setRoomRetentionQuery = `
+UPDATE rooms SET deleted_retention_days = $2, message_retention_days = $3
+WHERE id = $1
+`
+
+const setDirectRetentionQuery = `
+INSERT INTO direct_chat_settings (user_low, user_high, deleted_retention_days, message_retention_days)
+VALUES (LEAST($1::BIGINT, $2::BIGINT), GREATEST($1::BIGINT, $2::BIGINT), $3, $4)
+ON CONFLICT (user_low, user_high) DO UPDATE
+SET deleted_retention_days = EXCLUDED.deleted_retention_days, message_retention_days = EXCLUDED.message_retention_days
+`
+
+// SetRetention задаёт сроки хранения переписки; LegalHold из policy не используется
+func (m messageRepo) SetRetention(ctx context.Context, params RetentionParams, policy models.RetentionPolicy) error {
+	if params.RoomID != 0 {
+		res, err := m.db.ExecContext(ctx, setRoomRetentionQuery, params.RoomID, policy.DeletedDays, policy.MessageDays)
+		if err != nil {
+			return fmt.Errorf("failed to set room retention: %w", err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if affected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	}
+
+	if _, err := m.db.ExecContext(ctx, setDirectRetentionQuery,
+		params.UserIDs[0], params.UserIDs[1], policy.DeletedDays, policy.MessageDays,
+	); err != nil {
+		return fmt.Errorf("failed to set direct retention: %w", err)
+	}
+	return nil
+}
+
+const setRoomLegalHoldQuery = `
+UPDATE rooms SET legal_hold = $2
+WHERE id = $1
+`
+
+const setDirectLegalHoldQuery = `
+INSERT INTO direct_chat_settings (user_low, user_high, legal_hold)
+VALUES (LEAST($1::BIGINT, $2::BIGINT), GREATEST($1::BIGINT, $2::BIGINT), $3)
+ON CONFLICT (user_low, user_high) DO UPDATE SET legal_hold = EXCLUDED.legal_hold
+`
+
+// SetLegalHold исключает переписку из очистки по срокам хранения или возвращает её
+func (m messageRepo) SetLegalHold(ctx context.Context, params RetentionParams, hold bool) error {
+	if params.RoomID != 0 {
+		res, err := m.db.ExecContext(ctx, setRoomLegalHoldQuery, params.RoomID, hold)
+		if err != nil {
+			return fmt.Errorf("failed to set room legal hold: %w", err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if affected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	}
+
+	if _, err := m.db.ExecContext(ctx, setDirectLegalHoldQuery, params.UserIDs[0], params.UserIDs[1], hold); err != nil {
+		return fmt.Errorf("failed to set direct legal hold: %w", err)
+	}
+	return nil
+}
+
+const getRoomRetentionQuery = `
+SELECT deleted_retention_days, message_retention_days, legal_hold FROM rooms
+WHERE id = $1
+`
+
+const getDirectRetentionQuery = `
+SELECT deleted_retention_days, message_retention_days, legal_hold FROM direct_chat_settings
+WHERE user_low = LEAST($1::BIGINT, $2::BIGINT) AND user_high = GREATEST($1::BIGINT, $2::BIGINT)
+`
+
+type retentionPolicy struct {
+	DeletedDays sql.NullInt32 `db:"deleted_retention_days"`
+	MessageDays sql.NullInt32 `db:"message_retention_days"`
+	LegalHold   bool          `db:"legal_hold"`
+}
+
+func (r retentionPolicy) toModel() models.RetentionPolicy {
+	policy := models.RetentionPolicy{LegalHold: r.LegalHold}
+	if r.DeletedDays.Valid {
+		days := int(r.DeletedDays.Int32)
+		policy.DeletedDays = &days
+	}
+	if r.MessageDays.Valid {
+		days := int(r.MessageDays.Int32)
+		policy.MessageDays = &days
+	}
+	return policy
+}
+
+// GetRetention возвращает собственные настройки переписки без учёта глобальных значений
+func (m messageRepo) GetRetention(ctx context.Context, params RetentionParams) (*models.RetentionPolicy, error) {
+	var (
+		row retentionPolicy
+		err error
+	)
+	if params.RoomID != 0 {
+		err = m.db.GetContext(ctx, &row, getRoomRetentionQuery, params.RoomID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+	} else {
+		err = m.db.GetContext(ctx, &row, getDirectRetentionQuery, params.UserIDs[0], params.UserIDs[1])
+		// Настройки личной переписки создаются при первом изменении
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention: %w", err)
+	}
+
+	policy := row.toModel()
+	return &policy, nil
+}
+
+type PurgeRetainedParams struct {
+	// DeletedDays глобальный срок хранения удалённых сообщений; 0 — бессрочно
+	DeletedDays int
+	// MessageDays глобальный срок хранения всех сообщений; 0 — бессрочно
+	MessageDays int
+	Limit       int
+}
+
+// conversationSettingsJoin присоединяет к сообщению m настройки его переписки: r — комнаты, d — личного диалога.
+// Личные настройки присоединяются только к сообщениям без комнаты, поэтому COALESCE по r и d берёт
+// либо настройку комнаты, либо настройку диалога
+const conversationSettingsJoin = `
+LEFT JOIN rooms r ON r.id = m.room_id
+LEFT JOIN direct_chat_settings d ON m.room_id IS NULL
+    AND d.user_low = LEAST(m.sender_id, m.receiver_id) AND d.user_high = GREATEST(m.sender_id, m.receiver_id)
+`
+
+// legalHold условие «переписка под legal hold» для conversationSettingsJoin
+const legalHold = `COALESCE(r.legal_hold, d.legal_hold, FALSE)`
+
+// Настройки переписки перекрывают глобальные сроки ($1, $2)
+const lockRetainedMessagesQuery = `
+SELECT m.id FROM messages m
+` + conversationSettingsJoin + `
+WHERE NOT ` + legalHold + `
+AND (
+    (m.deleted_at IS NOT NULL
+        AND COALESCE(r.deleted_retention_days, d.deleted_retention_days, $1::INT) > 0
+        AND m.deleted_at <= CURRENT_TIMESTAMP - MAKE_INTERVAL(days => COALESCE(r.deleted_retention_days, d.deleted_retention_days, $1::INT)))
+    OR (COALESCE(r.message_retention_days, d.message_retention_days, $2::INT) > 0
+        AND m.created_at <= CURRENT_TIMESTAMP - MAKE_INTERVAL(days => COALESCE(r.message_retention_days, d.message_retention_days, $2::INT)))
+)
+ORDER BY m.id
+LIMIT $3
+FOR UPDATE OF m SKIP LOCKED
+`
+
+// PurgeRetainedMessages окончательно удаляет сообщения, срок хранения которых истёк.
+// Переписки под legal hold пропускаются; удаление идёт так же, как у истёкших сообщений
+func (m messageRepo) PurgeRetainedMessages(ctx context.Context, params PurgeRetainedParams) (*PurgeResult, error) {
+	return m.purgeMessages(ctx, lockRetainedMessagesQuery, params.DeletedDays, params.MessageDays, params.Limit)
+}
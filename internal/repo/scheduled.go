@@ -0,0 +1,311 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"messanger/internal/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrLocked отложенное сообщение прямо сейчас отправляется и не может быть изменено
+var ErrLocked = errors.New("locked")
+
+type ScheduledMessageRepo interface {
+	CreateScheduledMessage(ctx context.Context, params CreateScheduledMessageParams) (*models.ScheduledMessage, error)
+	GetScheduledMessage(ctx context.Context, id int64) (*models.ScheduledMessage, error)
+	GetScheduledMessages(ctx context.Context, senderID int64) ([]models.ScheduledMessage, error)
+	UpdateScheduledMessage(ctx context.Context, params UpdateScheduledMessageParams) (*models.ScheduledMessage, error)
+	DeleteScheduledMessage(ctx context.Context, id int64) error
+	ClaimDueScheduledMessages(ctx context.Context, params ClaimScheduledMessagesParams) ([]ClaimedScheduledMessage, error)
+	CompleteScheduledMessage(ctx context.Context, id int64) error
+	FailScheduledMessage(ctx context.Context, id int64, reason string) error
+}
+
+type scheduledMessageRepo struct {
+	db *sqlx.DB
+}
+
+func NewScheduledMessageRepo(db *sqlx.DB) ScheduledMessageRepo {
+	return &scheduledMessageRepo{db: db}
+}
+
+type scheduledMessage struct {
+	ID            int64         `db:"id"`
+	SenderID      int64         `db:"sender_id"`
+	ReceiverID    int64         `db:"receiver_id"`
+	RoomID        *int64        `db:"room_id"`
+	Content       string        `db:"content"`
+	RichContent   []byte        `db:"rich_content"`
+	AttachmentIDs pq.Int64Array `db:"attachment_ids"`
+	SendAt        time.Time     `db:"send_at"`
+	Status        string        `db:"status"`
+	Error         *string       `db:"error"`
+	Attempts      int           `db:"attempts"`
+	LockedUntil   *time.Time    `db:"locked_until"`
+	CreatedAt     time.Time     `db:"created_at"`
+	UpdatedAt     time.Time     `db:"updated_at"`
+}
+
+const scheduledMessageColumns = `id, sender_id, receiver_id, room_id, content, rich_content, attachment_ids, send_at, status, error, attempts, locked_until, created_at, updated_at`
+
+func (s scheduledMessage) toModel() models.ScheduledMessage {
+	result := models.ScheduledMessage{
+		ID:          s.ID,
+		SenderID:    s.SenderID,
+		ReceiverID:  s.ReceiverID,
+		Content:     s.Content,
+		RichContent: decodeRichContent(s.RichContent),
+		SendAt:      s.SendAt,
+		Status:      s.Status,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+	if s.RoomID != nil {
+		result.RoomID = *s.RoomID
+	}
+	if len(s.AttachmentIDs) > 0 {
+		result.AttachmentIDs = s.AttachmentIDs
+	}
+	if s.Error != nil {
+		result.Error = *s.Error
+	}
+	return result
+}
+
+type CreateScheduledMessageParams struct {
+	SenderID      int64
+	ReceiverID    int64
+	RoomID        int64
+	Content       string
+	RichContent   *models.RichContent
+	AttachmentIDs []int64
+	SendAt        time.Time
+}
+
+const createScheduledMessageQuery = `
+INSERT INTO scheduled_messages (sender_id, receiver_id, room_id, content, rich_content, attachment_ids, send_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING ` + scheduledMessageColumns
+
+func (r scheduledMessageRepo) CreateScheduledMessage(ctx context.Context, params CreateScheduledMessageParams) (*models.ScheduledMessage, error) {
+	richContent, err := encodeRichContent(params.RichContent)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg scheduledMessage
+	err = r.db.GetContext(ctx, &msg, createScheduledMessageQuery,
+		params.SenderID,
+		params.ReceiverID,
+		nullableID(params.RoomID),
+		params.Content,
+		richContent,
+		pq.Array(params.AttachmentIDs),
+		params.SendAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scheduled message: %w", err)
+	}
+
+	result := msg.toModel()
+	return &result, nil
+}
+
+const getScheduledMessageQuery = `
+SELECT ` + scheduledMessageColumns + ` FROM scheduled_messages
+WHERE id = $1
+`
+
+func (r scheduledMessageRepo) GetScheduledMessage(ctx context.Context, id int64) (*models.ScheduledMessage, error) {
+	var msg scheduledMessage
+	if err := r.db.GetContext(ctx, &msg, getScheduledMessageQuery, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get scheduled message: %w", err)
+	}
+
+	result := msg.toModel()
+	return &result, nil
+}
+
+const getScheduledMessagesQuery = `
+SELECT ` + scheduledMessageColumns + ` FROM scheduled_messages
+WHERE sender_id = $1
+ORDER BY send_at, id
+`
+
+func (r scheduledMessageRepo) GetScheduledMessages(ctx context.Context, senderID int64) ([]models.ScheduledMessage, error) {
+	var messages []scheduledMessage
+	if err := r.db.SelectContext(ctx, &messages, getScheduledMessagesQuery, senderID); err != nil {
+		return nil, fmt.Errorf("failed to get scheduled messages: %w", err)
+	}
+
+	result := make([]models.ScheduledMessage, len(messages))
+	for i, m := range messages {
+		result[i] = m.toModel()
+	}
+	return result, nil
+}
+
+type UpdateScheduledMessageParams struct {
+	ID          int64
+	Content     string
+	RichContent *models.RichContent
+	SendAt      time.Time
+}
+
+const lockScheduledMessageQuery = `
+SELECT locked_until > CURRENT_TIMESTAMP AS locked FROM scheduled_messages
+WHERE id = $1
+FOR UPDATE
+`
+
+// Правка возвращает неудачно отправленное сообщение в очередь
+const updateScheduledMessageQuery = `
+UPDATE scheduled_messages
+SET content = $2, rich_content = $3, send_at = $4, status = 'pending', error = NULL, attempts = 0, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING ` + scheduledMessageColumns
+
+// UpdateScheduledMessage меняет содержимое и время отправки; если сообщение как раз отправляется, возвращает ErrLocked
+func (r scheduledMessageRepo) UpdateScheduledMessage(ctx context.Context, params UpdateScheduledMessageParams) (*models.ScheduledMessage, error) {
+	richContent, err := encodeRichContent(params.RichContent)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = lockScheduledMessage(ctx, tx, params.ID); err != nil {
+		return nil, err
+	}
+
+	var msg scheduledMessage
+	if err = tx.GetContext(ctx, &msg, updateScheduledMessageQuery, params.ID, params.Content, richContent, params.SendAt); err != nil {
+		return nil, fmt.Errorf("failed to update scheduled message: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result := msg.toModel()
+	return &result, nil
+}
+
+const deleteScheduledMessageQuery = `
+DELETE FROM scheduled_messages
+WHERE id = $1
+`
+
+// DeleteScheduledMessage отменяет отправку; если сообщение как раз отправляется, возвращает ErrLocked
+func (r scheduledMessageRepo) DeleteScheduledMessage(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = lockScheduledMessage(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteScheduledMessageQuery, id); err != nil {
+		return fmt.Errorf("failed to delete scheduled message: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// lockScheduledMessage блокирует строку до конца транзакции; если её арендовал отправляющий экземпляр, возвращает ErrLocked
+func lockScheduledMessage(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	var locked sql.NullBool
+	if err := tx.GetContext(ctx, &locked, lockScheduledMessageQuery, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to lock scheduled message: %w", err)
+	}
+	if locked.Bool {
+		return ErrLocked
+	}
+	return nil
+}
+
+type ClaimScheduledMessagesParams struct {
+	Limit int
+	// Lease время, на которое сообщение закрепляется за экземпляром; по его истечении
+	// неотправленное сообщение снова становится доступным
+	Lease time.Duration
+}
+
+// ClaimedScheduledMessage отложенное сообщение, арендованное для отправки
+type ClaimedScheduledMessage struct {
+	models.ScheduledMessage
+	// Attempts номер текущей попытки отправки, начиная с 1
+	Attempts int
+}
+
+// SKIP LOCKED позволяет нескольким экземплярам разбирать очередь параллельно, не дожидаясь друг друга
+const claimDueScheduledMessagesQuery = `
+UPDATE scheduled_messages
+SET locked_until = CURRENT_TIMESTAMP + make_interval(secs => $2), attempts = attempts + 1
+WHERE id IN (
+    SELECT id FROM scheduled_messages
+    WHERE status = 'pending' AND send_at <= CURRENT_TIMESTAMP
+    AND (locked_until IS NULL OR locked_until <= CURRENT_TIMESTAMP)
+    ORDER BY send_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING ` + scheduledMessageColumns
+
+// ClaimDueScheduledMessages арендует сообщения, время отправки которых наступило
+func (r scheduledMessageRepo) ClaimDueScheduledMessages(ctx context.Context, params ClaimScheduledMessagesParams) ([]ClaimedScheduledMessage, error) {
+	var messages []scheduledMessage
+	if err := r.db.SelectContext(ctx, &messages, claimDueScheduledMessagesQuery, params.Limit, params.Lease.Seconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim scheduled messages: %w", err)
+	}
+
+	result := make([]ClaimedScheduledMessage, len(messages))
+	for i, m := range messages {
+		result[i] = ClaimedScheduledMessage{ScheduledMessage: m.toModel(), Attempts: m.Attempts}
+	}
+	return result, nil
+}
+
+// CompleteScheduledMessage удаляет отправленное сообщение из очереди
+func (r scheduledMessageRepo) CompleteScheduledMessage(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, deleteScheduledMessageQuery, id); err != nil {
+		return fmt.Errorf("failed to complete scheduled message: %w", err)
+	}
+	return nil
+}
+
+const failScheduledMessageQuery = `
+UPDATE scheduled_messages
+SET status = 'failed', error = $2, locked_until = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+// FailScheduledMessage снимает сообщение с очереди, сохраняя причину для пользователя
+func (r scheduledMessageRepo) FailScheduledMessage(ctx context.Context, id int64, reason string) error {
+	if _, err := r.db.ExecContext(ctx, failScheduledMessageQuery, id, reason); err != nil {
+		return fmt.Errorf("failed to mark scheduled message as failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"messanger/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Порядок сортировки результатов поиска
+const (
+	SearchOrderRelevance = "relevance"
+	SearchOrderDate      = "date"
+)
+
+type SearchRepo interface {
+	SearchMessages(ctx context.Context, params SearchMessagesParams) ([]models.SearchResult, error)
+}
+
+type searchRepo struct {
+	db *sqlx.DB
+}
+
+func NewSearchRepo(db *sqlx.DB) SearchRepo {
+	return &searchRepo{db: db}
+}
+
+// SearchCursor позиция последнего результата предыдущей страницы
+type SearchCursor struct {
+	Rank      float64
+	CreatedAt time.Time
+	ID        int64
+}
+
+type SearchMessagesParams struct {
+	UserID int64
+	// TSQuery запрос в синтаксисе to_tsquery, уже очищенный сервисом
+	TSQuery  string
+	RoomID   int64
+	PeerID   int64
+	SenderID int64
+	From     *time.Time
+	To       *time.Time
+	OrderBy  string
+	After    *SearchCursor
+	Limit    int
+}
+
+type searchRow struct {
+	message
+	Rank    float64 `db:"rank"`
+	Snippet string  `db:"snippet"`
+}
+
+// escapedContent текст сообщения с экранированным HTML, как html.EscapeString. Фрагмент отдаётся
+// клиенту как HTML с разметкой <mark>, поэтому сам текст сообщения разметки содержать не должен
+const escapedContent = `replace(replace(replace(replace(replace(f.content,
+    '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`
+
+// Поиск идёт только по переписке, доступной пользователю: его личным диалогам и комнатам, где он состоит.
+// Удалённые и скрытые пользователем сообщения не учитываются
+const searchMessagesBaseQuery = `
+WITH q AS (SELECT to_tsquery('simple', $1) AS query),
+found AS (
+    SELECT ` + messageColumns + `, ts_rank(m.search_vector, q.query) AS rank
+    FROM messages m, q
+    WHERE m.search_vector @@ q.query
+    AND m.deleted_at IS NULL
+    AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP)
+    AND (
+        (m.room_id IS NULL AND (m.sender_id = $2 OR m.receiver_id = $2))
+        OR m.room_id IN (SELECT room_id FROM room_members WHERE user_id = $2)
+    )
+    AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.user_id = $2)
+    %s
+)
+SELECT f.*, ts_headline('simple', ` + escapedContent + `, q.query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5') AS snippet
+FROM found f, q
+%s
+ORDER BY %s
+LIMIT %s
+`
+
+func (r *searchRepo) SearchMessages(ctx context.Context, params SearchMessagesParams) ([]models.SearchResult, error) {
+	args := []any{params.TSQuery, params.UserID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var filters []string
+	if params.RoomID != 0 {
+		filters = append(filters, "AND m.room_id = "+arg(params.RoomID))
+	}
+	if params.PeerID != 0 {
+		peer := arg(params.PeerID)
+		filters = append(filters, fmt.Sprintf(
+			"AND m.room_id IS NULL AND ((m.sender_id = $2 AND m.receiver_id = %[1]s) OR (m.sender_id = %[1]s AND m.receiver_id = $2))", peer))
+	}
+	if params.SenderID != 0 {
+		filters = append(filters, "AND m.sender_id = "+arg(params.SenderID))
+	}
+	if params.From != nil {
+		filters = append(filters, "AND m.created_at >= "+arg(*params.From))
+	}
+	if params.To != nil {
+		filters = append(filters, "AND m.created_at < "+arg(*params.To))
+	}
+
+	var cursor, order string
+	switch params.OrderBy {
+	case SearchOrderDate:
+		order = "f.created_at DESC, f.id DESC"
+		if params.After != nil {
+			cursor = fmt.Sprintf("WHERE (f.created_at, f.id) < (%s, %s)", arg(params.After.CreatedAt), arg(params.After.ID))
+		}
+	default:
+		order = "f.rank DESC, f.id DESC"
+		if params.After != nil {
+			// ts_rank возвращает real: сравнение в том же типе не теряет точность при передаче через курсор
+			cursor = fmt.Sprintf("WHERE (f.rank, f.id) < (%s::REAL, %s)", arg(params.After.Rank), arg(params.After.ID))
+		}
+	}
+
+	query := fmt.Sprintf(searchMessagesBaseQuery, strings.Join(filters, "\n    "), cursor, order, arg(params.Limit))
+
+	var rows []searchRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+
+	results := make([]models.SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = models.SearchResult{
+			Message: row.message.toModel(),
+			Snippet: row.Snippet,
+			Rank:    row.Rank,
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// UserRepo справочник пользователей, известных сервису
+type UserRepo interface {
+	RegisterUser(ctx context.Context, userID int64) error
+	UserExists(ctx context.Context, userID int64) (bool, error)
+}
+
+type userRepo struct {
+	db *sqlx.DB
+}
+
+func NewUserRepo(db *sqlx.DB) UserRepo {
+	return &userRepo{db: db}
+}
+
+const registerUserQuery = `
+INSERT INTO users (id) VALUES ($1)
+ON CONFLICT (id) DO NOTHING
+`
+
+// RegisterUser добавляет пользователя в справочник; повторная регистрация ничего не меняет
+func (r *userRepo) RegisterUser(ctx context.Context, userID int64) error {
+	if _, err := r.db.ExecContext(ctx, registerUserQuery, userID); err != nil {
+		return fmt.Errorf("failed to register user: %w", err)
+	}
+	return nil
+}
+
+const userExistsQuery = `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`
+
+func (r *userRepo) UserExists(ctx context.Context, userID int64) (bool, error) {
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, userExistsQuery, userID); err != nil {
+		return false, fmt.Errorf("failed to check user: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/pkg/blobstore"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type AttachmentService interface {
+	Upload(ctx context.Context, params UploadParams) (*models.Attachment, error)
+	Open(ctx context.Context, params OpenAttachmentParams) (*models.Attachment, io.ReadCloser, error)
+	OpenThumbnail(ctx context.Context, params OpenThumbnailParams) (*models.Thumbnail, io.ReadCloser, error)
+}
+
+type attachmentService struct {
+	repo     repo.AttachmentRepo
+	messages repo.MessageRepo
+	store    blobstore.BlobStore
+
+	maxSize int64
+}
+
+type AttachmentServiceConfig struct {
+	Repo     repo.AttachmentRepo
+	Messages repo.MessageRepo
+	Store    blobstore.BlobStore
+
+	// MaxSize максимальный размер одного файла в байтах
+	MaxSize int64
+}
+
+func NewAttachmentService(cfg AttachmentServiceConfig) AttachmentService {
+	return &attachmentService{
+		repo:     cfg.Repo,
+		messages: cfg.Messages,
+		store:    cfg.Store,
+		maxSize:  cfg.MaxSize,
+	}
+}
+
+type UploadParams struct {
+	UploaderID int64
+	FileName   string
+	Content    io.Reader
+}
+
+const maxFileNameLength = 255
+
+func (s *attachmentService) Upload(ctx context.Context, params UploadParams) (*models.Attachment, error) {
+	fileName := sanitizeFileName(params.FileName)
+	if fileName == "" {
+		return nil, fmt.Errorf("%w: file name is required", ErrValidation)
+	}
+
+	// Файл буферизуется во временном файле: ключ в хранилище — хэш содержимого,
+	// который известен только после чтения всего потока и очистки метаданных
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, fmt.Errorf("os.CreateTemp: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, io.LimitReader(params.Content, s.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrValidation)
+	}
+	if size > s.maxSize {
+		return nil, fmt.Errorf("%w: file is larger than %d bytes", ErrValidation, s.maxSize)
+	}
+
+	sniff := make([]byte, 512)
+	n, err := tmp.ReadAt(sniff, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	mimeType := http.DetectContentType(sniff[:n])
+
+	create := repo.CreateAttachmentParams{
+		UploaderID: params.UploaderID,
+		FileName:   fileName,
+		MimeType:   mimeType,
+		Size:       size,
+	}
+
+	var (
+		content    io.ReadSeeker = tmp
+		thumbnails []encodedThumbnail
+	)
+	if isImage(mimeType) {
+		data, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upload: %w", err)
+		}
+		processed, err := processImage(data, mimeType)
+		if err != nil {
+			return nil, err
+		}
+
+		content = bytes.NewReader(processed.Data)
+		create.Size = int64(len(processed.Data))
+		create.Width, create.Height, create.Blurhash = processed.Width, processed.Height, processed.Blurhash
+		thumbnails = processed.Thumbnails
+	}
+
+	hash := sha256.New()
+	if _, err = content.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind upload: %w", err)
+	}
+	if _, err = io.Copy(hash, content); err != nil {
+		return nil, fmt.Errorf("failed to hash upload: %w", err)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	create.SHA256, create.StorageKey = sum, sum
+
+	// До записи вложения очистка не должна удалить объект, наличие которого проверено здесь
+	unlock, err := s.messages.LockBlobs(ctx, []string{sum})
+	if err != nil {
+		return nil, fmt.Errorf("s.messages.LockBlobs: %w", err)
+	}
+	defer unlock()
+
+	exists, err := s.store.Exists(ctx, sum)
+	if err != nil {
+		return nil, fmt.Errorf("s.store.Exists: %w", err)
+	}
+	for _, t := range thumbnails {
+		t.StorageKey = thumbnailKey(sum, t.Size)
+		create.Thumbnails = append(create.Thumbnails, t.Thumbnail)
+
+		// Миниатюры однозначно определяются оригиналом, поэтому при его наличии уже сохранены
+		if exists {
+			continue
+		}
+		if err = s.store.Put(ctx, t.StorageKey, bytes.NewReader(t.Data), int64(len(t.Data)), t.MimeType); err != nil {
+			return nil, fmt.Errorf("s.store.Put: %w", err)
+		}
+	}
+	if !exists {
+		if _, err = content.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind upload: %w", err)
+		}
+		if err = s.store.Put(ctx, sum, content, create.Size, mimeType); err != nil {
+			return nil, fmt.Errorf("s.store.Put: %w", err)
+		}
+	}
+
+	attachment, err := s.repo.CreateAttachment(ctx, create)
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.CreateAttachment: %w", err)
+	}
+
+	return attachment, nil
+}
+
+// thumbnailKey ключ миниатюры: хэш оригинала и размер
+func thumbnailKey(sum string, size int) string {
+	return fmt.Sprintf("%s-%d", sum, size)
+}
+
+// blobHash хэш содержимого, к которому относится ключ оригинала или миниатюры
+func blobHash(key string) string {
+	hash, _, _ := strings.Cut(key, "-")
+	return hash
+}
+
+// sanitizeFileName оставляет от имени файла только базовую часть без управляющих символов
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	for len(name) > maxFileNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+type OpenAttachmentParams struct {
+	AttachmentID int64
+	UserID       int64
+}
+
+func (s *attachmentService) Open(ctx context.Context, params OpenAttachmentParams) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.getAccessible(ctx, params.AttachmentID, params.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.openBlob(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attachment, content, nil
+}
+
+type OpenThumbnailParams struct {
+	AttachmentID int64
+	UserID       int64
+	// Size сторона квадрата, в который вписана миниатюра
+	Size int
+}
+
+func (s *attachmentService) OpenThumbnail(ctx context.Context, params OpenThumbnailParams) (*models.Thumbnail, io.ReadCloser, error) {
+	attachment, err := s.getAccessible(ctx, params.AttachmentID, params.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	idx := slices.IndexFunc(attachment.Thumbnails, func(t models.Thumbnail) bool { return t.Size == params.Size })
+	if idx < 0 {
+		return nil, nil, fmt.Errorf("%w: thumbnail %d of attachment %d", ErrNotFound, params.Size, params.AttachmentID)
+	}
+	thumbnail := attachment.Thumbnails[idx]
+
+	content, err := s.openBlob(ctx, thumbnail.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &thumbnail, content, nil
+}
+
+func (s *attachmentService) getAccessible(ctx context.Context, attachmentID, userID int64) (*models.Attachment, error) {
+	attachment, err := s.repo.GetAttachment(ctx, attachmentID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, fmt.Errorf("%w: attachment %d", ErrNotFound, attachmentID)
+		}
+		return nil, fmt.Errorf("s.repo.GetAttachment: %w", err)
+	}
+
+	if err = s.checkAccess(ctx, *attachment, userID); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (s *attachmentService) openBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	content, err := s.store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return nil, fmt.Errorf("%w: blob %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("s.store.Get: %w", err)
+	}
+	return content, nil
+}
+
+// checkAccess разрешает скачивание неотправленного вложения только загрузившему его пользователю,
+// а отправленного — участникам переписки
+func (s *attachmentService) checkAccess(ctx context.Context, attachment models.Attachment, userID int64) error {
+	if attachment.MessageID == 0 {
+		if attachment.UploaderID != userID {
+			return fmt.Errorf("%w: attachment %d", ErrNotFound, attachment.ID)
+		}
+		return nil
+	}
+
+	msg, err := s.messages.GetMessageByID(ctx, attachment.MessageID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("%w: attachment %d", ErrNotFound, attachment.ID)
+		}
+		return fmt.Errorf("s.messages.GetMessageByID: %w", err)
+	}
+	// Истёкшее сообщение (в том числе уже просмотренное view once) недоступно ещё до окончательного удаления
+	if msg.DeletedAt != nil || (msg.ExpiresAt != nil && !msg.ExpiresAt.After(time.Now())) {
+		return fmt.Errorf("%w: attachment %d", ErrNotFound, attachment.ID)
+	}
+
+	members, err := conversationMembers(ctx, s.messages, *msg)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(members, userID) {
+		return fmt.Errorf("%w: not a member of the conversation", ErrForbidden)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+	"messanger/pkg/blobstore"
+)
+
+// MockAttachmentRepo реализует интерфейс repo.AttachmentRepo для тестов
+type MockAttachmentRepo struct {
+	mock.Mock
+}
+
+func (m *MockAttachmentRepo) CreateAttachment(ctx context.Context, params repo.CreateAttachmentParams) (*models.Attachment, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentRepo) GetAttachment(ctx context.Context, attachmentID int64) (*models.Attachment, error) {
+	args := m.Called(ctx, attachmentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+// sha256 от "hello world"
+const helloSHA256 = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+
+func newAttachmentService(t *testing.T, attachments *MockAttachmentRepo, messages *MockMessageRepo) (services.AttachmentService, blobstore.BlobStore) {
+	store, err := blobstore.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	messages.On("LockBlobs", mock.Anything, mock.Anything).Return(func() {}, nil).Maybe()
+
+	return services.NewAttachmentService(services.AttachmentServiceConfig{
+		Repo:     attachments,
+		Messages: messages,
+		Store:    store,
+		MaxSize:  16,
+	}), store
+}
+
+func TestAttachmentService_Upload(t *testing.T) {
+	attachments := new(MockAttachmentRepo)
+	attachments.On("CreateAttachment", mock.Anything, repo.CreateAttachmentParams{
+		UploaderID: 1,
+		FileName:   "notes.txt",
+		MimeType:   "text/plain; charset=utf-8",
+		Size:       11,
+		SHA256:     helloSHA256,
+		StorageKey: helloSHA256,
+	}).Return(&models.Attachment{ID: 3, UploaderID: 1, FileName: "notes.txt"}, nil).Twice()
+
+	service, store := newAttachmentService(t, attachments, new(MockMessageRepo))
+
+	for i := 0; i < 2; i++ {
+		result, err := service.Upload(context.Background(), services.UploadParams{
+			UploaderID: 1,
+			FileName:   "../../etc/notes.txt",
+			Content:    strings.NewReader("hello world"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), result.ID)
+	}
+
+	exists, err := store.Exists(context.Background(), helloSHA256)
+	require.NoError(t, err)
+	assert.True(t, exists, "content must be stored under its hash")
+	attachments.AssertExpectations(t)
+}
+
+func TestAttachmentService_UploadImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 600, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 600; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, img))
+
+	attachments := new(MockAttachmentRepo)
+	attachments.On("CreateAttachment", mock.Anything, mock.Anything).
+		Return(&models.Attachment{ID: 3}, nil)
+
+	messages := new(MockMessageRepo)
+	messages.On("LockBlobs", mock.Anything, mock.Anything).Return(func() {}, nil)
+
+	store, err := blobstore.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	service := services.NewAttachmentService(services.AttachmentServiceConfig{
+		Repo:     attachments,
+		Messages: messages,
+		Store:    store,
+		MaxSize:  int64(encoded.Len()),
+	})
+
+	_, err = service.Upload(context.Background(), services.UploadParams{
+		UploaderID: 1,
+		FileName:   "photo.png",
+		Content:    bytes.NewReader(encoded.Bytes()),
+	})
+	require.NoError(t, err)
+
+	params := attachments.Calls[0].Arguments.Get(1).(repo.CreateAttachmentParams)
+	assert.Equal(t, "image/png", params.MimeType)
+	assert.Equal(t, 600, params.Width)
+	assert.Equal(t, 300, params.Height)
+	assert.Len(t, params.Blurhash, 28)
+	require.Len(t, params.Thumbnails, 2, "no thumbnail larger than the original")
+	assert.Equal(t, models.Thumbnail{Size: 480, Width: 480, Height: 240, MimeType: "image/png", StorageKey: params.SHA256 + "-480"}, params.Thumbnails[0])
+	assert.Equal(t, models.Thumbnail{Size: 160, Width: 160, Height: 80, MimeType: "image/png", StorageKey: params.SHA256 + "-160"}, params.Thumbnails[1])
+
+	content, err := store.Get(context.Background(), params.SHA256+"-160")
+	require.NoError(t, err)
+	defer content.Close()
+	thumb, err := png.Decode(content)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 160, 80), thumb.Bounds())
+}
+
+func TestAttachmentService_UploadValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+	}{
+		{name: "empty file", fileName: "a.txt", content: ""},
+		{name: "too large", fileName: "a.txt", content: strings.Repeat("x", 17)},
+		{name: "missing name", fileName: "", content: "hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attachments := new(MockAttachmentRepo)
+			service, _ := newAttachmentService(t, attachments, new(MockMessageRepo))
+
+			result, err := service.Upload(context.Background(), services.UploadParams{
+				UploaderID: 1,
+				FileName:   tt.fileName,
+				Content:    strings.NewReader(tt.content),
+			})
+
+			assert.ErrorIs(t, err, services.ErrValidation)
+			assert.Nil(t, result)
+			attachments.AssertNotCalled(t, "CreateAttachment", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAttachmentService_Open(t *testing.T) {
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name          string
+		attachment    *models.Attachment
+		userID        int64
+		repoSetup     func(*MockMessageRepo)
+		expectedError error
+	}{
+		{
+			name:       "uploader before sending",
+			attachment: &models.Attachment{ID: 1, UploaderID: 1, StorageKey: helloSHA256},
+			userID:     1,
+			repoSetup:  func(m *MockMessageRepo) {},
+		},
+		{
+			name:          "someone else before sending",
+			attachment:    &models.Attachment{ID: 1, UploaderID: 1, StorageKey: helloSHA256},
+			userID:        2,
+			repoSetup:     func(m *MockMessageRepo) {},
+			expectedError: services.ErrNotFound,
+		},
+		{
+			name:       "direct message participant",
+			attachment: &models.Attachment{ID: 1, MessageID: 10, UploaderID: 1, StorageKey: helloSHA256},
+			userID:     2,
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(10)).
+					Return(&models.Message{ID: 10, SenderID: 1, ReceiverID: 2}, nil)
+			},
+		},
+		{
+			name:       "room outsider",
+			attachment: &models.Attachment{ID: 1, MessageID: 10, UploaderID: 1, StorageKey: helloSHA256},
+			userID:     4,
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(10)).
+					Return(&models.Message{ID: 10, SenderID: 1, RoomID: 5}, nil)
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:       "message deleted for everyone",
+			attachment: &models.Attachment{ID: 1, MessageID: 10, UploaderID: 1, StorageKey: helloSHA256},
+			userID:     2,
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(10)).
+					Return(&models.Message{ID: 10, SenderID: 1, ReceiverID: 2, DeletedAt: &deletedAt}, nil)
+			},
+			expectedError: services.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attachments := new(MockAttachmentRepo)
+			attachments.On("GetAttachment", mock.Anything, tt.attachment.ID).Return(tt.attachment, nil)
+			messages := new(MockMessageRepo)
+			tt.repoSetup(messages)
+
+			service, store := newAttachmentService(t, attachments, messages)
+			require.NoError(t, store.Put(context.Background(), helloSHA256, strings.NewReader("hello world"), 11, "text/plain"))
+
+			result, content, err := service.Open(context.Background(), services.OpenAttachmentParams{
+				AttachmentID: tt.attachment.ID,
+				UserID:       tt.userID,
+			})
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, content)
+			} else {
+				require.NoError(t, err)
+				defer content.Close()
+				data, err := io.ReadAll(content)
+				require.NoError(t, err)
+				assert.Equal(t, "hello world", string(data))
+				assert.Equal(t, tt.attachment, result)
+			}
+
+			messages.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_SaveMessage_Attachments(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("SaveMessage", mock.Anything, repo.SaveMessageParams{
+		SenderID:      1,
+		ReceiverID:    2,
+		AttachmentIDs: []int64{3, 4},
+		RecipientIDs:  []int64{2},
+		ClearDraft:    true,
+	}).Return(nil, repo.ErrAttachmentUnavailable)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+	result, err := service.SaveMessage(context.Background(), services.SaveMessageParams{
+		SenderID:      1,
+		ReceiverID:    2,
+		AttachmentIDs: []int64{4, 3, 4},
+	})
+
+	assert.ErrorIs(t, err, services.ErrValidation)
+	assert.Nil(t, result)
+	mockRepo.AssertExpectations(t)
+}
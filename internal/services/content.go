@@ -0,0 +1,174 @@
+package services
+
+import (
+	"fmt"
+	"messanger/internal/models"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxTextLength       = 4096
+	maxContentEntities  = 100
+	maxLanguageLength   = 32
+	maxPollQuestionSize = 300
+	maxPollOptionSize   = 100
+	minPollOptions      = 2
+	maxPollOptions      = 10
+	maxPollDuration     = 365 * 24 * time.Hour
+)
+
+var entityTypes = []string{
+	models.EntityBold,
+	models.EntityItalic,
+	models.EntityStrike,
+	models.EntityUnderline,
+	models.EntitySpoiler,
+	models.EntityCode,
+	models.EntityPre,
+	models.EntityLink,
+	models.EntityMention,
+	models.EntityRoomMention,
+	models.EntityHereMention,
+}
+
+// validateContent проверяет структурированное содержимое, присланное клиентом.
+// Служебные сообщения создаются только сервером, поэтому от клиента не принимаются
+func validateContent(content *models.RichContent) error {
+	if content.Version == 0 {
+		content.Version = models.ContentVersion
+	}
+	if content.Version != models.ContentVersion {
+		return fmt.Errorf("%w: unsupported content version %d", ErrValidation, content.Version)
+	}
+
+	if err := validateText(content.Text, content.Entities); err != nil {
+		return err
+	}
+
+	switch content.Kind {
+	case models.ContentKindText:
+		if strings.TrimSpace(content.Text) == "" && len(content.Attachments) == 0 {
+			return fmt.Errorf("%w: text or attachments are required", ErrValidation)
+		}
+		if content.Poll != nil || content.System != nil {
+			return fmt.Errorf("%w: unexpected payload for kind %q", ErrValidation, content.Kind)
+		}
+	case models.ContentKindPoll:
+		if content.Poll == nil {
+			return fmt.Errorf("%w: poll is required", ErrValidation)
+		}
+		if content.System != nil {
+			return fmt.Errorf("%w: unexpected payload for kind %q", ErrValidation, content.Kind)
+		}
+		if err := validatePoll(content.Poll); err != nil {
+			return err
+		}
+	case models.ContentKindSystem:
+		return fmt.Errorf("%w: system messages can only be created by the server", ErrForbidden)
+	default:
+		return fmt.Errorf("%w: unknown content kind %q", ErrValidation, content.Kind)
+	}
+
+	if len(content.Attachments) > maxMessageAttachments {
+		return fmt.Errorf("%w: more than %d attachments", ErrValidation, maxMessageAttachments)
+	}
+
+	return nil
+}
+
+// validateText проверяет текст структурированного содержимого. В отличие от простого текста он не нормализуется:
+// удаление символов сдвинуло бы разметку, поэтому управляющие символы отклоняются
+func validateText(text string, entities []models.ContentEntity) error {
+	if !utf8.ValidString(text) {
+		return fmt.Errorf("%w: text is not valid UTF-8", ErrValidation)
+	}
+	if strings.ContainsFunc(text, isForbiddenControl) {
+		return fmt.Errorf("%w: text contains control characters", ErrValidation)
+	}
+
+	length := utf8.RuneCountInString(text)
+	if length > maxTextLength {
+		return fmt.Errorf("%w: text is longer than %d characters", ErrValidation, maxTextLength)
+	}
+	if len(entities) > maxContentEntities {
+		return fmt.Errorf("%w: more than %d entities", ErrValidation, maxContentEntities)
+	}
+
+	for i, e := range entities {
+		if !slices.Contains(entityTypes, e.Type) {
+			return fmt.Errorf("%w: entity %d: unknown type %q", ErrValidation, i, e.Type)
+		}
+		if e.Offset < 0 || e.Length <= 0 || e.Offset+e.Length > length {
+			return fmt.Errorf("%w: entity %d: range is outside of the text", ErrValidation, i)
+		}
+
+		switch e.Type {
+		case models.EntityLink:
+			u, err := url.Parse(e.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%w: entity %d: link must be an absolute http(s) URL", ErrValidation, i)
+			}
+		case models.EntityMention:
+			if e.UserID <= 0 {
+				return fmt.Errorf("%w: entity %d: mention requires user_id", ErrValidation, i)
+			}
+		case models.EntityPre:
+			if len(e.Language) > maxLanguageLength {
+				return fmt.Errorf("%w: entity %d: language is too long", ErrValidation, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validatePoll(poll *models.PollContent) error {
+	poll.Question = strings.TrimSpace(poll.Question)
+	if poll.Question == "" || utf8.RuneCountInString(poll.Question) > maxPollQuestionSize {
+		return fmt.Errorf("%w: poll question must be 1-%d characters", ErrValidation, maxPollQuestionSize)
+	}
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return fmt.Errorf("%w: poll must have %d-%d options", ErrValidation, minPollOptions, maxPollOptions)
+	}
+
+	seen := make(map[string]struct{}, len(poll.Options))
+	for i, option := range poll.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > maxPollOptionSize {
+			return fmt.Errorf("%w: poll option %d must be 1-%d characters", ErrValidation, i, maxPollOptionSize)
+		}
+		if _, ok := seen[option]; ok {
+			return fmt.Errorf("%w: duplicate poll option %q", ErrValidation, option)
+		}
+		seen[option] = struct{}{}
+		poll.Options[i] = option
+	}
+
+	if poll.ClosesAt != nil {
+		until := time.Until(*poll.ClosesAt)
+		if until <= 0 || until > maxPollDuration {
+			return fmt.Errorf("%w: poll must close within %s", ErrValidation, maxPollDuration)
+		}
+	}
+
+	return nil
+}
+
+// plainText строит текстовое представление содержимого для старых клиентов и полнотекстового поиска
+func plainText(content *models.RichContent) string {
+	switch content.Kind {
+	case models.ContentKindPoll:
+		return "📊 " + content.Poll.Question + "\n" + "• " + strings.Join(content.Poll.Options, "\n• ")
+	case models.ContentKindSystem:
+		if content.Text == "" && content.System != nil {
+			return content.System.Event
+		}
+		return content.Text
+	default:
+		return content.Text
+	}
+}
@@ -0,0 +1,174 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+func TestMessageService_SaveMessage_RichContent(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         models.RichContent
+		expectedContent string
+		expectedError   error
+	}{
+		{
+			name: "formatted text",
+			content: models.RichContent{
+				Kind: models.ContentKindText,
+				Text: "Привет, Bob! docs",
+				Entities: []models.ContentEntity{
+					{Type: models.EntityBold, Offset: 0, Length: 6},
+					{Type: models.EntityMention, Offset: 8, Length: 3, UserID: 2},
+					{Type: models.EntityLink, Offset: 13, Length: 4, URL: "https://example.com/docs"},
+				},
+			},
+			expectedContent: "Привет, Bob! docs",
+		},
+		{
+			name: "poll",
+			content: models.RichContent{
+				Kind: models.ContentKindPoll,
+				Poll: &models.PollContent{Question: " Lunch? ", Options: []string{"Pizza", "Sushi"}},
+			},
+			expectedContent: "📊 Lunch?\n• Pizza\n• Sushi",
+		},
+		{
+			name:          "entity outside of text",
+			content:       models.RichContent{Kind: models.ContentKindText, Text: "hi", Entities: []models.ContentEntity{{Type: models.EntityBold, Offset: 1, Length: 5}}},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "javascript link",
+			content:       models.RichContent{Kind: models.ContentKindText, Text: "click", Entities: []models.ContentEntity{{Type: models.EntityLink, Length: 5, URL: "javascript:alert(1)"}}},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "unknown entity",
+			content:       models.RichContent{Kind: models.ContentKindText, Text: "hi", Entities: []models.ContentEntity{{Type: "blink", Length: 2}}},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "empty text",
+			content:       models.RichContent{Kind: models.ContentKindText, Text: "  "},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "future version",
+			content:       models.RichContent{Version: 2, Kind: models.ContentKindText, Text: "hi"},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "poll with one option",
+			content:       models.RichContent{Kind: models.ContentKindPoll, Poll: &models.PollContent{Question: "?", Options: []string{"yes"}}},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "system message from a client",
+			content:       models.RichContent{Kind: models.ContentKindSystem, System: &models.SystemContent{Event: "member_joined"}},
+			expectedError: services.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			if tt.expectedError == nil {
+				mockRepo.On("SaveMessage", mock.Anything, mock.Anything).
+					Return(&models.Message{ID: 1, Content: tt.expectedContent}, nil)
+			}
+
+			content := tt.content
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+			_, err := service.SaveMessage(context.Background(), services.SaveMessageParams{
+				SenderID:    1,
+				ReceiverID:  2,
+				Content:     "ignored",
+				RichContent: &content,
+			})
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				mockRepo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+				return
+			}
+
+			require.NoError(t, err)
+			params := mockRepo.Calls[0].Arguments.Get(1).(repo.SaveMessageParams)
+			assert.Equal(t, tt.expectedContent, params.Content)
+			assert.Equal(t, models.ContentVersion, params.RichContent.Version)
+		})
+	}
+}
+
+func TestMessageService_SaveMessage_RichContentAttachments(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("SaveMessage", mock.Anything, mock.Anything).Return(&models.Message{ID: 1}, nil)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+	_, err := service.SaveMessage(context.Background(), services.SaveMessageParams{
+		SenderID:      1,
+		ReceiverID:    2,
+		RichContent:   &models.RichContent{Kind: models.ContentKindText, Attachments: []int64{7, 5}},
+		AttachmentIDs: []int64{5},
+	})
+	require.NoError(t, err)
+
+	params := mockRepo.Calls[0].Arguments.Get(1).(repo.SaveMessageParams)
+	assert.Equal(t, []int64{5, 7}, params.AttachmentIDs, "attachments from content are linked to the message")
+	assert.Equal(t, []int64{7, 5}, params.RichContent.Attachments, "display order is preserved")
+}
+
+func TestMessageService_EditMessage_RichContent(t *testing.T) {
+	t.Run("plain edit keeps attachments", func(t *testing.T) {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("GetMessageByID", mock.Anything, int64(1)).Return(&models.Message{
+			ID: 1, SenderID: 1, ReceiverID: 2,
+			RichContent: &models.RichContent{Version: 1, Kind: models.ContentKindText, Text: "**old**", Attachments: []int64{3}},
+		}, nil)
+		mockRepo.On("EditMessage", mock.Anything, repo.EditMessageParams{
+			MessageID: 1,
+			EditorID:  1,
+			Content:   "new",
+			RichContent: &models.RichContent{
+				Version: 1, Kind: models.ContentKindText, Text: "new", Attachments: []int64{3},
+			},
+		}).Return(&models.Message{ID: 1, SenderID: 1, ReceiverID: 2, Content: "new"}, nil)
+
+		service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+		_, err := service.EditMessage(context.Background(), services.EditMessageParams{
+			MessageID: 1,
+			EditorID:  1,
+			Content:   "new",
+		})
+
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("polls cannot be edited", func(t *testing.T) {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("GetMessageByID", mock.Anything, int64(1)).Return(&models.Message{
+			ID: 1, SenderID: 1, ReceiverID: 2,
+			RichContent: &models.RichContent{Version: 1, Kind: models.ContentKindPoll, Poll: &models.PollContent{Question: "?", Options: []string{"a", "b"}}},
+		}, nil)
+
+		service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+		_, err := service.EditMessage(context.Background(), services.EditMessageParams{
+			MessageID: 1,
+			EditorID:  1,
+			Content:   "new",
+		})
+
+		assert.ErrorIs(t, err, services.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "EditMessage", mock.Anything, mock.Anything)
+	})
+}
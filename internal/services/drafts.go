@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type SaveDraftParams struct {
+	UserID     int64
+	ReceiverID int64
+	// RoomID задаётся для комнаты; ReceiverID в этом случае не используется
+	RoomID int64
+	// Content текст черновика; пустой текст удаляет черновик
+	Content string
+}
+
+// SaveDraft сохраняет черновик пользователя и рассылает его остальным устройствам пользователя
+func (s *messageService) SaveDraft(ctx context.Context, params SaveDraftParams) (*models.Draft, error) {
+	if utf8.RuneCountInString(params.Content) > maxTextLength {
+		return nil, fmt.Errorf("%w: draft is longer than %d characters", ErrValidation, maxTextLength)
+	}
+
+	key, err := s.draftParams(ctx, params.UserID, params.ReceiverID, params.RoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	var draft *models.Draft
+	if strings.TrimSpace(params.Content) == "" {
+		if _, err = s.repo.DeleteDraft(ctx, key); err != nil {
+			return nil, fmt.Errorf("s.repo.DeleteDraft: %w", err)
+		}
+		draft = &models.Draft{ReceiverID: key.ReceiverID, RoomID: key.RoomID, UpdatedAt: time.Now()}
+	} else {
+		draft, err = s.repo.SaveDraft(ctx, key, params.Content)
+		if err != nil {
+			return nil, fmt.Errorf("s.repo.SaveDraft: %w", err)
+		}
+	}
+
+	s.publish([]int64{params.UserID}, events.Event{Type: events.DraftUpdated, Payload: *draft})
+	return draft, nil
+}
+
+// draftParams определяет переписку черновика и проверяет, что пользователь в ней участвует
+func (s *messageService) draftParams(ctx context.Context, userID, receiverID, roomID int64) (repo.DraftParams, error) {
+	switch {
+	case roomID != 0:
+		members, err := s.repo.GetRoomMemberIDs(ctx, roomID)
+		if err != nil {
+			return repo.DraftParams{}, fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+		}
+		if !slices.Contains(members, userID) {
+			return repo.DraftParams{}, fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+		return repo.DraftParams{UserID: userID, RoomID: roomID}, nil
+	case receiverID != 0:
+		return repo.DraftParams{UserID: userID, ReceiverID: receiverID}, nil
+	default:
+		return repo.DraftParams{}, fmt.Errorf("%w: receiver_id or room_id is required", ErrValidation)
+	}
+}
+
+// publishDraftCleared сообщает устройствам автора, что черновик переписки удалён отправкой сообщения
+func (s *messageService) publishDraftCleared(msg models.Message) {
+	draft := models.Draft{RoomID: msg.RoomID, UpdatedAt: msg.CreatedAt}
+	if msg.RoomID == 0 {
+		draft.ReceiverID = msg.ReceiverID
+	}
+	s.publish([]int64{msg.SenderID}, events.Event{Type: events.DraftUpdated, Payload: draft})
+}
+
+// GetConversations возвращает переписки пользователя вместе с черновиками. Переписки только с черновиком
+// тоже попадают в список; порядок — по последней активности: новому сообщению или изменению черновика
+func (s *messageService) GetConversations(ctx context.Context, userID int64) ([]models.Conversation, error) {
+	conversations, err := s.repo.GetConversations(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetConversations: %w", err)
+	}
+
+	drafts, err := s.repo.GetDrafts(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetDrafts: %w", err)
+	}
+
+	byKey := make(map[[2]int64]int, len(conversations))
+	for i, c := range conversations {
+		byKey[[2]int64{c.RoomID, c.ReceiverID}] = i
+	}
+	for _, d := range drafts {
+		if i, ok := byKey[[2]int64{d.RoomID, d.ReceiverID}]; ok {
+			conversations[i].Draft = &d
+			continue
+		}
+		conversations = append(conversations, models.Conversation{ReceiverID: d.ReceiverID, RoomID: d.RoomID, Draft: &d})
+	}
+
+	slices.SortStableFunc(conversations, func(a, b models.Conversation) int {
+		return lastActivity(b).Compare(lastActivity(a))
+	})
+	return conversations, nil
+}
+
+func lastActivity(c models.Conversation) time.Time {
+	var at time.Time
+	if c.LastMessage != nil {
+		at = c.LastMessage.CreatedAt
+	}
+	if c.Draft != nil && c.Draft.UpdatedAt.After(at) {
+		at = c.Draft.UpdatedAt
+	}
+	return at
+}
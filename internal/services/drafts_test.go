@@ -0,0 +1,154 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+func TestMessageService_SaveDraft(t *testing.T) {
+	tests := []struct {
+		name          string
+		params        services.SaveDraftParams
+		mockBehavior  func(r *MockMessageRepo)
+		expectedDraft models.Draft
+		expectedError error
+	}{
+		{
+			name:   "direct chat",
+			params: services.SaveDraftParams{UserID: 1, ReceiverID: 2, Content: "see you"},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("SaveDraft", mock.Anything, repo.DraftParams{UserID: 1, ReceiverID: 2}, "see you").
+					Return(&models.Draft{ReceiverID: 2, Content: "see you"}, nil)
+			},
+			expectedDraft: models.Draft{ReceiverID: 2, Content: "see you"},
+		},
+		{
+			name:   "room",
+			params: services.SaveDraftParams{UserID: 1, ReceiverID: 2, RoomID: 5, Content: "hello"},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 3}, nil)
+				r.On("SaveDraft", mock.Anything, repo.DraftParams{UserID: 1, RoomID: 5}, "hello").
+					Return(&models.Draft{RoomID: 5, Content: "hello"}, nil)
+			},
+			expectedDraft: models.Draft{RoomID: 5, Content: "hello"},
+		},
+		{
+			name:   "empty content deletes the draft",
+			params: services.SaveDraftParams{UserID: 1, ReceiverID: 2, Content: "  "},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("DeleteDraft", mock.Anything, repo.DraftParams{UserID: 1, ReceiverID: 2}).Return(true, nil)
+			},
+			expectedDraft: models.Draft{ReceiverID: 2},
+		},
+		{
+			name:   "not a member of the room",
+			params: services.SaveDraftParams{UserID: 4, RoomID: 5, Content: "hello"},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 3}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:          "no conversation",
+			params:        services.SaveDraftParams{UserID: 1, Content: "hello"},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.mockBehavior(mockRepo)
+
+			var notified []int64
+			var published *models.Draft
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				if event.Type == events.DraftUpdated {
+					notified = userIDs
+					draft := event.Payload.(models.Draft)
+					published = &draft
+				}
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+			draft, err := service.SaveDraft(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, published)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedDraft.Content, draft.Content)
+			assert.Equal(t, tt.expectedDraft.RoomID, draft.RoomID)
+			assert.Equal(t, tt.expectedDraft.ReceiverID, draft.ReceiverID)
+			assert.Equal(t, []int64{tt.params.UserID}, notified)
+			assert.Equal(t, draft, published)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_SaveMessage_ClearsDraft(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2}, nil)
+	mockRepo.On("SaveMessage", mock.Anything, mock.MatchedBy(func(p repo.SaveMessageParams) bool {
+		return p.ClearDraft
+	})).Return(&models.Message{ID: 3, SenderID: 1, RoomID: 5}, nil)
+
+	var cleared []models.Draft
+	bus := events.NewBus()
+	bus.Subscribe(func(userIDs []int64, event events.Event) {
+		if event.Type == events.DraftUpdated {
+			assert.Equal(t, []int64{1}, userIDs)
+			cleared = append(cleared, event.Payload.(models.Draft))
+		}
+	})
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+	_, err := service.SaveMessage(context.Background(), services.SaveMessageParams{SenderID: 1, RoomID: 5, Content: "hi"})
+	require.NoError(t, err)
+
+	require.Len(t, cleared, 1)
+	assert.Equal(t, int64(5), cleared[0].RoomID)
+	assert.Empty(t, cleared[0].Content)
+}
+
+func TestMessageService_GetConversations(t *testing.T) {
+	now := time.Now()
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetConversations", mock.Anything, int64(1)).Return([]models.Conversation{
+		{ReceiverID: 2, LastMessage: &models.Message{ID: 10, CreatedAt: now.Add(-time.Minute)}, UnreadCount: 1},
+		{RoomID: 5, LastMessage: &models.Message{ID: 9, CreatedAt: now.Add(-time.Hour)}},
+	}, nil)
+	mockRepo.On("GetDrafts", mock.Anything, int64(1)).Return([]models.Draft{
+		{RoomID: 5, Content: "later", UpdatedAt: now},
+		{ReceiverID: 7, Content: "new chat", UpdatedAt: now.Add(-2 * time.Hour)},
+	}, nil)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+	conversations, err := service.GetConversations(context.Background(), 1)
+	require.NoError(t, err)
+
+	require.Len(t, conversations, 3)
+	assert.Equal(t, int64(5), conversations[0].RoomID)
+	require.NotNil(t, conversations[0].Draft)
+	assert.Equal(t, "later", conversations[0].Draft.Content)
+	assert.Equal(t, int64(2), conversations[1].ReceiverID)
+	assert.Nil(t, conversations[1].Draft)
+	assert.Equal(t, int64(7), conversations[2].ReceiverID)
+	assert.Nil(t, conversations[2].LastMessage)
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"fmt"
+
+	"messanger/internal/apperr"
+)
+
+// Ошибки объявлены в apperr, чтобы транспорт мог сопоставлять их без зависимости от сервисов
+var (
+	ErrNotFound    = apperr.ErrNotFound
+	ErrForbidden   = apperr.ErrForbidden
+	ErrValidation  = apperr.ErrValidation
+	ErrRateLimited = apperr.ErrRateLimited
+	ErrConflict    = apperr.ErrConflict
+	// ErrMessageRejected сообщение отклонено фильтром; подробности в *RejectionError
+	ErrMessageRejected = apperr.ErrMessageRejected
+
+	ErrEditWindowExpired = fmt.Errorf("%w: edit window has expired", ErrForbidden)
+)
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/pkg/blobstore"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	minMessageTTL = 5 * time.Second
+	maxMessageTTL = 365 * 24 * time.Hour
+)
+
+func validateTTL(ttl time.Duration) error {
+	if ttl == 0 {
+		return nil
+	}
+	if ttl < minMessageTTL || ttl > maxMessageTTL {
+		return fmt.Errorf("%w: ttl must be between %s and %s", ErrValidation, minMessageTTL, maxMessageTTL)
+	}
+	return nil
+}
+
+type SetMessageTTLParams struct {
+	UserID     int64
+	ReceiverID int64
+	// RoomID задаётся для комнаты; ReceiverID в этом случае не используется
+	RoomID int64
+	// TTL время жизни новых сообщений; 0 отключает исчезающие сообщения
+	TTL time.Duration
+}
+
+// SetMessageTTL включает исчезающие сообщения в переписке. В комнате это может сделать только администратор,
+// в личной переписке — любой из участников. Об изменении участники узнают из служебного сообщения
+func (s *messageService) SetMessageTTL(ctx context.Context, params SetMessageTTLParams) error {
+	if err := validateTTL(params.TTL); err != nil {
+		return err
+	}
+
+	update := repo.SetMessageTTLParams{RoomID: params.RoomID, TTL: params.TTL}
+	var recipients []int64
+	switch {
+	case params.RoomID != 0:
+		member, err := s.repo.GetRoomMember(ctx, params.RoomID, params.UserID)
+		if errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+		if err != nil {
+			return fmt.Errorf("s.repo.GetRoomMember: %w", err)
+		}
+		if !member.IsAdmin {
+			return fmt.Errorf("%w: only room admins can change disappearing messages", ErrForbidden)
+		}
+
+		members, err := s.repo.GetRoomMemberIDs(ctx, params.RoomID)
+		if err != nil {
+			return fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+		}
+		for _, id := range members {
+			if id != params.UserID {
+				recipients = append(recipients, id)
+			}
+		}
+		params.ReceiverID = 0
+	case params.ReceiverID != 0:
+		update.UserIDs = [2]int64{params.UserID, params.ReceiverID}
+		recipients = []int64{params.ReceiverID}
+	default:
+		return fmt.Errorf("%w: receiver_id or room_id is required", ErrValidation)
+	}
+
+	if err := s.repo.SetMessageTTL(ctx, update); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("%w: room %d", ErrNotFound, params.RoomID)
+		}
+		return fmt.Errorf("s.repo.SetMessageTTL: %w", err)
+	}
+
+	text := "disappearing messages turned off"
+	if params.TTL > 0 {
+		text = "disappearing messages set to " + params.TTL.String()
+	}
+	content := &models.RichContent{
+		Kind: models.ContentKindSystem,
+		Text: text,
+		System: &models.SystemContent{
+			Event:      models.SystemEventMessageTTLChanged,
+			ActorID:    params.UserID,
+			MessageTTL: int64(params.TTL.Seconds()),
+		},
+	}
+	msg, err := s.repo.SaveMessage(ctx, repo.SaveMessageParams{
+		SenderID:     params.UserID,
+		ReceiverID:   params.ReceiverID,
+		RoomID:       params.RoomID,
+		Content:      plainText(content),
+		RichContent:  content,
+		RecipientIDs: recipients,
+	})
+	if err != nil {
+		return fmt.Errorf("s.repo.SaveMessage: %w", err)
+	}
+
+	s.publish(append(recipients, params.UserID), events.Event{Type: events.MessageNew, Payload: msg})
+	return nil
+}
+
+const (
+	defaultPurgeInterval  = 10 * time.Second
+	defaultPurgeBatchSize = 500
+)
+
+// MessagePurger окончательно удаляет истёкшие сообщения вместе с файлами вложений
+// и рассылает участникам переписки событие message.expired
+type MessagePurger struct {
+	repo   repo.MessageRepo
+	store  blobstore.BlobStore
+	events events.Publisher
+	log    *logrus.Logger
+
+	interval  time.Duration
+	batchSize int
+}
+
+type MessagePurgerConfig struct {
+	Repo   repo.MessageRepo
+	Store  blobstore.BlobStore
+	Events events.Publisher
+	Log    *logrus.Logger
+
+	Interval  time.Duration
+	BatchSize int
+}
+
+func NewMessagePurger(cfg MessagePurgerConfig) *MessagePurger {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultPurgeInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultPurgeBatchSize
+	}
+	return &MessagePurger{
+		repo:      cfg.Repo,
+		store:     cfg.Store,
+		events:    cfg.Events,
+		log:       cfg.Log,
+		interval:  cfg.Interval,
+		batchSize: cfg.BatchSize,
+	}
+}
+
+// Run удаляет истёкшие сообщения с заданным интервалом до отмены контекста
+func (p *MessagePurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			purged, err := p.purge(ctx)
+			if err != nil {
+				p.log.Errorf("failed to purge expired messages: %v", err)
+				break
+			}
+			if purged < p.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *MessagePurger) purge(ctx context.Context) (int, error) {
+	result, err := p.repo.PurgeExpiredMessages(ctx, p.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("p.repo.PurgeExpiredMessages: %w", err)
+	}
+
+	releasePurged(ctx, purgeDeps{repo: p.repo, store: p.store, events: p.events, log: p.log}, result)
+	return len(result.Messages), nil
+}
+
+// purgeDeps зависимости, нужные после окончательного удаления сообщений
+type purgeDeps struct {
+	repo   repo.MessageRepo
+	store  blobstore.BlobStore
+	events events.Publisher
+	log    *logrus.Logger
+}
+
+// releaseBlobs удаляет объекты хранилища, на которые больше не ссылается ни одно вложение.
+// Проверка и удаление идут под блокировкой содержимого, которую берёт и загрузка: иначе
+// одновременная загрузка того же файла могла бы переиспользовать объект перед самым удалением
+func releaseBlobs(ctx context.Context, messages repo.MessageRepo, store blobstore.BlobStore, log *logrus.Logger, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	hashes := make([]string, len(keys))
+	for i, key := range keys {
+		hashes[i] = blobHash(key)
+	}
+	unlock, err := messages.LockBlobs(ctx, hashes)
+	if err != nil {
+		log.Errorf("failed to lock blobs: %v", err)
+		return
+	}
+	defer unlock()
+
+	orphaned, err := messages.GetOrphanedStorageKeys(ctx, keys)
+	if err != nil {
+		log.Errorf("failed to find orphaned blobs: %v", err)
+		return
+	}
+	for _, key := range orphaned {
+		// Сообщения уже удалены, поэтому ошибка хранилища не прерывает очистку: останется лишь недоступный объект
+		if err := store.Delete(ctx, key); err != nil && !errors.Is(err, blobstore.ErrNotFound) {
+			log.Errorf("failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+// releasePurged удаляет осиротевшие объекты хранилища и рассылает участникам событие message.expired
+func releasePurged(ctx context.Context, deps purgeDeps, result *repo.PurgeResult) {
+	releaseBlobs(ctx, deps.repo, deps.store, deps.log, result.StorageKeys)
+
+	roomMembers := make(map[int64][]int64)
+	for _, msg := range result.Messages {
+		members, ok := roomMembers[msg.RoomID]
+		if !ok || msg.RoomID == 0 {
+			var err error
+			members, err = conversationMembers(ctx, deps.repo, msg)
+			if err != nil {
+				deps.log.Errorf("failed to notify about purged message %d: %v", msg.ID, err)
+				continue
+			}
+			if msg.RoomID != 0 {
+				roomMembers[msg.RoomID] = members
+			}
+		}
+
+		if deps.events != nil {
+			deps.events.Publish(members, events.Event{
+				Type:    events.MessageExpired,
+				Payload: models.MessageExpiration{MessageID: msg.ID},
+			})
+		}
+	}
+}
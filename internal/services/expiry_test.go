@@ -0,0 +1,177 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+	"messanger/pkg/blobstore"
+)
+
+func TestMessageService_SaveMessage_Disappearing(t *testing.T) {
+	tests := []struct {
+		name          string
+		params        services.SaveMessageParams
+		expectedError error
+	}{
+		{
+			name:   "ttl is passed to the repository",
+			params: services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "bye", TTL: time.Hour},
+		},
+		{
+			name:   "view once media",
+			params: services.SaveMessageParams{SenderID: 1, ReceiverID: 2, AttachmentIDs: []int64{4}, ViewOnce: true},
+		},
+		{
+			name:          "view once without media",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "secret", ViewOnce: true},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "ttl too short",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "bye", TTL: time.Second},
+			expectedError: services.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			mockRepo.On("SaveMessage", mock.Anything, mock.MatchedBy(func(p repo.SaveMessageParams) bool {
+				return p.TTL == tt.params.TTL && p.ViewOnce == tt.params.ViewOnce
+			})).Return(&models.Message{ID: 1}, nil)
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+			_, err := service.SaveMessage(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				mockRepo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMessageService_SetMessageTTL(t *testing.T) {
+	t.Run("room admin", func(t *testing.T) {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("GetRoomMember", mock.Anything, int64(5), int64(1)).Return(&models.RoomMember{RoomID: 5, UserID: 1, IsAdmin: true}, nil)
+		mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+		mockRepo.On("SetMessageTTL", mock.Anything, repo.SetMessageTTLParams{RoomID: 5, TTL: 24 * time.Hour}).Return(nil)
+		mockRepo.On("SaveMessage", mock.Anything, mock.Anything).Return(&models.Message{ID: 9, RoomID: 5}, nil)
+
+		var notified []int64
+		bus := events.NewBus()
+		bus.Subscribe(func(userIDs []int64, event events.Event) {
+			if event.Type == events.MessageNew {
+				notified = userIDs
+			}
+		})
+
+		service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+		err := service.SetMessageTTL(context.Background(), services.SetMessageTTLParams{UserID: 1, RoomID: 5, TTL: 24 * time.Hour})
+		require.NoError(t, err)
+
+		saved := mockRepo.Calls[3].Arguments.Get(1).(repo.SaveMessageParams)
+		require.NotNil(t, saved.RichContent)
+		assert.Equal(t, models.ContentKindSystem, saved.RichContent.Kind)
+		assert.Equal(t, int64(86400), saved.RichContent.System.MessageTTL)
+		assert.Equal(t, []int64{2, 3}, saved.RecipientIDs)
+		assert.ElementsMatch(t, []int64{1, 2, 3}, notified)
+	})
+
+	t.Run("regular room member", func(t *testing.T) {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("GetRoomMember", mock.Anything, int64(5), int64(2)).Return(&models.RoomMember{RoomID: 5, UserID: 2}, nil)
+
+		service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+		err := service.SetMessageTTL(context.Background(), services.SetMessageTTLParams{UserID: 2, RoomID: 5, TTL: time.Hour})
+		assert.ErrorIs(t, err, services.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "SetMessageTTL", mock.Anything, mock.Anything)
+	})
+
+	t.Run("direct conversation", func(t *testing.T) {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("SetMessageTTL", mock.Anything, repo.SetMessageTTLParams{UserIDs: [2]int64{2, 1}}).Return(nil)
+		mockRepo.On("SaveMessage", mock.Anything, mock.Anything).Return(&models.Message{ID: 9}, nil)
+
+		service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+		err := service.SetMessageTTL(context.Background(), services.SetMessageTTLParams{UserID: 2, ReceiverID: 1})
+		require.NoError(t, err)
+
+		saved := mockRepo.Calls[1].Arguments.Get(1).(repo.SaveMessageParams)
+		assert.Equal(t, "disappearing messages turned off", saved.Content)
+	})
+}
+
+func TestMessagePurger(t *testing.T) {
+	store, err := blobstore.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	for _, key := range []string{"orphaned", "shared"} {
+		require.NoError(t, store.Put(context.Background(), key, strings.NewReader("data"), 4, "text/plain"))
+	}
+
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("PurgeExpiredMessages", mock.Anything, 2).Return(&repo.PurgeResult{
+		Messages: []models.Message{
+			{ID: 1, SenderID: 1, RoomID: 5},
+			{ID: 2, SenderID: 2, RoomID: 5},
+		},
+		StorageKeys: []string{"orphaned", "shared"},
+	}, nil).Once()
+	mockRepo.On("LockBlobs", mock.Anything, []string{"orphaned", "shared"}).Return(func() {}, nil).Once()
+	mockRepo.On("GetOrphanedStorageKeys", mock.Anything, []string{"orphaned", "shared"}).Return([]string{"orphaned"}, nil).Once()
+	mockRepo.On("PurgeExpiredMessages", mock.Anything, 2).Return(&repo.PurgeResult{
+		Messages: []models.Message{{ID: 3, SenderID: 1, ReceiverID: 2}},
+	}, nil).Once()
+	mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil).Once()
+
+	expired := make(chan int64, 3)
+	bus := events.NewBus()
+	bus.Subscribe(func(userIDs []int64, event events.Event) {
+		if event.Type == events.MessageExpired {
+			expired <- event.Payload.(models.MessageExpiration).MessageID
+		}
+	})
+
+	purger := services.NewMessagePurger(services.MessagePurgerConfig{
+		Repo:      mockRepo,
+		Store:     store,
+		Events:    bus,
+		Log:       discardLogger(),
+		Interval:  time.Hour,
+		BatchSize: 2,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go purger.Run(ctx)
+
+	var ids []int64
+	for range 3 {
+		select {
+		case id := <-expired:
+			ids = append(ids, id)
+		case <-time.After(time.Second):
+			t.Fatal("expired messages were not announced")
+		}
+	}
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+
+	exists, err := store.Exists(context.Background(), "orphaned")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = store.Exists(context.Background(), "shared")
+	require.NoError(t, err)
+	assert.True(t, exists, "objects still referenced by other attachments are kept")
+}
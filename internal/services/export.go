@@ -0,0 +1,374 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Форматы выгрузки переписки
+const (
+	ExportFormatJSONL = "jsonl"
+	ExportFormatCSV   = "csv"
+	ExportFormatHTML  = "html"
+)
+
+// exportBatchSize сколько сообщений читается из базы за один запрос во время выгрузки
+const exportBatchSize = 500
+
+type ExportService interface {
+	Export(ctx context.Context, params ExportParams) (Export, error)
+}
+
+// Export подготовленная выгрузка переписки
+type Export interface {
+	ContentType() string
+	FileName() string
+	// Write записывает выгрузку в w. Ошибка посреди записи оставляет выгрузку неполной
+	Write(ctx context.Context, w io.Writer) error
+}
+
+type exportService struct {
+	repo repo.MessageRepo
+}
+
+func NewExportService(messages repo.MessageRepo) ExportService {
+	return &exportService{repo: messages}
+}
+
+type ExportParams struct {
+	UserID     int64
+	ReceiverID int64
+	// RoomID задаётся для комнаты; ReceiverID в этом случае не используется
+	RoomID int64
+	// Compliance выгрузка для администратора сервиса: участие в переписке не проверяется,
+	// личная переписка задаётся парой UserIDs, а скрытые кем-либо «у себя» сообщения не пропускаются
+	Compliance bool
+	UserIDs    [2]int64
+	// Format jsonl (по умолчанию), csv или html
+	Format string
+}
+
+// Export проверяет доступ к переписке и готовит выгрузку. Сообщения читаются частями во время записи,
+// поэтому выгрузку можно отдавать потоком, не держа всю переписку в памяти
+func (s *exportService) Export(ctx context.Context, params ExportParams) (Export, error) {
+	format := params.Format
+	if format == "" {
+		format = ExportFormatJSONL
+	}
+	if !slices.Contains([]string{ExportFormatJSONL, ExportFormatCSV, ExportFormatHTML}, format) {
+		return nil, fmt.Errorf("%w: unknown export format %q", ErrValidation, params.Format)
+	}
+
+	query := repo.ExportMessagesParams{RoomID: params.RoomID, Limit: exportBatchSize}
+	switch {
+	case params.Compliance && params.RoomID == 0:
+		if params.UserIDs[0] <= 0 || params.UserIDs[1] <= 0 || params.UserIDs[0] == params.UserIDs[1] {
+			return nil, fmt.Errorf("%w: room_id or two different user_ids are required", ErrValidation)
+		}
+		query.UserIDs = params.UserIDs
+	case params.Compliance:
+	case params.RoomID != 0:
+		members, err := s.repo.GetRoomMemberIDs(ctx, params.RoomID)
+		if err != nil {
+			return nil, fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+		}
+		if !slices.Contains(members, params.UserID) {
+			return nil, fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+		query.ViewerID = params.UserID
+	default:
+		if err := validateTarget(params.UserID, params.ReceiverID, 0); err != nil {
+			return nil, err
+		}
+		query.UserIDs = [2]int64{params.UserID, params.ReceiverID}
+		query.ViewerID = params.UserID
+	}
+
+	export := &conversationExport{
+		repo:   s.repo,
+		query:  query,
+		format: format,
+	}
+	if query.RoomID != 0 {
+		export.fileName = fmt.Sprintf("room-%d.%s", query.RoomID, format)
+		export.title = fmt.Sprintf("Room %d", query.RoomID)
+	} else {
+		export.fileName = fmt.Sprintf("direct-%d-%d.%s", query.UserIDs[0], query.UserIDs[1], format)
+		export.title = fmt.Sprintf("Conversation between user %d and user %d", query.UserIDs[0], query.UserIDs[1])
+	}
+	return export, nil
+}
+
+var exportContentTypes = map[string]string{
+	ExportFormatJSONL: "application/x-ndjson",
+	ExportFormatCSV:   "text/csv; charset=utf-8",
+	ExportFormatHTML:  "text/html; charset=utf-8",
+}
+
+type conversationExport struct {
+	repo     repo.MessageRepo
+	query    repo.ExportMessagesParams
+	format   string
+	fileName string
+	title    string
+}
+
+func (e *conversationExport) ContentType() string {
+	return exportContentTypes[e.format]
+}
+
+func (e *conversationExport) FileName() string {
+	return e.fileName
+}
+
+func (e *conversationExport) Write(ctx context.Context, w io.Writer) error {
+	encoder := newExportEncoder(e.format, w)
+	if err := encoder.begin(e.title); err != nil {
+		return fmt.Errorf("failed to write export header: %w", err)
+	}
+
+	query := e.query
+	for {
+		batch, err := e.repo.ExportMessages(ctx, query)
+		if err != nil {
+			return fmt.Errorf("e.repo.ExportMessages: %w", err)
+		}
+		for _, msg := range batch {
+			if err = encoder.encode(msg); err != nil {
+				return fmt.Errorf("failed to write message %d: %w", msg.ID, err)
+			}
+		}
+		if len(batch) < query.Limit {
+			break
+		}
+		query.AfterID = batch[len(batch)-1].ID
+	}
+
+	if err := encoder.end(); err != nil {
+		return fmt.Errorf("failed to finish export: %w", err)
+	}
+	return nil
+}
+
+type exportEncoder interface {
+	begin(title string) error
+	encode(msg models.ExportedMessage) error
+	end() error
+}
+
+func newExportEncoder(format string, w io.Writer) exportEncoder {
+	switch format {
+	case ExportFormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}
+	case ExportFormatHTML:
+		return &htmlEncoder{w: w}
+	default:
+		return &jsonlEncoder{enc: json.NewEncoder(w)}
+	}
+}
+
+// jsonlEncoder пишет по одному сообщению в строке в том же виде, что и API истории
+type jsonlEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlEncoder) begin(string) error {
+	return nil
+}
+
+func (e *jsonlEncoder) encode(msg models.ExportedMessage) error {
+	return e.enc.Encode(msg)
+}
+
+func (e *jsonlEncoder) end() error {
+	return nil
+}
+
+var csvHeader = []string{
+	"id", "created_at", "sender_id", "receiver_id", "room_id", "content",
+	"edited_at", "deleted_at", "deleted_by", "forwarded_from_message_id", "forwarded_from_sender_id",
+	"attachments", "reactions", "revisions",
+}
+
+// csvEncoder пишет сообщение в строку; вложения, реакции и правки сохраняются в ячейках как JSON
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) begin(string) error {
+	return e.w.Write(csvHeader)
+}
+
+func (e *csvEncoder) encode(msg models.ExportedMessage) error {
+	var forwardedID, forwardedSender string
+	if msg.ForwardedFrom != nil {
+		forwardedID = strconv.FormatInt(msg.ForwardedFrom.MessageID, 10)
+		forwardedSender = strconv.FormatInt(msg.ForwardedFrom.SenderID, 10)
+	}
+	var deletedBy string
+	if msg.DeletedBy != 0 {
+		deletedBy = strconv.FormatInt(msg.DeletedBy, 10)
+	}
+
+	attachments, err := csvJSON(msg.Attachments)
+	if err != nil {
+		return err
+	}
+	reactions, err := csvJSON(msg.Reactions)
+	if err != nil {
+		return err
+	}
+	revisions, err := csvJSON(msg.Revisions)
+	if err != nil {
+		return err
+	}
+
+	if err = e.w.Write([]string{
+		strconv.FormatInt(msg.ID, 10),
+		formatExportTime(msg.CreatedAt),
+		strconv.FormatInt(msg.SenderID, 10),
+		strconv.FormatInt(msg.ReceiverID, 10),
+		strconv.FormatInt(msg.RoomID, 10),
+		csvText(msg.Content),
+		formatExportTimePtr(msg.EditedAt),
+		formatExportTimePtr(msg.DeletedAt),
+		deletedBy,
+		forwardedID,
+		forwardedSender,
+		attachments,
+		reactions,
+		revisions,
+	}); err != nil {
+		return err
+	}
+	return e.w.Error()
+}
+
+func (e *csvEncoder) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// csvText защищает от выполнения формул при открытии выгрузки в табличном редакторе
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func csvJSON[T any](values []T) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func formatExportTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatExportTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatExportTime(*t)
+}
+
+// htmlEncoder пишет самодостаточную HTML-страницу: стили встроены, внешних ресурсов нет.
+// Вложения описываются метаданными, их содержимое в страницу не встраивается
+type htmlEncoder struct {
+	w io.Writer
+}
+
+func (e *htmlEncoder) begin(title string) error {
+	return htmlTranscript.ExecuteTemplate(e.w, "begin", title)
+}
+
+func (e *htmlEncoder) encode(msg models.ExportedMessage) error {
+	return htmlTranscript.ExecuteTemplate(e.w, "message", msg)
+}
+
+func (e *htmlEncoder) end() error {
+	return htmlTranscript.ExecuteTemplate(e.w, "end", nil)
+}
+
+var htmlTranscript = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time": formatExportTime,
+}).Parse(`
+{{- define "begin" -}}
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body{font-family:system-ui,sans-serif;max-width:960px;margin:2em auto;color:#222}
+ol.messages{list-style:none;padding:0}
+.message{border-bottom:1px solid #eee;padding:.6em 0}
+.meta{color:#666;font-size:.85em}
+.text{white-space:pre-wrap;margin:.3em 0}
+.deleted .text{color:#999;font-style:italic}
+.forwarded{color:#666;font-size:.85em;border-left:3px solid #ccc;padding-left:.5em}
+.attachments,.revisions{font-size:.9em;color:#444}
+.reactions span{display:inline-block;margin-right:.5em;font-size:.9em}
+</style>
+</head>
+<body>
+<h1>{{.}}</h1>
+<ol class="messages">
+{{end}}
+
+{{- define "message" -}}
+<li class="message{{if .DeletedAt}} deleted{{end}}" id="m{{.ID}}">
+<div class="meta">#{{.ID}} · user {{.SenderID}} · <time datetime="{{time .CreatedAt}}">{{time .CreatedAt}}</time>{{if .EditedAt}} · edited {{time .EditedAt}}{{end}}</div>
+{{- if .ForwardedFrom}}
+<div class="forwarded">forwarded from user {{.ForwardedFrom.SenderID}}, {{time .ForwardedFrom.SentAt}}</div>
+{{- end}}
+{{- if .DeletedAt}}
+<p class="text">message deleted {{time .DeletedAt}}</p>
+{{- else}}
+<p class="text">{{.Content}}</p>
+{{- end}}
+{{- if .Attachments}}
+<ul class="attachments">
+{{- range .Attachments}}
+<li>{{.FileName}} ({{.MimeType}}, {{.Size}} bytes, sha256 {{.SHA256}})</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Reactions}}
+<div class="reactions">{{range .Reactions}}<span>{{.Emoji}} {{.Count}}</span>{{end}}</div>
+{{- end}}
+{{- if .Revisions}}
+<details class="revisions"><summary>{{len .Revisions}} previous version(s)</summary>
+<ol>
+{{- range .Revisions}}
+<li><time datetime="{{time .EditedAt}}">{{time .EditedAt}}</time>: <span class="text">{{.Content}}</span></li>
+{{- end}}
+</ol>
+</details>
+{{- end}}
+</li>
+{{end}}
+
+{{- define "end" -}}
+</ol>
+</body>
+</html>
+{{end}}
+`))
@@ -0,0 +1,156 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+func exportedMessages() []models.ExportedMessage {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	editedAt := createdAt.Add(time.Minute)
+	deletedAt := createdAt.Add(time.Hour)
+	return []models.ExportedMessage{
+		{
+			Message: models.Message{
+				ID: 1, SenderID: 1, ReceiverID: 2, Content: "<b>hi</b>", CreatedAt: createdAt,
+				Edited: true, EditedAt: &editedAt,
+				Attachments: []models.Attachment{{ID: 3, FileName: "report.pdf", MimeType: "application/pdf", Size: 1024}},
+				Reactions:   []models.ReactionSummary{{Emoji: "👍", Count: 2}},
+			},
+			Revisions: []models.MessageRevision{{ID: 4, MessageID: 1, EditorID: 1, Content: "hello", EditedAt: editedAt}},
+		},
+		{
+			Message: models.Message{ID: 2, SenderID: 2, ReceiverID: 1, Content: "=SUM(A1)", CreatedAt: createdAt},
+		},
+		{
+			Message: models.Message{ID: 5, SenderID: 2, ReceiverID: 1, CreatedAt: createdAt, DeletedAt: &deletedAt, DeletedBy: 2},
+		},
+	}
+}
+
+func TestExportService_Export(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("ExportMessages", mock.Anything, repo.ExportMessagesParams{
+		UserIDs:  [2]int64{1, 2},
+		ViewerID: 1,
+		Limit:    500,
+	}).Return(exportedMessages(), nil)
+
+	service := services.NewExportService(mockRepo)
+
+	t.Run("jsonl", func(t *testing.T) {
+		export, err := service.Export(context.Background(), services.ExportParams{UserID: 1, ReceiverID: 2})
+		require.NoError(t, err)
+		assert.Equal(t, "application/x-ndjson", export.ContentType())
+		assert.Equal(t, "direct-1-2.jsonl", export.FileName())
+
+		var buf bytes.Buffer
+		require.NoError(t, export.Write(context.Background(), &buf))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 3)
+		var first models.ExportedMessage
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+		assert.Equal(t, "hello", first.Revisions[0].Content)
+		assert.Equal(t, "report.pdf", first.Attachments[0].FileName)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		export, err := service.Export(context.Background(), services.ExportParams{UserID: 1, ReceiverID: 2, Format: "csv"})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, export.Write(context.Background(), &buf))
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		assert.Equal(t, "id", records[0][0])
+		assert.Equal(t, "2024-05-01T10:01:00Z", records[1][6])
+		assert.Contains(t, records[1][11], "report.pdf")
+		assert.Equal(t, "'=SUM(A1)", records[2][5], "formulas are neutralized")
+		assert.Equal(t, "2", records[3][8])
+	})
+
+	t.Run("html", func(t *testing.T) {
+		export, err := service.Export(context.Background(), services.ExportParams{UserID: 1, ReceiverID: 2, Format: "html"})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, export.Write(context.Background(), &buf))
+
+		page := buf.String()
+		assert.True(t, strings.HasPrefix(page, "<!DOCTYPE html>"))
+		assert.True(t, strings.HasSuffix(strings.TrimSpace(page), "</html>"))
+		assert.Contains(t, page, "&lt;b&gt;hi&lt;/b&gt;", "content is escaped")
+		assert.Contains(t, page, "report.pdf")
+		assert.Contains(t, page, "message deleted")
+		assert.NotContains(t, page, "<link", "transcript has no external resources")
+	})
+}
+
+func TestExportService_Export_pagination(t *testing.T) {
+	batch := make([]models.ExportedMessage, 500)
+	for i := range batch {
+		batch[i].ID = int64(i + 1)
+	}
+
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2}, nil)
+	mockRepo.On("ExportMessages", mock.Anything, repo.ExportMessagesParams{RoomID: 5, ViewerID: 1, Limit: 500}).
+		Return(batch, nil).Once()
+	mockRepo.On("ExportMessages", mock.Anything, repo.ExportMessagesParams{RoomID: 5, ViewerID: 1, AfterID: 500, Limit: 500}).
+		Return([]models.ExportedMessage{{Message: models.Message{ID: 501}}}, nil).Once()
+
+	export, err := services.NewExportService(mockRepo).Export(context.Background(), services.ExportParams{UserID: 1, RoomID: 5})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, export.Write(context.Background(), &buf))
+	assert.Equal(t, 501, strings.Count(buf.String(), "\n"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestExportService_Export_access(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2}, nil)
+
+	service := services.NewExportService(mockRepo)
+
+	tests := []struct {
+		name          string
+		params        services.ExportParams
+		expectedError error
+	}{
+		{name: "not a room member", params: services.ExportParams{UserID: 3, RoomID: 5}, expectedError: services.ErrForbidden},
+		{name: "no conversation", params: services.ExportParams{UserID: 1}, expectedError: services.ErrValidation},
+		{name: "unknown format", params: services.ExportParams{UserID: 1, ReceiverID: 2, Format: "pdf"}, expectedError: services.ErrValidation},
+		{name: "compliance without conversation", params: services.ExportParams{Compliance: true, UserIDs: [2]int64{1, 1}}, expectedError: services.ErrValidation},
+		{name: "compliance room export", params: services.ExportParams{Compliance: true, RoomID: 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Export(context.Background(), tt.params)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	mockRepo.AssertNotCalled(t, "GetRoomMemberIDs", mock.Anything, int64(7))
+}
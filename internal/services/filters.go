@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Имена встроенных этапов конвейера
+const (
+	StageWords    = "words"
+	StageMaxLinks = "max_links"
+	StageLinkSpam = "link_spam"
+	StageRepeat   = "repeat"
+)
+
+// BuiltinStages встроенные фильтры в порядке выполнения: сначала проверки текста,
+// затем проверка повторов, которой нужен запрос к базе
+func BuiltinStages(messages repo.MessageRepo) []MessageStage {
+	return []MessageStage{
+		WordFilter{},
+		MaxLinksFilter{},
+		LinkSpamFilter{},
+		RepeatFilter{Repo: messages},
+	}
+}
+
+// WordFilter находит запрещённые слова и в зависимости от WordAction отклоняет сообщение,
+// заменяет слова звёздочками или помечает сообщение
+type WordFilter struct{}
+
+func (WordFilter) Name() string { return StageWords }
+
+func (f WordFilter) Process(_ context.Context, msg *PipelineMessage) error {
+	if len(msg.Filters.Words) == 0 {
+		return nil
+	}
+
+	var found []string
+	msg.ReplaceText(func(text string) string {
+		return replaceWords(text, func(word string) string {
+			lower := strings.ToLower(word)
+			if _, ok := slices.BinarySearch(msg.Filters.Words, lower); !ok {
+				return word
+			}
+			found = append(found, lower)
+			if msg.Filters.WordAction == models.FilterActionMask {
+				return strings.Repeat("*", utf8.RuneCountInString(word))
+			}
+			return word
+		})
+	})
+	if len(found) == 0 {
+		return nil
+	}
+
+	switch msg.Filters.WordAction {
+	case models.FilterActionMask:
+		return nil
+	case models.FilterActionFlag:
+		found = slices.Compact(slices.Sorted(slices.Values(found)))
+		msg.Flag(f.Name(), "forbidden words: "+strings.Join(found, ", "))
+		return nil
+	default:
+		return &RejectionError{Stage: f.Name(), Reason: "message contains a forbidden word"}
+	}
+}
+
+// replaceWords заменяет каждое слово текста — последовательность букв и цифр — результатом replace
+func replaceWords(text string, replace func(word string) string) string {
+	var b strings.Builder
+	start := -1
+	for i, r := range text {
+		switch {
+		case isWordRune(r) && start < 0:
+			start = i
+		case !isWordRune(r) && start >= 0:
+			b.WriteString(replace(text[start:i]))
+			start = -1
+		}
+		if start < 0 {
+			b.WriteRune(r)
+		}
+	}
+	if start >= 0 {
+		b.WriteString(replace(text[start:]))
+	}
+	return b.String()
+}
+
+// MaxLinksFilter отклоняет сообщения, в которых больше MaxLinks ссылок
+type MaxLinksFilter struct{}
+
+func (MaxLinksFilter) Name() string { return StageMaxLinks }
+
+func (f MaxLinksFilter) Process(_ context.Context, msg *PipelineMessage) error {
+	if msg.Filters.MaxLinks == 0 {
+		return nil
+	}
+	if links := msg.Links(); len(links) > msg.Filters.MaxLinks {
+		return &RejectionError{
+			Stage:  f.Name(),
+			Reason: fmt.Sprintf("message contains %d links, at most %d are allowed", len(links), msg.Filters.MaxLinks),
+		}
+	}
+	return nil
+}
+
+// linkShorteners сервисы коротких ссылок, скрывающие настоящий адрес
+var linkShorteners = []string{
+	"bit.ly", "cutt.ly", "goo.gl", "is.gd", "ow.ly", "rb.gy", "shorturl.at", "t.co", "tinyurl.com", "v.gd",
+}
+
+const (
+	// minSpamLinks сколько ссылок должно быть в сообщении, чтобы оно считалось состоящим из ссылок
+	minSpamLinks = 2
+	// minLinkCommentLetters сколько букв помимо ссылок нужно, чтобы сообщение не считалось рассылкой
+	minLinkCommentLetters = 20
+)
+
+// LinkSpamFilter помечает сообщения, похожие на рассылку ссылок: ссылки через сервисы коротких ссылок
+// и сообщения из нескольких ссылок почти без текста. Сообщение не отклоняется — решение за модераторами
+type LinkSpamFilter struct{}
+
+func (LinkSpamFilter) Name() string { return StageLinkSpam }
+
+func (f LinkSpamFilter) Process(_ context.Context, msg *PipelineMessage) error {
+	if !msg.Filters.LinkSpam {
+		return nil
+	}
+	links := msg.Links()
+	if len(links) == 0 {
+		return nil
+	}
+
+	for _, link := range links {
+		u, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+		if host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."); slices.Contains(linkShorteners, host) {
+			msg.Flag(f.Name(), "shortened link "+host)
+			return nil
+		}
+	}
+
+	if len(links) >= minSpamLinks {
+		comment := plainURLRe.ReplaceAllString(msg.Content, "")
+		letters := 0
+		for _, r := range comment {
+			if unicode.IsLetter(r) {
+				letters++
+			}
+		}
+		if letters < minLinkCommentLetters {
+			msg.Flag(f.Name(), fmt.Sprintf("%d links with almost no text", len(links)))
+		}
+	}
+	return nil
+}
+
+// RepeatFilter отклоняет сообщение, если отправитель уже отправил RepeatLimit таких же сообщений
+// за последние RepeatWindow секунд в любые переписки. Правки не проверяются
+type RepeatFilter struct {
+	Repo repo.MessageRepo
+}
+
+func (RepeatFilter) Name() string { return StageRepeat }
+
+func (f RepeatFilter) Process(ctx context.Context, msg *PipelineMessage) error {
+	if msg.Edit || msg.Filters.RepeatLimit == 0 || msg.Content == "" {
+		return nil
+	}
+
+	count, err := f.Repo.CountRecentMessages(ctx, repo.CountRecentMessagesParams{
+		SenderID: msg.SenderID,
+		Content:  msg.Content,
+		Since:    time.Now().Add(-time.Duration(msg.Filters.RepeatWindow) * time.Second),
+	})
+	if err != nil {
+		return fmt.Errorf("f.Repo.CountRecentMessages: %w", err)
+	}
+	if count >= msg.Filters.RepeatLimit {
+		return &RejectionError{
+			Stage:  f.Name(),
+			Reason: fmt.Sprintf("the same message was sent %d times in the last %d seconds", count, msg.Filters.RepeatWindow),
+		}
+	}
+	return nil
+}
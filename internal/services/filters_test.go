@@ -0,0 +1,327 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+func newFilteredMessageService(r *MockMessageRepo, defaults models.MessageFilters, stages ...services.MessageStage) services.MessageService {
+	if stages == nil {
+		stages = services.BuiltinStages(r)
+	}
+	return services.NewMessageService(services.MessageServiceConfig{
+		Repo: r,
+		Pipeline: services.NewMessagePipeline(services.MessagePipelineConfig{
+			Stages:   stages,
+			Defaults: defaults,
+		}),
+	})
+}
+
+func TestMessageService_SaveMessage_Filters(t *testing.T) {
+	defaults := models.MessageFilters{
+		Words:        []string{"Spam"},
+		WordAction:   models.FilterActionMask,
+		RepeatLimit:  2,
+		RepeatWindow: 60,
+		LinkSpam:     true,
+		MaxLinks:     2,
+	}
+
+	tests := []struct {
+		name          string
+		params        services.SaveMessageParams
+		mockBehavior  func(r *MockMessageRepo)
+		expectedStage string
+	}{
+		{
+			name:   "forbidden word is masked",
+			params: services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "buy SPAM, not spammer"},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("CountRecentMessages", mock.Anything, mock.MatchedBy(func(params repo.CountRecentMessagesParams) bool {
+					return params.SenderID == 1 && params.Content == "buy ****, not spammer"
+				})).Return(0, nil)
+				r.On("SaveMessage", mock.Anything, mock.MatchedBy(func(params repo.SaveMessageParams) bool {
+					return params.Content == "buy ****, not spammer" && params.Flags == nil
+				})).Return(&models.Message{ID: 1}, nil)
+			},
+		},
+		{
+			name: "masked in rich text without moving entities",
+			params: services.SaveMessageParams{SenderID: 1, ReceiverID: 2, RichContent: &models.RichContent{
+				Kind:     models.ContentKindText,
+				Text:     "спам и spam",
+				Entities: []models.ContentEntity{{Type: models.EntityBold, Offset: 7, Length: 4}},
+			}},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("CountRecentMessages", mock.Anything, mock.Anything).Return(0, nil)
+				r.On("SaveMessage", mock.Anything, mock.MatchedBy(func(params repo.SaveMessageParams) bool {
+					return params.Content == "спам и ****" && params.RichContent.Text == "спам и ****"
+				})).Return(&models.Message{ID: 1}, nil)
+			},
+		},
+		{
+			name:          "too many links",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "https://a.example https://b.example https://c.example"},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedStage: services.StageMaxLinks,
+		},
+		{
+			name:   "shortened link is flagged",
+			params: services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "see https://bit.ly/abc"},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("CountRecentMessages", mock.Anything, mock.Anything).Return(0, nil)
+				r.On("SaveMessage", mock.Anything, mock.MatchedBy(func(params repo.SaveMessageParams) bool {
+					return len(params.Flags) == 1 && params.Flags[0].Stage == services.StageLinkSpam
+				})).Return(&models.Message{ID: 1}, nil)
+			},
+		},
+		{
+			name:   "bare links are flagged",
+			params: services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "https://a.example https://b.example"},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("CountRecentMessages", mock.Anything, mock.Anything).Return(0, nil)
+				r.On("SaveMessage", mock.Anything, mock.MatchedBy(func(params repo.SaveMessageParams) bool {
+					return len(params.Flags) == 1 && params.Flags[0].Stage == services.StageLinkSpam
+				})).Return(&models.Message{ID: 1}, nil)
+			},
+		},
+		{
+			name:   "repeated message",
+			params: services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "hello"},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("CountRecentMessages", mock.Anything, mock.Anything).Return(2, nil)
+			},
+			expectedStage: services.StageRepeat,
+		},
+		{
+			name:   "room filters override defaults",
+			params: services.SaveMessageParams{SenderID: 1, RoomID: 5, Content: "no spam please"},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2}, nil)
+				r.On("GetMessageFilters", mock.Anything, int64(5)).Return(&models.MessageFilters{
+					Words:      []string{"spam"},
+					WordAction: models.FilterActionReject,
+				}, nil)
+			},
+			expectedStage: services.StageWords,
+		},
+		{
+			name:   "room without own filters",
+			params: services.SaveMessageParams{SenderID: 1, RoomID: 5, Content: "hello"},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2}, nil)
+				r.On("GetMessageFilters", mock.Anything, int64(5)).Return(nil, nil)
+				r.On("CountRecentMessages", mock.Anything, mock.Anything).Return(1, nil)
+				r.On("SaveMessage", mock.Anything, mock.Anything).Return(&models.Message{ID: 1, RoomID: 5}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.mockBehavior(mockRepo)
+
+			_, err := newFilteredMessageService(mockRepo, defaults).SaveMessage(context.Background(), tt.params)
+			if tt.expectedStage != "" {
+				assert.ErrorIs(t, err, services.ErrMessageRejected)
+				var rejection *services.RejectionError
+				require.ErrorAs(t, err, &rejection)
+				assert.Equal(t, tt.expectedStage, rejection.Stage)
+				assert.NotEmpty(t, rejection.Reason)
+				mockRepo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+			} else {
+				require.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_EditMessage_Filters(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetMessageByID", mock.Anything, int64(10)).Return(&models.Message{ID: 10, SenderID: 1, ReceiverID: 2}, nil)
+	mockRepo.On("EditMessage", mock.Anything, repo.EditMessageParams{
+		MessageID: 10,
+		EditorID:  1,
+		Content:   "some spam",
+		Flags:     []models.MessageFlag{{Stage: services.StageWords, Reason: "forbidden words: spam"}},
+	}).Return(&models.Message{ID: 10, SenderID: 1, ReceiverID: 2}, nil)
+
+	service := newFilteredMessageService(mockRepo, models.MessageFilters{
+		Words:        []string{"spam"},
+		WordAction:   models.FilterActionFlag,
+		RepeatLimit:  1,
+		RepeatWindow: 60,
+	})
+	_, err := service.EditMessage(context.Background(), services.EditMessageParams{MessageID: 10, EditorID: 1, Content: "some spam"})
+	require.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "CountRecentMessages", mock.Anything, mock.Anything)
+}
+
+func TestMessageService_ForwardMessages_Filters(t *testing.T) {
+	source := &models.Message{ID: 7, SenderID: 2, ReceiverID: 1, Content: "cheap spam here"}
+	roomFilters := &models.MessageFilters{Words: []string{"spam"}, WordAction: models.FilterActionReject}
+
+	t.Run("rejected by the target room", func(t *testing.T) {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(20)).Return([]int64{1, 4}, nil)
+		mockRepo.On("GetMessageByID", mock.Anything, int64(7)).Return(source, nil)
+		mockRepo.On("ForwardingDisabled", mock.Anything, int64(0), [2]int64{1, 2}).Return(false, nil)
+		mockRepo.On("GetMessageFilters", mock.Anything, int64(20)).Return(roomFilters, nil)
+
+		service := newFilteredMessageService(mockRepo, models.MessageFilters{})
+		_, err := service.ForwardMessages(context.Background(), services.ForwardMessagesParams{UserID: 1, MessageIDs: []int64{7}, RoomID: 20})
+
+		var rejection *services.RejectionError
+		require.ErrorAs(t, err, &rejection)
+		assert.Equal(t, services.StageWords, rejection.Stage)
+		mockRepo.AssertNotCalled(t, "SaveMessages", mock.Anything, mock.Anything)
+	})
+
+	t.Run("masked and flagged by server defaults", func(t *testing.T) {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("GetMessageByID", mock.Anything, int64(7)).Return(source, nil)
+		mockRepo.On("ForwardingDisabled", mock.Anything, int64(0), [2]int64{1, 2}).Return(false, nil)
+		mockRepo.On("SaveMessages", mock.Anything, mock.MatchedBy(func(copies []repo.SaveMessageParams) bool {
+			return len(copies) == 1 && copies[0].Content == "cheap **** here" &&
+				copies[0].ForwardedFrom != nil && copies[0].ForwardedFrom.MessageID == 7
+		})).Return([]models.Message{{ID: 100, ReceiverID: 3}}, nil)
+
+		service := newFilteredMessageService(mockRepo, models.MessageFilters{Words: []string{"spam"}, WordAction: models.FilterActionMask},
+			services.WordFilter{})
+		forwarded, err := service.ForwardMessages(context.Background(), services.ForwardMessagesParams{UserID: 1, MessageIDs: []int64{7}, ReceiverID: 3})
+		require.NoError(t, err)
+		assert.Len(t, forwarded, 1)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+type failingStage struct{}
+
+func (failingStage) Name() string { return "failing" }
+
+func (failingStage) Process(context.Context, *services.PipelineMessage) error {
+	return errors.New("classifier unavailable")
+}
+
+func TestMessageService_SaveMessage_StageError(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+
+	_, err := newFilteredMessageService(mockRepo, models.MessageFilters{}, failingStage{}).SaveMessage(context.Background(),
+		services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "hello"})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, services.ErrMessageRejected)
+	mockRepo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+}
+
+func TestMessageService_SetMessageFilters(t *testing.T) {
+	filters := &models.MessageFilters{Words: []string{" Spam ", "spam"}, MaxLinks: 3}
+
+	tests := []struct {
+		name          string
+		params        services.SetMessageFiltersParams
+		mockBehavior  func(r *MockMessageRepo)
+		expectedError error
+	}{
+		{
+			name:   "room admin",
+			params: services.SetMessageFiltersParams{UserID: 1, RoomID: 5, Filters: filters},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(1)).Return(&models.RoomMember{IsAdmin: true}, nil)
+				r.On("SetMessageFilters", mock.Anything, int64(5), &models.MessageFilters{
+					Words:      []string{"spam"},
+					WordAction: models.FilterActionReject,
+					MaxLinks:   3,
+				}).Return(nil)
+			},
+		},
+		{
+			name:   "reset",
+			params: services.SetMessageFiltersParams{UserID: 1, RoomID: 5},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(1)).Return(&models.RoomMember{IsAdmin: true}, nil)
+				r.On("SetMessageFilters", mock.Anything, int64(5), (*models.MessageFilters)(nil)).Return(nil)
+			},
+		},
+		{
+			name:   "regular member",
+			params: services.SetMessageFiltersParams{UserID: 2, RoomID: 5, Filters: filters},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(2)).Return(&models.RoomMember{}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name: "unknown word action",
+			params: services.SetMessageFiltersParams{UserID: 1, RoomID: 5, Filters: &models.MessageFilters{
+				Words: []string{"spam"}, WordAction: "ban",
+			}},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name: "phrase instead of a word",
+			params: services.SetMessageFiltersParams{UserID: 1, RoomID: 5, Filters: &models.MessageFilters{
+				Words: []string{"buy now"},
+			}},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name: "repeat limit without window",
+			params: services.SetMessageFiltersParams{UserID: 1, RoomID: 5, Filters: &models.MessageFilters{
+				RepeatLimit: 3,
+			}},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "no room",
+			params:        services.SetMessageFiltersParams{UserID: 1, Filters: filters},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.mockBehavior(mockRepo)
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+			err := service.SetMessageFilters(context.Background(), tt.params)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_GetFlaggedMessages(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetRoomMember", mock.Anything, int64(5), int64(1)).Return(&models.RoomMember{IsAdmin: true}, nil)
+	mockRepo.On("GetFlaggedMessages", mock.Anything, repo.GetFlaggedMessagesParams{RoomID: 5, Limit: 100}).
+		Return([]models.FlaggedMessage{{Message: models.Message{ID: 3}}}, nil)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+	flagged, err := service.GetFlaggedMessages(context.Background(), services.GetFlaggedMessagesParams{UserID: 1, RoomID: 5, Limit: 500})
+	require.NoError(t, err)
+	assert.Len(t, flagged, 1)
+	mockRepo.AssertExpectations(t)
+}
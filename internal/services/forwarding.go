@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"slices"
+	"time"
+)
+
+// maxForwardedMessages сколько сообщений можно переслать за один раз
+const maxForwardedMessages = 100
+
+type ForwardMessagesParams struct {
+	UserID     int64
+	MessageIDs []int64
+	ReceiverID int64
+	// RoomID задаётся для пересылки в комнату; ReceiverID в этом случае не используется
+	RoomID int64
+}
+
+// ForwardMessages пересылает сообщения в другую переписку. Копии сохраняют ссылку на оригинал
+// и переиспользуют его вложения. Пересылать можно только из переписок, где пользователь участвует
+// и где пересылка не запрещена; ни одно сообщение не пересылается, если нельзя переслать хотя бы одно
+func (s *messageService) ForwardMessages(ctx context.Context, params ForwardMessagesParams) ([]models.Message, error) {
+	if err := validateTarget(params.UserID, params.ReceiverID, params.RoomID); err != nil {
+		return nil, err
+	}
+	if len(params.MessageIDs) == 0 {
+		return nil, fmt.Errorf("%w: message_ids is required", ErrValidation)
+	}
+	if len(params.MessageIDs) > maxForwardedMessages {
+		return nil, fmt.Errorf("%w: at most %d messages can be forwarded at once", ErrValidation, maxForwardedMessages)
+	}
+
+	var recipients []int64
+	switch {
+	case params.RoomID != 0:
+		members, err := s.repo.GetRoomMemberIDs(ctx, params.RoomID)
+		if err != nil {
+			return nil, fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+		}
+		if !slices.Contains(members, params.UserID) {
+			return nil, fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+		recipients = slices.DeleteFunc(members, func(id int64) bool { return id == params.UserID })
+		params.ReceiverID = 0
+	default:
+		if err := s.checkReceiver(ctx, params.ReceiverID); err != nil {
+			return nil, err
+		}
+		recipients = []int64{params.ReceiverID}
+	}
+
+	sources := make([]models.Message, 0, len(params.MessageIDs))
+	seen := make(map[int64]bool, len(params.MessageIDs))
+	disabled := make(map[conversation]bool)
+	for _, id := range params.MessageIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		msg, err := s.forwardSource(ctx, id, params.UserID, disabled)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, *msg)
+	}
+
+	// Копии проходят фильтры переписки назначения до сохранения первой из них:
+	// если хотя бы одна отклонена, ничего не пересылается
+	filtered := make([]PipelineMessage, len(sources))
+	for i, source := range sources {
+		filtered[i] = PipelineMessage{
+			SenderID:    params.UserID,
+			ReceiverID:  params.ReceiverID,
+			RoomID:      params.RoomID,
+			Content:     source.Content,
+			RichContent: source.RichContent,
+		}
+		if err := s.filterMessage(ctx, &filtered[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	copies := make([]repo.SaveMessageParams, len(sources))
+	for i, source := range sources {
+		copies[i] = repo.SaveMessageParams{
+			SenderID:            params.UserID,
+			ReceiverID:          params.ReceiverID,
+			RoomID:              params.RoomID,
+			Content:             filtered[i].Content,
+			RichContent:         filtered[i].RichContent,
+			RecipientIDs:        recipients,
+			ForwardedFrom:       forwardedFrom(source),
+			CopyAttachmentsFrom: source.ID,
+			Flags:               filtered[i].Flags,
+		}
+	}
+
+	// Копии сохраняются одной транзакцией, а события рассылаются только после её фиксации
+	forwarded, err := s.repo.SaveMessages(ctx, copies)
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.SaveMessages: %w", err)
+	}
+
+	audience := append(slices.Clip(recipients), params.UserID)
+	for _, msg := range forwarded {
+		s.publish(audience, events.Event{Type: events.MessageNew, Payload: &msg})
+		s.enqueueLinkPreview(msg)
+	}
+
+	return forwarded, nil
+}
+
+// conversation комната или упорядоченная пара участников личной переписки
+type conversation struct {
+	roomID  int64
+	userIDs [2]int64
+}
+
+// forwardSource проверяет, что сообщение можно переслать. disabled кэширует настройки переписок,
+// чтобы не запрашивать их для каждого сообщения
+func (s *messageService) forwardSource(ctx context.Context, messageID, userID int64, disabled map[conversation]bool) (*models.Message, error) {
+	msg, err := s.getMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.DeletedAt != nil || (msg.ExpiresAt != nil && !msg.ExpiresAt.After(time.Now())) {
+		return nil, fmt.Errorf("%w: message %d", ErrNotFound, messageID)
+	}
+
+	members, err := s.conversationMembers(ctx, *msg)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(members, userID) {
+		return nil, fmt.Errorf("%w: not a member of the conversation", ErrForbidden)
+	}
+
+	if msg.ViewOnce {
+		return nil, fmt.Errorf("%w: view once messages cannot be forwarded", ErrForbidden)
+	}
+	if msg.RichContent != nil && msg.RichContent.Kind == models.ContentKindSystem {
+		return nil, fmt.Errorf("%w: system messages cannot be forwarded", ErrValidation)
+	}
+
+	source := conversation{roomID: msg.RoomID}
+	if msg.RoomID == 0 {
+		source.userIDs = [2]int64{min(msg.SenderID, msg.ReceiverID), max(msg.SenderID, msg.ReceiverID)}
+	}
+	off, ok := disabled[source]
+	if !ok {
+		off, err = s.repo.ForwardingDisabled(ctx, source.roomID, source.userIDs)
+		if err != nil {
+			return nil, fmt.Errorf("s.repo.ForwardingDisabled: %w", err)
+		}
+		disabled[source] = off
+	}
+	if off {
+		return nil, fmt.Errorf("%w: forwarding is disabled in the conversation of message %d", ErrForbidden, messageID)
+	}
+
+	return msg, nil
+}
+
+// forwardedFrom возвращает происхождение копии: пересланное сообщение ссылается на свой оригинал
+func forwardedFrom(msg models.Message) *models.ForwardedFrom {
+	if msg.ForwardedFrom != nil {
+		return msg.ForwardedFrom
+	}
+	origin := &models.ForwardedFrom{
+		MessageID: msg.ID,
+		SenderID:  msg.SenderID,
+		RoomID:    msg.RoomID,
+		SentAt:    msg.CreatedAt,
+	}
+	if msg.RoomID == 0 {
+		origin.ReceiverID = msg.ReceiverID
+	}
+	return origin
+}
+
+type SetForwardingParams struct {
+	UserID     int64
+	ReceiverID int64
+	// RoomID задаётся для комнаты; ReceiverID в этом случае не используется
+	RoomID   int64
+	Disabled bool
+}
+
+// SetForwarding запрещает или разрешает пересылку сообщений из переписки.
+// В комнате это может сделать только администратор, в личной переписке — любой из участников
+func (s *messageService) SetForwarding(ctx context.Context, params SetForwardingParams) error {
+	update := repo.SetForwardingParams{RoomID: params.RoomID, Disabled: params.Disabled}
+	switch {
+	case params.RoomID != 0:
+		member, err := s.repo.GetRoomMember(ctx, params.RoomID, params.UserID)
+		if errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+		if err != nil {
+			return fmt.Errorf("s.repo.GetRoomMember: %w", err)
+		}
+		if !member.IsAdmin {
+			return fmt.Errorf("%w: only room admins can change forwarding", ErrForbidden)
+		}
+	case params.ReceiverID != 0:
+		update.UserIDs = [2]int64{params.UserID, params.ReceiverID}
+	default:
+		return fmt.Errorf("%w: receiver_id or room_id is required", ErrValidation)
+	}
+
+	if err := s.repo.SetForwarding(ctx, update); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("%w: room %d", ErrNotFound, params.RoomID)
+		}
+		return fmt.Errorf("s.repo.SetForwarding: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+var errSaveFailed = errors.New("save failed")
+
+func TestMessageService_ForwardMessages(t *testing.T) {
+	sentAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	direct := &models.Message{ID: 7, SenderID: 2, ReceiverID: 1, Content: "hi", CreatedAt: sentAt}
+	roomMessage := &models.Message{ID: 8, SenderID: 3, RoomID: 5, Content: "news", CreatedAt: sentAt}
+	alreadyForwarded := &models.Message{ID: 9, SenderID: 1, ReceiverID: 2, Content: "hi", ForwardedFrom: &models.ForwardedFrom{
+		MessageID: 4, SenderID: 6, RoomID: 11, SentAt: sentAt,
+	}}
+
+	tests := []struct {
+		name          string
+		params        services.ForwardMessagesParams
+		mockBehavior  func(r *MockMessageRepo)
+		expectedFrom  []models.ForwardedFrom
+		expectedError error
+	}{
+		{
+			name:   "direct and room messages into a room",
+			params: services.ForwardMessagesParams{UserID: 1, MessageIDs: []int64{7, 8, 7}, RoomID: 20},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMemberIDs", mock.Anything, int64(20)).Return([]int64{1, 4}, nil)
+				r.On("GetMessageByID", mock.Anything, int64(7)).Return(direct, nil)
+				r.On("GetMessageByID", mock.Anything, int64(8)).Return(roomMessage, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 3}, nil)
+				r.On("ForwardingDisabled", mock.Anything, int64(0), [2]int64{1, 2}).Return(false, nil)
+				r.On("ForwardingDisabled", mock.Anything, int64(5), [2]int64{}).Return(false, nil)
+				r.On("SaveMessages", mock.Anything, mock.MatchedBy(func(copies []repo.SaveMessageParams) bool {
+					for _, p := range copies {
+						if p.SenderID != 1 || p.RoomID != 20 || p.CopyAttachmentsFrom == 0 {
+							return false
+						}
+					}
+					return len(copies) == 2
+				})).Return([]models.Message{{ID: 100, RoomID: 20}, {ID: 101, RoomID: 20}}, nil)
+			},
+			expectedFrom: []models.ForwardedFrom{
+				{MessageID: 7, SenderID: 2, ReceiverID: 1, SentAt: sentAt},
+				{MessageID: 8, SenderID: 3, RoomID: 5, SentAt: sentAt},
+			},
+		},
+		{
+			name:   "forwarding a forwarded message keeps the original",
+			params: services.ForwardMessagesParams{UserID: 1, MessageIDs: []int64{9}, ReceiverID: 3},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(9)).Return(alreadyForwarded, nil)
+				r.On("ForwardingDisabled", mock.Anything, int64(0), [2]int64{1, 2}).Return(false, nil)
+				r.On("SaveMessages", mock.Anything, mock.Anything).Return([]models.Message{{ID: 101}}, nil)
+			},
+			expectedFrom: []models.ForwardedFrom{*alreadyForwarded.ForwardedFrom},
+		},
+		{
+			name:   "forwarding disabled in the source conversation",
+			params: services.ForwardMessagesParams{UserID: 1, MessageIDs: []int64{8}, ReceiverID: 3},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(8)).Return(roomMessage, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 3}, nil)
+				r.On("ForwardingDisabled", mock.Anything, int64(5), [2]int64{}).Return(true, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "not a member of the source conversation",
+			params: services.ForwardMessagesParams{UserID: 4, MessageIDs: []int64{7}, ReceiverID: 3},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(7)).Return(direct, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "not a member of the target room",
+			params: services.ForwardMessagesParams{UserID: 1, MessageIDs: []int64{7}, RoomID: 21},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMemberIDs", mock.Anything, int64(21)).Return([]int64{4}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "view once",
+			params: services.ForwardMessagesParams{UserID: 1, MessageIDs: []int64{10}, ReceiverID: 3},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(10)).Return(&models.Message{ID: 10, SenderID: 2, ReceiverID: 1, ViewOnce: true}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "nothing is forwarded when saving fails",
+			params: services.ForwardMessagesParams{UserID: 1, MessageIDs: []int64{9}, ReceiverID: 3},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(9)).Return(alreadyForwarded, nil)
+				r.On("ForwardingDisabled", mock.Anything, int64(0), [2]int64{1, 2}).Return(false, nil)
+				r.On("SaveMessages", mock.Anything, mock.Anything).Return(nil, errSaveFailed)
+			},
+			expectedError: errSaveFailed,
+		},
+		{
+			name:          "to yourself",
+			params:        services.ForwardMessagesParams{UserID: 1, MessageIDs: []int64{7}, ReceiverID: 1},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "no target",
+			params:        services.ForwardMessagesParams{UserID: 1, MessageIDs: []int64{7}},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.mockBehavior(mockRepo)
+
+			var published int
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				if event.Type == events.MessageNew {
+					published++
+				}
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+			forwarded, err := service.ForwardMessages(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Zero(t, published)
+				mockRepo.AssertExpectations(t)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, forwarded, len(tt.expectedFrom))
+			assert.Equal(t, len(tt.expectedFrom), published)
+
+			var from []models.ForwardedFrom
+			for _, call := range mockRepo.Calls {
+				if call.Method == "SaveMessages" {
+					for _, p := range call.Arguments.Get(1).([]repo.SaveMessageParams) {
+						from = append(from, *p.ForwardedFrom)
+					}
+				}
+			}
+			assert.Equal(t, tt.expectedFrom, from)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_EditMessage_Forwarded(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetMessageByID", mock.Anything, int64(9)).Return(&models.Message{
+		ID: 9, SenderID: 1, ReceiverID: 2, ForwardedFrom: &models.ForwardedFrom{MessageID: 4, SenderID: 6},
+	}, nil)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+	_, err := service.EditMessage(context.Background(), services.EditMessageParams{MessageID: 9, EditorID: 1, Content: "changed"})
+
+	assert.ErrorIs(t, err, services.ErrForbidden)
+	mockRepo.AssertNotCalled(t, "EditMessage", mock.Anything, mock.Anything)
+}
+
+func TestMessageService_SetForwarding(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetRoomMember", mock.Anything, int64(5), int64(1)).Return(&models.RoomMember{RoomID: 5, UserID: 1, IsAdmin: true}, nil)
+	mockRepo.On("GetRoomMember", mock.Anything, int64(5), int64(2)).Return(&models.RoomMember{RoomID: 5, UserID: 2}, nil)
+	mockRepo.On("SetForwarding", mock.Anything, repo.SetForwardingParams{RoomID: 5, Disabled: true}).Return(nil)
+	mockRepo.On("SetForwarding", mock.Anything, repo.SetForwardingParams{UserIDs: [2]int64{2, 3}, Disabled: true}).Return(nil)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+
+	err := service.SetForwarding(context.Background(), services.SetForwardingParams{UserID: 1, RoomID: 5, Disabled: true})
+	assert.NoError(t, err)
+
+	err = service.SetForwarding(context.Background(), services.SetForwardingParams{UserID: 2, RoomID: 5, Disabled: true})
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	err = service.SetForwarding(context.Background(), services.SetForwardingParams{UserID: 2, ReceiverID: 3, Disabled: true})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/pkg/chatimport"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxRoomNameLength ограничение колонки rooms.name
+const maxRoomNameLength = 255
+
+type ImportService interface {
+	Import(ctx context.Context, params ImportParams) (*models.ImportReport, error)
+}
+
+type importService struct {
+	repo        repo.ImportRepo
+	attachments AttachmentService
+	log         *logrus.Logger
+}
+
+type ImportServiceConfig struct {
+	Repo repo.ImportRepo
+	// Attachments сохраняет файлы из выгрузки так же, как загруженные пользователями
+	Attachments AttachmentService
+	Log         *logrus.Logger
+}
+
+func NewImportService(cfg ImportServiceConfig) ImportService {
+	return &importService{
+		repo:        cfg.Repo,
+		attachments: cfg.Attachments,
+		log:         cfg.Log,
+	}
+}
+
+type ImportParams struct {
+	// Source telegram или slack
+	Source  string
+	Archive fs.FS
+	// Users соответствие внешних ID пользователей нашим
+	Users map[string]int64
+}
+
+// Import переносит историю из выгрузки: для групповых переписок создаются комнаты, сообщения сохраняются
+// с исходным временем отправки, ответами и вложениями. Уже перенесённые комнаты и сообщения пропускаются,
+// поэтому импорт можно повторять, например после дополнения соответствия пользователей.
+// Импорт не рассылает событий и не заводит статусов доставки: история не становится непрочитанной
+func (s *importService) Import(ctx context.Context, params ImportParams) (*models.ImportReport, error) {
+	if len(params.Users) == 0 {
+		return nil, fmt.Errorf("%w: user mapping for %s is empty", ErrValidation, params.Source)
+	}
+	archive, err := chatimport.Parse(params.Source, params.Archive)
+	if err != nil {
+		if errors.Is(err, chatimport.ErrUnsupportedArchive) || errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %v", ErrValidation, err)
+		}
+		return nil, fmt.Errorf("chatimport.Parse: %w", err)
+	}
+
+	report := &models.ImportReport{Source: archive.Source, Chats: make([]models.ImportedChat, 0, len(archive.Chats))}
+	unmapped := make(map[string]struct{})
+	for _, chat := range archive.Chats {
+		result, err := s.importChat(ctx, archive, chat, params.Users, unmapped)
+		if err != nil {
+			return nil, fmt.Errorf("chat %s: %w", chat.ID, err)
+		}
+		s.log.Infof("imported %s chat %s: %d new, %d already imported, %d skipped",
+			archive.Source, chat.ID, result.Imported, result.AlreadyImported, result.Skipped)
+		report.Chats = append(report.Chats, result)
+	}
+
+	for id := range unmapped {
+		report.UnmappedUsers = append(report.UnmappedUsers, id)
+	}
+	slices.Sort(report.UnmappedUsers)
+	return report, nil
+}
+
+func (s *importService) importChat(ctx context.Context, archive *chatimport.Archive, chat chatimport.Chat, users map[string]int64, unmapped map[string]struct{}) (models.ImportedChat, error) {
+	result := models.ImportedChat{ExternalID: chat.ID, Name: chat.Name}
+
+	var members []int64
+	mapUser := func(externalID string) {
+		userID, ok := users[externalID]
+		if !ok {
+			unmapped[externalID] = struct{}{}
+			return
+		}
+		if !slices.Contains(members, userID) {
+			members = append(members, userID)
+		}
+	}
+	for _, id := range chat.MemberIDs {
+		mapUser(id)
+	}
+	for _, msg := range chat.Messages {
+		mapUser(msg.AuthorID)
+	}
+
+	target := repo.ImportMessageParams{Source: archive.Source, ExternalChatID: chat.ID}
+	if chat.Direct {
+		// Второго участника личной переписки можно узнать только по его сообщениям или списку участников
+		if len(members) != 2 {
+			result.Skipped = len(chat.Messages)
+			result.Error = "direct chat requires exactly two mapped participants"
+			return result, nil
+		}
+		result.UserIDs = members
+	} else {
+		if len(members) == 0 {
+			result.Skipped = len(chat.Messages)
+			result.Error = "room has no mapped members"
+			return result, nil
+		}
+		roomID, err := s.repo.ImportRoom(ctx, importRoomParams(archive.Source, chat, users, members))
+		if err != nil {
+			return result, fmt.Errorf("s.repo.ImportRoom: %w", err)
+		}
+		result.RoomID, target.RoomID = roomID, roomID
+	}
+
+	imported, err := s.repo.GetImportedMessageIDs(ctx, archive.Source, chat.ID)
+	if err != nil {
+		return result, fmt.Errorf("s.repo.GetImportedMessageIDs: %w", err)
+	}
+
+	for _, msg := range chat.Messages {
+		if _, ok := imported[msg.ID]; ok {
+			result.AlreadyImported++
+			continue
+		}
+		senderID, ok := users[msg.AuthorID]
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		content, err := normalizeText(msg.Text)
+		if err != nil {
+			result.Skipped++
+			continue
+		}
+
+		params := target
+		params.ExternalID = msg.ID
+		params.SenderID = senderID
+		params.Content = content
+		params.SentAt = msg.SentAt.UTC()
+		params.ReplyToExternalID = msg.ReplyToID
+		if chat.Direct {
+			params.ReceiverID = members[0]
+			if params.ReceiverID == senderID {
+				params.ReceiverID = members[1]
+			}
+		}
+		for _, file := range msg.Files {
+			attachmentID, err := s.importFile(ctx, archive, file, senderID)
+			if err != nil {
+				return result, err
+			}
+			if attachmentID == 0 {
+				result.MissingFiles++
+				continue
+			}
+			params.AttachmentIDs = append(params.AttachmentIDs, attachmentID)
+		}
+		if params.Content == "" && len(params.AttachmentIDs) == 0 {
+			result.Skipped++
+			continue
+		}
+
+		if _, err = s.repo.ImportMessage(ctx, params); err != nil {
+			if errors.Is(err, repo.ErrDuplicate) {
+				result.AlreadyImported++
+				continue
+			}
+			return result, fmt.Errorf("s.repo.ImportMessage: %w", err)
+		}
+		result.Imported++
+	}
+	return result, nil
+}
+
+// importRoomParams выбирает создателя комнаты: автора исходной переписки, если он сопоставлен,
+// иначе первого из участников
+func importRoomParams(source string, chat chatimport.Chat, users map[string]int64, members []int64) repo.ImportRoomParams {
+	params := repo.ImportRoomParams{
+		Source:     source,
+		ExternalID: chat.ID,
+		Name:       chat.Name,
+		CreatorID:  members[0],
+		CreatedAt:  chat.CreatedAt.UTC(),
+		MemberIDs:  members,
+	}
+	if creatorID, ok := users[chat.CreatorID]; ok {
+		params.CreatorID = creatorID
+	}
+	if params.Name == "" {
+		params.Name = fmt.Sprintf("%s chat %s", source, chat.ID)
+	}
+	for utf8.RuneCountInString(params.Name) > maxRoomNameLength {
+		_, size := utf8.DecodeLastRuneInString(params.Name)
+		params.Name = params.Name[:len(params.Name)-size]
+	}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.CreatedAt.IsZero() {
+		params.CreatedAt = time.Now().UTC()
+		if len(chat.Messages) > 0 {
+			params.CreatedAt = chat.Messages[0].SentAt.UTC()
+		}
+	}
+	return params
+}
+
+// importFile сохраняет файл из выгрузки как вложение отправителя. Файлы, которых нет в выгрузке
+// или которые не проходят проверки загрузки (например, по размеру), пропускаются: возвращается 0
+func (s *importService) importFile(ctx context.Context, archive *chatimport.Archive, file chatimport.File, uploaderID int64) (int64, error) {
+	if file.Path == "" {
+		return 0, nil
+	}
+
+	content, err := archive.FS.Open(file.Path)
+	if err != nil {
+		s.log.Warnf("failed to open imported file %s: %v", file.Path, err)
+		return 0, nil
+	}
+	defer content.Close()
+
+	attachment, err := s.attachments.Upload(ctx, UploadParams{
+		UploaderID: uploaderID,
+		FileName:   file.Name,
+		Content:    content,
+	})
+	if err != nil {
+		if errors.Is(err, ErrValidation) {
+			s.log.Warnf("skipped imported file %s: %v", file.Path, err)
+			return 0, nil
+		}
+		return 0, fmt.Errorf("s.attachments.Upload: %w", err)
+	}
+	return attachment.ID, nil
+}
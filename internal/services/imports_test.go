@@ -0,0 +1,190 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+// MockImportRepo реализует интерфейс repo.ImportRepo для тестов
+type MockImportRepo struct {
+	mock.Mock
+}
+
+func (m *MockImportRepo) ImportRoom(ctx context.Context, params repo.ImportRoomParams) (int64, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockImportRepo) GetImportedMessageIDs(ctx context.Context, source, externalChatID string) (map[string]int64, error) {
+	args := m.Called(ctx, source, externalChatID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MockImportRepo) ImportMessage(ctx context.Context, params repo.ImportMessageParams) (int64, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+const telegramGroupExport = `{
+  "name": "Team",
+  "type": "private_group",
+  "id": 1001,
+  "messages": [
+    {"id": 1, "type": "message", "date_unixtime": "1673776800", "from_id": "user1", "text": "first"},
+    {"id": 2, "type": "message", "date_unixtime": "1673776860", "from_id": "user2", "text": " reply ", "reply_to_message_id": 1,
+     "file": "files/notes.txt", "file_name": "notes.txt", "mime_type": "text/plain"},
+    {"id": 3, "type": "message", "date_unixtime": "1673776920", "from_id": "user2", "text": "", "photo": "(File not included.)"},
+    {"id": 4, "type": "message", "date_unixtime": "1673776980", "from_id": "user3", "text": "who am I"}
+  ]
+}`
+
+func newImportService(t *testing.T, imports *MockImportRepo, attachments *MockAttachmentRepo) services.ImportService {
+	attachmentService, _ := newAttachmentService(t, attachments, new(MockMessageRepo))
+	return services.NewImportService(services.ImportServiceConfig{
+		Repo:        imports,
+		Attachments: attachmentService,
+		Log:         discardLogger(),
+	})
+}
+
+func TestImportService_ImportTelegramRoom(t *testing.T) {
+	imports := new(MockImportRepo)
+	imports.On("ImportRoom", mock.Anything, repo.ImportRoomParams{
+		Source:     "telegram",
+		ExternalID: "1001",
+		Name:       "Team",
+		CreatorID:  10,
+		CreatedAt:  time.Unix(1673776800, 0).UTC(),
+		MemberIDs:  []int64{10, 20},
+	}).Return(int64(7), nil)
+	imports.On("GetImportedMessageIDs", mock.Anything, "telegram", "1001").
+		Return(map[string]int64{"1": 100}, nil)
+	imports.On("ImportMessage", mock.Anything, repo.ImportMessageParams{
+		Source:            "telegram",
+		ExternalChatID:    "1001",
+		ExternalID:        "2",
+		SenderID:          20,
+		RoomID:            7,
+		Content:           "reply",
+		SentAt:            time.Unix(1673776860, 0).UTC(),
+		ReplyToExternalID: "1",
+		AttachmentIDs:     []int64{3},
+	}).Return(int64(101), nil).Once()
+
+	attachments := new(MockAttachmentRepo)
+	attachments.On("CreateAttachment", mock.Anything, mock.MatchedBy(func(params repo.CreateAttachmentParams) bool {
+		return params.UploaderID == 20 && params.FileName == "notes.txt"
+	})).Return(&models.Attachment{ID: 3}, nil).Once()
+
+	service := newImportService(t, imports, attachments)
+	report, err := service.Import(context.Background(), services.ImportParams{
+		Source: "telegram",
+		Archive: fstest.MapFS{
+			"result.json":     {Data: []byte(telegramGroupExport)},
+			"files/notes.txt": {Data: []byte("hello world")},
+		},
+		Users: map[string]int64{"user1": 10, "user2": 20},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, &models.ImportReport{
+		Source: "telegram",
+		Chats: []models.ImportedChat{{
+			ExternalID:      "1001",
+			Name:            "Team",
+			RoomID:          7,
+			Imported:        1,
+			AlreadyImported: 1,
+			Skipped:         2,
+			MissingFiles:    1,
+		}},
+		UnmappedUsers: []string{"user3"},
+	}, report)
+	imports.AssertExpectations(t)
+	attachments.AssertExpectations(t)
+}
+
+func TestImportService_ImportDirectChat(t *testing.T) {
+	export := `{"name": "Bob", "type": "personal_chat", "id": 2, "messages": [
+		{"id": 1, "type": "message", "date_unixtime": "1673776800", "from_id": "user1", "text": "hi"},
+		{"id": 2, "type": "message", "date_unixtime": "1673776860", "from_id": "user2", "text": "hello"}
+	]}`
+	archive := fstest.MapFS{"result.json": {Data: []byte(export)}}
+
+	t.Run("imports between the two participants", func(t *testing.T) {
+		imports := new(MockImportRepo)
+		imports.On("GetImportedMessageIDs", mock.Anything, "telegram", "2").Return(map[string]int64{}, nil)
+		imports.On("ImportMessage", mock.Anything, mock.MatchedBy(func(params repo.ImportMessageParams) bool {
+			return params.ExternalID == "1" && params.SenderID == 10 && params.ReceiverID == 20 && params.RoomID == 0
+		})).Return(int64(1), nil).Once()
+		// Сообщение успел перенести параллельный запуск
+		imports.On("ImportMessage", mock.Anything, mock.MatchedBy(func(params repo.ImportMessageParams) bool {
+			return params.ExternalID == "2" && params.SenderID == 20 && params.ReceiverID == 10
+		})).Return(int64(0), repo.ErrDuplicate).Once()
+
+		report, err := newImportService(t, imports, new(MockAttachmentRepo)).Import(context.Background(), services.ImportParams{
+			Source:  "telegram",
+			Archive: archive,
+			Users:   map[string]int64{"user1": 10, "user2": 20},
+		})
+		require.NoError(t, err)
+		require.Len(t, report.Chats, 1)
+		assert.Equal(t, []int64{10, 20}, report.Chats[0].UserIDs)
+		assert.Equal(t, 1, report.Chats[0].Imported)
+		assert.Equal(t, 1, report.Chats[0].AlreadyImported)
+		imports.AssertExpectations(t)
+	})
+
+	t.Run("skipped without the second participant", func(t *testing.T) {
+		imports := new(MockImportRepo)
+
+		report, err := newImportService(t, imports, new(MockAttachmentRepo)).Import(context.Background(), services.ImportParams{
+			Source:  "telegram",
+			Archive: archive,
+			Users:   map[string]int64{"user1": 10},
+		})
+		require.NoError(t, err)
+		require.Len(t, report.Chats, 1)
+		assert.Equal(t, 2, report.Chats[0].Skipped)
+		assert.NotEmpty(t, report.Chats[0].Error)
+		assert.Equal(t, []string{"user2"}, report.UnmappedUsers)
+		imports.AssertNotCalled(t, "ImportMessage", mock.Anything, mock.Anything)
+	})
+}
+
+func TestImportService_ImportValidation(t *testing.T) {
+	service := newImportService(t, new(MockImportRepo), new(MockAttachmentRepo))
+
+	_, err := service.Import(context.Background(), services.ImportParams{
+		Source:  "telegram",
+		Archive: fstest.MapFS{"result.json": {Data: []byte(`{}`)}},
+	})
+	assert.ErrorIs(t, err, services.ErrValidation, "empty mapping")
+
+	_, err = service.Import(context.Background(), services.ImportParams{
+		Source:  "slack",
+		Archive: fstest.MapFS{"result.json": {Data: []byte(`{}`)}},
+		Users:   map[string]int64{"U1": 1},
+	})
+	assert.ErrorIs(t, err, services.ErrValidation, "not a Slack export")
+
+	_, err = service.Import(context.Background(), services.ImportParams{
+		Source:  "whatsapp",
+		Archive: fstest.MapFS{},
+		Users:   map[string]int64{"U1": 1},
+	})
+	assert.ErrorIs(t, err, services.ErrValidation, "unknown source")
+}
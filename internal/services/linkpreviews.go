@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/pkg/linkpreview"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLinkPreviewWorkers   = 4
+	defaultLinkPreviewQueueSize = 1024
+	defaultLinkPreviewCacheTTL  = 24 * time.Hour
+	maxLinkPreviewURLLength     = 2048
+)
+
+// plainURLRe находит ссылки в простом тексте; завершающая пунктуация отрезается отдельно
+var plainURLRe = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// LinkPreviewer в фоне получает превью первой ссылки сообщения, кэширует его по URL
+// и рассылает участникам переписки событие message.link_preview
+type LinkPreviewer struct {
+	repo     repo.LinkPreviewRepo
+	messages repo.MessageRepo
+	fetcher  linkpreview.Fetcher
+	events   events.Publisher
+	log      *logrus.Logger
+
+	workers  int
+	cacheTTL time.Duration
+	jobs     chan linkPreviewJob
+}
+
+type LinkPreviewerConfig struct {
+	Repo     repo.LinkPreviewRepo
+	Messages repo.MessageRepo
+	Fetcher  linkpreview.Fetcher
+	Events   events.Publisher
+	Log      *logrus.Logger
+
+	// Workers число одновременных загрузок страниц
+	Workers int
+	// QueueSize размер очереди; при переполнении новые ссылки остаются без превью
+	QueueSize int
+	// CacheTTL время, в течение которого превью (или неудача загрузки) берётся из кэша
+	CacheTTL time.Duration
+}
+
+func NewLinkPreviewer(cfg LinkPreviewerConfig) *LinkPreviewer {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultLinkPreviewWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultLinkPreviewQueueSize
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = defaultLinkPreviewCacheTTL
+	}
+	return &LinkPreviewer{
+		repo:     cfg.Repo,
+		messages: cfg.Messages,
+		fetcher:  cfg.Fetcher,
+		events:   cfg.Events,
+		log:      cfg.Log,
+		workers:  cfg.Workers,
+		cacheTTL: cfg.CacheTTL,
+		jobs:     make(chan linkPreviewJob, cfg.QueueSize),
+	}
+}
+
+type linkPreviewJob struct {
+	messageID int64
+	editedAt  *time.Time
+	url       string
+}
+
+// Run обрабатывает очередь до отмены контекста
+func (p *LinkPreviewer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-p.jobs:
+					if err := p.process(ctx, job); err != nil && ctx.Err() == nil {
+						p.log.Errorf("link preview for message %d: %v", job.messageID, err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// enqueue ставит сообщение в очередь, если в нём есть ссылка; отправку сообщения не задерживает
+func (p *LinkPreviewer) enqueue(msg models.Message) {
+	link := firstLink(msg)
+	if link == "" {
+		return
+	}
+
+	select {
+	case p.jobs <- linkPreviewJob{messageID: msg.ID, editedAt: msg.EditedAt, url: link}:
+	default:
+		p.log.Warnf("link preview queue is full, message %d is skipped", msg.ID)
+	}
+}
+
+func (p *LinkPreviewer) process(ctx context.Context, job linkPreviewJob) error {
+	preview, err := p.preview(ctx, job.url)
+	if err != nil || preview == nil {
+		return err
+	}
+
+	msg, err := p.repo.SetMessageLinkPreview(ctx, repo.SetMessageLinkPreviewParams{
+		MessageID: job.messageID,
+		EditedAt:  job.editedAt,
+		Preview:   preview,
+	})
+	if errors.Is(err, repo.ErrNotFound) {
+		// Сообщение успели удалить или отредактировать: новая версия поставлена в очередь отдельно
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	members, err := conversationMembers(ctx, p.messages, *msg)
+	if err != nil {
+		return err
+	}
+	if p.events != nil {
+		p.events.Publish(members, events.Event{
+			Type:    events.MessageLinkPreview,
+			Payload: models.MessageLinkPreview{MessageID: msg.ID, LinkPreview: msg.LinkPreview},
+		})
+	}
+	return nil
+}
+
+// preview берёт превью из кэша или загружает страницу; nil означает, что превью для ссылки нет
+func (p *LinkPreviewer) preview(ctx context.Context, link string) (*models.LinkPreview, error) {
+	cached, err := p.repo.GetCachedLinkPreview(ctx, link, time.Now().Add(-p.cacheTTL))
+	if err == nil {
+		return cached.Preview, nil
+	}
+	if !errors.Is(err, repo.ErrNotFound) {
+		return nil, err
+	}
+
+	var preview *models.LinkPreview
+	fetched, err := p.fetcher.Fetch(ctx, link)
+	switch {
+	case err == nil:
+		preview = &models.LinkPreview{
+			URL:         link,
+			Title:       fetched.Title,
+			Description: fetched.Description,
+			ImageURL:    fetched.ImageURL,
+			SiteName:    fetched.SiteName,
+			FetchedAt:   time.Now().UTC(),
+		}
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	default:
+		p.log.Debugf("failed to fetch link preview for %s: %v", link, err)
+	}
+
+	// Неудача тоже кэшируется, чтобы недоступный сайт не запрашивался для каждого сообщения
+	if err = p.repo.SaveCachedLinkPreview(ctx, link, preview); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// firstLink возвращает первую ссылку текстового сообщения: из разметки, а при её отсутствии — из текста
+func firstLink(msg models.Message) string {
+	if msg.RichContent != nil {
+		if msg.RichContent.Kind != models.ContentKindText {
+			return ""
+		}
+
+		var links []models.ContentEntity
+		for _, e := range msg.RichContent.Entities {
+			if e.Type == models.EntityLink {
+				links = append(links, e)
+			}
+		}
+		if len(links) > 0 {
+			link := slices.MinFunc(links, func(a, b models.ContentEntity) int { return a.Offset - b.Offset })
+			return normalizeLink(link.URL)
+		}
+	}
+
+	for _, match := range plainURLRe.FindAllString(msg.Content, -1) {
+		if link := normalizeLink(strings.TrimRight(match, ".,;:!?)]}")); link != "" {
+			return link
+		}
+	}
+	return ""
+}
+
+func normalizeLink(link string) string {
+	if len(link) > maxLinkPreviewURLLength {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	u.Fragment = ""
+	return u.String()
+}
@@ -0,0 +1,261 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+	"messanger/pkg/linkpreview"
+)
+
+// MockLinkPreviewRepo реализует интерфейс repo.LinkPreviewRepo для тестов
+type MockLinkPreviewRepo struct {
+	mock.Mock
+}
+
+func (m *MockLinkPreviewRepo) GetCachedLinkPreview(ctx context.Context, url string, notBefore time.Time) (*repo.CachedLinkPreview, error) {
+	args := m.Called(ctx, url, notBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repo.CachedLinkPreview), args.Error(1)
+}
+
+func (m *MockLinkPreviewRepo) SaveCachedLinkPreview(ctx context.Context, url string, preview *models.LinkPreview) error {
+	args := m.Called(ctx, url, preview)
+	return args.Error(0)
+}
+
+func (m *MockLinkPreviewRepo) SetMessageLinkPreview(ctx context.Context, params repo.SetMessageLinkPreviewParams) (*models.Message, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+type fakeFetcher struct {
+	mu      sync.Mutex
+	fetched []string
+	preview *linkpreview.Preview
+	err     error
+}
+
+func (f *fakeFetcher) Fetch(_ context.Context, url string) (*linkpreview.Preview, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fetched = append(f.fetched, url)
+	return f.preview, f.err
+}
+
+func (f *fakeFetcher) calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.fetched...)
+}
+
+func discardLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+func TestLinkPreviewer(t *testing.T) {
+	const link = "https://example.com/article"
+
+	tests := []struct {
+		name          string
+		params        services.SaveMessageParams
+		cached        *repo.CachedLinkPreview
+		fetchErr      error
+		expectedFetch []string
+		expectPreview bool
+	}{
+		{
+			name:          "link in plain text is fetched and cached",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "look: " + link + "#intro."},
+			expectedFetch: []string{link},
+			expectPreview: true,
+		},
+		{
+			name: "link entity wins over text",
+			params: services.SaveMessageParams{SenderID: 1, ReceiverID: 2, RichContent: &models.RichContent{
+				Kind: models.ContentKindText,
+				Text: "article and https://other.example",
+				Entities: []models.ContentEntity{
+					{Type: models.EntityLink, Offset: 0, Length: 7, URL: link},
+				},
+			}},
+			expectedFetch: []string{link},
+			expectPreview: true,
+		},
+		{
+			name:          "cached preview is used without fetching",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: link},
+			cached:        &repo.CachedLinkPreview{Preview: &models.LinkPreview{URL: link, Title: "Cached"}},
+			expectPreview: true,
+		},
+		{
+			name:          "cached failure is not retried",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: link},
+			cached:        &repo.CachedLinkPreview{},
+			expectPreview: false,
+		},
+		{
+			name:          "failed fetch is cached as missing preview",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: link},
+			fetchErr:      linkpreview.ErrBlockedAddress,
+			expectedFetch: []string{link},
+			expectPreview: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &models.Message{ID: 10, SenderID: 1, ReceiverID: 2, Content: tt.params.Content, RichContent: tt.params.RichContent}
+			mockRepo := new(MockMessageRepo)
+			mockRepo.On("SaveMessage", mock.Anything, mock.Anything).Return(msg, nil)
+
+			previewRepo := new(MockLinkPreviewRepo)
+			if tt.cached != nil {
+				previewRepo.On("GetCachedLinkPreview", mock.Anything, link, mock.Anything).Return(tt.cached, nil)
+			} else {
+				previewRepo.On("GetCachedLinkPreview", mock.Anything, link, mock.Anything).Return(nil, repo.ErrNotFound)
+			}
+			saved := make(chan *models.LinkPreview, 1)
+			previewRepo.On("SaveCachedLinkPreview", mock.Anything, link, mock.Anything).
+				Run(func(args mock.Arguments) { saved <- args.Get(2).(*models.LinkPreview) }).
+				Return(nil)
+			previewRepo.On("SetMessageLinkPreview", mock.Anything, mock.Anything).
+				Return(&models.Message{ID: 10, SenderID: 1, ReceiverID: 2, LinkPreview: &models.LinkPreview{URL: link}}, nil)
+
+			fetcher := &fakeFetcher{
+				preview: &linkpreview.Preview{URL: link, Title: "Article", ImageURL: "https://example.com/cover.png"},
+				err:     tt.fetchErr,
+			}
+
+			published := make(chan events.Event, 1)
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				if event.Type == events.MessageLinkPreview {
+					assert.ElementsMatch(t, []int64{1, 2}, userIDs)
+					published <- event
+				}
+			})
+
+			previewer := services.NewLinkPreviewer(services.LinkPreviewerConfig{
+				Repo:     previewRepo,
+				Messages: mockRepo,
+				Fetcher:  fetcher,
+				Events:   bus,
+				Log:      discardLogger(),
+				Workers:  1,
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go previewer.Run(ctx)
+
+			service := services.NewMessageService(services.MessageServiceConfig{
+				Repo:         mockRepo,
+				Events:       bus,
+				LinkPreviews: previewer,
+			})
+			_, err := service.SaveMessage(context.Background(), tt.params)
+			require.NoError(t, err)
+
+			if tt.cached == nil {
+				select {
+				case preview := <-saved:
+					assert.Equal(t, tt.fetchErr == nil, preview != nil)
+					if preview != nil {
+						assert.Equal(t, link, preview.URL)
+						assert.Equal(t, "Article", preview.Title)
+						assert.Equal(t, "https://example.com/cover.png", preview.ImageURL)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("preview was not cached")
+				}
+			}
+
+			if tt.expectPreview {
+				select {
+				case event := <-published:
+					payload := event.Payload.(models.MessageLinkPreview)
+					assert.Equal(t, int64(10), payload.MessageID)
+					assert.Equal(t, link, payload.LinkPreview.URL)
+				case <-time.After(time.Second):
+					t.Fatal("link preview event was not published")
+				}
+			} else {
+				select {
+				case <-published:
+					t.Fatal("unexpected link preview event")
+				case <-time.After(50 * time.Millisecond):
+				}
+				previewRepo.AssertNotCalled(t, "SetMessageLinkPreview", mock.Anything, mock.Anything)
+			}
+			assert.Equal(t, tt.expectedFetch, fetcher.calls())
+		})
+	}
+}
+
+func TestLinkPreviewer_SkipsStaleMessages(t *testing.T) {
+	const link = "https://example.com"
+
+	previewRepo := new(MockLinkPreviewRepo)
+	previewRepo.On("GetCachedLinkPreview", mock.Anything, link, mock.Anything).
+		Return(&repo.CachedLinkPreview{Preview: &models.LinkPreview{URL: link}}, nil)
+	processed := make(chan struct{})
+	previewRepo.On("SetMessageLinkPreview", mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { close(processed) }).
+		Return(nil, repo.ErrNotFound)
+
+	editedAt := time.Now()
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetMessageByID", mock.Anything, int64(10)).
+		Return(&models.Message{ID: 10, SenderID: 1, ReceiverID: 2, CreatedAt: time.Now()}, nil)
+	mockRepo.On("EditMessage", mock.Anything, mock.Anything).
+		Return(&models.Message{ID: 10, SenderID: 1, ReceiverID: 2, Content: link, EditedAt: &editedAt}, nil)
+
+	bus := events.NewBus()
+	bus.Subscribe(func(_ []int64, event events.Event) {
+		assert.NotEqual(t, events.MessageLinkPreview, event.Type)
+	})
+
+	previewer := services.NewLinkPreviewer(services.LinkPreviewerConfig{
+		Repo:     previewRepo,
+		Messages: mockRepo,
+		Fetcher:  &fakeFetcher{err: errors.New("must not be called")},
+		Events:   bus,
+		Log:      discardLogger(),
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go previewer.Run(ctx)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus, LinkPreviews: previewer})
+	_, err := service.EditMessage(context.Background(), services.EditMessageParams{MessageID: 10, EditorID: 1, Content: link})
+	require.NoError(t, err)
+
+	select {
+	case <-processed:
+	case <-time.After(time.Second):
+		t.Fatal("edited message was not queued for a preview")
+	}
+	params := previewRepo.Calls[1].Arguments.Get(1).(repo.SetMessageLinkPreviewParams)
+	assert.Equal(t, &editedAt, params.EditedAt)
+}
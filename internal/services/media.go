@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"messanger/internal/models"
+	"messanger/pkg/imaging"
+)
+
+// Стороны квадратов, в которые вписываются миниатюры, по убыванию
+var thumbnailSizes = []int{1280, 480, 160}
+
+const (
+	// maxImagePixels защищает от «бомб» — маленьких файлов с огромным разрешением
+	maxImagePixels = 50_000_000
+	blurhashSide   = 32
+)
+
+// processedImage результат обработки загруженного изображения
+type processedImage struct {
+	// Data содержимое оригинала без сведений о местоположении
+	Data       []byte
+	Width      int
+	Height     int
+	Blurhash   string
+	Thumbnails []encodedThumbnail
+}
+
+type encodedThumbnail struct {
+	models.Thumbnail
+	Data []byte
+}
+
+func isImage(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// processImage очищает метаданные, определяет размеры с учётом EXIF-ориентации,
+// строит миниатюры и blurhash. Если изображение не удаётся декодировать, возвращается
+// только очищенное содержимое — такой файл сохраняется как обычное вложение
+func processImage(data []byte, mimeType string) (*processedImage, error) {
+	result := &processedImage{Data: data}
+
+	orientation := 1
+	switch mimeType {
+	case "image/jpeg":
+		clean, o, err := imaging.SanitizeJPEG(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed JPEG: %v", ErrValidation, err)
+		}
+		result.Data, orientation = clean, o
+	case "image/png":
+		clean, o, err := imaging.SanitizePNG(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed PNG: %v", ErrValidation, err)
+		}
+		result.Data, orientation = clean, o
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(result.Data))
+	if err != nil || cfg.Width*cfg.Height > maxImagePixels {
+		return result, nil
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(result.Data))
+	if err != nil {
+		return result, nil
+	}
+
+	img := imaging.Orient(imaging.ToRGBA(decoded), orientation)
+	result.Width, result.Height = img.Rect.Dx(), img.Rect.Dy()
+
+	// Каждая следующая миниатюра строится из предыдущей, что заметно быстрее, чем из оригинала
+	source := img
+	for _, size := range thumbnailSizes {
+		if result.Width <= size && result.Height <= size {
+			continue
+		}
+		source = imaging.Fit(source, size)
+
+		thumbnail, err := encodeThumbnail(source, mimeType)
+		if err != nil {
+			return nil, err
+		}
+		thumbnail.Size = size
+		result.Thumbnails = append(result.Thumbnails, *thumbnail)
+	}
+
+	result.Blurhash = imaging.Blurhash(imaging.Fit(source, blurhashSide), 4, 3)
+
+	return result, nil
+}
+
+// encodeThumbnail кодирует фотографии в JPEG, а PNG и GIF — в PNG, чтобы сохранить прозрачность
+func encodeThumbnail(img *image.RGBA, mimeType string) (*encodedThumbnail, error) {
+	var buf bytes.Buffer
+	thumbnail := &encodedThumbnail{
+		Thumbnail: models.Thumbnail{Width: img.Rect.Dx(), Height: img.Rect.Dy()},
+	}
+
+	if mimeType == "image/jpeg" {
+		thumbnail.MimeType = "image/jpeg"
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}); err != nil {
+			return nil, fmt.Errorf("jpeg.Encode: %w", err)
+		}
+	} else {
+		thumbnail.MimeType = "image/png"
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("png.Encode: %w", err)
+		}
+	}
+
+	thumbnail.Data = buf.Bytes()
+	return thumbnail, nil
+}
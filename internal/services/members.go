@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+)
+
+type RoomMemberParams struct {
+	RoomID int64
+	// UserID кто меняет состав комнаты
+	UserID int64
+	// MemberID кого добавляют или исключают; совпадает с UserID, когда пользователь выходит сам
+	MemberID int64
+}
+
+// AddRoomMember добавляет пользователя в комнату; это может только администратор комнаты.
+// Участники, включая нового, получают событие member.joined; повторное добавление ничего не меняет
+func (s *messageService) AddRoomMember(ctx context.Context, params RoomMemberParams) error {
+	if params.MemberID <= 0 {
+		return fmt.Errorf("%w: user_id is required", ErrValidation)
+	}
+	if err := s.requireRoomAdmin(ctx, params.RoomID, params.UserID); err != nil {
+		return err
+	}
+
+	_, err := s.repo.AddRoomMember(ctx, params.RoomID, params.MemberID)
+	if errors.Is(err, repo.ErrDuplicate) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("s.repo.AddRoomMember: %w", err)
+	}
+
+	members, err := s.repo.GetRoomMemberIDs(ctx, params.RoomID)
+	if err != nil {
+		return fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+	}
+	s.publish(members, events.Event{Type: events.MemberJoined, Payload: models.MembershipChange{
+		RoomID:    params.RoomID,
+		UserID:    params.MemberID,
+		ChangedBy: params.UserID,
+	}})
+
+	return nil
+}
+
+// RemoveRoomMember исключает участника из комнаты. Выйти может любой участник, исключить
+// другого — только администратор. Событие member.left получают оставшиеся участники и сам исключённый
+func (s *messageService) RemoveRoomMember(ctx context.Context, params RoomMemberParams) error {
+	if params.MemberID != params.UserID {
+		if err := s.requireRoomAdmin(ctx, params.RoomID, params.UserID); err != nil {
+			return err
+		}
+	}
+
+	removed, err := s.repo.RemoveRoomMember(ctx, params.RoomID, params.MemberID)
+	if err != nil {
+		return fmt.Errorf("s.repo.RemoveRoomMember: %w", err)
+	}
+	if !removed {
+		return fmt.Errorf("%w: user %d is not a member of room %d", ErrNotFound, params.MemberID, params.RoomID)
+	}
+
+	members, err := s.repo.GetRoomMemberIDs(ctx, params.RoomID)
+	if err != nil {
+		return fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+	}
+	s.publish(append(members, params.MemberID), events.Event{Type: events.MemberLeft, Payload: models.MembershipChange{
+		RoomID:    params.RoomID,
+		UserID:    params.MemberID,
+		ChangedBy: params.UserID,
+	}})
+
+	return nil
+}
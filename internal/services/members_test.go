@@ -0,0 +1,238 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+func TestMessageService_AddRoomMember(t *testing.T) {
+	tests := []struct {
+		name          string
+		params        services.RoomMemberParams
+		mockBehavior  func(r *MockMessageRepo)
+		expectedError error
+		expectedTo    []int64
+	}{
+		{
+			name:   "room admin",
+			params: services.RoomMemberParams{RoomID: 5, UserID: 1, MemberID: 4},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(1)).Return(&models.RoomMember{RoomID: 5, UserID: 1, IsAdmin: true}, nil)
+				r.On("AddRoomMember", mock.Anything, int64(5), int64(4)).Return(&models.RoomMember{RoomID: 5, UserID: 4}, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 4}, nil)
+			},
+			expectedTo: []int64{1, 2, 4},
+		},
+		{
+			name:   "already a member",
+			params: services.RoomMemberParams{RoomID: 5, UserID: 1, MemberID: 2},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(1)).Return(&models.RoomMember{RoomID: 5, UserID: 1, IsAdmin: true}, nil)
+				r.On("AddRoomMember", mock.Anything, int64(5), int64(2)).Return(nil, repo.ErrDuplicate)
+			},
+		},
+		{
+			name:   "member without admin rights",
+			params: services.RoomMemberParams{RoomID: 5, UserID: 2, MemberID: 4},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(2)).Return(&models.RoomMember{RoomID: 5, UserID: 2}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "outsider",
+			params: services.RoomMemberParams{RoomID: 5, UserID: 9, MemberID: 9},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(9)).Return(nil, repo.ErrNotFound)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:          "no user",
+			params:        services.RoomMemberParams{RoomID: 5, UserID: 1},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.mockBehavior(mockRepo)
+
+			var published []deliveredEvent
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				published = append(published, deliveredEvent{userIDs: userIDs, event: event})
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+			err := service.AddRoomMember(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tt.expectedTo != nil {
+				require.Len(t, published, 1)
+				assert.Equal(t, tt.expectedTo, published[0].userIDs)
+				assert.Equal(t, events.MemberJoined, published[0].event.Type)
+				assert.Equal(t, models.MembershipChange{RoomID: 5, UserID: tt.params.MemberID, ChangedBy: tt.params.UserID}, published[0].event.Payload)
+			} else {
+				assert.Empty(t, published)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_RemoveRoomMember(t *testing.T) {
+	tests := []struct {
+		name          string
+		params        services.RoomMemberParams
+		mockBehavior  func(r *MockMessageRepo)
+		expectedError error
+		expectedTo    []int64
+	}{
+		{
+			name:   "leave",
+			params: services.RoomMemberParams{RoomID: 5, UserID: 2, MemberID: 2},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("RemoveRoomMember", mock.Anything, int64(5), int64(2)).Return(true, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 3}, nil)
+			},
+			expectedTo: []int64{1, 3, 2},
+		},
+		{
+			name:   "kick",
+			params: services.RoomMemberParams{RoomID: 5, UserID: 1, MemberID: 3},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(1)).Return(&models.RoomMember{RoomID: 5, UserID: 1, IsAdmin: true}, nil)
+				r.On("RemoveRoomMember", mock.Anything, int64(5), int64(3)).Return(true, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2}, nil)
+			},
+			expectedTo: []int64{1, 2, 3},
+		},
+		{
+			name:   "kick without admin rights",
+			params: services.RoomMemberParams{RoomID: 5, UserID: 2, MemberID: 3},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(2)).Return(&models.RoomMember{RoomID: 5, UserID: 2}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "not a member",
+			params: services.RoomMemberParams{RoomID: 5, UserID: 9, MemberID: 9},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("RemoveRoomMember", mock.Anything, int64(5), int64(9)).Return(false, nil)
+			},
+			expectedError: services.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.mockBehavior(mockRepo)
+
+			var published []deliveredEvent
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				published = append(published, deliveredEvent{userIDs: userIDs, event: event})
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+			err := service.RemoveRoomMember(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tt.expectedTo != nil {
+				require.Len(t, published, 1)
+				assert.Equal(t, tt.expectedTo, published[0].userIDs)
+				assert.Equal(t, events.MemberLeft, published[0].event.Type)
+			} else {
+				assert.Empty(t, published)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// memoryEventRepo журнал событий в памяти: нумерует события каждого пользователя, как user_event_seqs
+type memoryEventRepo struct {
+	events map[int64][]models.SyncEvent
+}
+
+func (r *memoryEventRepo) AppendEvent(_ context.Context, userIDs []int64, eventType string, payload []byte) (map[int64]int64, error) {
+	seqs := make(map[int64]int64, len(userIDs))
+	for _, userID := range userIDs {
+		seq := int64(len(r.events[userID]) + 1)
+		r.events[userID] = append(r.events[userID], models.SyncEvent{Seq: seq, Type: eventType, Payload: payload})
+		seqs[userID] = seq
+	}
+	return seqs, nil
+}
+
+func (r *memoryEventRepo) GetEventLogState(_ context.Context, userID int64) (*repo.EventLogState, error) {
+	return &repo.EventLogState{LastSeq: int64(len(r.events[userID]))}, nil
+}
+
+func (r *memoryEventRepo) GetEvents(_ context.Context, params repo.GetEventsParams) ([]models.SyncEvent, error) {
+	logged := r.events[params.UserID][params.Since:]
+	return logged[:min(len(logged), params.Limit)], nil
+}
+
+func (r *memoryEventRepo) CompactEvents(context.Context, time.Time, int) (int, error) {
+	return 0, nil
+}
+
+func TestRoomMembership_Sync(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetRoomMember", mock.Anything, int64(5), int64(1)).Return(&models.RoomMember{RoomID: 5, UserID: 1, IsAdmin: true}, nil)
+	mockRepo.On("AddRoomMember", mock.Anything, int64(5), int64(3)).Return(&models.RoomMember{RoomID: 5, UserID: 3}, nil)
+	mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil).Once()
+	mockRepo.On("RemoveRoomMember", mock.Anything, int64(5), int64(3)).Return(true, nil)
+	mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2}, nil).Once()
+
+	eventRepo := &memoryEventRepo{events: make(map[int64][]models.SyncEvent)}
+	service := services.NewMessageService(services.MessageServiceConfig{
+		Repo:   mockRepo,
+		Events: services.NewEventLog(services.EventLogConfig{Repo: eventRepo, Next: events.NewBus(), Log: discardLogger()}),
+	})
+	sync := services.NewSyncService(eventRepo)
+	ctx := context.Background()
+
+	require.NoError(t, service.AddRoomMember(ctx, services.RoomMemberParams{RoomID: 5, UserID: 1, MemberID: 3}))
+	require.NoError(t, service.RemoveRoomMember(ctx, services.RoomMemberParams{RoomID: 5, UserID: 1, MemberID: 3}))
+
+	for _, userID := range []int64{2, 3} {
+		batch, err := sync.GetEvents(ctx, services.SyncParams{UserID: userID})
+		require.NoError(t, err)
+		require.Len(t, batch.Events, 2)
+
+		assert.Equal(t, events.MemberJoined, batch.Events[0].Type)
+		assert.Equal(t, events.MemberLeft, batch.Events[1].Type)
+		var change models.MembershipChange
+		require.NoError(t, json.Unmarshal(batch.Events[1].Payload, &change))
+		assert.Equal(t, models.MembershipChange{RoomID: 5, UserID: 3, ChangedBy: 1}, change)
+	}
+	mockRepo.AssertExpectations(t)
+}
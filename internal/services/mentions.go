@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"regexp"
+	"slices"
+)
+
+const (
+	defaultMentionsLimit = 50
+	maxMentionsLimit     = 200
+)
+
+// broadcastMentionRe находит @room и @here в простом тексте, не задевая адреса почты и слова вроде @roomy
+var broadcastMentionRe = regexp.MustCompile(`(?:^|[^\pL\pN_@.])@(room|here)(?:$|[^\pL\pN_])`)
+
+// mentionEntities возвращает упоминания из разметки; в простом тексте распознаются только @room и @here,
+// так как пользователей без разметки однозначно указать нельзя
+func mentionEntities(params SaveMessageParams) []models.ContentEntity {
+	if params.RichContent != nil {
+		return params.RichContent.Entities
+	}
+
+	var entities []models.ContentEntity
+	for _, match := range broadcastMentionRe.FindAllStringSubmatch(params.Content, -1) {
+		entityType := models.EntityRoomMention
+		if match[1] == "here" {
+			entityType = models.EntityHereMention
+		}
+		entities = append(entities, models.ContentEntity{Type: entityType})
+	}
+	return entities
+}
+
+// resolveMentions определяет, кого упоминает сообщение. Упомянуть можно только участников переписки,
+// а @room и @here в больших комнатах доступны лишь администраторам
+func (s *messageService) resolveMentions(ctx context.Context, params SaveMessageParams, recipients []int64) ([]repo.MentionParams, error) {
+	kinds := make(map[int64]string)
+	var room, here bool
+	for _, e := range mentionEntities(params) {
+		switch e.Type {
+		case models.EntityMention:
+			if slices.Contains(recipients, e.UserID) {
+				kinds[e.UserID] = models.MentionUser
+			}
+		case models.EntityRoomMention:
+			room = true
+		case models.EntityHereMention:
+			here = true
+		}
+	}
+
+	if room || here {
+		if err := s.checkBroadcastMention(ctx, params, len(recipients)+1); err != nil {
+			return nil, err
+		}
+
+		kind, targets := models.MentionRoom, recipients
+		if !room {
+			kind, targets = models.MentionHere, nil
+			if s.presence != nil {
+				targets = s.presence.Online(recipients)
+			}
+		}
+		for _, id := range targets {
+			// Прямое упоминание важнее массового
+			if _, ok := kinds[id]; !ok {
+				kinds[id] = kind
+			}
+		}
+	}
+
+	var mentions []repo.MentionParams
+	for _, id := range slices.Sorted(maps.Keys(kinds)) {
+		mentions = append(mentions, repo.MentionParams{UserID: id, Kind: kinds[id]})
+	}
+	return mentions, nil
+}
+
+func (s *messageService) checkBroadcastMention(ctx context.Context, params SaveMessageParams, roomSize int) error {
+	if params.RoomID == 0 || s.largeRoomSize <= 0 || roomSize < s.largeRoomSize {
+		return nil
+	}
+
+	member, err := s.repo.GetRoomMember(ctx, params.RoomID, params.SenderID)
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		return fmt.Errorf("s.repo.GetRoomMember: %w", err)
+	}
+	if member == nil || !member.IsAdmin {
+		return fmt.Errorf("%w: only admins can mention everyone in rooms of %d or more members", ErrForbidden, s.largeRoomSize)
+	}
+	return nil
+}
+
+// publishMentions уведомляет упомянутых пользователей отдельным событием
+func (s *messageService) publishMentions(msg *models.Message, mentions []repo.MentionParams) {
+	byKind := make(map[string][]int64)
+	for _, m := range mentions {
+		byKind[m.Kind] = append(byKind[m.Kind], m.UserID)
+	}
+
+	for _, kind := range slices.Sorted(maps.Keys(byKind)) {
+		s.publish(byKind[kind], events.Event{Type: events.Mention, Payload: models.Mention{
+			MessageID: msg.ID,
+			RoomID:    msg.RoomID,
+			SenderID:  msg.SenderID,
+			Kind:      kind,
+			CreatedAt: msg.CreatedAt,
+			Message:   msg,
+		}})
+	}
+}
+
+type GetMentionsParams struct {
+	UserID     int64
+	UnreadOnly bool
+	BeforeID   int64
+	Limit      int
+}
+
+// GetMentions возвращает входящий список упоминаний пользователя по всем перепискам
+func (s *messageService) GetMentions(ctx context.Context, params GetMentionsParams) (*models.MentionInbox, error) {
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultMentionsLimit
+	}
+	if limit > maxMentionsLimit {
+		limit = maxMentionsLimit
+	}
+
+	mentions, err := s.repo.GetMentions(ctx, repo.GetMentionsParams{
+		UserID:     params.UserID,
+		UnreadOnly: params.UnreadOnly,
+		BeforeID:   params.BeforeID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetMentions: %w", err)
+	}
+
+	unread, err := s.repo.CountUnreadMentions(ctx, params.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.CountUnreadMentions: %w", err)
+	}
+
+	inbox := &models.MentionInbox{Mentions: mentions, UnreadCount: unread}
+	if len(mentions) == limit {
+		inbox.NextBeforeID = mentions[len(mentions)-1].MessageID
+	}
+	return inbox, nil
+}
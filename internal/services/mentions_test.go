@@ -0,0 +1,150 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+func TestMessageService_SaveMessage_Mentions(t *testing.T) {
+	tests := []struct {
+		name             string
+		params           services.SaveMessageParams
+		online           []int64
+		expectedMentions []repo.MentionParams
+	}{
+		{
+			name: "user mentions outside of the room are ignored",
+			params: services.SaveMessageParams{
+				SenderID: 1,
+				RoomID:   5,
+				RichContent: &models.RichContent{Kind: models.ContentKindText, Text: "@two @nine", Entities: []models.ContentEntity{
+					{Type: models.EntityMention, Offset: 0, Length: 4, UserID: 2},
+					{Type: models.EntityMention, Offset: 5, Length: 5, UserID: 9},
+					{Type: models.EntityMention, Offset: 0, Length: 4, UserID: 1},
+				}},
+			},
+			expectedMentions: []repo.MentionParams{{UserID: 2, Kind: models.MentionUser}},
+		},
+		{
+			name:   "@here in plain text mentions members who are online",
+			params: services.SaveMessageParams{SenderID: 1, RoomID: 5, Content: "deploy is done, @here!"},
+			online: []int64{1, 3},
+			expectedMentions: []repo.MentionParams{
+				{UserID: 3, Kind: models.MentionHere},
+			},
+		},
+		{
+			name: "direct mention wins over @room",
+			params: services.SaveMessageParams{
+				SenderID: 1,
+				RoomID:   5,
+				RichContent: &models.RichContent{Kind: models.ContentKindText, Text: "@room @two", Entities: []models.ContentEntity{
+					{Type: models.EntityRoomMention, Offset: 0, Length: 5},
+					{Type: models.EntityMention, Offset: 6, Length: 4, UserID: 2},
+				}},
+			},
+			expectedMentions: []repo.MentionParams{
+				{UserID: 2, Kind: models.MentionUser},
+				{UserID: 3, Kind: models.MentionRoom},
+			},
+		},
+		{
+			name:             "e-mail addresses are not mentions",
+			params:           services.SaveMessageParams{SenderID: 1, RoomID: 5, Content: "write to ops@room.example"},
+			expectedMentions: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+			mockRepo.On("SaveMessage", mock.Anything, mock.Anything).
+				Return(&models.Message{ID: 10, SenderID: 1, RoomID: 5}, nil)
+
+			presence := events.NewPresence()
+			for _, id := range tt.online {
+				presence.Connect(id)
+			}
+
+			notified := map[int64][]events.Event{}
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				if event.Type != events.Mention {
+					return
+				}
+				for _, id := range userIDs {
+					notified[id] = append(notified[id], event)
+				}
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{
+				Repo:     mockRepo,
+				Events:   bus,
+				Presence: presence,
+			})
+			_, err := service.SaveMessage(context.Background(), tt.params)
+			require.NoError(t, err)
+
+			params := mockRepo.Calls[1].Arguments.Get(1).(repo.SaveMessageParams)
+			assert.Equal(t, tt.expectedMentions, params.Mentions)
+
+			assert.Len(t, notified, len(tt.expectedMentions))
+			for _, m := range tt.expectedMentions {
+				require.Len(t, notified[m.UserID], 1)
+				assert.Equal(t, m.Kind, notified[m.UserID][0].Payload.(models.Mention).Kind)
+			}
+		})
+	}
+}
+
+func TestMessageService_SaveMessage_RoomMentionInLargeRoom(t *testing.T) {
+	for _, isAdmin := range []bool{false, true} {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+		mockRepo.On("GetRoomMember", mock.Anything, int64(5), int64(1)).
+			Return(&models.RoomMember{RoomID: 5, UserID: 1, IsAdmin: isAdmin}, nil)
+		if isAdmin {
+			mockRepo.On("SaveMessage", mock.Anything, mock.Anything).
+				Return(&models.Message{ID: 10, SenderID: 1, RoomID: 5}, nil)
+		}
+
+		service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, LargeRoomSize: 3})
+		_, err := service.SaveMessage(context.Background(), services.SaveMessageParams{
+			SenderID: 1,
+			RoomID:   5,
+			Content:  "@room please review",
+		})
+
+		if isAdmin {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorIs(t, err, services.ErrForbidden)
+		}
+		mockRepo.AssertExpectations(t)
+	}
+}
+
+func TestMessageService_GetMentions(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetMentions", mock.Anything, repo.GetMentionsParams{UserID: 2, UnreadOnly: true, Limit: 2}).
+		Return([]models.Mention{{MessageID: 9}, {MessageID: 7}}, nil)
+	mockRepo.On("CountUnreadMentions", mock.Anything, int64(2)).Return(5, nil)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+	inbox, err := service.GetMentions(context.Background(), services.GetMentionsParams{UserID: 2, UnreadOnly: true, Limit: 2})
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, inbox.UnreadCount)
+	assert.Equal(t, int64(7), inbox.NextBeforeID)
+	mockRepo.AssertExpectations(t)
+}
@@ -2,52 +2,323 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"golang.org/x/net/websocket"
+	"messanger/internal/events"
 	"messanger/internal/models"
 	"messanger/internal/repo"
+	"messanger/pkg/blobstore"
+	"messanger/pkg/ratelimit"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 type MessageService interface {
-	SaveMessage(ctx context.Context, params SaveMessageParams) error
+	SaveMessage(ctx context.Context, params SaveMessageParams) (*models.Message, error)
 	GetHistory(ctx context.Context, params GetHistoryParams) ([]models.Message, error)
+	EditMessage(ctx context.Context, params EditMessageParams) (*models.Message, error)
+	GetRevisions(ctx context.Context, params GetRevisionsParams) ([]models.MessageRevision, error)
+	DeleteMessage(ctx context.Context, params DeleteMessageParams) error
+	AddReaction(ctx context.Context, params ReactionParams) error
+	RemoveReaction(ctx context.Context, params ReactionParams) error
+	MarkDelivered(ctx context.Context, params ReceiptParams) error
+	MarkRead(ctx context.Context, params ReceiptParams) error
+	GetMentions(ctx context.Context, params GetMentionsParams) (*models.MentionInbox, error)
+	SetMessageTTL(ctx context.Context, params SetMessageTTLParams) error
+	PinMessage(ctx context.Context, params PinParams) error
+	UnpinMessage(ctx context.Context, params PinParams) error
+	AddRoomMember(ctx context.Context, params RoomMemberParams) error
+	RemoveRoomMember(ctx context.Context, params RoomMemberParams) error
+	GetPinnedMessages(ctx context.Context, params GetPinnedMessagesParams) ([]models.PinnedMessage, error)
+	ForwardMessages(ctx context.Context, params ForwardMessagesParams) ([]models.Message, error)
+	SetForwarding(ctx context.Context, params SetForwardingParams) error
+	SetRetention(ctx context.Context, params SetRetentionParams) error
+	GetRetention(ctx context.Context, params GetRetentionParams) (*models.RetentionPolicy, error)
+	SetLegalHold(ctx context.Context, params SetLegalHoldParams) error
+	SaveDraft(ctx context.Context, params SaveDraftParams) (*models.Draft, error)
+	SendTyping(ctx context.Context, params TypingParams) error
+	GetConversations(ctx context.Context, userID int64) ([]models.Conversation, error)
+	Vote(ctx context.Context, params VoteParams) (*models.PollResults, error)
+	RetractVote(ctx context.Context, params PollParams) (*models.PollResults, error)
+	ClosePoll(ctx context.Context, params PollParams) (*models.PollResults, error)
+	GetPollResults(ctx context.Context, params PollParams) (*models.PollResults, error)
+	SetMessageFilters(ctx context.Context, params SetMessageFiltersParams) error
+	GetMessageFilters(ctx context.Context, params GetMessageFiltersParams) (*models.MessageFilters, error)
+	GetFlaggedMessages(ctx context.Context, params GetFlaggedMessagesParams) ([]models.FlaggedMessage, error)
+	RegisterUser(ctx context.Context, userID int64) error
 }
 
 type messageService struct {
-	repo repo.MessageRepo
-	ws   *websocket.Conn
+	repo      repo.MessageRepo
+	users     repo.UserRepo
+	events    events.Publisher
+	presence  events.OnlineChecker
+	store     blobstore.BlobStore
+	log       *logrus.Logger
+	previewer *LinkPreviewer
+	pipeline  *MessagePipeline
+
+	editWindow      time.Duration
+	reactionLimiter *ratelimit.Limiter
+	largeRoomSize   int
+
+	// registered пользователи, уже записанные в справочник этим экземпляром сервиса
+	registered sync.Map
+}
+
+type MessageServiceConfig struct {
+	Repo repo.MessageRepo
+	// Users проверяет адресатов личных сообщений; nil отключает проверку
+	Users  repo.UserRepo
+	Events events.Publisher
+	// Presence нужен для @here; без него @here никого не упоминает
+	Presence events.OnlineChecker
+	// Store удаляет объекты вложений сообщений, удалённых для всех; nil оставляет объекты в хранилище
+	Store blobstore.BlobStore
+	// Log обязателен вместе со Store: ошибки удаления объектов только журналируются
+	Log *logrus.Logger
+	// LinkPreviews получает превью ссылок в фоне; nil отключает превью
+	LinkPreviews *LinkPreviewer
+	// Pipeline фильтрует сообщения перед сохранением; nil отключает фильтрацию
+	Pipeline *MessagePipeline
+
+	// EditWindow ограничивает время, в течение которого автор может редактировать сообщение; 0 — без ограничений
+	EditWindow time.Duration
+	// ReactionsPerMinute ограничивает число изменений реакций одним пользователем; 0 — без ограничений
+	ReactionsPerMinute int
+	// LargeRoomSize размер комнаты, начиная с которого @room и @here доступны только администраторам; 0 — без ограничений
+	LargeRoomSize int
 }
 
-func NewMessageService(repo repo.MessageRepo) MessageService {
-	return &messageService{repo: repo}
+func NewMessageService(cfg MessageServiceConfig) MessageService {
+	service := &messageService{
+		repo:          cfg.Repo,
+		users:         cfg.Users,
+		events:        cfg.Events,
+		presence:      cfg.Presence,
+		store:         cfg.Store,
+		log:           cfg.Log,
+		previewer:     cfg.LinkPreviews,
+		pipeline:      cfg.Pipeline,
+		editWindow:    cfg.EditWindow,
+		largeRoomSize: cfg.LargeRoomSize,
+	}
+	if cfg.ReactionsPerMinute > 0 {
+		service.reactionLimiter = ratelimit.New(cfg.ReactionsPerMinute, time.Minute)
+	}
+	return service
 }
 
 type SaveMessageParams struct {
 	SenderID   int64
 	ReceiverID int64
-	Content    string
+	// RoomID задаётся для сообщений в комнату; ReceiverID в этом случае не используется
+	RoomID  int64
+	Content string
+	// RichContent структурированное содержимое; если задано, Content вычисляется из него
+	RichContent *models.RichContent
+	// ClientMsgID необязательный ключ идемпотентности: повторная отправка с тем же ключом
+	// возвращает ранее сохранённое сообщение вместо создания дубликата
+	ClientMsgID string
+	// AttachmentIDs ранее загруженные отправителем вложения
+	AttachmentIDs []int64
+	// TTL время жизни сообщения; 0 — действует настройка переписки
+	TTL time.Duration
+	// ViewOnce вложения можно просмотреть один раз: сообщение удаляется после первого прочтения
+	ViewOnce bool
+	// KeepDraft сохраняет черновик переписки; иначе отправка сообщения его удаляет
+	KeepDraft bool
 }
 
-func (s *messageService) SaveMessage(ctx context.Context, params SaveMessageParams) error {
-	if err := s.repo.SaveMessage(ctx, repo.SaveMessageParams{
-		SenderID:   params.SenderID,
-		ReceiverID: params.ReceiverID,
-		Content:    params.Content,
-	}); err != nil {
-		return fmt.Errorf("s.repo.SaveMessage: %w", err)
+const (
+	maxClientMsgIDLength  = 64
+	maxMessageAttachments = 10
+)
+
+func (s *messageService) SaveMessage(ctx context.Context, params SaveMessageParams) (*models.Message, error) {
+	if err := validateTarget(params.SenderID, params.ReceiverID, params.RoomID); err != nil {
+		return nil, err
 	}
-	return nil
+	if params.RoomID == 0 {
+		if err := s.checkReceiver(ctx, params.ReceiverID); err != nil {
+			return nil, err
+		}
+	}
+	if len(params.ClientMsgID) > maxClientMsgIDLength {
+		return nil, fmt.Errorf("%w: client_msg_id is longer than %d characters", ErrValidation, maxClientMsgIDLength)
+	}
+	if params.RichContent != nil {
+		if err := validateContent(params.RichContent); err != nil {
+			return nil, err
+		}
+		params.Content = plainText(params.RichContent)
+		params.AttachmentIDs = append(params.AttachmentIDs, params.RichContent.Attachments...)
+	} else {
+		content, err := normalizeText(params.Content)
+		if err != nil {
+			return nil, err
+		}
+		if content == "" && len(params.AttachmentIDs) == 0 {
+			return nil, fmt.Errorf("%w: content or attachments are required", ErrValidation)
+		}
+		params.Content = content
+	}
+	if len(params.AttachmentIDs) > maxMessageAttachments {
+		return nil, fmt.Errorf("%w: more than %d attachments", ErrValidation, maxMessageAttachments)
+	}
+	if len(params.AttachmentIDs) > 0 {
+		params.AttachmentIDs = slices.Compact(slices.Sorted(slices.Values(params.AttachmentIDs)))
+	}
+	if err := validateTTL(params.TTL); err != nil {
+		return nil, err
+	}
+	if params.ViewOnce && len(params.AttachmentIDs) == 0 {
+		return nil, fmt.Errorf("%w: view once requires an attachment", ErrValidation)
+	}
+
+	recipients := []int64{params.ReceiverID}
+	if params.RoomID != 0 {
+		members, err := s.repo.GetRoomMemberIDs(ctx, params.RoomID)
+		if err != nil {
+			return nil, fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+		}
+		if len(members) == 0 {
+			return nil, fmt.Errorf("%w: room %d", ErrNotFound, params.RoomID)
+		}
+		if !slices.Contains(members, params.SenderID) {
+			return nil, fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+
+		recipients = slices.DeleteFunc(members, func(id int64) bool { return id == params.SenderID })
+		params.ReceiverID = 0
+	}
+
+	// Повтор отправки возвращает сохранённое сообщение до фильтров и упоминаний:
+	// иначе RepeatFilter отклонил бы повтор как спам
+	if params.ClientMsgID != "" {
+		original, err := s.repo.GetMessageByClientMsgID(ctx, params.SenderID, params.ClientMsgID)
+		if err == nil {
+			return original, nil
+		}
+		if !errors.Is(err, repo.ErrNotFound) {
+			return nil, fmt.Errorf("s.repo.GetMessageByClientMsgID: %w", err)
+		}
+	}
+
+	filtered := PipelineMessage{
+		SenderID:    params.SenderID,
+		ReceiverID:  params.ReceiverID,
+		RoomID:      params.RoomID,
+		Content:     params.Content,
+		RichContent: params.RichContent,
+	}
+	if err := s.filterMessage(ctx, &filtered); err != nil {
+		return nil, err
+	}
+	params.Content = filtered.Content
+
+	mentions, err := s.resolveMentions(ctx, params, recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := s.repo.SaveMessage(ctx, repo.SaveMessageParams{
+		SenderID:      params.SenderID,
+		ReceiverID:    params.ReceiverID,
+		RoomID:        params.RoomID,
+		Content:       params.Content,
+		RichContent:   params.RichContent,
+		ClientMsgID:   params.ClientMsgID,
+		AttachmentIDs: params.AttachmentIDs,
+		Mentions:      mentions,
+		RecipientIDs:  recipients,
+		TTL:           params.TTL,
+		ViewOnce:      params.ViewOnce,
+		ClearDraft:    !params.KeepDraft,
+		Flags:         filtered.Flags,
+	})
+	if errors.Is(err, repo.ErrDuplicate) {
+		// Параллельный повтор успел сохранить сообщение: получатели его уже получили, возвращаем сохранённое
+		original, err := s.repo.GetMessageByClientMsgID(ctx, params.SenderID, params.ClientMsgID)
+		if err != nil {
+			return nil, fmt.Errorf("s.repo.GetMessageByClientMsgID: %w", err)
+		}
+		return original, nil
+	}
+	if errors.Is(err, repo.ErrAttachmentUnavailable) {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.SaveMessage: %w", err)
+	}
+
+	// Отправитель тоже получает событие: журнал синхронизации доставит
+	// сообщение на другие его устройства.
+	s.publish(append(recipients, params.SenderID), events.Event{Type: events.MessageNew, Payload: msg})
+	s.publishMentions(msg, mentions)
+	s.enqueueLinkPreview(*msg)
+	if !params.KeepDraft {
+		s.publishDraftCleared(*msg)
+	}
+
+	return msg, nil
 }
 
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 100
+)
+
 type GetHistoryParams struct {
 	SenderID   int64
 	ReceiverID int64
+	// RoomID задаётся для истории комнаты; ReceiverID в этом случае не используется
+	RoomID int64
+	// BeforeSeq страница сообщений перед указанным номером; 0 — последние сообщения
+	BeforeSeq int64
+	// AfterSeq страница сообщений после указанного номера, например чтобы догрузить пропуск
+	AfterSeq int64
+	Limit    int
 }
 
+// GetHistory возвращает страницу истории личной переписки или комнаты от новых сообщений к старым.
+// Историю комнаты видят только её участники
 func (s *messageService) GetHistory(ctx context.Context, params GetHistoryParams) ([]models.Message, error) {
+	if params.BeforeSeq < 0 || params.AfterSeq < 0 {
+		return nil, fmt.Errorf("%w: seq must not be negative", ErrValidation)
+	}
+	if params.BeforeSeq > 0 && params.AfterSeq > 0 {
+		return nil, fmt.Errorf("%w: before_seq and after_seq are mutually exclusive", ErrValidation)
+	}
+	if params.Limit <= 0 {
+		params.Limit = defaultHistoryLimit
+	}
+	params.Limit = min(params.Limit, maxHistoryLimit)
+
+	if params.RoomID != 0 {
+		members, err := s.repo.GetRoomMemberIDs(ctx, params.RoomID)
+		if err != nil {
+			return nil, fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+		}
+		if len(members) == 0 {
+			return nil, fmt.Errorf("%w: room %d", ErrNotFound, params.RoomID)
+		}
+		if !slices.Contains(members, params.SenderID) {
+			return nil, fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+		params.ReceiverID = 0
+	}
+
 	messages, err := s.repo.GetHistory(ctx, repo.GetHistoryParams{
 		SenderID:   params.SenderID,
 		ReceiverID: params.ReceiverID,
+		RoomID:     params.RoomID,
+		BeforeSeq:  params.BeforeSeq,
+		AfterSeq:   params.AfterSeq,
+		Limit:      params.Limit,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("s.repo.GetHistory: %w", err)
@@ -55,3 +326,280 @@ func (s *messageService) GetHistory(ctx context.Context, params GetHistoryParams
 
 	return messages, nil
 }
+
+type EditMessageParams struct {
+	MessageID int64
+	EditorID  int64
+	Content   string
+	// RichContent новое структурированное содержимое; если задано, Content вычисляется из него
+	RichContent *models.RichContent
+}
+
+func (s *messageService) EditMessage(ctx context.Context, params EditMessageParams) (*models.Message, error) {
+	msg, err := s.getMessage(ctx, params.MessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: message %d", ErrNotFound, params.MessageID)
+	}
+	if msg.SenderID != params.EditorID {
+		return nil, fmt.Errorf("%w: only the author can edit the message", ErrForbidden)
+	}
+	if msg.ForwardedFrom != nil {
+		return nil, fmt.Errorf("%w: forwarded messages cannot be edited", ErrForbidden)
+	}
+	if s.editWindow > 0 && time.Since(msg.CreatedAt) > s.editWindow {
+		return nil, ErrEditWindowExpired
+	}
+
+	content, err := editedContent(msg.RichContent, params)
+	if err != nil {
+		return nil, err
+	}
+	if content != nil {
+		params.Content = plainText(content)
+	} else if params.Content, err = normalizeText(params.Content); err != nil {
+		return nil, err
+	} else if params.Content == "" && len(msg.Attachments) == 0 {
+		return nil, fmt.Errorf("%w: content is required", ErrValidation)
+	}
+
+	filtered := PipelineMessage{
+		SenderID:    msg.SenderID,
+		ReceiverID:  msg.ReceiverID,
+		RoomID:      msg.RoomID,
+		Content:     params.Content,
+		RichContent: content,
+		Edit:        true,
+	}
+	if err = s.filterMessage(ctx, &filtered); err != nil {
+		return nil, err
+	}
+
+	edited, err := s.repo.EditMessage(ctx, repo.EditMessageParams{
+		MessageID:   params.MessageID,
+		EditorID:    params.EditorID,
+		Content:     filtered.Content,
+		RichContent: content,
+		Flags:       filtered.Flags,
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, fmt.Errorf("%w: message %d", ErrNotFound, params.MessageID)
+		}
+		return nil, fmt.Errorf("s.repo.EditMessage: %w", err)
+	}
+
+	members, err := s.conversationMembers(ctx, *edited)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(members, events.Event{Type: events.MessageEdited, Payload: edited})
+	// Правка сбрасывает превью: ссылка могла измениться
+	s.enqueueLinkPreview(*edited)
+
+	return edited, nil
+}
+
+// editedContent вычисляет новое структурированное содержимое при редактировании.
+// Редактировать можно только текст: вид сообщения и набор вложений правкой не меняются
+func editedContent(current *models.RichContent, params EditMessageParams) (*models.RichContent, error) {
+	if current != nil && current.Kind != models.ContentKindText {
+		return nil, fmt.Errorf("%w: only text messages can be edited", ErrForbidden)
+	}
+
+	content := params.RichContent
+	if content == nil {
+		if current == nil {
+			return nil, nil
+		}
+		// Старый клиент прислал простой текст: разметка сбрасывается, вложения сохраняются
+		content = &models.RichContent{Kind: models.ContentKindText, Text: params.Content}
+	}
+	if content.Kind != models.ContentKindText {
+		return nil, fmt.Errorf("%w: content kind cannot be changed by editing", ErrValidation)
+	}
+
+	content.Attachments = nil
+	if current != nil {
+		content.Attachments = current.Attachments
+	}
+	if err := validateContent(content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+type GetRevisionsParams struct {
+	MessageID int64
+	UserID    int64
+}
+
+func (s *messageService) GetRevisions(ctx context.Context, params GetRevisionsParams) ([]models.MessageRevision, error) {
+	msg, err := s.getMessage(ctx, params.MessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.conversationMembers(ctx, *msg)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(members, params.UserID) {
+		return nil, fmt.Errorf("%w: not a member of the conversation", ErrForbidden)
+	}
+
+	// Правки удалённого сообщения сохраняются только под legal hold и участникам не показываются
+	if msg.DeletedAt != nil {
+		return []models.MessageRevision{}, nil
+	}
+
+	revisions, err := s.repo.GetMessageRevisions(ctx, params.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetMessageRevisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// Режимы удаления сообщения
+const (
+	DeleteForMe       = "me"
+	DeleteForEveryone = "everyone"
+)
+
+type DeleteMessageParams struct {
+	MessageID int64
+	UserID    int64
+	Scope     string
+}
+
+func (s *messageService) DeleteMessage(ctx context.Context, params DeleteMessageParams) error {
+	msg, err := s.getMessage(ctx, params.MessageID)
+	if err != nil {
+		return err
+	}
+
+	members, err := s.conversationMembers(ctx, *msg)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(members, params.UserID) {
+		return fmt.Errorf("%w: not a member of the conversation", ErrForbidden)
+	}
+
+	deletion := models.MessageDeletion{
+		MessageID: params.MessageID,
+		Scope:     params.Scope,
+		DeletedBy: params.UserID,
+	}
+
+	switch params.Scope {
+	case DeleteForMe:
+		if err = s.repo.HideMessage(ctx, repo.HideMessageParams{
+			MessageID: params.MessageID,
+			UserID:    params.UserID,
+		}); err != nil {
+			return fmt.Errorf("s.repo.HideMessage: %w", err)
+		}
+
+		// Остальные устройства пользователя тоже должны скрыть сообщение
+		s.publish([]int64{params.UserID}, events.Event{Type: events.MessageDeleted, Payload: deletion})
+	case DeleteForEveryone:
+		if msg.DeletedAt != nil {
+			return fmt.Errorf("%w: message %d", ErrNotFound, params.MessageID)
+		}
+
+		allowed, err := s.canModerate(ctx, *msg, params.UserID)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("%w: only the author or a moderator can delete the message for everyone", ErrForbidden)
+		}
+
+		deleted, err := s.repo.DeleteMessageForEveryone(ctx, repo.DeleteMessageParams{
+			MessageID: params.MessageID,
+			DeletedBy: params.UserID,
+		})
+		if err != nil {
+			if errors.Is(err, repo.ErrNotFound) {
+				return fmt.Errorf("%w: message %d", ErrNotFound, params.MessageID)
+			}
+			return fmt.Errorf("s.repo.DeleteMessageForEveryone: %w", err)
+		}
+		if s.store != nil {
+			releaseBlobs(ctx, s.repo, s.store, s.log, deleted.StorageKeys)
+		}
+
+		s.publish(members, events.Event{Type: events.MessageDeleted, Payload: deletion})
+	default:
+		return fmt.Errorf("%w: unknown delete scope %q", ErrValidation, params.Scope)
+	}
+
+	return nil
+}
+
+// canModerate проверяет, является ли пользователь автором сообщения или администратором комнаты
+func (s *messageService) canModerate(ctx context.Context, msg models.Message, userID int64) (bool, error) {
+	if msg.SenderID == userID {
+		return true, nil
+	}
+	if msg.RoomID == 0 {
+		return false, nil
+	}
+
+	member, err := s.repo.GetRoomMember(ctx, msg.RoomID, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("s.repo.GetRoomMember: %w", err)
+	}
+	return member.IsAdmin, nil
+}
+
+func (s *messageService) getMessage(ctx context.Context, messageID int64) (*models.Message, error) {
+	msg, err := s.repo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, fmt.Errorf("%w: message %d", ErrNotFound, messageID)
+		}
+		return nil, fmt.Errorf("s.repo.GetMessageByID: %w", err)
+	}
+	return msg, nil
+}
+
+func (s *messageService) conversationMembers(ctx context.Context, msg models.Message) ([]int64, error) {
+	return conversationMembers(ctx, s.repo, msg)
+}
+
+// conversationMembers возвращает участников переписки, к которой относится сообщение:
+// участников комнаты или отправителя и получателя личного диалога
+func conversationMembers(ctx context.Context, messages repo.MessageRepo, msg models.Message) ([]int64, error) {
+	if msg.RoomID == 0 {
+		return []int64{msg.SenderID, msg.ReceiverID}, nil
+	}
+
+	members, err := messages.GetRoomMemberIDs(ctx, msg.RoomID)
+	if err != nil {
+		return nil, fmt.Errorf("GetRoomMemberIDs: %w", err)
+	}
+	return members, nil
+}
+
+func (s *messageService) enqueueLinkPreview(msg models.Message) {
+	if s.previewer == nil {
+		return
+	}
+	s.previewer.enqueue(msg)
+}
+
+func (s *messageService) publish(userIDs []int64, event events.Event) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(userIDs, event)
+}
@@ -3,6 +3,7 @@ package services_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,9 +11,11 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"messanger/internal/events"
 	"messanger/internal/models"
 	"messanger/internal/repo"
 	"messanger/internal/services"
+	"messanger/pkg/blobstore"
 )
 
 // MockMessageRepo реализует интерфейс repo.MessageRepo для тестов
@@ -20,9 +23,20 @@ type MockMessageRepo struct {
 	mock.Mock
 }
 
-func (m *MockMessageRepo) SaveMessage(ctx context.Context, params repo.SaveMessageParams) error {
+func (m *MockMessageRepo) SaveMessage(ctx context.Context, params repo.SaveMessageParams) (*models.Message, error) {
 	args := m.Called(ctx, params)
-	return args.Error(0)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+func (m *MockMessageRepo) SaveMessages(ctx context.Context, params []repo.SaveMessageParams) ([]models.Message, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Message), args.Error(1)
 }
 
 func (m *MockMessageRepo) GetHistory(ctx context.Context, params repo.GetHistoryParams) ([]models.Message, error) {
@@ -30,12 +44,271 @@ func (m *MockMessageRepo) GetHistory(ctx context.Context, params repo.GetHistory
 	return args.Get(0).([]models.Message), args.Error(1)
 }
 
+func (m *MockMessageRepo) GetMessageByID(ctx context.Context, messageID int64) (*models.Message, error) {
+	args := m.Called(ctx, messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+func (m *MockMessageRepo) GetMessageByClientMsgID(ctx context.Context, senderID int64, clientMsgID string) (*models.Message, error) {
+	args := m.Called(ctx, senderID, clientMsgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+func (m *MockMessageRepo) EditMessage(ctx context.Context, params repo.EditMessageParams) (*models.Message, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+func (m *MockMessageRepo) GetMessageRevisions(ctx context.Context, messageID int64) ([]models.MessageRevision, error) {
+	args := m.Called(ctx, messageID)
+	return args.Get(0).([]models.MessageRevision), args.Error(1)
+}
+
+func (m *MockMessageRepo) GetRoomMemberIDs(ctx context.Context, roomID int64) ([]int64, error) {
+	args := m.Called(ctx, roomID)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockMessageRepo) GetRoomMember(ctx context.Context, roomID, userID int64) (*models.RoomMember, error) {
+	args := m.Called(ctx, roomID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RoomMember), args.Error(1)
+}
+
+func (m *MockMessageRepo) AddRoomMember(ctx context.Context, roomID, userID int64) (*models.RoomMember, error) {
+	args := m.Called(ctx, roomID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RoomMember), args.Error(1)
+}
+
+func (m *MockMessageRepo) RemoveRoomMember(ctx context.Context, roomID, userID int64) (bool, error) {
+	args := m.Called(ctx, roomID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMessageRepo) DeleteMessageForEveryone(ctx context.Context, params repo.DeleteMessageParams) (*repo.DeletedMessage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repo.DeletedMessage), args.Error(1)
+}
+
+func (m *MockMessageRepo) LockBlobs(ctx context.Context, hashes []string) (func(), error) {
+	args := m.Called(ctx, hashes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(func()), args.Error(1)
+}
+
+func (m *MockMessageRepo) GetOrphanedStorageKeys(ctx context.Context, keys []string) ([]string, error) {
+	args := m.Called(ctx, keys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockMessageRepo) HideMessage(ctx context.Context, params repo.HideMessageParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepo) AddReaction(ctx context.Context, params repo.ReactionParams) (bool, error) {
+	args := m.Called(ctx, params)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMessageRepo) RemoveReaction(ctx context.Context, params repo.ReactionParams) (bool, error) {
+	args := m.Called(ctx, params)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMessageRepo) UpdateReceipts(ctx context.Context, params repo.UpdateReceiptsParams) ([]models.Receipt, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]models.Receipt), args.Error(1)
+}
+
+func (m *MockMessageRepo) GetMentions(ctx context.Context, params repo.GetMentionsParams) ([]models.Mention, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]models.Mention), args.Error(1)
+}
+
+func (m *MockMessageRepo) CountUnreadMentions(ctx context.Context, userID int64) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockMessageRepo) MarkMentionsRead(ctx context.Context, userID int64, messageIDs []int64) error {
+	args := m.Called(ctx, userID, messageIDs)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepo) SetMessageTTL(ctx context.Context, params repo.SetMessageTTLParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepo) SetForwarding(ctx context.Context, params repo.SetForwardingParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepo) ForwardingDisabled(ctx context.Context, roomID int64, userIDs [2]int64) (bool, error) {
+	args := m.Called(ctx, roomID, userIDs)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMessageRepo) SaveDraft(ctx context.Context, params repo.DraftParams, content string) (*models.Draft, error) {
+	args := m.Called(ctx, params, content)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Draft), args.Error(1)
+}
+
+func (m *MockMessageRepo) DeleteDraft(ctx context.Context, params repo.DraftParams) (bool, error) {
+	args := m.Called(ctx, params)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMessageRepo) GetDrafts(ctx context.Context, userID int64) ([]models.Draft, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Draft), args.Error(1)
+}
+
+func (m *MockMessageRepo) GetConversations(ctx context.Context, userID int64) ([]models.Conversation, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Conversation), args.Error(1)
+}
+
+func (m *MockMessageRepo) ExportMessages(ctx context.Context, params repo.ExportMessagesParams) ([]models.ExportedMessage, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]models.ExportedMessage), args.Error(1)
+}
+
+func (m *MockMessageRepo) SetRetention(ctx context.Context, params repo.RetentionParams, policy models.RetentionPolicy) error {
+	args := m.Called(ctx, params, policy)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepo) SetLegalHold(ctx context.Context, params repo.RetentionParams, hold bool) error {
+	args := m.Called(ctx, params, hold)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepo) GetRetention(ctx context.Context, params repo.RetentionParams) (*models.RetentionPolicy, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RetentionPolicy), args.Error(1)
+}
+
+func (m *MockMessageRepo) PurgeRetainedMessages(ctx context.Context, params repo.PurgeRetainedParams) (*repo.PurgeResult, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repo.PurgeResult), args.Error(1)
+}
+
+func (m *MockMessageRepo) Vote(ctx context.Context, params repo.VoteParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepo) ClosePoll(ctx context.Context, messageID int64) (bool, error) {
+	args := m.Called(ctx, messageID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMessageRepo) CloseDuePolls(ctx context.Context, limit int) ([]int64, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockMessageRepo) GetPollResults(ctx context.Context, messageIDs []int64, userID int64) (map[int64]*models.PollResults, error) {
+	args := m.Called(ctx, messageIDs, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int64]*models.PollResults), args.Error(1)
+}
+
+func (m *MockMessageRepo) SetMessageFilters(ctx context.Context, roomID int64, filters *models.MessageFilters) error {
+	args := m.Called(ctx, roomID, filters)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepo) GetMessageFilters(ctx context.Context, roomID int64) (*models.MessageFilters, error) {
+	args := m.Called(ctx, roomID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MessageFilters), args.Error(1)
+}
+
+func (m *MockMessageRepo) CountRecentMessages(ctx context.Context, params repo.CountRecentMessagesParams) (int, error) {
+	args := m.Called(ctx, params)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockMessageRepo) GetFlaggedMessages(ctx context.Context, params repo.GetFlaggedMessagesParams) ([]models.FlaggedMessage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.FlaggedMessage), args.Error(1)
+}
+
+func (m *MockMessageRepo) PinMessage(ctx context.Context, params repo.PinParams) (*models.Pin, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Pin), args.Error(1)
+}
+
+func (m *MockMessageRepo) UnpinMessage(ctx context.Context, messageID int64) (bool, error) {
+	args := m.Called(ctx, messageID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMessageRepo) GetPinnedMessages(ctx context.Context, params repo.GetPinnedMessagesParams) ([]models.PinnedMessage, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]models.PinnedMessage), args.Error(1)
+}
+
+func (m *MockMessageRepo) PurgeExpiredMessages(ctx context.Context, limit int) (*repo.PurgeResult, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repo.PurgeResult), args.Error(1)
+}
+
 func TestMessageService_SaveMessage(t *testing.T) {
 	tests := []struct {
 		name          string
 		params        services.SaveMessageParams
 		repoSetup     func(*MockMessageRepo)
 		expectedError error
+		notified      []int64
 	}{
 		{
 			name: "successful message save",
@@ -46,12 +319,47 @@ func TestMessageService_SaveMessage(t *testing.T) {
 			},
 			repoSetup: func(m *MockMessageRepo) {
 				m.On("SaveMessage", mock.Anything, repo.SaveMessageParams{
-					SenderID:   1,
-					ReceiverID: 2,
-					Content:    "Hello",
-				}).Return(nil)
+					SenderID:     1,
+					ReceiverID:   2,
+					Content:      "Hello",
+					RecipientIDs: []int64{2},
+					ClearDraft:   true,
+				}).Return(&models.Message{ID: 1, SenderID: 1, ReceiverID: 2, Content: "Hello"}, nil)
 			},
 			expectedError: nil,
+			notified:      []int64{2, 1},
+		},
+		{
+			name: "room message",
+			params: services.SaveMessageParams{
+				SenderID: 1,
+				RoomID:   5,
+				Content:  "Hello",
+			},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				m.On("SaveMessage", mock.Anything, repo.SaveMessageParams{
+					SenderID:     1,
+					RoomID:       5,
+					Content:      "Hello",
+					RecipientIDs: []int64{2, 3},
+					ClearDraft:   true,
+				}).Return(&models.Message{ID: 2, SenderID: 1, RoomID: 5, Content: "Hello"}, nil)
+			},
+			expectedError: nil,
+			notified:      []int64{2, 3, 1},
+		},
+		{
+			name: "not a room member",
+			params: services.SaveMessageParams{
+				SenderID: 4,
+				RoomID:   5,
+				Content:  "Hello",
+			},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+			},
+			expectedError: services.ErrForbidden,
 		},
 		{
 			name: "repository error",
@@ -62,7 +370,7 @@ func TestMessageService_SaveMessage(t *testing.T) {
 			},
 			repoSetup: func(m *MockMessageRepo) {
 				m.On("SaveMessage", mock.Anything, mock.Anything).
-					Return(errors.New("database error"))
+					Return(nil, errors.New("database error"))
 			},
 			expectedError: errors.New("s.repo.SaveMessage: database error"),
 		},
@@ -73,14 +381,29 @@ func TestMessageService_SaveMessage(t *testing.T) {
 			mockRepo := new(MockMessageRepo)
 			tt.repoSetup(mockRepo)
 
-			service := services.NewMessageService(mockRepo)
-			err := service.SaveMessage(context.Background(), tt.params)
+			var notified []int64
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				if event.Type == events.MessageNew {
+					notified = userIDs
+				}
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+			result, err := service.SaveMessage(context.Background(), tt.params)
 
 			if tt.expectedError != nil {
-				assert.EqualError(t, err, tt.expectedError.Error())
+				if errors.Is(tt.expectedError, services.ErrForbidden) {
+					assert.ErrorIs(t, err, tt.expectedError)
+				} else {
+					assert.EqualError(t, err, tt.expectedError.Error())
+				}
+				assert.Nil(t, result)
 			} else {
-				assert.NoError(t, err)
+				require.NoError(t, err)
+				assert.Equal(t, tt.params.Content, result.Content)
 			}
+			assert.Equal(t, tt.notified, notified)
 
 			mockRepo.AssertExpectations(t)
 		})
@@ -129,6 +452,7 @@ func TestMessageService_GetHistory(t *testing.T) {
 				m.On("GetHistory", mock.Anything, repo.GetHistoryParams{
 					SenderID:   1,
 					ReceiverID: 2,
+					Limit:      100,
 				}).Return(testMessages, nil)
 			},
 			expectedResult: testMessages,
@@ -157,11 +481,79 @@ func TestMessageService_GetHistory(t *testing.T) {
 				m.On("GetHistory", mock.Anything, repo.GetHistoryParams{
 					SenderID:   3,
 					ReceiverID: 4,
+					Limit:      100,
 				}).Return([]models.Message{}, nil)
 			},
 			expectedResult: []models.Message{},
 			expectedError:  nil,
 		},
+		{
+			name: "page after a known seq",
+			params: services.GetHistoryParams{
+				SenderID:   1,
+				ReceiverID: 2,
+				AfterSeq:   7,
+				Limit:      500,
+			},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetHistory", mock.Anything, repo.GetHistoryParams{
+					SenderID:   1,
+					ReceiverID: 2,
+					AfterSeq:   7,
+					Limit:      100,
+				}).Return(testMessages, nil)
+			},
+			expectedResult: testMessages,
+		},
+		{
+			name: "room",
+			params: services.GetHistoryParams{
+				SenderID:   1,
+				ReceiverID: 9,
+				RoomID:     5,
+			},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				m.On("GetHistory", mock.Anything, repo.GetHistoryParams{
+					SenderID: 1,
+					RoomID:   5,
+					Limit:    100,
+				}).Return(testMessages, nil)
+			},
+			expectedResult: testMessages,
+		},
+		{
+			name: "not a member of the room",
+			params: services.GetHistoryParams{
+				SenderID: 4,
+				RoomID:   5,
+			},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+			},
+			expectedError: errors.New("forbidden: not a member of the room"),
+		},
+		{
+			name: "both cursors",
+			params: services.GetHistoryParams{
+				SenderID:   1,
+				ReceiverID: 2,
+				BeforeSeq:  10,
+				AfterSeq:   7,
+			},
+			repoSetup:     func(m *MockMessageRepo) {},
+			expectedError: errors.New("validation failed: before_seq and after_seq are mutually exclusive"),
+		},
+		{
+			name: "negative seq",
+			params: services.GetHistoryParams{
+				SenderID:   1,
+				ReceiverID: 2,
+				BeforeSeq:  -1,
+			},
+			repoSetup:     func(m *MockMessageRepo) {},
+			expectedError: errors.New("validation failed: seq must not be negative"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -169,7 +561,7 @@ func TestMessageService_GetHistory(t *testing.T) {
 			mockRepo := new(MockMessageRepo)
 			tt.repoSetup(mockRepo)
 
-			service := services.NewMessageService(mockRepo)
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
 			result, err := service.GetHistory(context.Background(), tt.params)
 
 			if tt.expectedError != nil {
@@ -185,3 +577,357 @@ func TestMessageService_GetHistory(t *testing.T) {
 		})
 	}
 }
+
+func TestMessageService_EditMessage(t *testing.T) {
+	now := time.Now()
+	original := &models.Message{
+		ID:         10,
+		SenderID:   1,
+		ReceiverID: 2,
+		Content:    "Hello",
+		CreatedAt:  now.Add(-time.Hour),
+	}
+	edited := &models.Message{
+		ID:         10,
+		SenderID:   1,
+		ReceiverID: 2,
+		Content:    "Hello, world",
+		CreatedAt:  now.Add(-time.Hour),
+		Edited:     true,
+		EditedAt:   &now,
+	}
+
+	tests := []struct {
+		name          string
+		params        services.EditMessageParams
+		editWindow    time.Duration
+		repoSetup     func(*MockMessageRepo)
+		expectedError error
+		expectEvent   bool
+	}{
+		{
+			name:   "successful edit",
+			params: services.EditMessageParams{MessageID: 10, EditorID: 1, Content: "Hello, world"},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(10)).Return(original, nil)
+				m.On("EditMessage", mock.Anything, repo.EditMessageParams{
+					MessageID: 10,
+					EditorID:  1,
+					Content:   "Hello, world",
+				}).Return(edited, nil)
+			},
+			expectEvent: true,
+		},
+		{
+			name:   "not the author",
+			params: services.EditMessageParams{MessageID: 10, EditorID: 2, Content: "Hello, world"},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(10)).Return(original, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:       "edit window expired",
+			params:     services.EditMessageParams{MessageID: 10, EditorID: 1, Content: "Hello, world"},
+			editWindow: time.Minute,
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(10)).Return(original, nil)
+			},
+			expectedError: services.ErrEditWindowExpired,
+		},
+		{
+			name:   "message not found",
+			params: services.EditMessageParams{MessageID: 11, EditorID: 1, Content: "Hello, world"},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(11)).Return(nil, repo.ErrNotFound)
+			},
+			expectedError: services.ErrNotFound,
+		},
+		{
+			name:   "empty content",
+			params: services.EditMessageParams{MessageID: 10, EditorID: 1, Content: " \u0007 "},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(10)).Return(original, nil)
+			},
+			expectedError: services.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.repoSetup(mockRepo)
+
+			var published []events.Event
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				assert.ElementsMatch(t, []int64{1, 2}, userIDs)
+				published = append(published, event)
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{
+				Repo:       mockRepo,
+				Events:     bus,
+				EditWindow: tt.editWindow,
+			})
+			result, err := service.EditMessage(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, result)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, edited, result)
+			}
+
+			if tt.expectEvent {
+				require.Len(t, published, 1)
+				assert.Equal(t, events.MessageEdited, published[0].Type)
+				assert.Equal(t, edited, published[0].Payload)
+			} else {
+				assert.Empty(t, published)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_GetRevisions(t *testing.T) {
+	roomMessage := &models.Message{ID: 20, SenderID: 1, RoomID: 5, Content: "Hi all"}
+	revisions := []models.MessageRevision{
+		{ID: 1, MessageID: 20, EditorID: 1, Content: "Hi"},
+	}
+
+	t.Run("room member", func(t *testing.T) {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("GetMessageByID", mock.Anything, int64(20)).Return(roomMessage, nil)
+		mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 3}, nil)
+		mockRepo.On("GetMessageRevisions", mock.Anything, int64(20)).Return(revisions, nil)
+
+		service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+		result, err := service.GetRevisions(context.Background(), services.GetRevisionsParams{MessageID: 20, UserID: 3})
+
+		require.NoError(t, err)
+		assert.Equal(t, revisions, result)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("outsider", func(t *testing.T) {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("GetMessageByID", mock.Anything, int64(20)).Return(roomMessage, nil)
+		mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 3}, nil)
+
+		service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+		result, err := service.GetRevisions(context.Background(), services.GetRevisionsParams{MessageID: 20, UserID: 4})
+
+		assert.ErrorIs(t, err, services.ErrForbidden)
+		assert.Nil(t, result)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestMessageService_DeleteMessage(t *testing.T) {
+	roomMessage := &models.Message{ID: 30, SenderID: 1, RoomID: 5, Content: "Hi all"}
+
+	tests := []struct {
+		name            string
+		params          services.DeleteMessageParams
+		repoSetup       func(*MockMessageRepo)
+		expectedError   error
+		expectedTargets []int64
+	}{
+		{
+			name:   "author deletes for everyone",
+			params: services.DeleteMessageParams{MessageID: 30, UserID: 1, Scope: services.DeleteForEveryone},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(30)).Return(roomMessage, nil)
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				m.On("DeleteMessageForEveryone", mock.Anything, repo.DeleteMessageParams{MessageID: 30, DeletedBy: 1}).
+					Return(&repo.DeletedMessage{Message: models.Message{ID: 30}}, nil)
+			},
+			expectedTargets: []int64{1, 2, 3},
+		},
+		{
+			name:   "room admin deletes for everyone",
+			params: services.DeleteMessageParams{MessageID: 30, UserID: 2, Scope: services.DeleteForEveryone},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(30)).Return(roomMessage, nil)
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				m.On("GetRoomMember", mock.Anything, int64(5), int64(2)).
+					Return(&models.RoomMember{RoomID: 5, UserID: 2, IsAdmin: true}, nil)
+				m.On("DeleteMessageForEveryone", mock.Anything, repo.DeleteMessageParams{MessageID: 30, DeletedBy: 2}).
+					Return(&repo.DeletedMessage{Message: models.Message{ID: 30}}, nil)
+			},
+			expectedTargets: []int64{1, 2, 3},
+		},
+		{
+			name:   "regular member cannot delete for everyone",
+			params: services.DeleteMessageParams{MessageID: 30, UserID: 3, Scope: services.DeleteForEveryone},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(30)).Return(roomMessage, nil)
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				m.On("GetRoomMember", mock.Anything, int64(5), int64(3)).
+					Return(&models.RoomMember{RoomID: 5, UserID: 3}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "member deletes for me",
+			params: services.DeleteMessageParams{MessageID: 30, UserID: 3, Scope: services.DeleteForMe},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(30)).Return(roomMessage, nil)
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				m.On("HideMessage", mock.Anything, repo.HideMessageParams{MessageID: 30, UserID: 3}).Return(nil)
+			},
+			expectedTargets: []int64{3},
+		},
+		{
+			name:   "outsider",
+			params: services.DeleteMessageParams{MessageID: 30, UserID: 4, Scope: services.DeleteForMe},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(30)).Return(roomMessage, nil)
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "unknown scope",
+			params: services.DeleteMessageParams{MessageID: 30, UserID: 3, Scope: "everything"},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(30)).Return(roomMessage, nil)
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+			},
+			expectedError: services.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.repoSetup(mockRepo)
+
+			var targets []int64
+			var published []events.Event
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				targets = userIDs
+				published = append(published, event)
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+			err := service.DeleteMessage(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Empty(t, published)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, published, 1)
+				assert.Equal(t, events.MessageDeleted, published[0].Type)
+				assert.Equal(t, models.MessageDeletion{
+					MessageID: tt.params.MessageID,
+					Scope:     tt.params.Scope,
+					DeletedBy: tt.params.UserID,
+				}, published[0].Payload)
+				assert.ElementsMatch(t, tt.expectedTargets, targets)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_SaveMessage_Idempotency(t *testing.T) {
+	original := &models.Message{ID: 7, SenderID: 1, ReceiverID: 2, ClientMsgID: "c-1", Content: "Hello"}
+
+	tests := []struct {
+		name         string
+		mockBehavior func(r *MockMessageRepo)
+	}{
+		{
+			// Повтор не проходит фильтры: RepeatFilter без мока CountRecentMessages уронил бы тест
+			name: "retry returns the stored message",
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByClientMsgID", mock.Anything, int64(1), "c-1").Return(original, nil)
+			},
+		},
+		{
+			name: "concurrent retry",
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByClientMsgID", mock.Anything, int64(1), "c-1").Return(nil, repo.ErrNotFound).Once()
+				r.On("CountRecentMessages", mock.Anything, mock.Anything).Return(0, nil)
+				r.On("SaveMessage", mock.Anything, repo.SaveMessageParams{
+					SenderID:     1,
+					ReceiverID:   2,
+					Content:      "Hello",
+					ClientMsgID:  "c-1",
+					RecipientIDs: []int64{2},
+					ClearDraft:   true,
+				}).Return(nil, repo.ErrDuplicate)
+				r.On("GetMessageByClientMsgID", mock.Anything, int64(1), "c-1").Return(original, nil).Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.mockBehavior(mockRepo)
+
+			var published []events.Event
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				published = append(published, event)
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{
+				Repo:   mockRepo,
+				Events: bus,
+				Pipeline: services.NewMessagePipeline(services.MessagePipelineConfig{
+					Stages:   []services.MessageStage{services.RepeatFilter{Repo: mockRepo}},
+					Defaults: models.MessageFilters{RepeatLimit: 1, RepeatWindow: 60},
+				}),
+			})
+			result, err := service.SaveMessage(context.Background(), services.SaveMessageParams{
+				SenderID:    1,
+				ReceiverID:  2,
+				Content:     "Hello",
+				ClientMsgID: "c-1",
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, original, result)
+			assert.Empty(t, published, "a retried message must not be delivered twice")
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_DeleteMessage_Blobs(t *testing.T) {
+	store, err := blobstore.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	for _, key := range []string{"orphaned", "orphaned-160", "shared"} {
+		require.NoError(t, store.Put(context.Background(), key, strings.NewReader("data"), 4, "text/plain"))
+	}
+
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetMessageByID", mock.Anything, int64(30)).Return(&models.Message{ID: 30, SenderID: 1, ReceiverID: 2}, nil)
+	mockRepo.On("DeleteMessageForEveryone", mock.Anything, repo.DeleteMessageParams{MessageID: 30, DeletedBy: 1}).
+		Return(&repo.DeletedMessage{Message: models.Message{ID: 30}, StorageKeys: []string{"orphaned", "orphaned-160", "shared"}}, nil)
+	var unlocked bool
+	mockRepo.On("LockBlobs", mock.Anything, []string{"orphaned", "orphaned", "shared"}).Return(func() { unlocked = true }, nil)
+	mockRepo.On("GetOrphanedStorageKeys", mock.Anything, []string{"orphaned", "orphaned-160", "shared"}).
+		Return([]string{"orphaned", "orphaned-160"}, nil)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Store: store, Log: discardLogger()})
+	err = service.DeleteMessage(context.Background(), services.DeleteMessageParams{MessageID: 30, UserID: 1, Scope: services.DeleteForEveryone})
+	require.NoError(t, err)
+
+	for key, kept := range map[string]bool{"orphaned": false, "orphaned-160": false, "shared": true} {
+		exists, err := store.Exists(context.Background(), key)
+		require.NoError(t, err)
+		assert.Equal(t, kept, exists, key)
+	}
+	assert.True(t, unlocked)
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"slices"
+)
+
+// maxPinnedMessages сколько сообщений можно закрепить в одной переписке
+const maxPinnedMessages = 50
+
+type PinParams struct {
+	MessageID int64
+	UserID    int64
+}
+
+func (s *messageService) PinMessage(ctx context.Context, params PinParams) error {
+	msg, members, err := s.preparePin(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	conversation := pinnedMessagesParams(*msg)
+	pin, err := s.repo.PinMessage(ctx, repo.PinParams{
+		MessageID: params.MessageID,
+		UserID:    params.UserID,
+		RoomID:    conversation.RoomID,
+		UserIDs:   conversation.UserIDs,
+		Limit:     maxPinnedMessages,
+	})
+	if errors.Is(err, repo.ErrDuplicate) {
+		return nil
+	}
+	if errors.Is(err, repo.ErrLimitReached) {
+		return fmt.Errorf("%w: at most %d messages can be pinned", ErrValidation, maxPinnedMessages)
+	}
+	if err != nil {
+		return fmt.Errorf("s.repo.PinMessage: %w", err)
+	}
+
+	pin.RoomID = msg.RoomID
+	s.publish(members, events.Event{Type: events.MessagePinned, Payload: *pin})
+
+	return nil
+}
+
+func (s *messageService) UnpinMessage(ctx context.Context, params PinParams) error {
+	msg, members, err := s.preparePin(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.repo.UnpinMessage(ctx, params.MessageID)
+	if err != nil {
+		return fmt.Errorf("s.repo.UnpinMessage: %w", err)
+	}
+
+	if removed {
+		s.publish(members, events.Event{Type: events.MessageUnpinned, Payload: models.Pin{
+			MessageID: params.MessageID,
+			RoomID:    msg.RoomID,
+			PinnedBy:  params.UserID,
+		}})
+	}
+
+	return nil
+}
+
+// preparePin проверяет право менять закрепления: в комнате это могут администраторы,
+// в личной переписке — оба участника. Возвращает сообщение и участников переписки
+func (s *messageService) preparePin(ctx context.Context, params PinParams) (*models.Message, []int64, error) {
+	msg, err := s.getMessage(ctx, params.MessageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if msg.DeletedAt != nil {
+		return nil, nil, fmt.Errorf("%w: message %d", ErrNotFound, params.MessageID)
+	}
+
+	members, err := s.conversationMembers(ctx, *msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !slices.Contains(members, params.UserID) {
+		return nil, nil, fmt.Errorf("%w: not a member of the conversation", ErrForbidden)
+	}
+
+	if msg.RoomID != 0 {
+		member, err := s.repo.GetRoomMember(ctx, msg.RoomID, params.UserID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("s.repo.GetRoomMember: %w", err)
+		}
+		if !member.IsAdmin {
+			return nil, nil, fmt.Errorf("%w: only room admins can pin messages", ErrForbidden)
+		}
+	}
+
+	return msg, members, nil
+}
+
+type GetPinnedMessagesParams struct {
+	UserID     int64
+	ReceiverID int64
+	// RoomID задаётся для комнаты; ReceiverID в этом случае не используется
+	RoomID int64
+}
+
+func (s *messageService) GetPinnedMessages(ctx context.Context, params GetPinnedMessagesParams) ([]models.PinnedMessage, error) {
+	query := repo.GetPinnedMessagesParams{RoomID: params.RoomID}
+	switch {
+	case params.RoomID != 0:
+		members, err := s.repo.GetRoomMemberIDs(ctx, params.RoomID)
+		if err != nil {
+			return nil, fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+		}
+		if !slices.Contains(members, params.UserID) {
+			return nil, fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+	case params.ReceiverID != 0:
+		query.UserIDs = [2]int64{params.UserID, params.ReceiverID}
+	default:
+		return nil, fmt.Errorf("%w: receiver_id or room_id is required", ErrValidation)
+	}
+
+	pinned, err := s.repo.GetPinnedMessages(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetPinnedMessages: %w", err)
+	}
+	return pinned, nil
+}
+
+// pinnedMessagesParams определяет переписку, к которой относится сообщение
+func pinnedMessagesParams(msg models.Message) repo.GetPinnedMessagesParams {
+	if msg.RoomID != 0 {
+		return repo.GetPinnedMessagesParams{RoomID: msg.RoomID}
+	}
+	return repo.GetPinnedMessagesParams{UserIDs: [2]int64{msg.SenderID, msg.ReceiverID}}
+}
@@ -0,0 +1,171 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+func TestMessageService_PinMessage(t *testing.T) {
+	roomMessage := &models.Message{ID: 7, SenderID: 2, RoomID: 5}
+	directMessage := &models.Message{ID: 8, SenderID: 1, ReceiverID: 2}
+
+	tests := []struct {
+		name          string
+		params        services.PinParams
+		mockBehavior  func(r *MockMessageRepo)
+		expectedError error
+		expectEvent   bool
+	}{
+		{
+			name:   "room admin",
+			params: services.PinParams{MessageID: 7, UserID: 1},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(7)).Return(roomMessage, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(1)).Return(&models.RoomMember{RoomID: 5, UserID: 1, IsAdmin: true}, nil)
+				r.On("PinMessage", mock.Anything, repo.PinParams{MessageID: 7, UserID: 1, RoomID: 5, Limit: 50}).
+					Return(&models.Pin{MessageID: 7, PinnedBy: 1, PinnedAt: time.Now()}, nil)
+			},
+			expectEvent: true,
+		},
+		{
+			name:   "room member without admin rights",
+			params: services.PinParams{MessageID: 7, UserID: 3},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(7)).Return(roomMessage, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(3)).Return(&models.RoomMember{RoomID: 5, UserID: 3}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "direct chat participant",
+			params: services.PinParams{MessageID: 8, UserID: 2},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(8)).Return(directMessage, nil)
+				r.On("PinMessage", mock.Anything, repo.PinParams{MessageID: 8, UserID: 2, UserIDs: [2]int64{1, 2}, Limit: 50}).
+					Return(&models.Pin{MessageID: 8, PinnedBy: 2, PinnedAt: time.Now()}, nil)
+			},
+			expectEvent: true,
+		},
+		{
+			name:   "outsider",
+			params: services.PinParams{MessageID: 8, UserID: 9},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(8)).Return(directMessage, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "already pinned",
+			params: services.PinParams{MessageID: 8, UserID: 1},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(8)).Return(directMessage, nil)
+				r.On("PinMessage", mock.Anything, mock.Anything).Return(nil, repo.ErrDuplicate)
+			},
+		},
+		{
+			name:   "limit reached",
+			params: services.PinParams{MessageID: 8, UserID: 1},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(8)).Return(directMessage, nil)
+				r.On("PinMessage", mock.Anything, mock.Anything).Return(nil, repo.ErrLimitReached)
+			},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:   "deleted message",
+			params: services.PinParams{MessageID: 8, UserID: 1},
+			mockBehavior: func(r *MockMessageRepo) {
+				deletedAt := time.Now()
+				r.On("GetMessageByID", mock.Anything, int64(8)).Return(&models.Message{ID: 8, SenderID: 1, ReceiverID: 2, DeletedAt: &deletedAt}, nil)
+			},
+			expectedError: services.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.mockBehavior(mockRepo)
+
+			var published []events.Event
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				published = append(published, event)
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+			err := service.PinMessage(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tt.expectEvent {
+				require.Len(t, published, 1)
+				assert.Equal(t, events.MessagePinned, published[0].Type)
+			} else {
+				assert.Empty(t, published)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_UnpinMessage(t *testing.T) {
+	for _, removed := range []bool{true, false} {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("GetMessageByID", mock.Anything, int64(8)).Return(&models.Message{ID: 8, SenderID: 1, ReceiverID: 2}, nil)
+		mockRepo.On("UnpinMessage", mock.Anything, int64(8)).Return(removed, nil)
+
+		var notified []int64
+		bus := events.NewBus()
+		bus.Subscribe(func(userIDs []int64, event events.Event) {
+			if event.Type == events.MessageUnpinned {
+				notified = userIDs
+			}
+		})
+
+		service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+		err := service.UnpinMessage(context.Background(), services.PinParams{MessageID: 8, UserID: 2})
+		require.NoError(t, err)
+
+		if removed {
+			assert.ElementsMatch(t, []int64{1, 2}, notified)
+		} else {
+			assert.Empty(t, notified)
+		}
+	}
+}
+
+func TestMessageService_GetPinnedMessages(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2}, nil)
+	mockRepo.On("GetPinnedMessages", mock.Anything, repo.GetPinnedMessagesParams{RoomID: 5}).
+		Return([]models.PinnedMessage{{Pin: models.Pin{MessageID: 7}}}, nil)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+
+	pinned, err := service.GetPinnedMessages(context.Background(), services.GetPinnedMessagesParams{UserID: 1, RoomID: 5})
+	require.NoError(t, err)
+	assert.Len(t, pinned, 1)
+
+	_, err = service.GetPinnedMessages(context.Background(), services.GetPinnedMessagesParams{UserID: 3, RoomID: 5})
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	_, err = service.GetPinnedMessages(context.Background(), services.GetPinnedMessagesParams{UserID: 1})
+	assert.ErrorIs(t, err, services.ErrValidation)
+}
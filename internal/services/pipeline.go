@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messanger/internal/apperr"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MessageStage этап обработки сообщения перед сохранением. Этап может изменить текст сообщения,
+// пометить его для модераторов или отклонить, вернув *RejectionError. Прочие ошибки прерывают отправку
+// как внутренние
+type MessageStage interface {
+	// Name имя этапа; попадает в пометки и в ошибку отклонения
+	Name() string
+	Process(ctx context.Context, msg *PipelineMessage) error
+}
+
+// PipelineMessage сообщение, проходящее через конвейер: отправляемое или новая версия редактируемого
+type PipelineMessage struct {
+	SenderID   int64
+	ReceiverID int64
+	RoomID     int64
+	// Content текстовое представление; при заданном RichContent вычисляется из него
+	Content     string
+	RichContent *models.RichContent
+	// Edit сообщение редактируется, а не отправляется
+	Edit bool
+	// Filters настройки фильтрации переписки
+	Filters models.MessageFilters
+	// Flags пометки, добавленные этапами
+	Flags []models.MessageFlag
+}
+
+// ReplaceText применяет f ко всем текстам сообщения: простому тексту, тексту с разметкой,
+// вопросу и вариантам опроса. f не должна менять число символов, иначе разметка съедет
+func (m *PipelineMessage) ReplaceText(f func(string) string) {
+	if m.RichContent == nil {
+		m.Content = f(m.Content)
+		return
+	}
+
+	m.RichContent.Text = f(m.RichContent.Text)
+	if poll := m.RichContent.Poll; poll != nil {
+		poll.Question = f(poll.Question)
+		for i := range poll.Options {
+			poll.Options[i] = f(poll.Options[i])
+		}
+	}
+	m.Content = plainText(m.RichContent)
+}
+
+// Flag помечает сообщение для модераторов
+func (m *PipelineMessage) Flag(stage, reason string) {
+	m.Flags = append(m.Flags, models.MessageFlag{Stage: stage, Reason: reason})
+}
+
+// Links возвращает ссылки сообщения без повторов: из разметки и из простого текста
+func (m *PipelineMessage) Links() []string {
+	var links []string
+	if m.RichContent != nil {
+		for _, e := range m.RichContent.Entities {
+			if e.Type == models.EntityLink && e.URL != "" {
+				links = append(links, e.URL)
+			}
+		}
+	}
+	for _, match := range plainURLRe.FindAllString(m.Content, -1) {
+		links = append(links, strings.TrimRight(match, ".,;:!?)]}"))
+	}
+	return slices.Compact(slices.Sorted(slices.Values(links)))
+}
+
+// RejectionError сообщение отклонено этапом конвейера. Отправитель получает её как структурированную ошибку
+type RejectionError = apperr.RejectionError
+
+// MessagePipeline последовательно пропускает сообщение через этапы. Первый отклонивший этап
+// останавливает обработку; изменения и пометки остальных этапов накапливаются
+type MessagePipeline struct {
+	stages   []MessageStage
+	defaults models.MessageFilters
+}
+
+type MessagePipelineConfig struct {
+	// Stages этапы в порядке выполнения; для встроенных фильтров — BuiltinStages
+	Stages []MessageStage
+	// Defaults настройки фильтрации для личных переписок и комнат без собственных настроек
+	Defaults models.MessageFilters
+}
+
+func NewMessagePipeline(cfg MessagePipelineConfig) *MessagePipeline {
+	return &MessagePipeline{
+		stages:   cfg.Stages,
+		defaults: normalizeFilters(cfg.Defaults),
+	}
+}
+
+// Run выполняет этапы над сообщением
+func (p *MessagePipeline) Run(ctx context.Context, msg *PipelineMessage) error {
+	for _, stage := range p.stages {
+		if err := stage.Process(ctx, msg); err != nil {
+			var rejection *RejectionError
+			if errors.As(err, &rejection) {
+				return rejection
+			}
+			return fmt.Errorf("stage %s: %w", stage.Name(), err)
+		}
+	}
+	return nil
+}
+
+const (
+	maxFilterWords        = 500
+	maxFilterWordLength   = 64
+	maxFilterRepeatWindow = 24 * 60 * 60
+	maxFilterLinks        = 100
+)
+
+// validateFilters проверяет настройки фильтрации, заданные администратором комнаты
+func validateFilters(filters models.MessageFilters) error {
+	if len(filters.Words) > maxFilterWords {
+		return fmt.Errorf("%w: more than %d filter words", ErrValidation, maxFilterWords)
+	}
+	for _, word := range filters.Words {
+		word = strings.TrimSpace(word)
+		if word == "" || utf8.RuneCountInString(word) > maxFilterWordLength {
+			return fmt.Errorf("%w: filter words must be 1 to %d characters long", ErrValidation, maxFilterWordLength)
+		}
+		if strings.IndexFunc(word, func(r rune) bool { return !isWordRune(r) }) >= 0 {
+			return fmt.Errorf("%w: filter word %q must consist of letters and digits", ErrValidation, word)
+		}
+	}
+
+	switch filters.WordAction {
+	case "", models.FilterActionReject, models.FilterActionMask, models.FilterActionFlag:
+	default:
+		return fmt.Errorf("%w: word_action must be reject, mask or flag", ErrValidation)
+	}
+
+	switch {
+	case filters.RepeatLimit < 0:
+		return fmt.Errorf("%w: repeat_limit must not be negative", ErrValidation)
+	case filters.RepeatWindow < 0 || filters.RepeatWindow > maxFilterRepeatWindow:
+		return fmt.Errorf("%w: repeat_window must be between 0 and %d seconds", ErrValidation, maxFilterRepeatWindow)
+	case filters.RepeatLimit > 0 && filters.RepeatWindow == 0:
+		return fmt.Errorf("%w: repeat_window is required with repeat_limit", ErrValidation)
+	case filters.MaxLinks < 0 || filters.MaxLinks > maxFilterLinks:
+		return fmt.Errorf("%w: max_links must be between 0 and %d", ErrValidation, maxFilterLinks)
+	}
+	return nil
+}
+
+// normalizeFilters приводит слова к нижнему регистру и задаёт действие по умолчанию
+func normalizeFilters(filters models.MessageFilters) models.MessageFilters {
+	words := make([]string, 0, len(filters.Words))
+	for _, word := range filters.Words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			words = append(words, word)
+		}
+	}
+	filters.Words = slices.Compact(slices.Sorted(slices.Values(words)))
+	if filters.WordAction == "" {
+		filters.WordAction = models.FilterActionReject
+	}
+	return filters
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// filterMessage пропускает сообщение через конвейер с настройками переписки
+func (s *messageService) filterMessage(ctx context.Context, msg *PipelineMessage) error {
+	if s.pipeline == nil {
+		return nil
+	}
+
+	filters, err := s.messageFilters(ctx, msg.RoomID)
+	if err != nil {
+		return err
+	}
+	msg.Filters = *filters
+
+	if err = s.pipeline.Run(ctx, msg); err != nil {
+		var rejection *RejectionError
+		if errors.As(err, &rejection) {
+			return err
+		}
+		return fmt.Errorf("s.pipeline.Run: %w", err)
+	}
+	return nil
+}
+
+// messageFilters возвращает действующие настройки фильтрации: собственные настройки комнаты
+// или настройки сервера
+func (s *messageService) messageFilters(ctx context.Context, roomID int64) (*models.MessageFilters, error) {
+	var defaults models.MessageFilters
+	if s.pipeline != nil {
+		defaults = s.pipeline.defaults
+	}
+	if roomID == 0 {
+		return &defaults, nil
+	}
+
+	filters, err := s.repo.GetMessageFilters(ctx, roomID)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, fmt.Errorf("%w: room %d", ErrNotFound, roomID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetMessageFilters: %w", err)
+	}
+	if filters == nil {
+		return &defaults, nil
+	}
+	normalized := normalizeFilters(*filters)
+	return &normalized, nil
+}
+
+type SetMessageFiltersParams struct {
+	UserID int64
+	RoomID int64
+	// Filters собственные настройки комнаты; nil возвращает комнату к настройкам сервера
+	Filters *models.MessageFilters
+}
+
+// SetMessageFilters задаёт настройки фильтрации комнаты; менять их может только администратор комнаты
+func (s *messageService) SetMessageFilters(ctx context.Context, params SetMessageFiltersParams) error {
+	if params.RoomID == 0 {
+		return fmt.Errorf("%w: room_id is required", ErrValidation)
+	}
+	if params.Filters != nil {
+		if err := validateFilters(*params.Filters); err != nil {
+			return err
+		}
+		normalized := normalizeFilters(*params.Filters)
+		params.Filters = &normalized
+	}
+
+	if err := s.requireRoomAdmin(ctx, params.RoomID, params.UserID); err != nil {
+		return err
+	}
+
+	if err := s.repo.SetMessageFilters(ctx, params.RoomID, params.Filters); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("%w: room %d", ErrNotFound, params.RoomID)
+		}
+		return fmt.Errorf("s.repo.SetMessageFilters: %w", err)
+	}
+	return nil
+}
+
+type GetMessageFiltersParams struct {
+	UserID int64
+	// RoomID комната; 0 — настройки личных переписок
+	RoomID int64
+}
+
+// GetMessageFilters возвращает действующие настройки фильтрации переписки
+func (s *messageService) GetMessageFilters(ctx context.Context, params GetMessageFiltersParams) (*models.MessageFilters, error) {
+	if params.RoomID != 0 {
+		members, err := s.repo.GetRoomMemberIDs(ctx, params.RoomID)
+		if err != nil {
+			return nil, fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+		}
+		if !slices.Contains(members, params.UserID) {
+			return nil, fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+	}
+
+	return s.messageFilters(ctx, params.RoomID)
+}
+
+type GetFlaggedMessagesParams struct {
+	UserID   int64
+	RoomID   int64
+	BeforeID int64
+	Limit    int
+}
+
+const (
+	defaultFlaggedLimit = 50
+	maxFlaggedLimit     = 100
+)
+
+// GetFlaggedMessages возвращает помеченные фильтрами сообщения комнаты; доступно администраторам комнаты
+func (s *messageService) GetFlaggedMessages(ctx context.Context, params GetFlaggedMessagesParams) ([]models.FlaggedMessage, error) {
+	if params.RoomID == 0 {
+		return nil, fmt.Errorf("%w: room_id is required", ErrValidation)
+	}
+	if params.Limit <= 0 {
+		params.Limit = defaultFlaggedLimit
+	}
+	params.Limit = min(params.Limit, maxFlaggedLimit)
+
+	if err := s.requireRoomAdmin(ctx, params.RoomID, params.UserID); err != nil {
+		return nil, err
+	}
+
+	flagged, err := s.repo.GetFlaggedMessages(ctx, repo.GetFlaggedMessagesParams{
+		RoomID:   params.RoomID,
+		BeforeID: params.BeforeID,
+		Limit:    params.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetFlaggedMessages: %w", err)
+	}
+	return flagged, nil
+}
+
+// requireRoomAdmin проверяет, что пользователь — администратор комнаты
+func (s *messageService) requireRoomAdmin(ctx context.Context, roomID, userID int64) error {
+	member, err := s.repo.GetRoomMember(ctx, roomID, userID)
+	if errors.Is(err, repo.ErrNotFound) {
+		return fmt.Errorf("%w: not a member of the room", ErrForbidden)
+	}
+	if err != nil {
+		return fmt.Errorf("s.repo.GetRoomMember: %w", err)
+	}
+	if !member.IsAdmin {
+		return fmt.Errorf("%w: only room admins can do this", ErrForbidden)
+	}
+	return nil
+}
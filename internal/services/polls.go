@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type PollParams struct {
+	MessageID int64
+	UserID    int64
+}
+
+type VoteParams struct {
+	MessageID int64
+	UserID    int64
+	// Options индексы выбранных вариантов; в опросе с одним ответом — ровно один
+	Options []int
+}
+
+// Vote принимает голос в опросе. Повторный голос заменяет прежний, только если опрос разрешает
+// менять решение; иначе возвращается ErrConflict. Участники получают обновлённые итоги событием poll.updated
+func (s *messageService) Vote(ctx context.Context, params VoteParams) (*models.PollResults, error) {
+	msg, members, err := s.preparePoll(ctx, PollParams{MessageID: params.MessageID, UserID: params.UserID})
+	if err != nil {
+		return nil, err
+	}
+	poll := msg.RichContent.Poll
+
+	if len(params.Options) == 0 {
+		return nil, fmt.Errorf("%w: at least one option is required", ErrValidation)
+	}
+	if !poll.MultipleVotes && len(params.Options) > 1 {
+		return nil, fmt.Errorf("%w: poll allows a single option", ErrValidation)
+	}
+	seen := make(map[int]struct{}, len(params.Options))
+	for _, option := range params.Options {
+		if option < 0 || option >= len(poll.Options) {
+			return nil, fmt.Errorf("%w: option %d does not exist", ErrValidation, option)
+		}
+		if _, ok := seen[option]; ok {
+			return nil, fmt.Errorf("%w: duplicate option %d", ErrValidation, option)
+		}
+		seen[option] = struct{}{}
+	}
+
+	err = s.repo.Vote(ctx, repo.VoteParams{
+		MessageID: params.MessageID,
+		UserID:    params.UserID,
+		Options:   params.Options,
+		Replace:   poll.AllowVoteChange,
+	})
+	if err != nil {
+		return nil, pollError(err, params.MessageID)
+	}
+
+	return s.publishPollResults(ctx, events.PollUpdated, msg.ID, params.UserID, members)
+}
+
+// RetractVote отзывает голос пользователя; доступно только в опросах, где решение можно менять
+func (s *messageService) RetractVote(ctx context.Context, params PollParams) (*models.PollResults, error) {
+	msg, members, err := s.preparePoll(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if !msg.RichContent.Poll.AllowVoteChange {
+		return nil, fmt.Errorf("%w: votes in this poll cannot be changed", ErrForbidden)
+	}
+
+	err = s.repo.Vote(ctx, repo.VoteParams{
+		MessageID: params.MessageID,
+		UserID:    params.UserID,
+		Replace:   true,
+	})
+	if err != nil {
+		return nil, pollError(err, params.MessageID)
+	}
+
+	return s.publishPollResults(ctx, events.PollUpdated, msg.ID, params.UserID, members)
+}
+
+// ClosePoll досрочно закрывает опрос. Это может сделать автор или администратор комнаты
+func (s *messageService) ClosePoll(ctx context.Context, params PollParams) (*models.PollResults, error) {
+	msg, members, err := s.preparePoll(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := s.canModerate(ctx, *msg, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: only the author or a room admin can close the poll", ErrForbidden)
+	}
+
+	closed, err := s.repo.ClosePoll(ctx, params.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.ClosePoll: %w", err)
+	}
+	if !closed {
+		return nil, fmt.Errorf("%w: poll is already closed", ErrConflict)
+	}
+
+	return s.publishPollResults(ctx, events.PollClosed, msg.ID, params.UserID, members)
+}
+
+func (s *messageService) GetPollResults(ctx context.Context, params PollParams) (*models.PollResults, error) {
+	msg, _, err := s.preparePoll(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return s.pollResults(ctx, msg.ID, params.UserID)
+}
+
+// preparePoll проверяет, что сообщение — доступный пользователю опрос.
+// Возвращает сообщение и участников переписки
+func (s *messageService) preparePoll(ctx context.Context, params PollParams) (*models.Message, []int64, error) {
+	msg, err := s.getMessage(ctx, params.MessageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if msg.DeletedAt != nil || (msg.ExpiresAt != nil && !msg.ExpiresAt.After(time.Now())) {
+		return nil, nil, fmt.Errorf("%w: message %d", ErrNotFound, params.MessageID)
+	}
+	if msg.RichContent == nil || msg.RichContent.Kind != models.ContentKindPoll || msg.RichContent.Poll == nil {
+		return nil, nil, fmt.Errorf("%w: message %d is not a poll", ErrValidation, params.MessageID)
+	}
+
+	members, err := s.conversationMembers(ctx, *msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !slices.Contains(members, params.UserID) {
+		return nil, nil, fmt.Errorf("%w: not a member of the conversation", ErrForbidden)
+	}
+
+	return msg, members, nil
+}
+
+func (s *messageService) pollResults(ctx context.Context, messageID, userID int64) (*models.PollResults, error) {
+	results, err := s.repo.GetPollResults(ctx, []int64{messageID}, userID)
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetPollResults: %w", err)
+	}
+	result, ok := results[messageID]
+	if !ok {
+		return nil, fmt.Errorf("%w: poll %d", ErrNotFound, messageID)
+	}
+	return result, nil
+}
+
+// publishPollResults рассылает итоги участникам и возвращает их с голосами пользователя userID
+func (s *messageService) publishPollResults(ctx context.Context, eventType string, messageID, userID int64, members []int64) (*models.PollResults, error) {
+	result, err := s.pollResults(ctx, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(members, events.Event{Type: eventType, Payload: sharedPollResults(*result)})
+	return result, nil
+}
+
+// sharedPollResults убирает из итогов голоса конкретного пользователя перед рассылкой всем участникам
+func sharedPollResults(result models.PollResults) models.PollResults {
+	result.MyVotes = nil
+	return result
+}
+
+func pollError(err error, messageID int64) error {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		return fmt.Errorf("%w: poll %d", ErrNotFound, messageID)
+	case errors.Is(err, repo.ErrPollClosed):
+		return fmt.Errorf("%w: poll is closed", ErrConflict)
+	case errors.Is(err, repo.ErrDuplicate):
+		return fmt.Errorf("%w: vote cannot be changed", ErrConflict)
+	}
+	return fmt.Errorf("s.repo.Vote: %w", err)
+}
+
+const (
+	defaultPollCloseInterval  = 5 * time.Second
+	defaultPollCloseBatchSize = 100
+)
+
+// PollCloser закрывает опросы, время которых истекло, и рассылает участникам событие poll.closed
+type PollCloser struct {
+	repo   repo.MessageRepo
+	events events.Publisher
+	log    *logrus.Logger
+
+	interval  time.Duration
+	batchSize int
+}
+
+type PollCloserConfig struct {
+	Repo   repo.MessageRepo
+	Events events.Publisher
+	Log    *logrus.Logger
+
+	Interval  time.Duration
+	BatchSize int
+}
+
+func NewPollCloser(cfg PollCloserConfig) *PollCloser {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultPollCloseInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultPollCloseBatchSize
+	}
+	return &PollCloser{
+		repo:      cfg.Repo,
+		events:    cfg.Events,
+		log:       cfg.Log,
+		interval:  cfg.Interval,
+		batchSize: cfg.BatchSize,
+	}
+}
+
+// Run закрывает истёкшие опросы с заданным интервалом до отмены контекста
+func (c *PollCloser) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			closed, err := c.close(ctx)
+			if err != nil {
+				c.log.Errorf("failed to close due polls: %v", err)
+				break
+			}
+			if closed < c.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *PollCloser) close(ctx context.Context) (int, error) {
+	ids, err := c.repo.CloseDuePolls(ctx, c.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("c.repo.CloseDuePolls: %w", err)
+	}
+	if len(ids) == 0 || c.events == nil {
+		return len(ids), nil
+	}
+
+	// Опросы уже закрыты, поэтому ошибки ниже лишь оставляют клиентов без уведомления
+	results, err := c.repo.GetPollResults(ctx, ids, 0)
+	if err != nil {
+		c.log.Errorf("failed to get results of closed polls: %v", err)
+		return len(ids), nil
+	}
+	for _, id := range ids {
+		result, ok := results[id]
+		if !ok {
+			continue
+		}
+
+		msg, err := c.repo.GetMessageByID(ctx, id)
+		if err != nil {
+			c.log.Errorf("failed to notify about closed poll %d: %v", id, err)
+			continue
+		}
+		members, err := conversationMembers(ctx, c.repo, *msg)
+		if err != nil {
+			c.log.Errorf("failed to notify about closed poll %d: %v", id, err)
+			continue
+		}
+		c.events.Publish(members, events.Event{Type: events.PollClosed, Payload: sharedPollResults(*result)})
+	}
+
+	return len(ids), nil
+}
@@ -0,0 +1,273 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+func pollMessage(id int64, poll models.PollContent) *models.Message {
+	return &models.Message{
+		ID:       id,
+		SenderID: 2,
+		RoomID:   5,
+		RichContent: &models.RichContent{
+			Kind: models.ContentKindPoll,
+			Poll: &poll,
+		},
+	}
+}
+
+func TestMessageService_Vote(t *testing.T) {
+	single := pollMessage(7, models.PollContent{Question: "Lunch?", Options: []string{"Pizza", "Sushi", "Salad"}})
+	multiple := pollMessage(8, models.PollContent{Question: "Days?", Options: []string{"Mon", "Tue"}, MultipleVotes: true, AllowVoteChange: true})
+	results := &models.PollResults{
+		MessageID:   7,
+		Options:     []models.PollOptionResult{{Option: 0}, {Option: 1, Votes: 1, Voters: []int64{1}}, {Option: 2}},
+		TotalVoters: 1,
+		MyVotes:     []int{1},
+	}
+
+	tests := []struct {
+		name          string
+		params        services.VoteParams
+		mockBehavior  func(r *MockMessageRepo)
+		expectedError error
+	}{
+		{
+			name:   "single choice",
+			params: services.VoteParams{MessageID: 7, UserID: 1, Options: []int{1}},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(7)).Return(single, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				r.On("Vote", mock.Anything, repo.VoteParams{MessageID: 7, UserID: 1, Options: []int{1}}).Return(nil)
+				r.On("GetPollResults", mock.Anything, []int64{7}, int64(1)).
+					Return(map[int64]*models.PollResults{7: results}, nil)
+			},
+		},
+		{
+			name:   "multiple choice replaces vote",
+			params: services.VoteParams{MessageID: 8, UserID: 1, Options: []int{0, 1}},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(8)).Return(multiple, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				r.On("Vote", mock.Anything, repo.VoteParams{MessageID: 8, UserID: 1, Options: []int{0, 1}, Replace: true}).Return(nil)
+				r.On("GetPollResults", mock.Anything, []int64{8}, int64(1)).
+					Return(map[int64]*models.PollResults{8: {MessageID: 8, MyVotes: []int{0, 1}}}, nil)
+			},
+		},
+		{
+			name:   "several options in single choice poll",
+			params: services.VoteParams{MessageID: 7, UserID: 1, Options: []int{0, 1}},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(7)).Return(single, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+			},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:   "unknown option",
+			params: services.VoteParams{MessageID: 7, UserID: 1, Options: []int{3}},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(7)).Return(single, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+			},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:   "not a poll",
+			params: services.VoteParams{MessageID: 9, UserID: 1, Options: []int{0}},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(9)).Return(&models.Message{ID: 9, SenderID: 2, RoomID: 5}, nil)
+			},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:   "outsider",
+			params: services.VoteParams{MessageID: 7, UserID: 9, Options: []int{0}},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(7)).Return(single, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "vote cannot be changed",
+			params: services.VoteParams{MessageID: 7, UserID: 1, Options: []int{2}},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(7)).Return(single, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				r.On("Vote", mock.Anything, mock.Anything).Return(repo.ErrDuplicate)
+			},
+			expectedError: services.ErrConflict,
+		},
+		{
+			name:   "closed poll",
+			params: services.VoteParams{MessageID: 7, UserID: 1, Options: []int{0}},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetMessageByID", mock.Anything, int64(7)).Return(single, nil)
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+				r.On("Vote", mock.Anything, mock.Anything).Return(repo.ErrPollClosed)
+			},
+			expectedError: services.ErrConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.mockBehavior(mockRepo)
+
+			var published []events.Event
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				published = append(published, event)
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+			result, err := service.Vote(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Empty(t, published)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.params.Options, result.MyVotes)
+
+				require.Len(t, published, 1)
+				assert.Equal(t, events.PollUpdated, published[0].Type)
+				assert.Nil(t, published[0].Payload.(models.PollResults).MyVotes, "personal votes are not broadcast")
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_RetractVote(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetMessageByID", mock.Anything, int64(7)).
+		Return(pollMessage(7, models.PollContent{Question: "Lunch?", Options: []string{"Pizza", "Sushi"}}), nil)
+	mockRepo.On("GetMessageByID", mock.Anything, int64(8)).
+		Return(pollMessage(8, models.PollContent{Question: "Days?", Options: []string{"Mon", "Tue"}, AllowVoteChange: true}), nil)
+	mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2}, nil)
+	mockRepo.On("Vote", mock.Anything, repo.VoteParams{MessageID: 8, UserID: 1, Replace: true}).Return(nil)
+	mockRepo.On("GetPollResults", mock.Anything, []int64{8}, int64(1)).
+		Return(map[int64]*models.PollResults{8: {MessageID: 8}}, nil)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+
+	_, err := service.RetractVote(context.Background(), services.PollParams{MessageID: 7, UserID: 1})
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	result, err := service.RetractVote(context.Background(), services.PollParams{MessageID: 8, UserID: 1})
+	require.NoError(t, err)
+	assert.Empty(t, result.MyVotes)
+}
+
+func TestMessageService_ClosePoll(t *testing.T) {
+	poll := pollMessage(7, models.PollContent{Question: "Lunch?", Options: []string{"Pizza", "Sushi"}})
+
+	tests := []struct {
+		name          string
+		userID        int64
+		closed        bool
+		expectedError error
+	}{
+		{name: "author", userID: 2, closed: true},
+		{name: "already closed", userID: 2, expectedError: services.ErrConflict},
+		{name: "member", userID: 3, expectedError: services.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			mockRepo.On("GetMessageByID", mock.Anything, int64(7)).Return(poll, nil)
+			mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+			mockRepo.On("GetRoomMember", mock.Anything, int64(5), int64(3)).Return(&models.RoomMember{RoomID: 5, UserID: 3}, nil).Maybe()
+			mockRepo.On("ClosePoll", mock.Anything, int64(7)).Return(tt.closed, nil).Maybe()
+			mockRepo.On("GetPollResults", mock.Anything, []int64{7}, tt.userID).
+				Return(map[int64]*models.PollResults{7: {MessageID: 7}}, nil).Maybe()
+
+			var published []events.Event
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				published = append(published, event)
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+			_, err := service.ClosePoll(context.Background(), services.PollParams{MessageID: 7, UserID: tt.userID})
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Empty(t, published)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, published, 1)
+			assert.Equal(t, events.PollClosed, published[0].Type)
+		})
+	}
+}
+
+func TestMessageService_SaveMessage_pollDeadline(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+
+	past := time.Now().Add(-time.Minute)
+	_, err := service.SaveMessage(context.Background(), services.SaveMessageParams{
+		SenderID: 1,
+		RoomID:   5,
+		RichContent: &models.RichContent{
+			Kind: models.ContentKindPoll,
+			Poll: &models.PollContent{Question: "Lunch?", Options: []string{"Pizza", "Sushi"}, ClosesAt: &past},
+		},
+	})
+	assert.ErrorIs(t, err, services.ErrValidation)
+	mockRepo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+}
+
+func TestPollCloser(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("CloseDuePolls", mock.Anything, 10).Return([]int64{7}, nil).Once()
+	mockRepo.On("CloseDuePolls", mock.Anything, 10).Return([]int64{}, nil)
+	mockRepo.On("GetPollResults", mock.Anything, []int64{7}, int64(0)).
+		Return(map[int64]*models.PollResults{7: {MessageID: 7, TotalVoters: 2}}, nil)
+	mockRepo.On("GetMessageByID", mock.Anything, int64(7)).
+		Return(pollMessage(7, models.PollContent{Question: "Lunch?", Options: []string{"Pizza", "Sushi"}}), nil)
+	mockRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2, 3}, nil)
+
+	closed := make(chan []int64, 1)
+	bus := events.NewBus()
+	bus.Subscribe(func(userIDs []int64, event events.Event) {
+		if event.Type == events.PollClosed {
+			closed <- userIDs
+		}
+	})
+
+	closer := services.NewPollCloser(services.PollCloserConfig{
+		Repo:      mockRepo,
+		Events:    bus,
+		Log:       discardLogger(),
+		Interval:  time.Hour,
+		BatchSize: 10,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go closer.Run(ctx)
+
+	select {
+	case userIDs := <-closed:
+		assert.Equal(t, []int64{1, 2, 3}, userIDs)
+	case <-time.After(time.Second):
+		t.Fatal("closed poll was not announced")
+	}
+}
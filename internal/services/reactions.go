@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxEmojiBytes = 32
+
+type ReactionParams struct {
+	MessageID int64
+	UserID    int64
+	Emoji     string
+}
+
+func (s *messageService) AddReaction(ctx context.Context, params ReactionParams) error {
+	members, err := s.prepareReaction(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	added, err := s.repo.AddReaction(ctx, repo.ReactionParams{
+		MessageID: params.MessageID,
+		UserID:    params.UserID,
+		Emoji:     params.Emoji,
+	})
+	if err != nil {
+		return fmt.Errorf("s.repo.AddReaction: %w", err)
+	}
+
+	if added {
+		s.publish(members, events.Event{Type: events.ReactionAdded, Payload: models.Reaction{
+			MessageID: params.MessageID,
+			UserID:    params.UserID,
+			Emoji:     params.Emoji,
+		}})
+	}
+
+	return nil
+}
+
+func (s *messageService) RemoveReaction(ctx context.Context, params ReactionParams) error {
+	members, err := s.prepareReaction(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.repo.RemoveReaction(ctx, repo.ReactionParams{
+		MessageID: params.MessageID,
+		UserID:    params.UserID,
+		Emoji:     params.Emoji,
+	})
+	if err != nil {
+		return fmt.Errorf("s.repo.RemoveReaction: %w", err)
+	}
+
+	if removed {
+		s.publish(members, events.Event{Type: events.ReactionRemoved, Payload: models.Reaction{
+			MessageID: params.MessageID,
+			UserID:    params.UserID,
+			Emoji:     params.Emoji,
+		}})
+	}
+
+	return nil
+}
+
+// prepareReaction проверяет реакцию, лимит и доступ к сообщению и возвращает участников переписки
+func (s *messageService) prepareReaction(ctx context.Context, params ReactionParams) ([]int64, error) {
+	if !validEmoji(params.Emoji) {
+		return nil, fmt.Errorf("%w: invalid emoji", ErrValidation)
+	}
+
+	if s.reactionLimiter != nil && !s.reactionLimiter.Allow(params.UserID) {
+		return nil, ErrRateLimited
+	}
+
+	msg, err := s.getMessage(ctx, params.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: message %d", ErrNotFound, params.MessageID)
+	}
+
+	members, err := s.conversationMembers(ctx, *msg)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(members, params.UserID) {
+		return nil, fmt.Errorf("%w: not a member of the conversation", ErrForbidden)
+	}
+
+	return members, nil
+}
+
+// validEmoji отсеивает пустые, слишком длинные и явно текстовые значения;
+// полноценная проверка по таблицам Unicode не нужна — достаточно, чтобы это не был обычный текст
+func validEmoji(emoji string) bool {
+	if emoji == "" || len(emoji) > maxEmojiBytes || !utf8.ValidString(emoji) {
+		return false
+	}
+
+	if strings.IndexFunc(emoji, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r) || unicode.IsLetter(r)
+	}) >= 0 {
+		return false
+	}
+
+	return strings.IndexFunc(emoji, func(r rune) bool { return r > unicode.MaxASCII }) >= 0
+}
@@ -0,0 +1,122 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+func TestMessageService_AddReaction(t *testing.T) {
+	dm := &models.Message{ID: 40, SenderID: 1, ReceiverID: 2, Content: "Hello"}
+	now := time.Now()
+	deleted := &models.Message{ID: 41, SenderID: 1, ReceiverID: 2, DeletedAt: &now}
+
+	tests := []struct {
+		name          string
+		params        services.ReactionParams
+		repoSetup     func(*MockMessageRepo)
+		expectedError error
+		expectEvent   bool
+	}{
+		{
+			name:   "new reaction",
+			params: services.ReactionParams{MessageID: 40, UserID: 2, Emoji: "👍"},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(40)).Return(dm, nil)
+				m.On("AddReaction", mock.Anything, repo.ReactionParams{MessageID: 40, UserID: 2, Emoji: "👍"}).
+					Return(true, nil)
+			},
+			expectEvent: true,
+		},
+		{
+			name:   "repeated reaction is not broadcast",
+			params: services.ReactionParams{MessageID: 40, UserID: 2, Emoji: "👍"},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(40)).Return(dm, nil)
+				m.On("AddReaction", mock.Anything, mock.Anything).Return(false, nil)
+			},
+		},
+		{
+			name:          "text instead of emoji",
+			params:        services.ReactionParams{MessageID: 40, UserID: 2, Emoji: "like"},
+			repoSetup:     func(m *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:   "outsider",
+			params: services.ReactionParams{MessageID: 40, UserID: 3, Emoji: "👍"},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(40)).Return(dm, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "deleted message",
+			params: services.ReactionParams{MessageID: 41, UserID: 2, Emoji: "👍"},
+			repoSetup: func(m *MockMessageRepo) {
+				m.On("GetMessageByID", mock.Anything, int64(41)).Return(deleted, nil)
+			},
+			expectedError: services.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.repoSetup(mockRepo)
+
+			var published []events.Event
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				assert.ElementsMatch(t, []int64{1, 2}, userIDs)
+				published = append(published, event)
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+			err := service.AddReaction(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if tt.expectEvent {
+				require.Len(t, published, 1)
+				assert.Equal(t, events.ReactionAdded, published[0].Type)
+				assert.Equal(t, models.Reaction{MessageID: 40, UserID: 2, Emoji: "👍"}, published[0].Payload)
+			} else {
+				assert.Empty(t, published)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_ReactionRateLimit(t *testing.T) {
+	dm := &models.Message{ID: 40, SenderID: 1, ReceiverID: 2, Content: "Hello"}
+
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("GetMessageByID", mock.Anything, int64(40)).Return(dm, nil)
+	mockRepo.On("AddReaction", mock.Anything, mock.Anything).Return(true, nil).Once()
+	mockRepo.On("RemoveReaction", mock.Anything, mock.Anything).Return(true, nil).Once()
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, ReactionsPerMinute: 2})
+	params := services.ReactionParams{MessageID: 40, UserID: 2, Emoji: "🔥"}
+
+	require.NoError(t, service.AddReaction(context.Background(), params))
+	require.NoError(t, service.RemoveReaction(context.Background(), params))
+	assert.ErrorIs(t, service.AddReaction(context.Background(), params), services.ErrRateLimited)
+
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+)
+
+// maxReceiptBatch ограничивает число сообщений в одном подтверждении
+const maxReceiptBatch = 500
+
+type ReceiptParams struct {
+	UserID     int64
+	MessageIDs []int64
+}
+
+// MarkDelivered фиксирует доставку сообщений получателю по подтверждению клиента
+func (s *messageService) MarkDelivered(ctx context.Context, params ReceiptParams) error {
+	return s.updateReceipts(ctx, params, models.ReceiptDelivered)
+}
+
+// MarkRead фиксирует прочтение сообщений получателем; прочитанные упоминания уходят из входящих
+func (s *messageService) MarkRead(ctx context.Context, params ReceiptParams) error {
+	if err := s.updateReceipts(ctx, params, models.ReceiptRead); err != nil {
+		return err
+	}
+	if len(params.MessageIDs) == 0 {
+		return nil
+	}
+
+	if err := s.repo.MarkMentionsRead(ctx, params.UserID, params.MessageIDs); err != nil {
+		return fmt.Errorf("s.repo.MarkMentionsRead: %w", err)
+	}
+	return nil
+}
+
+func (s *messageService) updateReceipts(ctx context.Context, params ReceiptParams, status string) error {
+	if len(params.MessageIDs) == 0 {
+		return nil
+	}
+	if len(params.MessageIDs) > maxReceiptBatch {
+		return fmt.Errorf("%w: too many messages in one receipt, max %d", ErrValidation, maxReceiptBatch)
+	}
+
+	receipts, err := s.repo.UpdateReceipts(ctx, repo.UpdateReceiptsParams{
+		UserID:     params.UserID,
+		MessageIDs: params.MessageIDs,
+		Status:     status,
+	})
+	if err != nil {
+		return fmt.Errorf("s.repo.UpdateReceipts: %w", err)
+	}
+
+	// Отправитель узнаёт об изменении статуса каждого своего сообщения
+	for _, receipt := range receipts {
+		s.publish([]int64{receipt.SenderID}, events.Event{Type: events.MessageReceipt, Payload: receipt})
+	}
+
+	return nil
+}
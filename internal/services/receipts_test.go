@@ -0,0 +1,84 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+func TestMessageService_MarkRead(t *testing.T) {
+	now := time.Now()
+	receipts := []models.Receipt{
+		{MessageID: 1, SenderID: 1, UserID: 3, Status: models.ReceiptRead, At: now},
+		{MessageID: 2, SenderID: 2, UserID: 3, Status: models.ReceiptRead, At: now},
+	}
+
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("UpdateReceipts", mock.Anything, repo.UpdateReceiptsParams{
+		UserID:     3,
+		MessageIDs: []int64{1, 2},
+		Status:     models.ReceiptRead,
+	}).Return(receipts, nil)
+	mockRepo.On("MarkMentionsRead", mock.Anything, int64(3), []int64{1, 2}).Return(nil)
+
+	notified := map[int64][]events.Event{}
+	bus := events.NewBus()
+	bus.Subscribe(func(userIDs []int64, event events.Event) {
+		for _, id := range userIDs {
+			notified[id] = append(notified[id], event)
+		}
+	})
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+	err := service.MarkRead(context.Background(), services.ReceiptParams{UserID: 3, MessageIDs: []int64{1, 2}})
+	require.NoError(t, err)
+
+	require.Len(t, notified[1], 1)
+	assert.Equal(t, events.Event{Type: events.MessageReceipt, Payload: receipts[0]}, notified[1][0])
+	require.Len(t, notified[2], 1)
+	assert.Equal(t, events.Event{Type: events.MessageReceipt, Payload: receipts[1]}, notified[2][0])
+	assert.Empty(t, notified[3], "the reader is not notified about own receipts")
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMessageService_MarkDelivered(t *testing.T) {
+	t.Run("nothing to acknowledge", func(t *testing.T) {
+		mockRepo := new(MockMessageRepo)
+
+		service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+		require.NoError(t, service.MarkDelivered(context.Background(), services.ReceiptParams{UserID: 3}))
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("already delivered", func(t *testing.T) {
+		mockRepo := new(MockMessageRepo)
+		mockRepo.On("UpdateReceipts", mock.Anything, repo.UpdateReceiptsParams{
+			UserID:     3,
+			MessageIDs: []int64{1},
+			Status:     models.ReceiptDelivered,
+		}).Return([]models.Receipt{}, nil)
+
+		var published []events.Event
+		bus := events.NewBus()
+		bus.Subscribe(func(userIDs []int64, event events.Event) {
+			published = append(published, event)
+		})
+
+		service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+		require.NoError(t, service.MarkDelivered(context.Background(), services.ReceiptParams{UserID: 3, MessageIDs: []int64{1}}))
+		assert.Empty(t, published)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/pkg/blobstore"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxRetentionDays верхняя граница срока хранения, которую можно задать переписке
+const maxRetentionDays = 3650
+
+func validateRetention(policy models.RetentionPolicy) error {
+	for name, days := range map[string]*int{
+		"deleted_retention_days": policy.DeletedDays,
+		"message_retention_days": policy.MessageDays,
+	} {
+		if days != nil && (*days < 0 || *days > maxRetentionDays) {
+			return fmt.Errorf("%w: %s must be between 0 and %d", ErrValidation, name, maxRetentionDays)
+		}
+	}
+	return nil
+}
+
+type SetRetentionParams struct {
+	UserID     int64
+	ReceiverID int64
+	// RoomID задаётся для комнаты; ReceiverID в этом случае не используется
+	RoomID int64
+	// Policy сроки хранения; LegalHold не используется — его меняет только SetLegalHold
+	Policy models.RetentionPolicy
+}
+
+// SetRetention задаёт сроки хранения переписки вместо глобальных. В комнате это может сделать
+// только администратор, в личной переписке — любой из участников
+func (s *messageService) SetRetention(ctx context.Context, params SetRetentionParams) error {
+	if err := validateRetention(params.Policy); err != nil {
+		return err
+	}
+
+	target := repo.RetentionParams{RoomID: params.RoomID}
+	switch {
+	case params.RoomID != 0:
+		member, err := s.repo.GetRoomMember(ctx, params.RoomID, params.UserID)
+		if errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+		if err != nil {
+			return fmt.Errorf("s.repo.GetRoomMember: %w", err)
+		}
+		if !member.IsAdmin {
+			return fmt.Errorf("%w: only room admins can change retention", ErrForbidden)
+		}
+	case params.ReceiverID != 0:
+		target.UserIDs = [2]int64{params.UserID, params.ReceiverID}
+	default:
+		return fmt.Errorf("%w: receiver_id or room_id is required", ErrValidation)
+	}
+
+	if err := s.repo.SetRetention(ctx, target, params.Policy); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("%w: room %d", ErrNotFound, params.RoomID)
+		}
+		return fmt.Errorf("s.repo.SetRetention: %w", err)
+	}
+	return nil
+}
+
+type GetRetentionParams struct {
+	UserID     int64
+	ReceiverID int64
+	// RoomID задаётся для комнаты; ReceiverID в этом случае не используется
+	RoomID int64
+}
+
+// GetRetention возвращает собственные настройки переписки; nil в сроках означает глобальное значение
+func (s *messageService) GetRetention(ctx context.Context, params GetRetentionParams) (*models.RetentionPolicy, error) {
+	target := repo.RetentionParams{RoomID: params.RoomID}
+	switch {
+	case params.RoomID != 0:
+		members, err := s.repo.GetRoomMemberIDs(ctx, params.RoomID)
+		if err != nil {
+			return nil, fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+		}
+		if !slices.Contains(members, params.UserID) {
+			return nil, fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+	case params.ReceiverID != 0:
+		target.UserIDs = [2]int64{params.UserID, params.ReceiverID}
+	default:
+		return nil, fmt.Errorf("%w: receiver_id or room_id is required", ErrValidation)
+	}
+
+	policy, err := s.repo.GetRetention(ctx, target)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, fmt.Errorf("%w: room %d", ErrNotFound, params.RoomID)
+		}
+		return nil, fmt.Errorf("s.repo.GetRetention: %w", err)
+	}
+	return policy, nil
+}
+
+type SetLegalHoldParams struct {
+	// RoomID задаётся для комнаты; иначе hold относится к личной переписке UserIDs
+	RoomID  int64
+	UserIDs [2]int64
+	Hold    bool
+}
+
+// SetLegalHold исключает переписку из очистки по срокам хранения. Права не проверяются:
+// вызывать метод может только администратор сервиса
+func (s *messageService) SetLegalHold(ctx context.Context, params SetLegalHoldParams) error {
+	target := repo.RetentionParams{RoomID: params.RoomID}
+	if params.RoomID == 0 {
+		if params.UserIDs[0] <= 0 || params.UserIDs[1] <= 0 {
+			return fmt.Errorf("%w: room_id or two user_ids are required", ErrValidation)
+		}
+		target.UserIDs = params.UserIDs
+	}
+
+	if err := s.repo.SetLegalHold(ctx, target, params.Hold); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("%w: room %d", ErrNotFound, params.RoomID)
+		}
+		return fmt.Errorf("s.repo.SetLegalHold: %w", err)
+	}
+	return nil
+}
+
+const (
+	defaultRetentionInterval  = time.Minute
+	defaultRetentionBatchSize = 500
+)
+
+// RetentionPurger окончательно удаляет сообщения, срок хранения которых истёк: удалённые — через DeletedDays
+// после удаления, любые — через MessageDays после отправки. Сообщения удаляются небольшими пачками
+// в отдельных транзакциях, чтобы не блокировать таблицу; переписки под legal hold пропускаются
+type RetentionPurger struct {
+	deps purgeDeps
+
+	interval    time.Duration
+	batchSize   int
+	deletedDays int
+	messageDays int
+}
+
+type RetentionPurgerConfig struct {
+	Repo   repo.MessageRepo
+	Store  blobstore.BlobStore
+	Events events.Publisher
+	Log    *logrus.Logger
+
+	Interval  time.Duration
+	BatchSize int
+	// DeletedDays глобальный срок хранения удалённых сообщений; 0 — бессрочно
+	DeletedDays int
+	// MessageDays глобальный срок хранения всех сообщений; 0 — бессрочно
+	MessageDays int
+}
+
+func NewRetentionPurger(cfg RetentionPurgerConfig) *RetentionPurger {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultRetentionInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultRetentionBatchSize
+	}
+	return &RetentionPurger{
+		deps: purgeDeps{
+			repo:   cfg.Repo,
+			store:  cfg.Store,
+			events: cfg.Events,
+			log:    cfg.Log,
+		},
+		interval:    cfg.Interval,
+		batchSize:   cfg.BatchSize,
+		deletedDays: cfg.DeletedDays,
+		messageDays: cfg.MessageDays,
+	}
+}
+
+// Run удаляет сообщения с истёкшим сроком хранения с заданным интервалом до отмены контекста
+func (p *RetentionPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			purged, err := p.purge(ctx)
+			if err != nil {
+				p.deps.log.Errorf("failed to purge retained messages: %v", err)
+				break
+			}
+			if purged < p.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *RetentionPurger) purge(ctx context.Context) (int, error) {
+	result, err := p.deps.repo.PurgeRetainedMessages(ctx, repo.PurgeRetainedParams{
+		DeletedDays: p.deletedDays,
+		MessageDays: p.messageDays,
+		Limit:       p.batchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("p.repo.PurgeRetainedMessages: %w", err)
+	}
+
+	releasePurged(ctx, p.deps, result)
+	return len(result.Messages), nil
+}
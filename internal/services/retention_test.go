@@ -0,0 +1,147 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+	"messanger/pkg/blobstore"
+)
+
+func days(n int) *int {
+	return &n
+}
+
+func TestMessageService_SetRetention(t *testing.T) {
+	policy := models.RetentionPolicy{DeletedDays: days(7), MessageDays: days(0)}
+
+	tests := []struct {
+		name          string
+		params        services.SetRetentionParams
+		mockBehavior  func(r *MockMessageRepo)
+		expectedError error
+	}{
+		{
+			name:   "room admin",
+			params: services.SetRetentionParams{UserID: 1, RoomID: 5, Policy: policy},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(1)).Return(&models.RoomMember{RoomID: 5, UserID: 1, IsAdmin: true}, nil)
+				r.On("SetRetention", mock.Anything, repo.RetentionParams{RoomID: 5}, policy).Return(nil)
+			},
+		},
+		{
+			name:   "room member without admin rights",
+			params: services.SetRetentionParams{UserID: 3, RoomID: 5, Policy: policy},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMember", mock.Anything, int64(5), int64(3)).Return(&models.RoomMember{RoomID: 5, UserID: 3}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:   "direct chat participant",
+			params: services.SetRetentionParams{UserID: 1, ReceiverID: 2, Policy: models.RetentionPolicy{DeletedDays: days(1)}},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("SetRetention", mock.Anything, repo.RetentionParams{UserIDs: [2]int64{1, 2}}, models.RetentionPolicy{DeletedDays: days(1)}).Return(nil)
+			},
+		},
+		{
+			name:          "negative days",
+			params:        services.SetRetentionParams{UserID: 1, ReceiverID: 2, Policy: models.RetentionPolicy{MessageDays: days(-1)}},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "too long",
+			params:        services.SetRetentionParams{UserID: 1, ReceiverID: 2, Policy: models.RetentionPolicy{DeletedDays: days(5000)}},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "no conversation",
+			params:        services.SetRetentionParams{UserID: 1, Policy: policy},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.mockBehavior(mockRepo)
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+			err := service.SetRetention(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				mockRepo.AssertNotCalled(t, "SetRetention", mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_SetLegalHold(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	mockRepo.On("SetLegalHold", mock.Anything, repo.RetentionParams{RoomID: 5}, true).Return(nil)
+	mockRepo.On("SetLegalHold", mock.Anything, repo.RetentionParams{RoomID: 6}, true).Return(repo.ErrNotFound)
+	mockRepo.On("SetLegalHold", mock.Anything, repo.RetentionParams{UserIDs: [2]int64{1, 2}}, false).Return(nil)
+
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo})
+
+	assert.NoError(t, service.SetLegalHold(context.Background(), services.SetLegalHoldParams{RoomID: 5, Hold: true}))
+	assert.ErrorIs(t, service.SetLegalHold(context.Background(), services.SetLegalHoldParams{RoomID: 6, Hold: true}), services.ErrNotFound)
+	assert.NoError(t, service.SetLegalHold(context.Background(), services.SetLegalHoldParams{UserIDs: [2]int64{1, 2}}))
+	assert.ErrorIs(t, service.SetLegalHold(context.Background(), services.SetLegalHoldParams{UserIDs: [2]int64{1, 0}}), services.ErrValidation)
+}
+
+func TestRetentionPurger(t *testing.T) {
+	mockRepo := new(MockMessageRepo)
+	params := repo.PurgeRetainedParams{DeletedDays: 30, MessageDays: 365, Limit: 1}
+	mockRepo.On("PurgeRetainedMessages", mock.Anything, params).Return(&repo.PurgeResult{
+		Messages: []models.Message{{ID: 1, SenderID: 1, ReceiverID: 2}},
+	}, nil).Once()
+	mockRepo.On("PurgeRetainedMessages", mock.Anything, params).Return(&repo.PurgeResult{}, nil)
+
+	store, err := blobstore.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	purged := make(chan []int64, 1)
+	bus := events.NewBus()
+	bus.Subscribe(func(userIDs []int64, event events.Event) {
+		if event.Type == events.MessageExpired {
+			purged <- userIDs
+		}
+	})
+
+	purger := services.NewRetentionPurger(services.RetentionPurgerConfig{
+		Repo:        mockRepo,
+		Store:       store,
+		Events:      bus,
+		Log:         discardLogger(),
+		Interval:    time.Hour,
+		BatchSize:   1,
+		DeletedDays: 30,
+		MessageDays: 365,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go purger.Run(ctx)
+
+	select {
+	case userIDs := <-purged:
+		assert.Equal(t, []int64{1, 2}, userIDs)
+	case <-time.After(time.Second):
+		t.Fatal("purged message was not announced")
+	}
+}
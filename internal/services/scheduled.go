@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ScheduledService interface {
+	ScheduleMessage(ctx context.Context, params ScheduleMessageParams) (*models.ScheduledMessage, error)
+	GetScheduledMessages(ctx context.Context, userID int64) ([]models.ScheduledMessage, error)
+	EditScheduledMessage(ctx context.Context, params EditScheduledMessageParams) (*models.ScheduledMessage, error)
+	CancelScheduledMessage(ctx context.Context, params CancelScheduledMessageParams) error
+}
+
+// maxScheduleAhead насколько далеко вперёд можно запланировать отправку
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+type scheduledService struct {
+	repo     repo.ScheduledMessageRepo
+	messages repo.MessageRepo
+}
+
+func NewScheduledService(scheduled repo.ScheduledMessageRepo, messages repo.MessageRepo) ScheduledService {
+	return &scheduledService{repo: scheduled, messages: messages}
+}
+
+type ScheduleMessageParams struct {
+	SenderID   int64
+	ReceiverID int64
+	// RoomID задаётся для сообщений в комнату; ReceiverID в этом случае не используется
+	RoomID        int64
+	Content       string
+	RichContent   *models.RichContent
+	AttachmentIDs []int64
+	SendAt        time.Time
+}
+
+func (s *scheduledService) ScheduleMessage(ctx context.Context, params ScheduleMessageParams) (*models.ScheduledMessage, error) {
+	if err := validateTarget(params.SenderID, params.ReceiverID, params.RoomID); err != nil {
+		return nil, err
+	}
+	if params.RoomID != 0 {
+		params.ReceiverID = 0
+
+		members, err := s.messages.GetRoomMemberIDs(ctx, params.RoomID)
+		if err != nil {
+			return nil, fmt.Errorf("s.messages.GetRoomMemberIDs: %w", err)
+		}
+		if !slices.Contains(members, params.SenderID) {
+			return nil, fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+	}
+
+	content, err := scheduledContent(params.Content, params.RichContent, params.AttachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateSendAt(params.SendAt); err != nil {
+		return nil, err
+	}
+	if len(params.AttachmentIDs) > 0 {
+		params.AttachmentIDs = slices.Compact(slices.Sorted(slices.Values(params.AttachmentIDs)))
+	}
+
+	scheduled, err := s.repo.CreateScheduledMessage(ctx, repo.CreateScheduledMessageParams{
+		SenderID:      params.SenderID,
+		ReceiverID:    params.ReceiverID,
+		RoomID:        params.RoomID,
+		Content:       content,
+		RichContent:   params.RichContent,
+		AttachmentIDs: params.AttachmentIDs,
+		SendAt:        params.SendAt.UTC(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.CreateScheduledMessage: %w", err)
+	}
+
+	return scheduled, nil
+}
+
+func (s *scheduledService) GetScheduledMessages(ctx context.Context, userID int64) ([]models.ScheduledMessage, error) {
+	scheduled, err := s.repo.GetScheduledMessages(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetScheduledMessages: %w", err)
+	}
+	return scheduled, nil
+}
+
+type EditScheduledMessageParams struct {
+	ID     int64
+	UserID int64
+	// Content и RichContent заменяют содержимое; если оба пусты, содержимое не меняется
+	Content     string
+	RichContent *models.RichContent
+	// SendAt новое время отправки; nil оставляет прежнее
+	SendAt *time.Time
+}
+
+func (s *scheduledService) EditScheduledMessage(ctx context.Context, params EditScheduledMessageParams) (*models.ScheduledMessage, error) {
+	scheduled, err := s.getOwn(ctx, params.ID, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := repo.UpdateScheduledMessageParams{
+		ID:          params.ID,
+		Content:     scheduled.Content,
+		RichContent: scheduled.RichContent,
+		SendAt:      scheduled.SendAt,
+	}
+	if params.Content != "" || params.RichContent != nil {
+		update.Content, err = scheduledContent(params.Content, params.RichContent, scheduled.AttachmentIDs)
+		if err != nil {
+			return nil, err
+		}
+		update.RichContent = params.RichContent
+	}
+	if params.SendAt != nil {
+		update.SendAt = params.SendAt.UTC()
+	}
+	// Неудачно отправленное сообщение после правки снова ставится в очередь, поэтому время проверяется всегда
+	if err = validateSendAt(update.SendAt); err != nil {
+		return nil, err
+	}
+
+	edited, err := s.repo.UpdateScheduledMessage(ctx, update)
+	if err != nil {
+		return nil, scheduledRepoError("s.repo.UpdateScheduledMessage", params.ID, err)
+	}
+	return edited, nil
+}
+
+type CancelScheduledMessageParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (s *scheduledService) CancelScheduledMessage(ctx context.Context, params CancelScheduledMessageParams) error {
+	if _, err := s.getOwn(ctx, params.ID, params.UserID); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteScheduledMessage(ctx, params.ID); err != nil {
+		return scheduledRepoError("s.repo.DeleteScheduledMessage", params.ID, err)
+	}
+	return nil
+}
+
+// getOwn возвращает отложенное сообщение пользователя; чужие сообщения неотличимы от несуществующих
+func (s *scheduledService) getOwn(ctx context.Context, id, userID int64) (*models.ScheduledMessage, error) {
+	scheduled, err := s.repo.GetScheduledMessage(ctx, id)
+	if errors.Is(err, repo.ErrNotFound) || (err == nil && scheduled.SenderID != userID) {
+		return nil, fmt.Errorf("%w: scheduled message %d", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetScheduledMessage: %w", err)
+	}
+	return scheduled, nil
+}
+
+func scheduledRepoError(op string, id int64, err error) error {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		// Сообщение уже отправлено или отменено
+		return fmt.Errorf("%w: scheduled message %d", ErrNotFound, id)
+	case errors.Is(err, repo.ErrLocked):
+		return fmt.Errorf("%w: scheduled message %d is being sent", ErrConflict, id)
+	default:
+		return fmt.Errorf("%s: %w", op, err)
+	}
+}
+
+// scheduledContent проверяет содержимое отложенного сообщения и возвращает его текстовое представление
+func scheduledContent(content string, richContent *models.RichContent, attachmentIDs []int64) (string, error) {
+	attachments := len(attachmentIDs)
+	if richContent != nil {
+		if err := validateContent(richContent); err != nil {
+			return "", err
+		}
+		content = plainText(richContent)
+		attachments += len(richContent.Attachments)
+	} else {
+		var err error
+		if content, err = normalizeText(content); err != nil {
+			return "", err
+		}
+	}
+	if strings.TrimSpace(content) == "" && attachments == 0 {
+		return "", fmt.Errorf("%w: message is empty", ErrValidation)
+	}
+	if attachments > maxMessageAttachments {
+		return "", fmt.Errorf("%w: more than %d attachments", ErrValidation, maxMessageAttachments)
+	}
+	return content, nil
+}
+
+func validateSendAt(sendAt time.Time) error {
+	now := time.Now()
+	if !sendAt.After(now) {
+		return fmt.Errorf("%w: send_at must be in the future", ErrValidation)
+	}
+	if sendAt.After(now.Add(maxScheduleAhead)) {
+		return fmt.Errorf("%w: send_at must be within a year", ErrValidation)
+	}
+	return nil
+}
+
+const (
+	defaultScheduledInterval    = 5 * time.Second
+	defaultScheduledBatchSize   = 50
+	defaultScheduledLease       = time.Minute
+	defaultScheduledMaxAttempts = 5
+)
+
+// ScheduledSender отправляет наступившие отложенные сообщения через MessageService,
+// поэтому они проходят те же проверки и рассылаются так же, как обычные.
+// Несколько экземпляров могут работать одновременно: каждое сообщение арендуется одним из них,
+// а повтор после сбоя не создаёт дубликат благодаря ключу идемпотентности
+type ScheduledSender struct {
+	repo     repo.ScheduledMessageRepo
+	messages MessageService
+	log      *logrus.Logger
+
+	interval    time.Duration
+	batchSize   int
+	lease       time.Duration
+	maxAttempts int
+}
+
+type ScheduledSenderConfig struct {
+	Repo     repo.ScheduledMessageRepo
+	Messages MessageService
+	Log      *logrus.Logger
+
+	// Interval как часто проверяется очередь
+	Interval  time.Duration
+	BatchSize int
+	// Lease на сколько сообщение закрепляется за экземпляром; после сбоя оно будет повторено не раньше
+	Lease time.Duration
+	// MaxAttempts после скольких неудачных попыток из-за временных ошибок сообщение помечается как failed
+	MaxAttempts int
+}
+
+func NewScheduledSender(cfg ScheduledSenderConfig) *ScheduledSender {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultScheduledInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultScheduledBatchSize
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = defaultScheduledLease
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultScheduledMaxAttempts
+	}
+	return &ScheduledSender{
+		repo:        cfg.Repo,
+		messages:    cfg.Messages,
+		log:         cfg.Log,
+		interval:    cfg.Interval,
+		batchSize:   cfg.BatchSize,
+		lease:       cfg.Lease,
+		maxAttempts: cfg.MaxAttempts,
+	}
+}
+
+// Run проверяет очередь с заданным интервалом до отмены контекста
+func (s *ScheduledSender) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ScheduledSender) sendDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed, err := s.repo.ClaimDueScheduledMessages(ctx, repo.ClaimScheduledMessagesParams{
+			Limit: s.batchSize,
+			Lease: s.lease,
+		})
+		if err != nil {
+			s.log.Errorf("s.repo.ClaimDueScheduledMessages: %v", err)
+			return
+		}
+
+		for _, msg := range claimed {
+			if err = s.send(ctx, msg); err != nil {
+				s.log.Errorf("failed to send scheduled message %d: %v", msg.ID, err)
+			}
+		}
+
+		if len(claimed) < s.batchSize {
+			return
+		}
+	}
+}
+
+func (s *ScheduledSender) send(ctx context.Context, msg repo.ClaimedScheduledMessage) error {
+	_, err := s.messages.SaveMessage(ctx, SaveMessageParams{
+		SenderID:      msg.SenderID,
+		ReceiverID:    msg.ReceiverID,
+		RoomID:        msg.RoomID,
+		Content:       msg.Content,
+		RichContent:   msg.RichContent,
+		ClientMsgID:   fmt.Sprintf("scheduled:%d", msg.ID),
+		AttachmentIDs: msg.AttachmentIDs,
+		// Черновик мог появиться уже после планирования и к этому сообщению не относится
+		KeepDraft: true,
+	})
+	switch {
+	case err == nil:
+		if err = s.repo.CompleteScheduledMessage(ctx, msg.ID); err != nil {
+			return fmt.Errorf("s.repo.CompleteScheduledMessage: %w", err)
+		}
+		return nil
+	case errors.Is(err, ErrValidation), errors.Is(err, ErrForbidden), errors.Is(err, ErrNotFound):
+		// Повтор не поможет: например, отправитель покинул комнату или вложение недоступно
+		return s.fail(ctx, msg.ID, err.Error())
+	case msg.Attempts >= s.maxAttempts:
+		if failErr := s.fail(ctx, msg.ID, "failed to send the message, please try again"); failErr != nil {
+			return failErr
+		}
+		return err
+	default:
+		// Аренда истечёт, и сообщение будет отправлено повторно
+		return err
+	}
+}
+
+func (s *ScheduledSender) fail(ctx context.Context, id int64, reason string) error {
+	if err := s.repo.FailScheduledMessage(ctx, id, reason); err != nil {
+		return fmt.Errorf("s.repo.FailScheduledMessage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+// MockScheduledMessageRepo реализует интерфейс repo.ScheduledMessageRepo для тестов
+type MockScheduledMessageRepo struct {
+	mock.Mock
+}
+
+func (m *MockScheduledMessageRepo) CreateScheduledMessage(ctx context.Context, params repo.CreateScheduledMessageParams) (*models.ScheduledMessage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledMessageRepo) GetScheduledMessage(ctx context.Context, id int64) (*models.ScheduledMessage, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledMessageRepo) GetScheduledMessages(ctx context.Context, senderID int64) ([]models.ScheduledMessage, error) {
+	args := m.Called(ctx, senderID)
+	return args.Get(0).([]models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledMessageRepo) UpdateScheduledMessage(ctx context.Context, params repo.UpdateScheduledMessageParams) (*models.ScheduledMessage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledMessageRepo) DeleteScheduledMessage(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockScheduledMessageRepo) ClaimDueScheduledMessages(ctx context.Context, params repo.ClaimScheduledMessagesParams) ([]repo.ClaimedScheduledMessage, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]repo.ClaimedScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledMessageRepo) CompleteScheduledMessage(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockScheduledMessageRepo) FailScheduledMessage(ctx context.Context, id int64, reason string) error {
+	args := m.Called(ctx, id, reason)
+	return args.Error(0)
+}
+
+func TestScheduledService_ScheduleMessage(t *testing.T) {
+	sendAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		params        services.ScheduleMessageParams
+		repoSetup     func(*MockScheduledMessageRepo, *MockMessageRepo)
+		expectedError error
+	}{
+		{
+			name:   "direct message",
+			params: services.ScheduleMessageParams{SenderID: 1, ReceiverID: 2, Content: "hi", SendAt: sendAt},
+			repoSetup: func(r *MockScheduledMessageRepo, _ *MockMessageRepo) {
+				r.On("CreateScheduledMessage", mock.Anything, repo.CreateScheduledMessageParams{
+					SenderID: 1, ReceiverID: 2, Content: "hi", SendAt: sendAt.UTC(),
+				}).Return(&models.ScheduledMessage{ID: 1}, nil)
+			},
+		},
+		{
+			name: "rich content is rendered to text",
+			params: services.ScheduleMessageParams{SenderID: 1, RoomID: 5, SendAt: sendAt, RichContent: &models.RichContent{
+				Kind: models.ContentKindText, Text: "release notes",
+			}},
+			repoSetup: func(r *MockScheduledMessageRepo, m *MockMessageRepo) {
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2}, nil)
+				r.On("CreateScheduledMessage", mock.Anything, mock.MatchedBy(func(p repo.CreateScheduledMessageParams) bool {
+					return p.RoomID == 5 && p.Content == "release notes" && p.RichContent != nil
+				})).Return(&models.ScheduledMessage{ID: 1}, nil)
+			},
+		},
+		{
+			name:   "not a member of the room",
+			params: services.ScheduleMessageParams{SenderID: 9, RoomID: 5, Content: "hi", SendAt: sendAt},
+			repoSetup: func(_ *MockScheduledMessageRepo, m *MockMessageRepo) {
+				m.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 2}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:          "send_at in the past",
+			params:        services.ScheduleMessageParams{SenderID: 1, ReceiverID: 2, Content: "hi", SendAt: time.Now().Add(-time.Minute)},
+			repoSetup:     func(*MockScheduledMessageRepo, *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "send_at too far ahead",
+			params:        services.ScheduleMessageParams{SenderID: 1, ReceiverID: 2, Content: "hi", SendAt: time.Now().AddDate(2, 0, 0)},
+			repoSetup:     func(*MockScheduledMessageRepo, *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "empty message",
+			params:        services.ScheduleMessageParams{SenderID: 1, ReceiverID: 2, Content: "  ", SendAt: sendAt},
+			repoSetup:     func(*MockScheduledMessageRepo, *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "no recipient",
+			params:        services.ScheduleMessageParams{SenderID: 1, Content: "hi", SendAt: sendAt},
+			repoSetup:     func(*MockScheduledMessageRepo, *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduledRepo := new(MockScheduledMessageRepo)
+			messageRepo := new(MockMessageRepo)
+			tt.repoSetup(scheduledRepo, messageRepo)
+
+			service := services.NewScheduledService(scheduledRepo, messageRepo)
+			_, err := service.ScheduleMessage(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			scheduledRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScheduledService_EditAndCancel(t *testing.T) {
+	sendAt := time.Now().Add(time.Hour).UTC()
+	existing := &models.ScheduledMessage{ID: 3, SenderID: 1, ReceiverID: 2, Content: "old", SendAt: sendAt, Status: models.ScheduledFailed}
+
+	t.Run("content is kept when only send_at changes", func(t *testing.T) {
+		later := sendAt.Add(time.Hour)
+		scheduledRepo := new(MockScheduledMessageRepo)
+		scheduledRepo.On("GetScheduledMessage", mock.Anything, int64(3)).Return(existing, nil)
+		scheduledRepo.On("UpdateScheduledMessage", mock.Anything, repo.UpdateScheduledMessageParams{
+			ID: 3, Content: "old", SendAt: later,
+		}).Return(&models.ScheduledMessage{ID: 3}, nil)
+
+		service := services.NewScheduledService(scheduledRepo, new(MockMessageRepo))
+		_, err := service.EditScheduledMessage(context.Background(), services.EditScheduledMessageParams{ID: 3, UserID: 1, SendAt: &later})
+		require.NoError(t, err)
+		scheduledRepo.AssertExpectations(t)
+	})
+
+	t.Run("other users cannot see the message", func(t *testing.T) {
+		scheduledRepo := new(MockScheduledMessageRepo)
+		scheduledRepo.On("GetScheduledMessage", mock.Anything, int64(3)).Return(existing, nil)
+
+		service := services.NewScheduledService(scheduledRepo, new(MockMessageRepo))
+		_, err := service.EditScheduledMessage(context.Background(), services.EditScheduledMessageParams{ID: 3, UserID: 2, Content: "new"})
+		assert.ErrorIs(t, err, services.ErrNotFound)
+		err = service.CancelScheduledMessage(context.Background(), services.CancelScheduledMessageParams{ID: 3, UserID: 2})
+		assert.ErrorIs(t, err, services.ErrNotFound)
+		scheduledRepo.AssertNotCalled(t, "DeleteScheduledMessage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("message that is being sent cannot be cancelled", func(t *testing.T) {
+		scheduledRepo := new(MockScheduledMessageRepo)
+		scheduledRepo.On("GetScheduledMessage", mock.Anything, int64(3)).Return(existing, nil)
+		scheduledRepo.On("DeleteScheduledMessage", mock.Anything, int64(3)).Return(repo.ErrLocked)
+
+		service := services.NewScheduledService(scheduledRepo, new(MockMessageRepo))
+		err := service.CancelScheduledMessage(context.Background(), services.CancelScheduledMessageParams{ID: 3, UserID: 1})
+		assert.ErrorIs(t, err, services.ErrConflict)
+	})
+}
+
+func TestScheduledSender(t *testing.T) {
+	due := []repo.ClaimedScheduledMessage{
+		{ScheduledMessage: models.ScheduledMessage{ID: 1, SenderID: 1, ReceiverID: 2, Content: "sent"}, Attempts: 1},
+		{ScheduledMessage: models.ScheduledMessage{ID: 2, SenderID: 1, RoomID: 5, Content: "left the room"}, Attempts: 1},
+		{ScheduledMessage: models.ScheduledMessage{ID: 3, SenderID: 1, ReceiverID: 3, Content: "db is down"}, Attempts: 1},
+		{ScheduledMessage: models.ScheduledMessage{ID: 4, SenderID: 1, ReceiverID: 4, Content: "gave up"}, Attempts: 3},
+	}
+
+	scheduledRepo := new(MockScheduledMessageRepo)
+	scheduledRepo.On("ClaimDueScheduledMessages", mock.Anything, repo.ClaimScheduledMessagesParams{Limit: 10, Lease: time.Minute}).
+		Return(due, nil).Once()
+	scheduledRepo.On("ClaimDueScheduledMessages", mock.Anything, mock.Anything).
+		Return([]repo.ClaimedScheduledMessage{}, nil)
+	scheduledRepo.On("CompleteScheduledMessage", mock.Anything, int64(1)).Return(nil)
+	scheduledRepo.On("FailScheduledMessage", mock.Anything, int64(2), mock.Anything).Return(nil)
+	done := make(chan struct{})
+	scheduledRepo.On("FailScheduledMessage", mock.Anything, int64(4), mock.Anything).
+		Run(func(mock.Arguments) { close(done) }).
+		Return(nil)
+
+	messageRepo := new(MockMessageRepo)
+	messageRepo.On("GetMessageByClientMsgID", mock.Anything, int64(1), mock.Anything).Return(nil, repo.ErrNotFound)
+	messageRepo.On("SaveMessage", mock.Anything, mock.MatchedBy(func(p repo.SaveMessageParams) bool { return p.ReceiverID == 2 })).
+		Return(&models.Message{ID: 100, SenderID: 1, ReceiverID: 2}, nil)
+	messageRepo.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{2, 3}, nil)
+	messageRepo.On("SaveMessage", mock.Anything, mock.Anything).Return(nil, errors.New("connection refused"))
+
+	sender := services.NewScheduledSender(services.ScheduledSenderConfig{
+		Repo:        scheduledRepo,
+		Messages:    services.NewMessageService(services.MessageServiceConfig{Repo: messageRepo}),
+		Log:         discardLogger(),
+		Interval:    time.Hour,
+		BatchSize:   10,
+		MaxAttempts: 3,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sender.Run(ctx)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduled messages were not processed")
+	}
+
+	messageRepo.AssertCalled(t, "GetMessageByClientMsgID", mock.Anything, int64(1), "scheduled:1")
+	saved := messageRepo.Calls[1].Arguments.Get(1).(repo.SaveMessageParams)
+	assert.Equal(t, "scheduled:1", saved.ClientMsgID, "the queue id makes retries idempotent")
+	scheduledRepo.AssertCalled(t, "FailScheduledMessage", mock.Anything, int64(2), mock.Anything)
+	scheduledRepo.AssertNotCalled(t, "FailScheduledMessage", mock.Anything, int64(3), mock.Anything)
+	scheduledRepo.AssertNotCalled(t, "CompleteScheduledMessage", mock.Anything, int64(3))
+}
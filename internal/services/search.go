@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchTerms     = 16
+)
+
+type SearchService interface {
+	SearchMessages(ctx context.Context, params SearchParams) (*models.SearchPage, error)
+}
+
+type searchService struct {
+	repo repo.SearchRepo
+}
+
+func NewSearchService(repo repo.SearchRepo) SearchService {
+	return &searchService{repo: repo}
+}
+
+type SearchParams struct {
+	UserID int64
+	// Query поддерживает фразы в кавычках ("добрый вечер") и поиск по префиксу (привет*)
+	Query string
+	// RoomID и PeerID ограничивают поиск комнатой или личным диалогом
+	RoomID   int64
+	PeerID   int64
+	SenderID int64
+	From     *time.Time
+	To       *time.Time
+	// Order — relevance (по умолчанию) или date
+	Order  string
+	Cursor string
+	Limit  int
+}
+
+func (s *searchService) SearchMessages(ctx context.Context, params SearchParams) (*models.SearchPage, error) {
+	tsQuery, err := buildTSQuery(params.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	order := params.Order
+	if order == "" {
+		order = repo.SearchOrderRelevance
+	}
+	if order != repo.SearchOrderRelevance && order != repo.SearchOrderDate {
+		return nil, fmt.Errorf("%w: unknown order %q", ErrValidation, params.Order)
+	}
+	if params.RoomID != 0 && params.PeerID != 0 {
+		return nil, fmt.Errorf("%w: room and peer filters are mutually exclusive", ErrValidation)
+	}
+	if params.From != nil && params.To != nil && !params.From.Before(*params.To) {
+		return nil, fmt.Errorf("%w: empty date range", ErrValidation)
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	var after *repo.SearchCursor
+	if params.Cursor != "" {
+		if after, err = decodeSearchCursor(params.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// Запрашиваем на один результат больше, чтобы понять, есть ли следующая страница
+	results, err := s.repo.SearchMessages(ctx, repo.SearchMessagesParams{
+		UserID:   params.UserID,
+		TSQuery:  tsQuery,
+		RoomID:   params.RoomID,
+		PeerID:   params.PeerID,
+		SenderID: params.SenderID,
+		From:     params.From,
+		To:       params.To,
+		OrderBy:  order,
+		After:    after,
+		Limit:    limit + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.SearchMessages: %w", err)
+	}
+
+	page := &models.SearchPage{Results: results}
+	if len(results) > limit {
+		page.Results = results[:limit]
+		last := page.Results[limit-1]
+		page.NextCursor = encodeSearchCursor(repo.SearchCursor{
+			Rank:      last.Rank,
+			CreatedAt: last.Message.CreatedAt,
+			ID:        last.Message.ID,
+		})
+	}
+
+	return page, nil
+}
+
+// buildTSQuery переводит пользовательский запрос в синтаксис to_tsquery.
+// Слова объединяются через &, фразы в кавычках — оператором следования <->, слово со звёздочкой
+// на конце ищется по префиксу. Всё, кроме букв и цифр, отбрасывается, поэтому результат безопасен
+// для передачи в to_tsquery
+func buildTSQuery(query string) (string, error) {
+	var terms []string
+
+	for i, part := range strings.Split(query, `"`) {
+		// Нечётные части находятся внутри кавычек
+		if i%2 == 1 {
+			if phrase := tsPhrase(strings.Fields(part)); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			if term := tsPhrase([]string{word}); term != "" {
+				terms = append(terms, term)
+			}
+		}
+	}
+
+	if len(terms) == 0 {
+		return "", fmt.Errorf("%w: search query is empty", ErrValidation)
+	}
+	if len(terms) > maxSearchTerms {
+		return "", fmt.Errorf("%w: search query has more than %d terms", ErrValidation, maxSearchTerms)
+	}
+
+	return strings.Join(terms, " & "), nil
+}
+
+// tsPhrase собирает лексемы слов в фразу. Знаки внутри слова (e-mail, 2024.01) делят его на лексемы
+// так же, как это делает to_tsvector; звёздочка на конце последнего слова включает поиск по префиксу
+func tsPhrase(words []string) string {
+	var lexemes []string
+	prefix := false
+
+	for _, word := range words {
+		prefix = strings.HasSuffix(word, "*")
+		lexemes = append(lexemes, strings.FieldsFunc(strings.ToLower(word), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+
+	if len(lexemes) == 0 {
+		return ""
+	}
+	if prefix {
+		lexemes[len(lexemes)-1] += ":*"
+	}
+
+	phrase := strings.Join(lexemes, " <-> ")
+	if len(lexemes) > 1 {
+		phrase = "(" + phrase + ")"
+	}
+	return phrase
+}
+
+type searchCursor struct {
+	Rank      float64   `json:"r"`
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"id"`
+}
+
+func encodeSearchCursor(c repo.SearchCursor) string {
+	data, _ := json.Marshal(searchCursor{Rank: c.Rank, CreatedAt: c.CreatedAt, ID: c.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchCursor(cursor string) (*repo.SearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrValidation)
+	}
+
+	var c searchCursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrValidation)
+	}
+
+	return &repo.SearchCursor{Rank: c.Rank, CreatedAt: c.CreatedAt, ID: c.ID}, nil
+}
@@ -0,0 +1,115 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+// MockSearchRepo реализует интерфейс repo.SearchRepo для тестов
+type MockSearchRepo struct {
+	mock.Mock
+}
+
+func (m *MockSearchRepo) SearchMessages(ctx context.Context, params repo.SearchMessagesParams) ([]models.SearchResult, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]models.SearchResult), args.Error(1)
+}
+
+func TestSearchService_QueryParsing(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedQuery string
+		expectedError error
+	}{
+		{name: "words", query: "Hello World", expectedQuery: "hello & world"},
+		{name: "phrase", query: `"добрый вечер" друзья`, expectedQuery: "(добрый <-> вечер) & друзья"},
+		{name: "prefix", query: "прив*", expectedQuery: "прив:*"},
+		{name: "punctuation is stripped", query: "e-mail it's & | !", expectedQuery: "(e <-> mail) & (it <-> s)"},
+		{name: "empty", query: `  "" & `, expectedError: services.ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockSearchRepo)
+			if tt.expectedError == nil {
+				mockRepo.On("SearchMessages", mock.Anything, mock.MatchedBy(func(p repo.SearchMessagesParams) bool {
+					return p.TSQuery == tt.expectedQuery
+				})).Return([]models.SearchResult{}, nil)
+			}
+
+			service := services.NewSearchService(mockRepo)
+			_, err := service.SearchMessages(context.Background(), services.SearchParams{UserID: 1, Query: tt.query})
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSearchService_Pagination(t *testing.T) {
+	now := time.Now().UTC()
+	results := []models.SearchResult{
+		{Message: models.Message{ID: 3, CreatedAt: now}, Rank: 0.5},
+		{Message: models.Message{ID: 2, CreatedAt: now.Add(-time.Minute)}, Rank: 0.25},
+		{Message: models.Message{ID: 1, CreatedAt: now.Add(-2 * time.Minute)}, Rank: 0.125},
+	}
+
+	mockRepo := new(MockSearchRepo)
+	mockRepo.On("SearchMessages", mock.Anything, mock.MatchedBy(func(p repo.SearchMessagesParams) bool {
+		return p.After == nil
+	})).Return(results, nil).Once()
+	mockRepo.On("SearchMessages", mock.Anything, repo.SearchMessagesParams{
+		UserID:  1,
+		TSQuery: "hello",
+		OrderBy: repo.SearchOrderRelevance,
+		After:   &repo.SearchCursor{Rank: 0.25, CreatedAt: now.Add(-time.Minute), ID: 2},
+		Limit:   3,
+	}).Return(results[2:], nil).Once()
+
+	service := services.NewSearchService(mockRepo)
+
+	first, err := service.SearchMessages(context.Background(), services.SearchParams{UserID: 1, Query: "hello", Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, results[:2], first.Results)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := service.SearchMessages(context.Background(), services.SearchParams{
+		UserID: 1,
+		Query:  "hello",
+		Limit:  2,
+		Cursor: first.NextCursor,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, results[2:], second.Results)
+	assert.Empty(t, second.NextCursor)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSearchService_Validation(t *testing.T) {
+	service := services.NewSearchService(new(MockSearchRepo))
+
+	_, err := service.SearchMessages(context.Background(), services.SearchParams{UserID: 1, Query: "hi", Order: "random"})
+	assert.ErrorIs(t, err, services.ErrValidation)
+
+	_, err = service.SearchMessages(context.Background(), services.SearchParams{UserID: 1, Query: "hi", RoomID: 1, PeerID: 2})
+	assert.ErrorIs(t, err, services.ErrValidation)
+
+	_, err = service.SearchMessages(context.Background(), services.SearchParams{UserID: 1, Query: "hi", Cursor: "!!!"})
+	assert.ErrorIs(t, err, services.ErrValidation)
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSyncLimit = 200
+	maxSyncLimit     = 1000
+
+	// eventLogWriteTimeout ограничивает запись события в журнал, чтобы медленная база не задерживала доставку
+	eventLogWriteTimeout = 5 * time.Second
+)
+
+type SyncService interface {
+	GetEvents(ctx context.Context, params SyncParams) (*models.SyncBatch, error)
+}
+
+type syncService struct {
+	repo repo.EventRepo
+}
+
+func NewSyncService(repo repo.EventRepo) SyncService {
+	return &syncService{repo: repo}
+}
+
+type SyncParams struct {
+	UserID int64
+	// Since номер последнего применённого клиентом события; 0 — с начала журнала
+	Since int64
+	Limit int
+}
+
+// GetEvents возвращает события пользователя после Since. Если часть из них удалена при сжатии журнала
+// или клиент знает номер, которого в журнале нет, событий не возвращает и требует полной синхронизации
+func (s *syncService) GetEvents(ctx context.Context, params SyncParams) (*models.SyncBatch, error) {
+	if params.Since < 0 {
+		return nil, fmt.Errorf("%w: since must not be negative", ErrValidation)
+	}
+	if params.Limit <= 0 {
+		params.Limit = defaultSyncLimit
+	}
+	params.Limit = min(params.Limit, maxSyncLimit)
+
+	state, err := s.repo.GetEventLogState(ctx, params.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetEventLogState: %w", err)
+	}
+
+	batch := &models.SyncBatch{Events: []models.SyncEvent{}, LastSeq: state.LastSeq}
+	// Номер больше последнего означает, что журнал был потерян или клиент пришёл с другого сервера
+	if params.Since < state.CompactedSeq || params.Since > state.LastSeq {
+		batch.ResyncRequired = true
+		return batch, nil
+	}
+	if params.Since == state.LastSeq {
+		return batch, nil
+	}
+
+	// Лишнее событие показывает, есть ли продолжение
+	events, err := s.repo.GetEvents(ctx, repo.GetEventsParams{
+		UserID: params.UserID,
+		Since:  params.Since,
+		Limit:  params.Limit + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.repo.GetEvents: %w", err)
+	}
+	if len(events) > params.Limit {
+		events = events[:params.Limit]
+		batch.HasMore = true
+	}
+	if len(events) > 0 {
+		batch.Events = events
+		// События, записанные после чтения положения журнала, тоже попадают в пачку
+		batch.LastSeq = max(batch.LastSeq, events[len(events)-1].Seq)
+	}
+	return batch, nil
+}
+
+// EventLog записывает публикуемые события в журналы получателей и передаёт каждому получателю
+// его копию с номером Seq. Сервисы публикуют события через EventLog, транспорт подписывается на Next.
+// Номера присваиваются в порядке записи в журнал, а доставляться события одновременных публикаций
+// могут в другом порядке: клиент упорядочивает их по Seq
+type EventLog struct {
+	repo repo.EventRepo
+	next events.Publisher
+	log  *logrus.Logger
+}
+
+type EventLogConfig struct {
+	Repo repo.EventRepo
+	// Next получает события с номерами; обычно шина, на которую подписан WebSocket
+	Next events.Publisher
+	Log  *logrus.Logger
+}
+
+func NewEventLog(cfg EventLogConfig) *EventLog {
+	return &EventLog{
+		repo: cfg.Repo,
+		next: cfg.Next,
+		log:  cfg.Log,
+	}
+}
+
+// Publish записывает событие в журнал и доставляет его. Если записать не удалось, событие
+// доставляется без номера: клиент в сети его получит, а при синхронизации оно будет пропущено.
+// Мгновенные события (events.Ephemeral) доставляются без записи
+func (l *EventLog) Publish(userIDs []int64, event events.Event) {
+	userIDs = slices.Compact(slices.Sorted(slices.Values(userIDs)))
+	if len(userIDs) == 0 {
+		return
+	}
+	if events.Ephemeral(event.Type) {
+		l.next.Publish(userIDs, event)
+		return
+	}
+
+	seqs, err := l.append(userIDs, event)
+	if err != nil {
+		l.log.Errorf("failed to write %s event to the log: %v", event.Type, err)
+		l.next.Publish(userIDs, event)
+		return
+	}
+
+	for _, userID := range userIDs {
+		numbered := event
+		numbered.Seq = seqs[userID]
+		l.next.Publish([]int64{userID}, numbered)
+	}
+}
+
+func (l *EventLog) append(userIDs []int64, event events.Event) (map[int64]int64, error) {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventLogWriteTimeout)
+	defer cancel()
+
+	seqs, err := l.repo.AppendEvent(ctx, userIDs, event.Type, payload)
+	if err != nil {
+		return nil, fmt.Errorf("l.repo.AppendEvent: %w", err)
+	}
+	return seqs, nil
+}
+
+const (
+	defaultEventLogRetention       = 7 * 24 * time.Hour
+	defaultEventLogCompactInterval = 10 * time.Minute
+	defaultEventLogCompactBatch    = 1000
+)
+
+// EventLogCompactor удаляет из журнала события старше Retention. Клиент, отставший больше чем
+// на Retention, получает требование полной синхронизации
+type EventLogCompactor struct {
+	repo repo.EventRepo
+	log  *logrus.Logger
+
+	retention time.Duration
+	interval  time.Duration
+	batchSize int
+}
+
+type EventLogCompactorConfig struct {
+	Repo repo.EventRepo
+	Log  *logrus.Logger
+
+	Retention time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+func NewEventLogCompactor(cfg EventLogCompactorConfig) *EventLogCompactor {
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultEventLogRetention
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultEventLogCompactInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultEventLogCompactBatch
+	}
+	return &EventLogCompactor{
+		repo:      cfg.Repo,
+		log:       cfg.Log,
+		retention: cfg.Retention,
+		interval:  cfg.Interval,
+		batchSize: cfg.BatchSize,
+	}
+}
+
+// Run сжимает журнал с заданным интервалом до отмены контекста
+func (c *EventLogCompactor) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			deleted, err := c.repo.CompactEvents(ctx, time.Now().Add(-c.retention), c.batchSize)
+			if err != nil {
+				c.log.Errorf("failed to compact event log: %v", err)
+				break
+			}
+			if deleted < c.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+// MockEventRepo реализует интерфейс repo.EventRepo для тестов
+type MockEventRepo struct {
+	mock.Mock
+}
+
+func (m *MockEventRepo) AppendEvent(ctx context.Context, userIDs []int64, eventType string, payload []byte) (map[int64]int64, error) {
+	args := m.Called(ctx, userIDs, eventType, payload)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int64]int64), args.Error(1)
+}
+
+func (m *MockEventRepo) GetEventLogState(ctx context.Context, userID int64) (*repo.EventLogState, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repo.EventLogState), args.Error(1)
+}
+
+func (m *MockEventRepo) GetEvents(ctx context.Context, params repo.GetEventsParams) ([]models.SyncEvent, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SyncEvent), args.Error(1)
+}
+
+func (m *MockEventRepo) CompactEvents(ctx context.Context, before time.Time, limit int) (int, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Int(0), args.Error(1)
+}
+
+func syncEvents(seqs ...int64) []models.SyncEvent {
+	result := make([]models.SyncEvent, len(seqs))
+	for i, seq := range seqs {
+		result[i] = models.SyncEvent{Seq: seq, Type: events.MessageNew, Payload: json.RawMessage(`{}`)}
+	}
+	return result
+}
+
+func TestSyncService_GetEvents(t *testing.T) {
+	tests := []struct {
+		name         string
+		params       services.SyncParams
+		mockBehavior func(r *MockEventRepo)
+		expected     *models.SyncBatch
+	}{
+		{
+			name:   "missed events",
+			params: services.SyncParams{UserID: 1, Since: 10},
+			mockBehavior: func(r *MockEventRepo) {
+				r.On("GetEventLogState", mock.Anything, int64(1)).Return(&repo.EventLogState{LastSeq: 12, CompactedSeq: 4}, nil)
+				r.On("GetEvents", mock.Anything, repo.GetEventsParams{UserID: 1, Since: 10, Limit: 201}).Return(syncEvents(11, 12, 13), nil)
+			},
+			expected: &models.SyncBatch{Events: syncEvents(11, 12, 13), LastSeq: 13},
+		},
+		{
+			name:   "more than a page",
+			params: services.SyncParams{UserID: 1, Since: 10, Limit: 2},
+			mockBehavior: func(r *MockEventRepo) {
+				r.On("GetEventLogState", mock.Anything, int64(1)).Return(&repo.EventLogState{LastSeq: 20}, nil)
+				r.On("GetEvents", mock.Anything, repo.GetEventsParams{UserID: 1, Since: 10, Limit: 3}).Return(syncEvents(11, 12, 13), nil)
+			},
+			expected: &models.SyncBatch{Events: syncEvents(11, 12), LastSeq: 20, HasMore: true},
+		},
+		{
+			name:   "up to date",
+			params: services.SyncParams{UserID: 1, Since: 20},
+			mockBehavior: func(r *MockEventRepo) {
+				r.On("GetEventLogState", mock.Anything, int64(1)).Return(&repo.EventLogState{LastSeq: 20}, nil)
+			},
+			expected: &models.SyncBatch{Events: []models.SyncEvent{}, LastSeq: 20},
+		},
+		{
+			name:   "log compacted",
+			params: services.SyncParams{UserID: 1, Since: 3},
+			mockBehavior: func(r *MockEventRepo) {
+				r.On("GetEventLogState", mock.Anything, int64(1)).Return(&repo.EventLogState{LastSeq: 20, CompactedSeq: 7}, nil)
+			},
+			expected: &models.SyncBatch{Events: []models.SyncEvent{}, LastSeq: 20, ResyncRequired: true},
+		},
+		{
+			name:   "unknown seq",
+			params: services.SyncParams{UserID: 1, Since: 50},
+			mockBehavior: func(r *MockEventRepo) {
+				r.On("GetEventLogState", mock.Anything, int64(1)).Return(&repo.EventLogState{LastSeq: 20}, nil)
+			},
+			expected: &models.SyncBatch{Events: []models.SyncEvent{}, LastSeq: 20, ResyncRequired: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockEventRepo)
+			tt.mockBehavior(mockRepo)
+
+			batch, err := services.NewSyncService(mockRepo).GetEvents(context.Background(), tt.params)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, batch)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+
+	_, err := services.NewSyncService(new(MockEventRepo)).GetEvents(context.Background(), services.SyncParams{UserID: 1, Since: -1})
+	assert.ErrorIs(t, err, services.ErrValidation)
+}
+
+type deliveredEvent struct {
+	userIDs []int64
+	event   events.Event
+}
+
+func TestEventLog_Publish(t *testing.T) {
+	var delivered []deliveredEvent
+	bus := events.NewBus()
+	bus.Subscribe(func(userIDs []int64, event events.Event) {
+		delivered = append(delivered, deliveredEvent{userIDs: userIDs, event: event})
+	})
+
+	t.Run("numbers the event for each recipient", func(t *testing.T) {
+		delivered = nil
+		mockRepo := new(MockEventRepo)
+		mockRepo.On("AppendEvent", mock.Anything, []int64{1, 2}, events.MessageDeleted, []byte(`{"message_id":5,"scope":"everyone","deleted_by":1}`)).
+			Return(map[int64]int64{1: 40, 2: 7}, nil)
+
+		log := services.NewEventLog(services.EventLogConfig{Repo: mockRepo, Next: bus, Log: discardLogger()})
+		log.Publish([]int64{2, 1, 2}, events.Event{
+			Type:    events.MessageDeleted,
+			Payload: models.MessageDeletion{MessageID: 5, Scope: "everyone", DeletedBy: 1},
+		})
+
+		require.Len(t, delivered, 2)
+		assert.Equal(t, []int64{1}, delivered[0].userIDs)
+		assert.Equal(t, int64(40), delivered[0].event.Seq)
+		assert.Equal(t, []int64{2}, delivered[1].userIDs)
+		assert.Equal(t, int64(7), delivered[1].event.Seq)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("delivers without a number when the log is unavailable", func(t *testing.T) {
+		delivered = nil
+		mockRepo := new(MockEventRepo)
+		mockRepo.On("AppendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("db is down"))
+
+		log := services.NewEventLog(services.EventLogConfig{Repo: mockRepo, Next: bus, Log: discardLogger()})
+		log.Publish([]int64{1, 2}, events.Event{Type: events.MessageNew, Payload: models.Message{ID: 5}})
+
+		require.Len(t, delivered, 1)
+		assert.Equal(t, []int64{1, 2}, delivered[0].userIDs)
+		assert.Zero(t, delivered[0].event.Seq)
+	})
+
+	t.Run("does not log ephemeral events", func(t *testing.T) {
+		delivered = nil
+		mockRepo := new(MockEventRepo)
+
+		log := services.NewEventLog(services.EventLogConfig{Repo: mockRepo, Next: bus, Log: discardLogger()})
+		log.Publish([]int64{3, 2}, events.Event{Type: events.Typing, Payload: models.Typing{UserID: 1}})
+
+		require.Len(t, delivered, 1)
+		assert.Equal(t, []int64{2, 3}, delivered[0].userIDs)
+		assert.Zero(t, delivered[0].event.Seq)
+		mockRepo.AssertNotCalled(t, "AppendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
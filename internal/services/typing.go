@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"slices"
+)
+
+type TypingParams struct {
+	UserID     int64
+	ReceiverID int64
+	// RoomID задаётся для комнаты; ReceiverID в этом случае не используется
+	RoomID int64
+}
+
+// SendTyping сообщает остальным участникам переписки, что пользователь набирает сообщение.
+// Индикатор ничего не сохраняет: клиент повторяет его, пока пользователь продолжает набор
+func (s *messageService) SendTyping(ctx context.Context, params TypingParams) error {
+	if err := validateTarget(params.UserID, params.ReceiverID, params.RoomID); err != nil {
+		return err
+	}
+
+	recipients := []int64{params.ReceiverID}
+	if params.RoomID != 0 {
+		members, err := s.repo.GetRoomMemberIDs(ctx, params.RoomID)
+		if err != nil {
+			return fmt.Errorf("s.repo.GetRoomMemberIDs: %w", err)
+		}
+		if !slices.Contains(members, params.UserID) {
+			return fmt.Errorf("%w: not a member of the room", ErrForbidden)
+		}
+		recipients = slices.DeleteFunc(members, func(id int64) bool { return id == params.UserID })
+	}
+
+	s.publish(recipients, events.Event{Type: events.Typing, Payload: models.Typing{
+		UserID: params.UserID,
+		RoomID: params.RoomID,
+	}})
+	return nil
+}
@@ -0,0 +1,87 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/events"
+	"messanger/internal/models"
+	"messanger/internal/services"
+)
+
+func TestMessageService_SendTyping(t *testing.T) {
+	tests := []struct {
+		name              string
+		params            services.TypingParams
+		mockBehavior      func(r *MockMessageRepo)
+		expectedNotified  []int64
+		expectedIndicator models.Typing
+		expectedError     error
+	}{
+		{
+			name:              "direct chat",
+			params:            services.TypingParams{UserID: 1, ReceiverID: 2},
+			mockBehavior:      func(r *MockMessageRepo) {},
+			expectedNotified:  []int64{2},
+			expectedIndicator: models.Typing{UserID: 1},
+		},
+		{
+			name:   "room",
+			params: services.TypingParams{UserID: 1, RoomID: 5},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 3, 4}, nil)
+			},
+			expectedNotified:  []int64{3, 4},
+			expectedIndicator: models.Typing{UserID: 1, RoomID: 5},
+		},
+		{
+			name:   "not a member of the room",
+			params: services.TypingParams{UserID: 2, RoomID: 5},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{1, 3}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+		{
+			name:          "no conversation",
+			params:        services.TypingParams{UserID: 1},
+			mockBehavior:  func(r *MockMessageRepo) {},
+			expectedError: services.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			tt.mockBehavior(mockRepo)
+
+			var notified []int64
+			var published *models.Typing
+			bus := events.NewBus()
+			bus.Subscribe(func(userIDs []int64, event events.Event) {
+				require.Equal(t, events.Typing, event.Type)
+				notified = userIDs
+				indicator := event.Payload.(models.Typing)
+				published = &indicator
+			})
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Events: bus})
+			err := service.SendTyping(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, published)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedNotified, notified)
+			assert.Equal(t, &tt.expectedIndicator, published)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
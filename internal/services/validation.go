@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// validateTarget проверяет адресата сообщения: комнату или другого пользователя.
+// Существование пользователя проверяет checkReceiver
+func validateTarget(senderID, receiverID, roomID int64) error {
+	switch {
+	case senderID <= 0:
+		return fmt.Errorf("%w: sender_id is required", ErrValidation)
+	case receiverID < 0 || roomID < 0:
+		return fmt.Errorf("%w: receiver_id and room_id must be positive", ErrValidation)
+	case roomID != 0:
+		return nil
+	case receiverID == 0:
+		return fmt.Errorf("%w: receiver_id or room_id is required", ErrValidation)
+	case receiverID == senderID:
+		return fmt.Errorf("%w: cannot send a message to yourself", ErrValidation)
+	}
+	return nil
+}
+
+// checkReceiver проверяет, что получатель личного сообщения известен сервису
+func (s *messageService) checkReceiver(ctx context.Context, receiverID int64) error {
+	if s.users == nil {
+		return nil
+	}
+	exists, err := s.users.UserExists(ctx, receiverID)
+	if err != nil {
+		return fmt.Errorf("s.users.UserExists: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: user %d", ErrNotFound, receiverID)
+	}
+	return nil
+}
+
+// RegisterUser добавляет пользователя в справочник, после чего ему можно писать личные сообщения.
+// Вызывается при подключении клиента и на каждом запросе к API, поэтому в базу обращается
+// только для пользователей, которых этот экземпляр ещё не регистрировал
+func (s *messageService) RegisterUser(ctx context.Context, userID int64) error {
+	if s.users == nil {
+		return nil
+	}
+	if _, ok := s.registered.Load(userID); ok {
+		return nil
+	}
+	if err := s.users.RegisterUser(ctx, userID); err != nil {
+		return fmt.Errorf("s.users.RegisterUser: %w", err)
+	}
+	s.registered.Store(userID, struct{}{})
+	return nil
+}
+
+// normalizeText готовит простой текст сообщения к сохранению: удаляет управляющие символы,
+// кроме перевода строки и табуляции, и пробелы по краям
+func normalizeText(text string) (string, error) {
+	// Заведомо слишком длинный текст отбрасывается до посимвольной обработки
+	if len(text) > maxTextLength*utf8.UTFMax {
+		return "", fmt.Errorf("%w: text is longer than %d characters", ErrValidation, maxTextLength)
+	}
+	if !utf8.ValidString(text) {
+		return "", fmt.Errorf("%w: text is not valid UTF-8", ErrValidation)
+	}
+
+	text = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if isForbiddenControl(r) {
+			return -1
+		}
+		return r
+	}, text))
+	if utf8.RuneCountInString(text) > maxTextLength {
+		return "", fmt.Errorf("%w: text is longer than %d characters", ErrValidation, maxTextLength)
+	}
+	return text, nil
+}
+
+// isForbiddenControl управляющие символы, недопустимые в тексте сообщения
+func isForbiddenControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\t'
+}
@@ -0,0 +1,161 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"messanger/internal/models"
+	"messanger/internal/repo"
+	"messanger/internal/services"
+)
+
+// knownUsers справочник пользователей для тестов
+type knownUsers map[int64]bool
+
+func (u knownUsers) RegisterUser(_ context.Context, userID int64) error {
+	u[userID] = true
+	return nil
+}
+
+func (u knownUsers) UserExists(_ context.Context, userID int64) (bool, error) {
+	return u[userID], nil
+}
+
+func TestMessageService_SaveMessage_Validation(t *testing.T) {
+	tests := []struct {
+		name            string
+		params          services.SaveMessageParams
+		mockBehavior    func(r *MockMessageRepo)
+		expectedContent string
+		expectedError   error
+	}{
+		{
+			name:            "content is trimmed and control characters are stripped",
+			params:          services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "  hello\x00\x1b world\n\tbye \r\n"},
+			expectedContent: "hello world\n\tbye",
+		},
+		{
+			name:          "empty content",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: " \n\x07 "},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "invalid UTF-8",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: "hello \xff"},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "content too long",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: strings.Repeat("я", 4097)},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "megabytes of text",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, Content: strings.Repeat("a", 5<<20)},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "control characters in rich content",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 2, RichContent: &models.RichContent{Kind: models.ContentKindText, Text: "a\x00b"}},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "no receiver",
+			params:        services.SaveMessageParams{SenderID: 1, Content: "hello"},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "message to yourself",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 1, Content: "hello"},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "no sender",
+			params:        services.SaveMessageParams{ReceiverID: 2, Content: "hello"},
+			expectedError: services.ErrValidation,
+		},
+		{
+			name:          "receiver does not exist",
+			params:        services.SaveMessageParams{SenderID: 1, ReceiverID: 404, Content: "hello"},
+			expectedError: services.ErrNotFound,
+		},
+		{
+			name:   "room does not exist",
+			params: services.SaveMessageParams{SenderID: 1, RoomID: 404, Content: "hello"},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMemberIDs", mock.Anything, int64(404)).Return([]int64{}, nil)
+			},
+			expectedError: services.ErrNotFound,
+		},
+		{
+			name:   "not a member of the room",
+			params: services.SaveMessageParams{SenderID: 1, RoomID: 5, Content: "hello"},
+			mockBehavior: func(r *MockMessageRepo) {
+				r.On("GetRoomMemberIDs", mock.Anything, int64(5)).Return([]int64{2, 3}, nil)
+			},
+			expectedError: services.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockMessageRepo)
+			if tt.mockBehavior != nil {
+				tt.mockBehavior(mockRepo)
+			}
+			mockRepo.On("SaveMessage", mock.Anything, mock.Anything).Return(&models.Message{ID: 1}, nil).Maybe()
+
+			service := services.NewMessageService(services.MessageServiceConfig{Repo: mockRepo, Users: knownUsers{1: true, 2: true}})
+			_, err := service.SaveMessage(context.Background(), tt.params)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				mockRepo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			mockRepo.AssertCalled(t, "SaveMessage", mock.Anything, mock.MatchedBy(func(p repo.SaveMessageParams) bool {
+				return p.Content == tt.expectedContent
+			}))
+		})
+	}
+}
+
+func TestMessageService_RegisterUser(t *testing.T) {
+	users := knownUsers{1: true}
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: new(MockMessageRepo), Users: users})
+
+	params := services.SaveMessageParams{SenderID: 1, ReceiverID: 3, Content: "hello"}
+	_, err := service.SaveMessage(context.Background(), params)
+	assert.ErrorIs(t, err, services.ErrNotFound)
+
+	assert.NoError(t, service.RegisterUser(context.Background(), 3))
+	assert.True(t, users[3])
+}
+
+// countingUsers считает обращения к справочнику при регистрации
+type countingUsers struct {
+	knownUsers
+	registrations int
+}
+
+func (u *countingUsers) RegisterUser(ctx context.Context, userID int64) error {
+	u.registrations++
+	return u.knownUsers.RegisterUser(ctx, userID)
+}
+
+func TestMessageService_RegisterUser_Cached(t *testing.T) {
+	users := &countingUsers{knownUsers: knownUsers{}}
+	service := services.NewMessageService(services.MessageServiceConfig{Repo: new(MockMessageRepo), Users: users})
+
+	for range 3 {
+		assert.NoError(t, service.RegisterUser(context.Background(), 3))
+	}
+	assert.NoError(t, service.RegisterUser(context.Background(), 4))
+	assert.Equal(t, 2, users.registrations)
+}
@@ -14,27 +14,57 @@ type Server struct {
 	addr string
 
 	messageService services.MessageService
-
-	log *logrus.Logger
-	app *fiber.App
+	searchService  services.SearchService
+	attachments    services.AttachmentService
+	scheduled      services.ScheduledService
+	exports        services.ExportService
+	imports        services.ImportService
+	sync           services.SyncService
+
+	log      *logrus.Logger
+	app      *fiber.App
+	tokenKey string
+	adminIDs []int64
 }
 
 type ServerConfig struct {
 	Addr string
 
 	MessageService services.MessageService
-
-	Log *logrus.Logger
+	SearchService  services.SearchService
+	Attachments    services.AttachmentService
+	Scheduled      services.ScheduledService
+	Exports        services.ExportService
+	Imports        services.ImportService
+	Sync           services.SyncService
+	// BodyLimit максимальный размер тела запроса; должен вмещать самое большое вложение
+	BodyLimit int
+
+	Log      *logrus.Logger
+	TokenKey string
+	// AdminIDs пользователи с доступом к административному API
+	AdminIDs []int64
 }
 
 func NewServer(cfg ServerConfig) *Server {
 	server := &Server{
 		addr:           cfg.Addr,
 		messageService: cfg.MessageService,
+		searchService:  cfg.SearchService,
+		attachments:    cfg.Attachments,
+		scheduled:      cfg.Scheduled,
+		exports:        cfg.Exports,
+		imports:        cfg.Imports,
+		sync:           cfg.Sync,
 		log:            cfg.Log,
+		tokenKey:       cfg.TokenKey,
+		adminIDs:       cfg.AdminIDs,
 	}
 
-	server.app = fiber.New(fiber.Config{})
+	server.app = fiber.New(fiber.Config{
+		BodyLimit:    cfg.BodyLimit,
+		ErrorHandler: v1.ErrorHandler,
+	})
 
 	server.init()
 
@@ -65,7 +95,16 @@ func (s *Server) init() {
 func (s *Server) setHandlers() {
 	handlerV1 := v1.NewHandler(v1.HandlerConfig{
 		MessageService: s.messageService,
+		SearchService:  s.searchService,
+		Attachments:    s.attachments,
+		Scheduled:      s.scheduled,
+		Exports:        s.exports,
+		Imports:        s.imports,
+		Sync:           s.sync,
+		Users:          s.messageService,
 		Log:            s.log,
+		TokenKey:       s.tokenKey,
+		AdminIDs:       s.adminIDs,
 	})
 	{
 		handlerV1.Init(s.app)
@@ -0,0 +1,13 @@
+package v1
+
+import "github.com/gofiber/fiber/v2"
+
+// initAdminRoutes административное API; доступно пользователям из HandlerConfig.AdminIDs
+func (h *Handler) initAdminRoutes(router fiber.Router) {
+	admin := router.Group("/admin", h.requireUser, h.requireAdmin)
+	{
+		admin.Put("/legal-hold", h.SetLegalHold)
+		admin.Get("/export", h.ExportConversationAsAdmin)
+		admin.Post("/import", h.ImportHistory)
+	}
+}
@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/services"
+	"mime"
+	"strconv"
+)
+
+func (h *Handler) initAttachmentRoutes(router fiber.Router) {
+	attachments := router.Group("/attachments")
+	{
+		attachments.Post("/", h.requireUser, h.UploadAttachment)
+		attachments.Get("/:id", h.requireUser, h.DownloadAttachment)
+		attachments.Get("/:id/thumbnails/:size", h.requireUser, h.DownloadThumbnail)
+	}
+}
+
+// UploadAttachment загружает файл для последующей отправки с сообщением
+// @Summary Загрузить вложение
+// @Tags attachments
+// @Description Сохраняет файл в хранилище и возвращает его метаданные. Тип файла определяется по содержимому.
+// @Description Для изображений удаляются сведения о местоположении из EXIF, возвращаются размеры, blurhash и миниатюры.
+// @Description Полученный id передаётся в attachment_ids при отправке сообщения
+// @Accept multipart/form-data
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param file formData file true "Файл"
+// @Success 201 {object} models.Attachment
+// @Failure 400 {object} HTTPError "Файл не передан, пуст или слишком велик"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /attachments [post]
+func (h *Handler) UploadAttachment(c *fiber.Ctx) error {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "file is required")
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("failed to open uploaded file: %v", err))
+	}
+	defer file.Close()
+
+	attachment, err := h.attachments.Upload(context.Background(), services.UploadParams{
+		UploaderID: currentUserID(c),
+		FileName:   header.Filename,
+		Content:    file,
+	})
+	if err != nil {
+		return serviceError("h.attachments.Upload", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(attachment)
+}
+
+// DownloadAttachment отдаёт содержимое вложения
+// @Summary Скачать вложение
+// @Tags attachments
+// @Description Доступно участникам переписки, в которую отправлено вложение, а до отправки — только загрузившему его пользователю
+// @Produce octet-stream
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID вложения"
+// @Success 200 {file} file
+// @Failure 400 {object} HTTPError "Неверный ID"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к переписке"
+// @Failure 404 {object} HTTPError "Вложение не найдено"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /attachments/{id} [get]
+func (h *Handler) DownloadAttachment(c *fiber.Ctx) error {
+	attachmentID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid attachmentID: %v", err))
+	}
+
+	attachment, content, err := h.attachments.Open(context.Background(), services.OpenAttachmentParams{
+		AttachmentID: attachmentID,
+		UserID:       currentUserID(c),
+	})
+	if err != nil {
+		return serviceError("h.attachments.Open", err)
+	}
+
+	c.Set(fiber.HeaderContentType, attachment.MimeType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+
+	// fasthttp закрывает поток после отправки ответа
+	return c.SendStream(content, int(attachment.Size))
+}
+
+// DownloadThumbnail отдаёт миниатюру изображения
+// @Summary Скачать миниатюру
+// @Tags attachments
+// @Description Доступные размеры перечислены в поле thumbnails вложения. Права доступа те же, что у оригинала
+// @Produce image/jpeg
+// @Produce image/png
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID вложения"
+// @Param size path int true "Размер миниатюры"
+// @Success 200 {file} file
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к переписке"
+// @Failure 404 {object} HTTPError "Миниатюра не найдена"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /attachments/{id}/thumbnails/{size} [get]
+func (h *Handler) DownloadThumbnail(c *fiber.Ctx) error {
+	attachmentID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid attachmentID: %v", err))
+	}
+	size, err := strconv.Atoi(c.Params("size"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid size: %v", err))
+	}
+
+	thumbnail, content, err := h.attachments.OpenThumbnail(context.Background(), services.OpenThumbnailParams{
+		AttachmentID: attachmentID,
+		UserID:       currentUserID(c),
+		Size:         size,
+	})
+	if err != nil {
+		return serviceError("h.attachments.OpenThumbnail", err)
+	}
+
+	c.Set(fiber.HeaderContentType, thumbnail.MimeType)
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+
+	return c.SendStream(content)
+}
@@ -0,0 +1,181 @@
+package v1_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+// MockAttachmentService реализует интерфейс services.AttachmentService для тестов
+type MockAttachmentService struct {
+	mock.Mock
+}
+
+func (m *MockAttachmentService) Upload(ctx context.Context, params services.UploadParams) (*models.Attachment, error) {
+	args := m.Called(ctx, params.UploaderID, params.FileName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentService) Open(ctx context.Context, params services.OpenAttachmentParams) (*models.Attachment, io.ReadCloser, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.Attachment), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func (m *MockAttachmentService) OpenThumbnail(ctx context.Context, params services.OpenThumbnailParams) (*models.Thumbnail, io.ReadCloser, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.Thumbnail), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func newAttachmentApp(attachments *MockAttachmentService) *fiber.App {
+	app := fiber.New()
+	h := v1.NewHandler(v1.HandlerConfig{
+		Attachments: attachments,
+		TokenKey:    testTokenKey,
+	})
+	h.Init(app)
+	return app
+}
+
+func TestHandler_uploadAttachment(t *testing.T) {
+	tests := []struct {
+		name           string
+		withFile       bool
+		mockBehavior   func(s *MockAttachmentService)
+		expectedStatus int
+	}{
+		{
+			name:     "OK",
+			withFile: true,
+			mockBehavior: func(s *MockAttachmentService) {
+				s.On("Upload", mock.Anything, int64(1), "photo.png").
+					Return(&models.Attachment{ID: 3, UploaderID: 1, FileName: "photo.png"}, nil)
+			},
+			expectedStatus: fiber.StatusCreated,
+		},
+		{
+			name:           "missing file",
+			mockBehavior:   func(s *MockAttachmentService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:     "too large",
+			withFile: true,
+			mockBehavior: func(s *MockAttachmentService) {
+				s.On("Upload", mock.Anything, int64(1), "photo.png").
+					Return(nil, fmt.Errorf("%w: file is too large", services.ErrValidation))
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attachments := new(MockAttachmentService)
+			tt.mockBehavior(attachments)
+			app := newAttachmentApp(attachments)
+
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			if tt.withFile {
+				part, err := form.CreateFormFile("file", "photo.png")
+				require.NoError(t, err)
+				_, err = part.Write([]byte("content"))
+				require.NoError(t, err)
+			}
+			require.NoError(t, form.Close())
+
+			req := httptest.NewRequest("POST", "/v1/attachments", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			attachments.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_downloadAttachment(t *testing.T) {
+	attachments := new(MockAttachmentService)
+	attachments.On("Open", mock.Anything, services.OpenAttachmentParams{AttachmentID: 3, UserID: 2}).
+		Return(&models.Attachment{ID: 3, FileName: "отчёт.pdf", MimeType: "application/pdf", Size: 7},
+			io.NopCloser(strings.NewReader("content")), nil)
+	attachments.On("Open", mock.Anything, services.OpenAttachmentParams{AttachmentID: 3, UserID: 4}).
+		Return(nil, nil, fmt.Errorf("%w: not a member of the conversation", services.ErrForbidden))
+	app := newAttachmentApp(attachments)
+
+	req := httptest.NewRequest("GET", "/v1/attachments/3", nil)
+	req.Header.Set("Authorization", testToken(t, 2))
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "attachment; filename*=utf-8''%D0%BE%D1%82%D1%87%D1%91%D1%82.pdf", resp.Header.Get("Content-Disposition"))
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+
+	req = httptest.NewRequest("GET", "/v1/attachments/3", nil)
+	req.Header.Set("Authorization", testToken(t, 4))
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+	attachments.AssertExpectations(t)
+}
+
+func TestHandler_downloadThumbnail(t *testing.T) {
+	attachments := new(MockAttachmentService)
+	attachments.On("OpenThumbnail", mock.Anything, services.OpenThumbnailParams{AttachmentID: 3, UserID: 2, Size: 160}).
+		Return(&models.Thumbnail{Size: 160, Width: 160, Height: 90, MimeType: "image/jpeg"},
+			io.NopCloser(strings.NewReader("thumb")), nil)
+	attachments.On("OpenThumbnail", mock.Anything, services.OpenThumbnailParams{AttachmentID: 3, UserID: 2, Size: 64}).
+		Return(nil, nil, fmt.Errorf("%w: thumbnail 64", services.ErrNotFound))
+	app := newAttachmentApp(attachments)
+
+	tests := []struct {
+		target         string
+		expectedStatus int
+	}{
+		{target: "/v1/attachments/3/thumbnails/160", expectedStatus: fiber.StatusOK},
+		{target: "/v1/attachments/3/thumbnails/64", expectedStatus: fiber.StatusNotFound},
+		{target: "/v1/attachments/3/thumbnails/big", expectedStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			req.Header.Set("Authorization", testToken(t, 2))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+
+	attachments.AssertExpectations(t)
+}
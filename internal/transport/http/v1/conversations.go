@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/services"
+)
+
+func (h *Handler) initConversationRoutes(router fiber.Router) {
+	router.Get("/conversations", h.requireUser, h.GetConversations)
+	router.Put("/drafts", h.requireUser, h.SaveDraft)
+}
+
+// GetConversations возвращает список переписок текущего пользователя
+// @Summary Список переписок
+// @Tags conversations
+// @Description Личные переписки и комнаты с последним сообщением, числом непрочитанных и черновиком,
+// @Description начиная с последней активности. Переписки, где есть только черновик, тоже входят в список
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Success 200 {array} models.Conversation
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /conversations [get]
+func (h *Handler) GetConversations(c *fiber.Ctx) error {
+	conversations, err := h.messageService.GetConversations(context.Background(), currentUserID(c))
+	if err != nil {
+		return serviceError("h.messageService.GetConversations", err)
+	}
+
+	return c.JSON(conversations)
+}
+
+type SaveDraftRequest struct {
+	ReceiverID int64 `json:"receiver_id"`
+	RoomID     int64 `json:"room_id"`
+	// Content текст черновика; пустая строка удаляет черновик
+	Content string `json:"content"`
+}
+
+// SaveDraft сохраняет черновик переписки
+// @Summary Сохранить черновик
+// @Tags conversations
+// @Description Сохраняет неотправленный текст для личной переписки (receiver_id) или комнаты (room_id).
+// @Description Устройства пользователя получают событие draft.updated; черновик удаляется при отправке сообщения в переписку
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param draft body SaveDraftRequest true "Переписка и текст"
+// @Success 200 {object} models.Draft
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к комнате"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /drafts [put]
+func (h *Handler) SaveDraft(c *fiber.Ctx) error {
+	var req SaveDraftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	draft, err := h.messageService.SaveDraft(context.Background(), services.SaveDraftParams{
+		UserID:     currentUserID(c),
+		ReceiverID: req.ReceiverID,
+		RoomID:     req.RoomID,
+		Content:    req.Content,
+	})
+	if err != nil {
+		return serviceError("h.messageService.SaveDraft", err)
+	}
+
+	return c.JSON(draft)
+}
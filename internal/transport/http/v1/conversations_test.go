@@ -0,0 +1,101 @@
+package v1_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+func TestHandler_getConversations(t *testing.T) {
+	messageService := new(MockMessageService)
+	messageService.On("GetConversations", mock.Anything, int64(1)).Return([]models.Conversation{
+		{RoomID: 5, UnreadCount: 2, Draft: &models.Draft{RoomID: 5, Content: "later"}},
+	}, nil)
+
+	app := fiber.New()
+	h := v1.NewHandler(v1.HandlerConfig{
+		MessageService: messageService,
+		TokenKey:       testTokenKey,
+	})
+	h.Init(app)
+
+	req := httptest.NewRequest("GET", "/v1/conversations", nil)
+	req.Header.Set("Authorization", testToken(t, 1))
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var conversations []models.Conversation
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&conversations))
+	require.Len(t, conversations, 1)
+	assert.Equal(t, "later", conversations[0].Draft.Content)
+
+	messageService.AssertExpectations(t)
+}
+
+func TestHandler_saveDraft(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+	}{
+		{
+			name: "OK",
+			body: `{"receiver_id":2,"content":"see you"}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("SaveDraft", mock.Anything, services.SaveDraftParams{
+					UserID:     1,
+					ReceiverID: 2,
+					Content:    "see you",
+				}).Return(&models.Draft{ReceiverID: 2, Content: "see you"}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name: "no conversation",
+			body: `{"content":"see you"}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("SaveDraft", mock.Anything, mock.Anything).Return(nil, services.ErrValidation)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest("PUT", "/v1/drafts", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			messageService.AssertExpectations(t)
+		})
+	}
+}
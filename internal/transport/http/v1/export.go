@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/services"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+func (h *Handler) initExportRoutes(router fiber.Router) {
+	router.Get("/export", h.requireUser, h.ExportConversation)
+}
+
+// ExportConversation выгружает переписку пользователя
+// @Summary Выгрузка переписки
+// @Tags export
+// @Description Отдаёт потоком историю комнаты (room_id) или личной переписки (receiver_id) с метаданными вложений,
+// @Description правками и реакциями. В выгрузку попадает только то, что пользователь видит в истории:
+// @Description скрытые им и истёкшие сообщения пропускаются, у удалённых остаётся только отметка об удалении.
+// @Description Форматы: jsonl (по умолчанию) — сообщение на строку, csv и html — самодостаточная страница
+// @Produce json
+// @Produce text/csv
+// @Produce text/html
+// @Param Authorization header string true "Bearer {token}"
+// @Param room_id query int false "ID комнаты"
+// @Param receiver_id query int false "ID собеседника"
+// @Param format query string false "jsonl, csv или html"
+// @Success 200 {file} file
+// @Failure 400 {object} HTTPError "Не указана переписка или неизвестный формат"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к комнате"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /export [get]
+func (h *Handler) ExportConversation(c *fiber.Ctx) error {
+	return h.sendExport(c, services.ExportParams{
+		UserID:     currentUserID(c),
+		ReceiverID: int64(c.QueryInt("receiver_id")),
+		RoomID:     int64(c.QueryInt("room_id")),
+		Format:     c.Query("format"),
+	})
+}
+
+// ExportConversationAsAdmin выгружает любую переписку для проверки
+// @Summary Выгрузка переписки администратором
+// @Tags admin
+// @Description Выгрузка комнаты (room_id) или личной переписки двух пользователей (user_ids=1,2) без проверки участия.
+// @Description Содержимое удалённых сообщений недоступно и администратору. Доступно только администраторам сервиса
+// @Produce json
+// @Produce text/csv
+// @Produce text/html
+// @Param Authorization header string true "Bearer {token}"
+// @Param room_id query int false "ID комнаты"
+// @Param user_ids query string false "ID участников личной переписки через запятую"
+// @Param format query string false "jsonl, csv или html"
+// @Success 200 {file} file
+// @Failure 400 {object} HTTPError "Не указана переписка или неизвестный формат"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет прав администратора"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /admin/export [get]
+func (h *Handler) ExportConversationAsAdmin(c *fiber.Ctx) error {
+	params := services.ExportParams{
+		Compliance: true,
+		RoomID:     int64(c.QueryInt("room_id")),
+		Format:     c.Query("format"),
+	}
+	if userIDs := c.Query("user_ids"); userIDs != "" {
+		parts := strings.Split(userIDs, ",")
+		if len(parts) != 2 {
+			return fiber.NewError(fiber.StatusBadRequest, "user_ids must contain two IDs")
+		}
+		for i, part := range parts {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid user_ids: %v", err))
+			}
+			params.UserIDs[i] = id
+		}
+	}
+
+	return h.sendExport(c, params)
+}
+
+// sendExport проверяет доступ до начала ответа, а затем отдаёт выгрузку потоком:
+// после отправки заголовков ошибка чтения только обрывает ответ и попадает в лог
+func (h *Handler) sendExport(c *fiber.Ctx, params services.ExportParams) error {
+	export, err := h.exports.Export(context.Background(), params)
+	if err != nil {
+		return serviceError("h.exports.Export", err)
+	}
+
+	c.Set(fiber.HeaderContentType, export.ContentType())
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": export.FileName()}))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := export.Write(context.Background(), w); err != nil && h.log != nil {
+			h.log.Errorf("failed to write export %s: %v", export.FileName(), err)
+		}
+		_ = w.Flush()
+	})
+	return nil
+}
@@ -0,0 +1,134 @@
+package v1_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+type MockExportService struct {
+	mock.Mock
+}
+
+func (m *MockExportService) Export(ctx context.Context, params services.ExportParams) (services.Export, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(services.Export), args.Error(1)
+}
+
+type stubExport struct {
+	body string
+}
+
+func (e stubExport) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (e stubExport) FileName() string {
+	return "room-5.csv"
+}
+
+func (e stubExport) Write(_ context.Context, w io.Writer) error {
+	_, err := io.WriteString(w, e.body)
+	return err
+}
+
+func TestHandler_export(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		userID         int64
+		mockBehavior   func(s *MockExportService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "member export",
+			url:    "/v1/export?room_id=5&format=csv",
+			userID: 1,
+			mockBehavior: func(s *MockExportService) {
+				s.On("Export", mock.Anything, services.ExportParams{UserID: 1, RoomID: 5, Format: "csv"}).
+					Return(stubExport{body: "id\n1\n"}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   "id\n1\n",
+		},
+		{
+			name:   "not a member",
+			url:    "/v1/export?room_id=5",
+			userID: 3,
+			mockBehavior: func(s *MockExportService) {
+				s.On("Export", mock.Anything, mock.Anything).Return(nil, services.ErrForbidden)
+			},
+			expectedStatus: fiber.StatusForbidden,
+		},
+		{
+			name:   "admin export of direct chat",
+			url:    "/v1/admin/export?user_ids=1,2&format=csv",
+			userID: 100,
+			mockBehavior: func(s *MockExportService) {
+				s.On("Export", mock.Anything, services.ExportParams{Compliance: true, UserIDs: [2]int64{1, 2}, Format: "csv"}).
+					Return(stubExport{body: "id\n"}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   "id\n",
+		},
+		{
+			name:           "admin export by regular user",
+			url:            "/v1/admin/export?room_id=5",
+			userID:         1,
+			mockBehavior:   func(s *MockExportService) {},
+			expectedStatus: fiber.StatusForbidden,
+		},
+		{
+			name:           "malformed user_ids",
+			url:            "/v1/admin/export?user_ids=1",
+			userID:         100,
+			mockBehavior:   func(s *MockExportService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exports := new(MockExportService)
+			tt.mockBehavior(exports)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				Exports:  exports,
+				TokenKey: testTokenKey,
+				AdminIDs: []int64{100},
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			req.Header.Set("Authorization", testToken(t, tt.userID))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus == fiber.StatusOK {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedBody, string(body))
+				assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+				assert.Contains(t, resp.Header.Get(fiber.HeaderContentDisposition), "room-5.csv")
+			}
+
+			exports.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/models"
+	"messanger/internal/services"
+)
+
+func (h *Handler) initFilterRoutes(router fiber.Router) {
+	router.Get("/filters", h.requireUser, h.GetMessageFilters)
+	router.Put("/filters", h.requireUser, h.SetMessageFilters)
+	router.Delete("/filters", h.requireUser, h.ResetMessageFilters)
+	router.Get("/flags", h.requireUser, h.GetFlaggedMessages)
+}
+
+// GetMessageFilters возвращает действующие настройки фильтрации сообщений
+// @Summary Фильтры сообщений
+// @Tags filters
+// @Description Настройки комнаты (room_id) или, если комната их не задавала или не указана, настройки сервера.
+// @Description Нулевое значение поля отключает соответствующий фильтр
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param room_id query int false "ID комнаты"
+// @Success 200 {object} models.MessageFilters
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к комнате"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /filters [get]
+func (h *Handler) GetMessageFilters(c *fiber.Ctx) error {
+	filters, err := h.messageService.GetMessageFilters(context.Background(), services.GetMessageFiltersParams{
+		UserID: currentUserID(c),
+		RoomID: int64(c.QueryInt("room_id")),
+	})
+	if err != nil {
+		return serviceError("h.messageService.GetMessageFilters", err)
+	}
+
+	return c.JSON(filters)
+}
+
+type SetMessageFiltersRequest struct {
+	RoomID int64 `json:"room_id"`
+	models.MessageFilters
+}
+
+// SetMessageFilters задаёт настройки фильтрации сообщений комнаты
+// @Summary Изменить фильтры сообщений
+// @Tags filters
+// @Description Заменяет настройки сервера для комнаты целиком; доступно администраторам комнаты.
+// @Description word_action: reject — отклонить сообщение, mask — заменить слово звёздочками, flag — пометить для модераторов
+// @Accept json
+// @Param Authorization header string true "Bearer {token}"
+// @Param filters body SetMessageFiltersRequest true "Комната и настройки"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Недостаточно прав"
+// @Failure 404 {object} HTTPError "Комната не найдена"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /filters [put]
+func (h *Handler) SetMessageFilters(c *fiber.Ctx) error {
+	var req SetMessageFiltersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	err := h.messageService.SetMessageFilters(context.Background(), services.SetMessageFiltersParams{
+		UserID:  currentUserID(c),
+		RoomID:  req.RoomID,
+		Filters: &req.MessageFilters,
+	})
+	if err != nil {
+		return serviceError("h.messageService.SetMessageFilters", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ResetMessageFilters возвращает комнату к настройкам фильтрации сервера
+// @Summary Сбросить фильтры сообщений
+// @Tags filters
+// @Description Удаляет собственные настройки комнаты; доступно администраторам комнаты
+// @Param Authorization header string true "Bearer {token}"
+// @Param room_id query int true "ID комнаты"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Не указана комната"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Недостаточно прав"
+// @Failure 404 {object} HTTPError "Комната не найдена"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /filters [delete]
+func (h *Handler) ResetMessageFilters(c *fiber.Ctx) error {
+	err := h.messageService.SetMessageFilters(context.Background(), services.SetMessageFiltersParams{
+		UserID: currentUserID(c),
+		RoomID: int64(c.QueryInt("room_id")),
+	})
+	if err != nil {
+		return serviceError("h.messageService.SetMessageFilters", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetFlaggedMessages возвращает сообщения комнаты, помеченные фильтрами
+// @Summary Помеченные сообщения
+// @Tags filters
+// @Description Сообщения, которые фильтры сохранили, но пометили как подозрительные, от новых к старым.
+// @Description Доступно администраторам комнаты
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param room_id query int true "ID комнаты"
+// @Param before_id query int false "Вернуть сообщения с ID меньше указанного"
+// @Param limit query int false "Количество сообщений (по умолчанию 50, не больше 100)"
+// @Success 200 {array} models.FlaggedMessage
+// @Failure 400 {object} HTTPError "Не указана комната"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Недостаточно прав"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /flags [get]
+func (h *Handler) GetFlaggedMessages(c *fiber.Ctx) error {
+	flagged, err := h.messageService.GetFlaggedMessages(context.Background(), services.GetFlaggedMessagesParams{
+		UserID:   currentUserID(c),
+		RoomID:   int64(c.QueryInt("room_id")),
+		BeforeID: int64(c.QueryInt("before_id")),
+		Limit:    c.QueryInt("limit"),
+	})
+	if err != nil {
+		return serviceError("h.messageService.GetFlaggedMessages", err)
+	}
+
+	return c.JSON(flagged)
+}
@@ -0,0 +1,143 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+func TestHandler_messageFilters(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+	}{
+		{
+			name:   "set",
+			method: "PUT",
+			url:    "/v1/filters",
+			body:   `{"room_id":5,"words":["spam"],"word_action":"mask","max_links":3}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("SetMessageFilters", mock.Anything, services.SetMessageFiltersParams{
+					UserID: 1,
+					RoomID: 5,
+					Filters: &models.MessageFilters{
+						Words:      []string{"spam"},
+						WordAction: models.FilterActionMask,
+						MaxLinks:   3,
+					},
+				}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:   "set invalid",
+			method: "PUT",
+			url:    "/v1/filters",
+			body:   `{"room_id":5,"word_action":"ban"}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("SetMessageFilters", mock.Anything, mock.Anything).Return(services.ErrValidation)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:   "reset",
+			method: "DELETE",
+			url:    "/v1/filters?room_id=5",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("SetMessageFilters", mock.Anything, services.SetMessageFiltersParams{UserID: 1, RoomID: 5}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:   "get",
+			method: "GET",
+			url:    "/v1/filters?room_id=5",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("GetMessageFilters", mock.Anything, services.GetMessageFiltersParams{UserID: 1, RoomID: 5}).
+					Return(&models.MessageFilters{MaxLinks: 10}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:   "flagged messages without admin rights",
+			method: "GET",
+			url:    "/v1/flags?room_id=5&before_id=40&limit=20",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("GetFlaggedMessages", mock.Anything, services.GetFlaggedMessagesParams{
+					UserID: 1, RoomID: 5, BeforeID: 40, Limit: 20,
+				}).Return(nil, services.ErrForbidden)
+			},
+			expectedStatus: fiber.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			messageService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestErrorHandler_rejectionDetails(t *testing.T) {
+	messageService := new(MockMessageService)
+	messageService.On("SaveMessage", mock.Anything, mock.Anything).
+		Return(nil, fmt.Errorf("filter: %w", &services.RejectionError{Stage: "words", Reason: "message contains a forbidden word"}))
+
+	app := fiber.New(fiber.Config{ErrorHandler: v1.ErrorHandler})
+	h := v1.NewHandler(v1.HandlerConfig{
+		MessageService: messageService,
+		TokenKey:       testTokenKey,
+	})
+	h.Init(app)
+
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"receiver_id":2,"content":"hi"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", testToken(t, 1))
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+
+	var body struct {
+		Code    string                  `json:"code"`
+		Details services.RejectionError `json:"details"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "message_rejected", body.Code)
+	assert.Equal(t, services.RejectionError{Stage: "words", Reason: "message contains a forbidden word"}, body.Details)
+}
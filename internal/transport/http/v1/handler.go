@@ -1,28 +1,163 @@
 package v1
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 	"messanger/internal/services"
+	"messanger/internal/transport/utils"
+	"slices"
 )
 
+const userIDLocal = "userID"
+
+// UserRegistrar записывает в справочник пользователей, обратившихся к API
+type UserRegistrar interface {
+	RegisterUser(ctx context.Context, userID int64) error
+}
+
 type Handler struct {
 	messageService services.MessageService
+	searchService  services.SearchService
+	attachments    services.AttachmentService
+	scheduled      services.ScheduledService
+	exports        services.ExportService
+	imports        services.ImportService
+	sync           services.SyncService
+	users          UserRegistrar
 	log            *logrus.Logger
+	tokenKey       string
+	adminIDs       []int64
 }
 
 type HandlerConfig struct {
 	MessageService services.MessageService
-	Log            *logrus.Logger
+	SearchService  services.SearchService
+	Attachments    services.AttachmentService
+	Scheduled      services.ScheduledService
+	Exports        services.ExportService
+	Imports        services.ImportService
+	Sync           services.SyncService
+	// Users регистрирует авторизованных пользователей, чтобы им можно было писать без подключения
+	// по WebSocket; nil отключает регистрацию
+	Users    UserRegistrar
+	Log      *logrus.Logger
+	TokenKey string
+	// AdminIDs пользователи с доступом к маршрутам /admin
+	AdminIDs []int64
 }
 
 func NewHandler(cfg HandlerConfig) *Handler {
 	return &Handler{
 		messageService: cfg.MessageService,
+		searchService:  cfg.SearchService,
+		attachments:    cfg.Attachments,
+		scheduled:      cfg.Scheduled,
+		exports:        cfg.Exports,
+		imports:        cfg.Imports,
+		sync:           cfg.Sync,
+		users:          cfg.Users,
 		log:            cfg.Log,
+		tokenKey:       cfg.TokenKey,
+		adminIDs:       cfg.AdminIDs,
 	}
 }
 
 func (h *Handler) Init(router fiber.Router) {
-	h.initMessageRoutes(router.Group("/v1"))
+	v1 := router.Group("/v1")
+
+	h.initMessageRoutes(v1)
+	h.initSearchRoutes(v1)
+	h.initAttachmentRoutes(v1)
+	h.initMentionRoutes(v1)
+	h.initScheduledRoutes(v1)
+	h.initPinRoutes(v1)
+	h.initConversationRoutes(v1)
+	h.initRoomRoutes(v1)
+	h.initPollRoutes(v1)
+	h.initRetentionRoutes(v1)
+	h.initFilterRoutes(v1)
+	h.initSyncRoutes(v1)
+	h.initExportRoutes(v1)
+	h.initAdminRoutes(v1)
+}
+
+// requireUser извлекает ID пользователя из токена и сохраняет его в контексте запроса
+func (h *Handler) requireUser(c *fiber.Ctx) error {
+	userID, err := utils.ExtractUserIDFromHeader(c.Get(fiber.HeaderAuthorization), h.tokenKey)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	// Ошибка регистрации не мешает самому запросу: пользователь лишь пока недоступен для личных сообщений
+	if h.users != nil {
+		if err = h.users.RegisterUser(context.Background(), userID); err != nil && h.log != nil {
+			h.log.Errorf("h.users.RegisterUser: %v", err)
+		}
+	}
+
+	c.Locals(userIDLocal, userID)
+	return c.Next()
+}
+
+// requireAdmin пропускает только администраторов сервиса; ставится после requireUser
+func (h *Handler) requireAdmin(c *fiber.Ctx) error {
+	if !slices.Contains(h.adminIDs, currentUserID(c)) {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+	return c.Next()
+}
+
+func currentUserID(c *fiber.Ctx) int64 {
+	userID, _ := c.Locals(userIDLocal).(int64)
+	return userID
+}
+
+// serviceError переводит ошибки сервисного слоя в HTTP-статусы
+func serviceError(op string, err error) error {
+	status, _ := utils.ServiceError(err)
+	if status == fiber.StatusInternalServerError {
+		return fiber.NewError(status, fmt.Sprintf("%s: %v", op, err))
+	}
+	if details := utils.ErrorDetails(err); details != nil {
+		return &detailedError{err: fiber.NewError(status, err.Error()), details: details}
+	}
+	return fiber.NewError(status, err.Error())
+}
+
+// detailedError ошибка с подробностями, которые ErrorHandler передаёт клиенту в HTTPError.Details
+type detailedError struct {
+	err     *fiber.Error
+	details any
+}
+
+func (e *detailedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *detailedError) Unwrap() error {
+	return e.err
+}
+
+// ErrorHandler отдаёт ошибки в виде HTTPError с кодом, общим с кадрами ошибок WebSocket
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		status = fiberErr.Code
+	}
+
+	var details any
+	var detailed *detailedError
+	if errors.As(err, &detailed) {
+		details = detailed.details
+	}
+
+	return c.Status(status).JSON(HTTPError{
+		Code:    utils.StatusCode(status),
+		Message: err.Error(),
+		Details: details,
+	})
 }
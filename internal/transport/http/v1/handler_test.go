@@ -0,0 +1,96 @@
+package v1_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		serviceErr     error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "validation",
+			serviceErr:     services.ErrValidation,
+			expectedStatus: fiber.StatusBadRequest,
+			expectedCode:   "validation_failed",
+		},
+		{
+			name:           "not found",
+			serviceErr:     services.ErrNotFound,
+			expectedStatus: fiber.StatusNotFound,
+			expectedCode:   "not_found",
+		},
+		{
+			name:           "forbidden",
+			serviceErr:     services.ErrForbidden,
+			expectedStatus: fiber.StatusForbidden,
+			expectedCode:   "forbidden",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			messageService.On("SaveMessage", mock.Anything, mock.Anything).Return(nil, tt.serviceErr)
+
+			app := fiber.New(fiber.Config{ErrorHandler: v1.ErrorHandler})
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(`{"receiver_id":2,"content":"hi"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var body v1.HTTPError
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tt.expectedCode, body.Code)
+			assert.NotEmpty(t, body.Message)
+		})
+	}
+}
+
+func TestHandler_requireUser_RegistersUser(t *testing.T) {
+	messageService := new(MockMessageService)
+	messageService.On("RegisterUser", mock.Anything, int64(1)).Return(nil)
+	messageService.On("GetConversations", mock.Anything, int64(1)).Return([]models.Conversation{}, nil)
+
+	app := fiber.New()
+	h := v1.NewHandler(v1.HandlerConfig{
+		MessageService: messageService,
+		Users:          messageService,
+		TokenKey:       testTokenKey,
+	})
+	h.Init(app)
+
+	req := httptest.NewRequest("GET", "/v1/conversations", nil)
+	req.Header.Set("Authorization", testToken(t, 1))
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	messageService.AssertExpectations(t)
+}
@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/services"
+	"messanger/pkg/chatimport"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImportHistory переносит историю из выгрузки Telegram или Slack
+// @Summary Импорт истории
+// @Tags admin
+// @Description Переносит историю из выгрузки Telegram (result.json или zip-архив папки выгрузки) или Slack (zip-архив).
+// @Description Для групповых переписок создаются комнаты, сообщения сохраняются с исходным временем, ответами и вложениями.
+// @Description Пользователи сопоставляются по файлу mapping вида {"telegram": {"user123": 1}, "slack": {"U024BE7LH": 2}}.
+// @Description Импорт можно повторять: уже перенесённые комнаты и сообщения пропускаются. Размер архива ограничен
+// @Description размером тела запроса; большие выгрузки импортируются командой cmd/import. Доступно только администраторам сервиса
+// @Accept multipart/form-data
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param source formData string true "telegram или slack"
+// @Param archive formData file true "Выгрузка"
+// @Param mapping formData file true "Соответствие пользователей"
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} HTTPError "Не переданы файлы, неизвестный источник или неверный формат выгрузки"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет прав администратора"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /admin/import [post]
+func (h *Handler) ImportHistory(c *fiber.Ctx) error {
+	source := c.FormValue("source")
+	if source != chatimport.SourceTelegram && source != chatimport.SourceSlack {
+		return fiber.NewError(fiber.StatusBadRequest, "source must be telegram or slack")
+	}
+
+	mappingHeader, err := c.FormFile("mapping")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "mapping is required")
+	}
+	mappingFile, err := mappingHeader.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("failed to open mapping: %v", err))
+	}
+	defer mappingFile.Close()
+	users, err := chatimport.ReadUserMap(mappingFile, source)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	archiveHeader, err := c.FormFile("archive")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "archive is required")
+	}
+
+	// Архив сохраняется во временный каталог: zip читается с произвольным доступом,
+	// а отдельный result.json открывается как каталог выгрузки без медиафайлов
+	dir, err := os.MkdirTemp("", "import-*")
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("failed to create temp dir: %v", err))
+	}
+	defer os.RemoveAll(dir)
+
+	name := "result.json"
+	if strings.EqualFold(filepath.Ext(archiveHeader.Filename), ".zip") {
+		name = "archive.zip"
+	}
+	archivePath := filepath.Join(dir, name)
+	if err = c.SaveFile(archiveHeader, archivePath); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("failed to save archive: %v", err))
+	}
+
+	archive, closer, err := chatimport.Open(archivePath)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	defer closer.Close()
+
+	report, err := h.imports.Import(context.Background(), services.ImportParams{
+		Source:  source,
+		Archive: archive,
+		Users:   users,
+	})
+	if err != nil {
+		return serviceError("h.imports.Import", err)
+	}
+
+	return c.JSON(report)
+}
@@ -0,0 +1,154 @@
+package v1_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/fs"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+// MockImportService реализует интерфейс services.ImportService для тестов
+type MockImportService struct {
+	mock.Mock
+}
+
+func (m *MockImportService) Import(ctx context.Context, params services.ImportParams) (*models.ImportReport, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImportReport), args.Error(1)
+}
+
+func TestHandler_importHistory(t *testing.T) {
+	const (
+		mapping = `{"telegram": {"user1": 1, "user2": 2}}`
+		export  = `{"name": "Team", "type": "private_group", "id": 1001, "messages": []}`
+	)
+
+	tests := []struct {
+		name           string
+		userID         int64
+		fields         map[string]string
+		files          map[string]string
+		mockBehavior   func(s *MockImportService)
+		expectedStatus int
+	}{
+		{
+			name:   "telegram result.json",
+			userID: 100,
+			fields: map[string]string{"source": "telegram"},
+			files:  map[string]string{"archive:export.json": export, "mapping:users.json": mapping},
+			mockBehavior: func(s *MockImportService) {
+				s.On("Import", mock.Anything, mock.MatchedBy(func(params services.ImportParams) bool {
+					data, err := fs.ReadFile(params.Archive, "result.json")
+					return err == nil && string(data) == export &&
+						params.Source == "telegram" && params.Users["user2"] == 2
+				})).Return(&models.ImportReport{
+					Source: "telegram",
+					Chats:  []models.ImportedChat{{ExternalID: "1001", RoomID: 7}},
+				}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:   "invalid archive",
+			userID: 100,
+			fields: map[string]string{"source": "telegram"},
+			files:  map[string]string{"archive:result.json": `{}`, "mapping:users.json": mapping},
+			mockBehavior: func(s *MockImportService) {
+				s.On("Import", mock.Anything, mock.Anything).Return(nil, services.ErrValidation)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "unknown source",
+			userID:         100,
+			fields:         map[string]string{"source": "icq"},
+			files:          map[string]string{"archive:result.json": export, "mapping:users.json": mapping},
+			mockBehavior:   func(s *MockImportService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "malformed mapping",
+			userID:         100,
+			fields:         map[string]string{"source": "telegram"},
+			files:          map[string]string{"archive:result.json": export, "mapping:users.json": `[1, 2]`},
+			mockBehavior:   func(s *MockImportService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "missing archive",
+			userID:         100,
+			fields:         map[string]string{"source": "telegram"},
+			files:          map[string]string{"mapping:users.json": mapping},
+			mockBehavior:   func(s *MockImportService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "regular user",
+			userID:         1,
+			fields:         map[string]string{"source": "telegram"},
+			files:          map[string]string{"archive:result.json": export, "mapping:users.json": mapping},
+			mockBehavior:   func(s *MockImportService) {},
+			expectedStatus: fiber.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports := new(MockImportService)
+			tt.mockBehavior(imports)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				Imports:  imports,
+				TokenKey: testTokenKey,
+				AdminIDs: []int64{100},
+			})
+			h.Init(app)
+
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			for name, value := range tt.fields {
+				require.NoError(t, form.WriteField(name, value))
+			}
+			for key, content := range tt.files {
+				field, fileName, _ := strings.Cut(key, ":")
+				part, err := form.CreateFormFile(field, fileName)
+				require.NoError(t, err)
+				_, err = part.Write([]byte(content))
+				require.NoError(t, err)
+			}
+			require.NoError(t, form.Close())
+
+			req := httptest.NewRequest("POST", "/v1/admin/import", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			req.Header.Set("Authorization", testToken(t, tt.userID))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus == fiber.StatusOK {
+				var report models.ImportReport
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+				assert.Equal(t, int64(7), report.Chats[0].RoomID)
+			}
+			imports.AssertExpectations(t)
+		})
+	}
+}
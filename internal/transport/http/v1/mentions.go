@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/services"
+)
+
+func (h *Handler) initMentionRoutes(router fiber.Router) {
+	mentions := router.Group("/mentions")
+	{
+		mentions.Get("/", h.requireUser, h.GetMentions)
+	}
+}
+
+// GetMentions возвращает входящие упоминания текущего пользователя
+// @Summary Входящие упоминания
+// @Tags mentions
+// @Description Упоминания текущего пользователя по всем перепискам, от новых к старым.
+// @Description Упоминание считается прочитанным, когда прочитано сообщение (POST /messages/read)
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param unread query bool false "Только непрочитанные"
+// @Param before query int false "Курсор: next_before_id предыдущей страницы"
+// @Param limit query int false "Размер страницы" default(50)
+// @Success 200 {object} models.MentionInbox
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /mentions [get]
+func (h *Handler) GetMentions(c *fiber.Ctx) error {
+	inbox, err := h.messageService.GetMentions(context.Background(), services.GetMentionsParams{
+		UserID:     currentUserID(c),
+		UnreadOnly: c.QueryBool("unread"),
+		BeforeID:   int64(c.QueryInt("before")),
+		Limit:      c.QueryInt("limit"),
+	})
+	if err != nil {
+		return serviceError("h.messageService.GetMentions", err)
+	}
+
+	return c.JSON(inbox)
+}
@@ -0,0 +1,55 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+func TestHandler_getMentions(t *testing.T) {
+	messageService := new(MockMessageService)
+	messageService.On("GetMentions", mock.Anything, services.GetMentionsParams{
+		UserID:     2,
+		UnreadOnly: true,
+		BeforeID:   40,
+		Limit:      10,
+	}).Return(&models.MentionInbox{
+		Mentions:    []models.Mention{{MessageID: 39, RoomID: 5, SenderID: 1, Kind: models.MentionRoom}},
+		UnreadCount: 3,
+	}, nil)
+
+	app := fiber.New()
+	h := v1.NewHandler(v1.HandlerConfig{
+		MessageService: messageService,
+		TokenKey:       testTokenKey,
+	})
+	h.Init(app)
+
+	req := httptest.NewRequest("GET", "/v1/mentions?unread=true&before=40&limit=10", nil)
+	req.Header.Set("Authorization", testToken(t, 2))
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var inbox models.MentionInbox
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&inbox))
+	assert.Equal(t, 3, inbox.UnreadCount)
+	require.Len(t, inbox.Mentions, 1)
+	assert.Equal(t, models.MentionRoom, inbox.Mentions[0].Kind)
+
+	req = httptest.NewRequest("GET", "/v1/mentions", nil)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	messageService.AssertExpectations(t)
+}
@@ -4,47 +4,77 @@ import (
 	"context"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
+	"messanger/internal/models"
 	"messanger/internal/services"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // HTTPError представляет ошибку HTTP-ответа
 type HTTPError struct {
+	// Code машиночитаемый код ошибки: validation_failed, unauthorized, forbidden, not_found, conflict, rate_limited,
+	// message_rejected, internal_error
+	Code    string `json:"code"`
 	Message string `json:"message"`
+	// Details подробности ошибки; для message_rejected — этап фильтра и причина отклонения
+	Details any `json:"details,omitempty"`
 }
 
 // MessageResponse DTO для ответа в API
-type MessageResponse struct {
-	ID         int64  `json:"id"`
-	SenderID   int64  `json:"sender_id"`
-	ReceiverID int64  `json:"receiver_id"`
-	Content    string `json:"content"`
-	CreatedAt  string `json:"created_at"`
-}
+type MessageResponse = models.Message
 
 func (h *Handler) initMessageRoutes(router fiber.Router) {
 	messages := router.Group("/messages")
 	{
-		messages.Get("/:id", h.GetMessagesByID)
-		messages.Post("/", h.CreateMessage)
+		messages.Get("/:id", h.requireUser, h.GetMessagesByID)
+		messages.Post("/", h.requireUser, h.CreateMessage)
+		messages.Post("/read", h.requireUser, h.MarkRead)
+		messages.Put("/ttl", h.requireUser, h.SetMessageTTL)
+		messages.Post("/forward", h.requireUser, h.ForwardMessages)
+		messages.Put("/forwarding", h.requireUser, h.SetForwarding)
+		messages.Patch("/:id", h.requireUser, h.EditMessage)
+		messages.Get("/:id/revisions", h.requireUser, h.GetMessageRevisions)
+		messages.Delete("/:id", h.requireUser, h.DeleteMessage)
+		messages.Post("/:id/reactions", h.requireUser, h.AddReaction)
+		messages.Delete("/:id/reactions", h.requireUser, h.RemoveReaction)
 	}
+	router.Get("/rooms/:id/messages", h.requireUser, h.GetRoomMessages)
 }
 
 type CreateMessageRequest struct {
-	SenderID   int64  `json:"sender_id"`
 	ReceiverID int64  `json:"receiver_id"`
+	RoomID     int64  `json:"room_id"`
 	Content    string `json:"content"`
+	// RichContent структурированное содержимое; Content в этом случае игнорируется
+	RichContent *models.RichContent `json:"rich_content"`
+	// ClientMsgID необязательный ключ идемпотентности, уникальный для отправителя
+	ClientMsgID string `json:"client_msg_id"`
+	// AttachmentIDs вложения, предварительно загруженные через POST /attachments
+	AttachmentIDs []int64 `json:"attachment_ids"`
+	// TTL время жизни сообщения в секундах; 0 — действует настройка переписки
+	TTL int64 `json:"ttl"`
+	// ViewOnce вложения можно просмотреть один раз
+	ViewOnce bool `json:"view_once"`
 }
 
 // CreateMessage создаёт новое сообщение
 // @Summary Создать сообщение
 // @Tags messages
-// @Description Сохраняет новое сообщение между двумя пользователями
+// @Description Сохраняет новое сообщение между двумя пользователями или в комнату (если указан room_id).
+// @Description Повторный запрос с тем же client_msg_id возвращает ранее сохранённое сообщение.
+// @Description Если передано rich_content, поле content вычисляется сервером как текстовое представление.
+// @Description Вложения из attachment_ids должны быть загружены отправителем и ещё не отправлены.
+// @Description Сообщение с ttl удаляется через указанное число секунд, с view_once — после первого прочтения
 // @Accept json
 // @Produce json
+// @Param Authorization header string true "Bearer {token}"
 // @Param message body CreateMessageRequest true "Данные сообщения"
-// @Success 201 {string} string "Created"
-// @Failure 400 {object} HTTPError "Ошибка при парсинге запроса"
+// @Success 201 {object} MessageResponse
+// @Failure 400 {object} HTTPError "Ошибка при парсинге запроса или недоступное вложение"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Отправитель не состоит в комнате"
+// @Failure 422 {object} HTTPError "Сообщение отклонено фильтром; этап и причина в details"
 // @Failure 500 {object} HTTPError "Ошибка при сохранении сообщения"
 // @Router /messages [post]
 func (h *Handler) CreateMessage(c *fiber.Ctx) error {
@@ -53,26 +83,40 @@ func (h *Handler) CreateMessage(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
 	}
 
-	err := h.messageService.SaveMessage(context.Background(), services.SaveMessageParams{
-		SenderID:   req.SenderID,
-		ReceiverID: req.ReceiverID,
-		Content:    req.Content,
+	message, err := h.messageService.SaveMessage(context.Background(), services.SaveMessageParams{
+		SenderID:      currentUserID(c),
+		ReceiverID:    req.ReceiverID,
+		RoomID:        req.RoomID,
+		Content:       req.Content,
+		RichContent:   req.RichContent,
+		ClientMsgID:   req.ClientMsgID,
+		AttachmentIDs: req.AttachmentIDs,
+		TTL:           time.Duration(req.TTL) * time.Second,
+		ViewOnce:      req.ViewOnce,
 	})
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("h.messageService.SaveMessage: %v", err))
+		return serviceError("h.messageService.SaveMessage", err)
 	}
 
-	return c.SendStatus(fiber.StatusCreated)
+	return c.Status(fiber.StatusCreated).JSON(message)
 }
 
 // GetMessagesByID возвращает историю сообщений между текущим пользователем и получателем
 // @Summary Получить сообщения по ID получателя
 // @Tags messages
-// @Description Возвращает историю сообщений между текущим пользователем (в будущем — по токену) и указанным получателем
+// @Description Возвращает историю сообщений между текущим пользователем и указанным получателем.
+// @Description Сообщения, удалённые для всех, возвращаются с пустым текстом и deleted_at, скрытые для себя — не возвращаются.
+// @Description Сообщения упорядочены по номеру seq от новых к старым. Следующая страница запрашивается с before_seq
+// @Description последнего сообщения; пропуск номеров в событиях WebSocket догружается запросом с after_seq
+// @Param Authorization header string true "Bearer {token}"
 // @Param id path int true "ID получателя"
+// @Param before_seq query int false "Сообщения с номером меньше указанного"
+// @Param after_seq query int false "Сообщения с номером больше указанного; не сочетается с before_seq"
+// @Param limit query int false "Количество сообщений (по умолчанию и не больше 100)"
 // @Produce json
 // @Success 200 {array} MessageResponse
-// @Failure 400 {object} HTTPError "Неверный ID"
+// @Failure 400 {object} HTTPError "Неверный ID или параметры страницы"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
 // @Failure 404 {object} HTTPError "Сообщения не найдены"
 // @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
 // @Router /messages/{id} [get]
@@ -88,11 +132,14 @@ func (h *Handler) GetMessagesByID(c *fiber.Ctx) error {
 	}
 
 	messages, err := h.messageService.GetHistory(context.Background(), services.GetHistoryParams{
-		SenderID:   0,
+		SenderID:   currentUserID(c),
 		ReceiverID: int64(receiverIDInt),
+		BeforeSeq:  int64(c.QueryInt("before_seq")),
+		AfterSeq:   int64(c.QueryInt("after_seq")),
+		Limit:      c.QueryInt("limit"),
 	})
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("h.messageService.GetHistory: %v", err))
+		return serviceError("h.messageService.GetHistory", err)
 	}
 
 	if len(messages) == 0 {
@@ -101,3 +148,396 @@ func (h *Handler) GetMessagesByID(c *fiber.Ctx) error {
 
 	return c.JSON(messages)
 }
+
+// GetRoomMessages возвращает историю сообщений комнаты
+// @Summary История комнаты
+// @Tags messages
+// @Description Возвращает сообщения комнаты от новых к старым в порядке номеров seq; доступно участникам комнаты.
+// @Description Для собственных сообщений заполняются status и seen_by — кто из участников и когда прочитал сообщение.
+// @Description Удалённые для всех сообщения возвращаются с пустым текстом и deleted_at, скрытые для себя — не возвращаются
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID комнаты"
+// @Param before_seq query int false "Сообщения с номером меньше указанного"
+// @Param after_seq query int false "Сообщения с номером больше указанного; не сочетается с before_seq"
+// @Param limit query int false "Количество сообщений (по умолчанию и не больше 100)"
+// @Produce json
+// @Success 200 {array} MessageResponse
+// @Failure 400 {object} HTTPError "Неверный ID или параметры страницы"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Пользователь не состоит в комнате"
+// @Failure 404 {object} HTTPError "Комната не найдена"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /rooms/{id}/messages [get]
+func (h *Handler) GetRoomMessages(c *fiber.Ctx) error {
+	roomID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid roomID: %v", err))
+	}
+
+	messages, err := h.messageService.GetHistory(context.Background(), services.GetHistoryParams{
+		SenderID:  currentUserID(c),
+		RoomID:    roomID,
+		BeforeSeq: int64(c.QueryInt("before_seq")),
+		AfterSeq:  int64(c.QueryInt("after_seq")),
+		Limit:     c.QueryInt("limit"),
+	})
+	if err != nil {
+		return serviceError("h.messageService.GetHistory", err)
+	}
+	if messages == nil {
+		messages = []models.Message{}
+	}
+
+	return c.JSON(messages)
+}
+
+type EditMessageRequest struct {
+	Content     string              `json:"content"`
+	RichContent *models.RichContent `json:"rich_content"`
+}
+
+// EditMessage редактирует сообщение
+// @Summary Редактировать сообщение
+// @Tags messages
+// @Description Заменяет текст сообщения, сохраняя предыдущую версию. Доступно только автору
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID сообщения"
+// @Param message body EditMessageRequest true "Новый текст"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} HTTPError "Ошибка при парсинге запроса"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Редактирование запрещено"
+// @Failure 404 {object} HTTPError "Сообщение не найдено"
+// @Failure 422 {object} HTTPError "Новый текст отклонён фильтром; этап и причина в details"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /messages/{id} [patch]
+func (h *Handler) EditMessage(c *fiber.Ctx) error {
+	messageID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid messageID: %v", err))
+	}
+
+	var req EditMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "content is required")
+	}
+
+	message, err := h.messageService.EditMessage(context.Background(), services.EditMessageParams{
+		MessageID:   messageID,
+		EditorID:    currentUserID(c),
+		Content:     req.Content,
+		RichContent: req.RichContent,
+	})
+	if err != nil {
+		return serviceError("h.messageService.EditMessage", err)
+	}
+
+	return c.JSON(message)
+}
+
+// GetMessageRevisions возвращает предыдущие версии сообщения
+// @Summary Получить историю правок сообщения
+// @Tags messages
+// @Description Возвращает предыдущие версии сообщения в порядке редактирования. Доступно участникам переписки
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID сообщения"
+// @Produce json
+// @Success 200 {array} models.MessageRevision
+// @Failure 400 {object} HTTPError "Неверный ID"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к переписке"
+// @Failure 404 {object} HTTPError "Сообщение не найдено"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /messages/{id}/revisions [get]
+func (h *Handler) GetMessageRevisions(c *fiber.Ctx) error {
+	messageID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid messageID: %v", err))
+	}
+
+	revisions, err := h.messageService.GetRevisions(context.Background(), services.GetRevisionsParams{
+		MessageID: messageID,
+		UserID:    currentUserID(c),
+	})
+	if err != nil {
+		return serviceError("h.messageService.GetRevisions", err)
+	}
+
+	return c.JSON(revisions)
+}
+
+// DeleteMessage удаляет сообщение
+// @Summary Удалить сообщение
+// @Tags messages
+// @Description scope=everyone стирает сообщение у всех участников (доступно автору и модераторам комнаты),
+// @Description scope=me скрывает сообщение только у текущего пользователя
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID сообщения"
+// @Param scope query string false "Режим удаления: me или everyone" default(me)
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Удаление запрещено"
+// @Failure 404 {object} HTTPError "Сообщение не найдено"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /messages/{id} [delete]
+func (h *Handler) DeleteMessage(c *fiber.Ctx) error {
+	messageID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid messageID: %v", err))
+	}
+
+	scope := c.Query("scope", services.DeleteForMe)
+	if scope != services.DeleteForMe && scope != services.DeleteForEveryone {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid scope: %q", scope))
+	}
+
+	err = h.messageService.DeleteMessage(context.Background(), services.DeleteMessageParams{
+		MessageID: messageID,
+		UserID:    currentUserID(c),
+		Scope:     scope,
+	})
+	if err != nil {
+		return serviceError("h.messageService.DeleteMessage", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type ReactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+// AddReaction добавляет реакцию на сообщение
+// @Summary Добавить реакцию
+// @Tags reactions
+// @Description Добавляет эмодзи-реакцию текущего пользователя на сообщение из доступной ему переписки
+// @Accept json
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID сообщения"
+// @Param reaction body ReactionRequest true "Эмодзи"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к переписке"
+// @Failure 404 {object} HTTPError "Сообщение не найдено"
+// @Failure 429 {object} HTTPError "Слишком много реакций"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /messages/{id}/reactions [post]
+func (h *Handler) AddReaction(c *fiber.Ctx) error {
+	messageID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid messageID: %v", err))
+	}
+
+	var req ReactionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	err = h.messageService.AddReaction(context.Background(), services.ReactionParams{
+		MessageID: messageID,
+		UserID:    currentUserID(c),
+		Emoji:     req.Emoji,
+	})
+	if err != nil {
+		return serviceError("h.messageService.AddReaction", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RemoveReaction снимает реакцию с сообщения
+// @Summary Удалить реакцию
+// @Tags reactions
+// @Description Снимает эмодзи-реакцию текущего пользователя с сообщения
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID сообщения"
+// @Param emoji query string true "Эмодзи"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к переписке"
+// @Failure 404 {object} HTTPError "Сообщение не найдено"
+// @Failure 429 {object} HTTPError "Слишком много реакций"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /messages/{id}/reactions [delete]
+func (h *Handler) RemoveReaction(c *fiber.Ctx) error {
+	messageID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid messageID: %v", err))
+	}
+
+	err = h.messageService.RemoveReaction(context.Background(), services.ReactionParams{
+		MessageID: messageID,
+		UserID:    currentUserID(c),
+		Emoji:     c.Query("emoji"),
+	})
+	if err != nil {
+		return serviceError("h.messageService.RemoveReaction", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type MarkReadRequest struct {
+	MessageIDs []int64 `json:"message_ids"`
+}
+
+// MarkRead отмечает сообщения прочитанными
+// @Summary Отметить сообщения прочитанными
+// @Tags receipts
+// @Description Переводит статус доставки указанных сообщений текущему пользователю в read и уведомляет отправителей
+// @Accept json
+// @Param Authorization header string true "Bearer {token}"
+// @Param receipt body MarkReadRequest true "ID прочитанных сообщений"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /messages/read [post]
+func (h *Handler) MarkRead(c *fiber.Ctx) error {
+	var req MarkReadRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	err := h.messageService.MarkRead(context.Background(), services.ReceiptParams{
+		UserID:     currentUserID(c),
+		MessageIDs: req.MessageIDs,
+	})
+	if err != nil {
+		return serviceError("h.messageService.MarkRead", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type SetMessageTTLRequest struct {
+	ReceiverID int64 `json:"receiver_id"`
+	RoomID     int64 `json:"room_id"`
+	// TTL время жизни новых сообщений в секундах; 0 отключает исчезающие сообщения
+	TTL int64 `json:"ttl"`
+}
+
+// SetMessageTTL настраивает исчезающие сообщения в переписке
+// @Summary Исчезающие сообщения
+// @Tags messages
+// @Description Задаёт время жизни сообщений, отправленных в переписку после изменения; уже отправленные не меняются.
+// @Description В комнате настройку меняют администраторы, в личной переписке — любой участник.
+// @Description Участники получают служебное сообщение, а истёкшие сообщения — событием message.expired
+// @Accept json
+// @Param Authorization header string true "Bearer {token}"
+// @Param settings body SetMessageTTLRequest true "Переписка и время жизни"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Недостаточно прав"
+// @Failure 404 {object} HTTPError "Комната не найдена"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /messages/ttl [put]
+func (h *Handler) SetMessageTTL(c *fiber.Ctx) error {
+	var req SetMessageTTLRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	err := h.messageService.SetMessageTTL(context.Background(), services.SetMessageTTLParams{
+		UserID:     currentUserID(c),
+		ReceiverID: req.ReceiverID,
+		RoomID:     req.RoomID,
+		TTL:        time.Duration(req.TTL) * time.Second,
+	})
+	if err != nil {
+		return serviceError("h.messageService.SetMessageTTL", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type ForwardMessagesRequest struct {
+	MessageIDs []int64 `json:"message_ids"`
+	ReceiverID int64   `json:"receiver_id"`
+	RoomID     int64   `json:"room_id"`
+}
+
+// ForwardMessages пересылает сообщения в другую переписку
+// @Summary Переслать сообщения
+// @Tags messages
+// @Description Пересылает сообщения в личную переписку (receiver_id) или комнату (room_id), в которой состоит пользователь.
+// @Description Копии содержат forwarded_from — ссылку на оригинал, его автора, переписку и время отправки — и переиспользуют вложения оригинала.
+// @Description Сообщения view once, служебные и из переписок с запретом пересылки переслать нельзя
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param forward body ForwardMessagesRequest true "Сообщения и переписка назначения"
+// @Success 201 {array} MessageResponse
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к переписке или пересылка запрещена"
+// @Failure 404 {object} HTTPError "Сообщение не найдено"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /messages/forward [post]
+func (h *Handler) ForwardMessages(c *fiber.Ctx) error {
+	var req ForwardMessagesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	forwarded, err := h.messageService.ForwardMessages(context.Background(), services.ForwardMessagesParams{
+		UserID:     currentUserID(c),
+		MessageIDs: req.MessageIDs,
+		ReceiverID: req.ReceiverID,
+		RoomID:     req.RoomID,
+	})
+	if err != nil {
+		return serviceError("h.messageService.ForwardMessages", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(forwarded)
+}
+
+type SetForwardingRequest struct {
+	ReceiverID int64 `json:"receiver_id"`
+	RoomID     int64 `json:"room_id"`
+	// Disabled запрещает пересылать сообщения из переписки
+	Disabled bool `json:"disabled"`
+}
+
+// SetForwarding запрещает или разрешает пересылку из переписки
+// @Summary Запрет пересылки
+// @Tags messages
+// @Description В комнате настройку меняют администраторы, в личной переписке — любой участник
+// @Accept json
+// @Param Authorization header string true "Bearer {token}"
+// @Param settings body SetForwardingRequest true "Переписка и настройка"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Недостаточно прав"
+// @Failure 404 {object} HTTPError "Комната не найдена"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /messages/forwarding [put]
+func (h *Handler) SetForwarding(c *fiber.Ctx) error {
+	var req SetForwardingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	err := h.messageService.SetForwarding(context.Background(), services.SetForwardingParams{
+		UserID:     currentUserID(c),
+		ReceiverID: req.ReceiverID,
+		RoomID:     req.RoomID,
+		Disabled:   req.Disabled,
+	})
+	if err != nil {
+		return serviceError("h.messageService.SetForwarding", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -26,9 +27,12 @@ type MockMessageService struct {
 	mock.Mock
 }
 
-func (m *MockMessageService) SaveMessage(ctx context.Context, params services.SaveMessageParams) error {
+func (m *MockMessageService) SaveMessage(ctx context.Context, params services.SaveMessageParams) (*models.Message, error) {
 	args := m.Called(ctx, params)
-	return args.Error(0)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
 }
 
 func (m *MockMessageService) GetHistory(ctx context.Context, params services.GetHistoryParams) ([]models.Message, error) {
@@ -36,6 +40,194 @@ func (m *MockMessageService) GetHistory(ctx context.Context, params services.Get
 	return args.Get(0).([]models.Message), args.Error(1)
 }
 
+func (m *MockMessageService) EditMessage(ctx context.Context, params services.EditMessageParams) (*models.Message, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+func (m *MockMessageService) GetRevisions(ctx context.Context, params services.GetRevisionsParams) ([]models.MessageRevision, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]models.MessageRevision), args.Error(1)
+}
+
+func (m *MockMessageService) DeleteMessage(ctx context.Context, params services.DeleteMessageParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) AddReaction(ctx context.Context, params services.ReactionParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) RemoveReaction(ctx context.Context, params services.ReactionParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) MarkDelivered(ctx context.Context, params services.ReceiptParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) MarkRead(ctx context.Context, params services.ReceiptParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) GetMentions(ctx context.Context, params services.GetMentionsParams) (*models.MentionInbox, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MentionInbox), args.Error(1)
+}
+
+func (m *MockMessageService) ForwardMessages(ctx context.Context, params services.ForwardMessagesParams) ([]models.Message, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+func (m *MockMessageService) SetForwarding(ctx context.Context, params services.SetForwardingParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) SaveDraft(ctx context.Context, params services.SaveDraftParams) (*models.Draft, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Draft), args.Error(1)
+}
+
+func (m *MockMessageService) SendTyping(ctx context.Context, params services.TypingParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) GetConversations(ctx context.Context, userID int64) ([]models.Conversation, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Conversation), args.Error(1)
+}
+
+func (m *MockMessageService) SetRetention(ctx context.Context, params services.SetRetentionParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) GetRetention(ctx context.Context, params services.GetRetentionParams) (*models.RetentionPolicy, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RetentionPolicy), args.Error(1)
+}
+
+func (m *MockMessageService) SetLegalHold(ctx context.Context, params services.SetLegalHoldParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) Vote(ctx context.Context, params services.VoteParams) (*models.PollResults, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PollResults), args.Error(1)
+}
+
+func (m *MockMessageService) RetractVote(ctx context.Context, params services.PollParams) (*models.PollResults, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PollResults), args.Error(1)
+}
+
+func (m *MockMessageService) ClosePoll(ctx context.Context, params services.PollParams) (*models.PollResults, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PollResults), args.Error(1)
+}
+
+func (m *MockMessageService) GetPollResults(ctx context.Context, params services.PollParams) (*models.PollResults, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PollResults), args.Error(1)
+}
+
+func (m *MockMessageService) SetMessageFilters(ctx context.Context, params services.SetMessageFiltersParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) GetMessageFilters(ctx context.Context, params services.GetMessageFiltersParams) (*models.MessageFilters, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MessageFilters), args.Error(1)
+}
+
+func (m *MockMessageService) GetFlaggedMessages(ctx context.Context, params services.GetFlaggedMessagesParams) ([]models.FlaggedMessage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.FlaggedMessage), args.Error(1)
+}
+
+func (m *MockMessageService) RegisterUser(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) AddRoomMember(ctx context.Context, params services.RoomMemberParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) RemoveRoomMember(ctx context.Context, params services.RoomMemberParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) PinMessage(ctx context.Context, params services.PinParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) UnpinMessage(ctx context.Context, params services.PinParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) GetPinnedMessages(ctx context.Context, params services.GetPinnedMessagesParams) ([]models.PinnedMessage, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]models.PinnedMessage), args.Error(1)
+}
+
+func (m *MockMessageService) SetMessageTTL(ctx context.Context, params services.SetMessageTTLParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+const testTokenKey = "test-key"
+
+func testToken(t *testing.T, userID int64) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": userID}).SignedString([]byte(testTokenKey))
+	require.NoError(t, err)
+	return "Bearer " + token
+}
+
 func TestHandler_getMessagesByID(t *testing.T) {
 	type mockBehavior func(s *MockMessageService, receiverID int64)
 
@@ -45,6 +237,7 @@ func TestHandler_getMessagesByID(t *testing.T) {
 			ID:         1,
 			SenderID:   1,
 			ReceiverID: 2,
+			Seq:        4,
 			Content:    "Hello",
 			SentAt:     &now,
 			CreatedAt:  now,
@@ -56,6 +249,7 @@ func TestHandler_getMessagesByID(t *testing.T) {
 	tests := []struct {
 		name             string
 		receiverID       string
+		query            string
 		mockBehavior     mockBehavior
 		expectedStatus   int
 		expectedResponse string
@@ -71,7 +265,33 @@ func TestHandler_getMessagesByID(t *testing.T) {
 				}).Return(testMessages, nil)
 			},
 			expectedStatus:   fiber.StatusOK,
-			expectedResponse: `[{"ID":1,"SenderID":1,"ReceiverID":2,"Content":"Hello","SentAt":"` + now.Format(time.RFC3339Nano) + `","CreatedAt":"` + now.Format(time.RFC3339Nano) + `","UpdatedAt":"` + now.Format(time.RFC3339Nano) + `","DeletedAt":null}]`,
+			expectedResponse: `[{"id":1,"sender_id":1,"receiver_id":2,"seq":4,"content":"Hello","sent_at":"` + now.Format(time.RFC3339Nano) + `","created_at":"` + now.Format(time.RFC3339Nano) + `","updated_at":"` + now.Format(time.RFC3339Nano) + `","edited":false}]`,
+		},
+		{
+			name:       "page before a seq",
+			receiverID: "2",
+			query:      "?before_seq=5&limit=20",
+			mockBehavior: func(s *MockMessageService, receiverID int64) {
+				s.On("GetHistory", mock.Anything, services.GetHistoryParams{
+					ReceiverID: 2,
+					BeforeSeq:  5,
+					Limit:      20,
+				}).Return(testMessages, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:       "both cursors",
+			receiverID: "2",
+			query:      "?before_seq=5&after_seq=2",
+			mockBehavior: func(s *MockMessageService, receiverID int64) {
+				s.On("GetHistory", mock.Anything, services.GetHistoryParams{
+					ReceiverID: 2,
+					BeforeSeq:  5,
+					AfterSeq:   2,
+				}).Return([]models.Message{}, services.ErrValidation)
+			},
+			expectedStatus: fiber.StatusBadRequest,
 		},
 		{
 			name:           "empty receiverID",
@@ -122,7 +342,7 @@ func TestHandler_getMessagesByID(t *testing.T) {
 			app.Get("/messages/:id", h.GetMessagesByID)
 
 			// Создание запроса
-			req := httptest.NewRequest("GET", "/messages/"+tt.receiverID, nil)
+			req := httptest.NewRequest("GET", "/messages/"+tt.receiverID+tt.query, nil)
 			resp, err := app.Test(req)
 			require.NoError(t, err)
 			defer resp.Body.Close()
@@ -151,7 +371,8 @@ func TestHandler_getMessagesByID(t *testing.T) {
 func TestHandler_createMessage(t *testing.T) {
 	type mockBehavior func(s *MockMessageService, req v1.CreateMessageRequest)
 	type request struct {
-		body string
+		body   string
+		userID int64
 	}
 
 	tests := []struct {
@@ -164,21 +385,62 @@ func TestHandler_createMessage(t *testing.T) {
 		{
 			name: "success",
 			input: request{
-				body: `{"sender_id":1,"receiver_id":2,"content":"Hello"}`,
+				body:   `{"receiver_id":2,"content":"Hello"}`,
+				userID: 1,
 			},
 			mockBehavior: func(s *MockMessageService, req v1.CreateMessageRequest) {
 				s.On("SaveMessage", mock.Anything, services.SaveMessageParams{
 					SenderID:   1,
 					ReceiverID: 2,
 					Content:    "Hello",
-				}).Return(nil)
+				}).Return(&models.Message{ID: 1, SenderID: 1, ReceiverID: 2, Content: "Hello"}, nil)
+			},
+			expectedStatus: fiber.StatusCreated,
+		},
+		{
+			name: "with client message id",
+			input: request{
+				body:   `{"receiver_id":2,"content":"Hello","client_msg_id":"c-1"}`,
+				userID: 1,
+			},
+			mockBehavior: func(s *MockMessageService, req v1.CreateMessageRequest) {
+				s.On("SaveMessage", mock.Anything, services.SaveMessageParams{
+					SenderID:    1,
+					ReceiverID:  2,
+					Content:     "Hello",
+					ClientMsgID: "c-1",
+				}).Return(&models.Message{ID: 7, SenderID: 1, ReceiverID: 2, ClientMsgID: "c-1", Content: "Hello"}, nil)
 			},
 			expectedStatus: fiber.StatusCreated,
 		},
+		{
+			name: "sender is taken from the token",
+			input: request{
+				body:   `{"sender_id":3,"room_id":5,"content":"Hello"}`,
+				userID: 1,
+			},
+			mockBehavior: func(s *MockMessageService, req v1.CreateMessageRequest) {
+				s.On("SaveMessage", mock.Anything, services.SaveMessageParams{
+					SenderID: 1,
+					RoomID:   5,
+					Content:  "Hello",
+				}).Return(&models.Message{ID: 2, SenderID: 1, RoomID: 5, Content: "Hello"}, nil)
+			},
+			expectedStatus: fiber.StatusCreated,
+		},
+		{
+			name: "unauthorized",
+			input: request{
+				body: `{"receiver_id":2,"content":"Hello"}`,
+			},
+			mockBehavior:   func(s *MockMessageService, req v1.CreateMessageRequest) {},
+			expectedStatus: fiber.StatusUnauthorized,
+		},
 		{
 			name: "invalid request body",
 			input: request{
-				body: `{"sender_id":"1","receiver_id":2,"content":"Hello"}`,
+				body:   `{"receiver_id":"2","content":"Hello"}`,
+				userID: 1,
 			},
 			mockBehavior:   func(s *MockMessageService, req v1.CreateMessageRequest) {},
 			expectedStatus: fiber.StatusBadRequest,
@@ -187,14 +449,15 @@ func TestHandler_createMessage(t *testing.T) {
 		{
 			name: "service error",
 			input: request{
-				body: `{"sender_id":1,"receiver_id":2,"content":"Hello"}`,
+				body:   `{"receiver_id":2,"content":"Hello"}`,
+				userID: 1,
 			},
 			mockBehavior: func(s *MockMessageService, req v1.CreateMessageRequest) {
 				s.On("SaveMessage", mock.Anything, services.SaveMessageParams{
 					SenderID:   1,
 					ReceiverID: 2,
 					Content:    "Hello",
-				}).Return(errors.New("database error"))
+				}).Return(nil, errors.New("database error"))
 			},
 			expectedStatus: fiber.StatusInternalServerError,
 			//expectedError:  "h.messageService.SaveMessage: database error",
@@ -219,13 +482,16 @@ func TestHandler_createMessage(t *testing.T) {
 			app := fiber.New()
 			h := v1.NewHandler(v1.HandlerConfig{
 				MessageService: messageService,
-				Log:            nil,
-			}) // предполагается, что у вас есть конструктор
-			app.Post("/messages", h.CreateMessage)
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
 
 			// Создание запроса
-			reqHTTP := httptest.NewRequest("POST", "/messages", bytes.NewBufferString(tt.input.body))
+			reqHTTP := httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(tt.input.body))
 			reqHTTP.Header.Set("Content-Type", "application/json")
+			if tt.input.userID != 0 {
+				reqHTTP.Header.Set("Authorization", testToken(t, tt.input.userID))
+			}
 			resp, err := app.Test(reqHTTP)
 			require.NoError(t, err)
 			defer resp.Body.Close()
@@ -244,3 +510,438 @@ func TestHandler_createMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_editMessage(t *testing.T) {
+	now := time.Now()
+	edited := &models.Message{ID: 10, SenderID: 1, ReceiverID: 2, Content: "Hello, world", Edited: true, EditedAt: &now}
+
+	tests := []struct {
+		name           string
+		messageID      string
+		body           string
+		authorized     bool
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+	}{
+		{
+			name:       "success",
+			messageID:  "10",
+			body:       `{"content":"Hello, world"}`,
+			authorized: true,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("EditMessage", mock.Anything, services.EditMessageParams{
+					MessageID: 10,
+					EditorID:  1,
+					Content:   "Hello, world",
+				}).Return(edited, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "unauthorized",
+			messageID:      "10",
+			body:           `{"content":"Hello, world"}`,
+			mockBehavior:   func(s *MockMessageService) {},
+			expectedStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:           "empty content",
+			messageID:      "10",
+			body:           `{"content":"  "}`,
+			authorized:     true,
+			mockBehavior:   func(s *MockMessageService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:       "not the author",
+			messageID:  "10",
+			body:       `{"content":"Hello, world"}`,
+			authorized: true,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("EditMessage", mock.Anything, mock.Anything).Return(nil, services.ErrForbidden)
+			},
+			expectedStatus: fiber.StatusForbidden,
+		},
+		{
+			name:       "message not found",
+			messageID:  "10",
+			body:       `{"content":"Hello, world"}`,
+			authorized: true,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("EditMessage", mock.Anything, mock.Anything).Return(nil, services.ErrNotFound)
+			},
+			expectedStatus: fiber.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest("PATCH", "/v1/messages/"+tt.messageID, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.authorized {
+				req.Header.Set("Authorization", testToken(t, 1))
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			messageService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_deleteMessage(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+	}{
+		{
+			name:   "delete for me by default",
+			target: "/v1/messages/10",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("DeleteMessage", mock.Anything, services.DeleteMessageParams{
+					MessageID: 10,
+					UserID:    1,
+					Scope:     services.DeleteForMe,
+				}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:   "delete for everyone",
+			target: "/v1/messages/10?scope=everyone",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("DeleteMessage", mock.Anything, services.DeleteMessageParams{
+					MessageID: 10,
+					UserID:    1,
+					Scope:     services.DeleteForEveryone,
+				}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:           "invalid scope",
+			target:         "/v1/messages/10?scope=all",
+			mockBehavior:   func(s *MockMessageService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:   "forbidden",
+			target: "/v1/messages/10?scope=everyone",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("DeleteMessage", mock.Anything, mock.Anything).Return(services.ErrForbidden)
+			},
+			expectedStatus: fiber.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest("DELETE", tt.target, nil)
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			messageService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_reactions(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+	}{
+		{
+			name:   "add reaction",
+			method: "POST",
+			target: "/v1/messages/10/reactions",
+			body:   `{"emoji":"👍"}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("AddReaction", mock.Anything, services.ReactionParams{MessageID: 10, UserID: 1, Emoji: "👍"}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:   "invalid emoji",
+			method: "POST",
+			target: "/v1/messages/10/reactions",
+			body:   `{"emoji":"like"}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("AddReaction", mock.Anything, mock.Anything).Return(services.ErrValidation)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:   "rate limited",
+			method: "POST",
+			target: "/v1/messages/10/reactions",
+			body:   `{"emoji":"👍"}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("AddReaction", mock.Anything, mock.Anything).Return(services.ErrRateLimited)
+			},
+			expectedStatus: fiber.StatusTooManyRequests,
+		},
+		{
+			name:   "remove reaction",
+			method: "DELETE",
+			target: "/v1/messages/10/reactions?emoji=%F0%9F%91%8D",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("RemoveReaction", mock.Anything, services.ReactionParams{MessageID: 10, UserID: 1, Emoji: "👍"}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			messageService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_setMessageTTL(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+	}{
+		{
+			name: "OK",
+			body: `{"room_id":5,"ttl":86400}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("SetMessageTTL", mock.Anything, services.SetMessageTTLParams{
+					UserID: 1,
+					RoomID: 5,
+					TTL:    24 * time.Hour,
+				}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name: "not an admin",
+			body: `{"room_id":5,"ttl":60}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("SetMessageTTL", mock.Anything, mock.Anything).Return(services.ErrForbidden)
+			},
+			expectedStatus: fiber.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest("PUT", "/v1/messages/ttl", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			messageService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_forwardMessages(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+	}{
+		{
+			name: "OK",
+			body: `{"message_ids":[7,8],"room_id":20}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("ForwardMessages", mock.Anything, services.ForwardMessagesParams{
+					UserID:     1,
+					MessageIDs: []int64{7, 8},
+					RoomID:     20,
+				}).Return([]models.Message{
+					{ID: 100, RoomID: 20, ForwardedFrom: &models.ForwardedFrom{MessageID: 7, SenderID: 2}},
+					{ID: 101, RoomID: 20, ForwardedFrom: &models.ForwardedFrom{MessageID: 8, SenderID: 3}},
+				}, nil)
+			},
+			expectedStatus: fiber.StatusCreated,
+		},
+		{
+			name: "forwarding disabled",
+			body: `{"message_ids":[7],"receiver_id":3}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("ForwardMessages", mock.Anything, mock.Anything).Return([]models.Message(nil), services.ErrForbidden)
+			},
+			expectedStatus: fiber.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest("POST", "/v1/messages/forward", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus == fiber.StatusCreated {
+				var forwarded []models.Message
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&forwarded))
+				require.Len(t, forwarded, 2)
+				assert.Equal(t, int64(7), forwarded[0].ForwardedFrom.MessageID)
+			}
+
+			messageService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_getRoomMessages(t *testing.T) {
+	readAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name           string
+		url            string
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "page with receipts",
+			url:  "/v1/rooms/5/messages?before_seq=10&limit=2",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("GetHistory", mock.Anything, services.GetHistoryParams{SenderID: 1, RoomID: 5, BeforeSeq: 10, Limit: 2}).
+					Return([]models.Message{{
+						ID: 3, SenderID: 1, RoomID: 5, Seq: 9, Content: "hi", Pinned: true, Status: models.ReceiptRead,
+						SeenBy: []models.SeenBy{{UserID: 2, ReadAt: readAt}},
+					}}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody: `[{"id":3,"sender_id":1,"receiver_id":0,"room_id":5,"seq":9,"content":"hi","created_at":"0001-01-01T00:00:00Z",
+				"updated_at":"0001-01-01T00:00:00Z","edited":false,"pinned":true,"status":"read","seen_by":[{"user_id":2,"read_at":"2026-01-02T03:04:05Z"}]}]`,
+		},
+		{
+			name: "empty room",
+			url:  "/v1/rooms/5/messages",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("GetHistory", mock.Anything, services.GetHistoryParams{SenderID: 1, RoomID: 5}).Return([]models.Message(nil), nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `[]`,
+		},
+		{
+			name: "not a member",
+			url:  "/v1/rooms/5/messages",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("GetHistory", mock.Anything, mock.Anything).Return([]models.Message(nil), services.ErrForbidden)
+			},
+			expectedStatus: fiber.StatusForbidden,
+		},
+		{
+			name:           "invalid room id",
+			url:            "/v1/rooms/abc/messages",
+			mockBehavior:   func(s *MockMessageService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedBody != "" {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.JSONEq(t, tt.expectedBody, string(body))
+			}
+			messageService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/services"
+	"strconv"
+)
+
+func (h *Handler) initPinRoutes(router fiber.Router) {
+	router.Post("/messages/:id/pin", h.requireUser, h.PinMessage)
+	router.Delete("/messages/:id/pin", h.requireUser, h.UnpinMessage)
+	router.Get("/pins", h.requireUser, h.GetPinnedMessages)
+}
+
+// PinMessage закрепляет сообщение
+// @Summary Закрепить сообщение
+// @Tags pins
+// @Description Закрепляет сообщение в переписке. В комнате это могут администраторы, в личной переписке — оба участника.
+// @Description Участники получают событие message.pinned; повторное закрепление ничего не меняет
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID сообщения"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверный ID или превышен лимит закреплённых сообщений"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Недостаточно прав"
+// @Failure 404 {object} HTTPError "Сообщение не найдено"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /messages/{id}/pin [post]
+func (h *Handler) PinMessage(c *fiber.Ctx) error {
+	messageID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid messageID: %v", err))
+	}
+
+	err = h.messageService.PinMessage(context.Background(), services.PinParams{
+		MessageID: messageID,
+		UserID:    currentUserID(c),
+	})
+	if err != nil {
+		return serviceError("h.messageService.PinMessage", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// UnpinMessage открепляет сообщение
+// @Summary Открепить сообщение
+// @Tags pins
+// @Description Участники получают событие message.unpinned
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID сообщения"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверный ID"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Недостаточно прав"
+// @Failure 404 {object} HTTPError "Сообщение не найдено"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /messages/{id}/pin [delete]
+func (h *Handler) UnpinMessage(c *fiber.Ctx) error {
+	messageID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid messageID: %v", err))
+	}
+
+	err = h.messageService.UnpinMessage(context.Background(), services.PinParams{
+		MessageID: messageID,
+		UserID:    currentUserID(c),
+	})
+	if err != nil {
+		return serviceError("h.messageService.UnpinMessage", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetPinnedMessages возвращает закреплённые сообщения переписки
+// @Summary Закреплённые сообщения
+// @Tags pins
+// @Description Закреплённые сообщения комнаты (room_id) или личной переписки с пользователем (receiver_id), начиная с последнего закреплённого
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param room_id query int false "ID комнаты"
+// @Param receiver_id query int false "ID собеседника"
+// @Success 200 {array} models.PinnedMessage
+// @Failure 400 {object} HTTPError "Не указана переписка"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к комнате"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /pins [get]
+func (h *Handler) GetPinnedMessages(c *fiber.Ctx) error {
+	pinned, err := h.messageService.GetPinnedMessages(context.Background(), services.GetPinnedMessagesParams{
+		UserID:     currentUserID(c),
+		ReceiverID: int64(c.QueryInt("receiver_id")),
+		RoomID:     int64(c.QueryInt("room_id")),
+	})
+	if err != nil {
+		return serviceError("h.messageService.GetPinnedMessages", err)
+	}
+
+	return c.JSON(pinned)
+}
@@ -0,0 +1,122 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+func TestHandler_pins(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+	}{
+		{
+			name:   "pin",
+			method: "POST",
+			url:    "/v1/messages/7/pin",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("PinMessage", mock.Anything, services.PinParams{MessageID: 7, UserID: 1}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:   "pin without admin rights",
+			method: "POST",
+			url:    "/v1/messages/7/pin",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("PinMessage", mock.Anything, mock.Anything).Return(services.ErrForbidden)
+			},
+			expectedStatus: fiber.StatusForbidden,
+		},
+		{
+			name:           "invalid message id",
+			method:         "POST",
+			url:            "/v1/messages/abc/pin",
+			mockBehavior:   func(s *MockMessageService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:   "unpin",
+			method: "DELETE",
+			url:    "/v1/messages/7/pin",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("UnpinMessage", mock.Anything, services.PinParams{MessageID: 7, UserID: 1}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:   "unpin missing message",
+			method: "DELETE",
+			url:    "/v1/messages/7/pin",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("UnpinMessage", mock.Anything, mock.Anything).Return(services.ErrNotFound)
+			},
+			expectedStatus: fiber.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			messageService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_getPinnedMessages(t *testing.T) {
+	messageService := new(MockMessageService)
+	messageService.On("GetPinnedMessages", mock.Anything, services.GetPinnedMessagesParams{UserID: 1, RoomID: 5}).
+		Return([]models.PinnedMessage{{Pin: models.Pin{MessageID: 7, RoomID: 5, PinnedBy: 2}}}, nil)
+
+	app := fiber.New()
+	h := v1.NewHandler(v1.HandlerConfig{
+		MessageService: messageService,
+		TokenKey:       testTokenKey,
+	})
+	h.Init(app)
+
+	req := httptest.NewRequest("GET", "/v1/pins?room_id=5", nil)
+	req.Header.Set("Authorization", testToken(t, 1))
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var pinned []models.PinnedMessage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&pinned))
+	require.Len(t, pinned, 1)
+	assert.Equal(t, int64(2), pinned[0].PinnedBy)
+
+	messageService.AssertExpectations(t)
+}
@@ -0,0 +1,158 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/services"
+	"strconv"
+)
+
+func (h *Handler) initPollRoutes(router fiber.Router) {
+	router.Get("/polls/:id", h.requireUser, h.GetPollResults)
+	router.Post("/polls/:id/votes", h.requireUser, h.Vote)
+	router.Delete("/polls/:id/votes", h.requireUser, h.RetractVote)
+	router.Post("/polls/:id/close", h.requireUser, h.ClosePoll)
+}
+
+// GetPollResults возвращает итоги опроса
+// @Summary Итоги опроса
+// @Tags polls
+// @Description Голоса по вариантам, число проголосовавших и выбор текущего пользователя (my_votes).
+// @Description В анонимных опросах список проголосовавших не раскрывается
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID сообщения с опросом"
+// @Success 200 {object} models.PollResults
+// @Failure 400 {object} HTTPError "Неверный ID или сообщение не является опросом"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к переписке"
+// @Failure 404 {object} HTTPError "Опрос не найден"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /polls/{id} [get]
+func (h *Handler) GetPollResults(c *fiber.Ctx) error {
+	messageID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid messageID: %v", err))
+	}
+
+	results, err := h.messageService.GetPollResults(context.Background(), services.PollParams{
+		MessageID: messageID,
+		UserID:    currentUserID(c),
+	})
+	if err != nil {
+		return serviceError("h.messageService.GetPollResults", err)
+	}
+
+	return c.JSON(results)
+}
+
+type VoteRequest struct {
+	// Options индексы выбранных вариантов; в опросе с одним ответом — ровно один
+	Options []int `json:"options"`
+}
+
+// Vote голосует в опросе
+// @Summary Проголосовать
+// @Tags polls
+// @Description Принимает голос; повторный голос заменяет прежний, если опрос разрешает менять решение.
+// @Description Участники переписки получают событие poll.updated с новыми итогами
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID сообщения с опросом"
+// @Param vote body VoteRequest true "Выбранные варианты"
+// @Success 200 {object} models.PollResults
+// @Failure 400 {object} HTTPError "Неверные варианты"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к переписке"
+// @Failure 404 {object} HTTPError "Опрос не найден"
+// @Failure 409 {object} HTTPError "Опрос закрыт или голос нельзя изменить"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /polls/{id}/votes [post]
+func (h *Handler) Vote(c *fiber.Ctx) error {
+	messageID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid messageID: %v", err))
+	}
+
+	var req VoteRequest
+	if err = c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	results, err := h.messageService.Vote(context.Background(), services.VoteParams{
+		MessageID: messageID,
+		UserID:    currentUserID(c),
+		Options:   req.Options,
+	})
+	if err != nil {
+		return serviceError("h.messageService.Vote", err)
+	}
+
+	return c.JSON(results)
+}
+
+// RetractVote отзывает голос
+// @Summary Отозвать голос
+// @Tags polls
+// @Description Доступно только в опросах, где можно менять решение. Участники получают событие poll.updated
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID сообщения с опросом"
+// @Success 200 {object} models.PollResults
+// @Failure 400 {object} HTTPError "Неверный ID"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Голос нельзя изменить"
+// @Failure 404 {object} HTTPError "Опрос не найден"
+// @Failure 409 {object} HTTPError "Опрос закрыт"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /polls/{id}/votes [delete]
+func (h *Handler) RetractVote(c *fiber.Ctx) error {
+	messageID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid messageID: %v", err))
+	}
+
+	results, err := h.messageService.RetractVote(context.Background(), services.PollParams{
+		MessageID: messageID,
+		UserID:    currentUserID(c),
+	})
+	if err != nil {
+		return serviceError("h.messageService.RetractVote", err)
+	}
+
+	return c.JSON(results)
+}
+
+// ClosePoll закрывает опрос досрочно
+// @Summary Закрыть опрос
+// @Tags polls
+// @Description Закрыть опрос может автор или администратор комнаты. Участники получают событие poll.closed
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID сообщения с опросом"
+// @Success 200 {object} models.PollResults
+// @Failure 400 {object} HTTPError "Неверный ID"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Недостаточно прав"
+// @Failure 404 {object} HTTPError "Опрос не найден"
+// @Failure 409 {object} HTTPError "Опрос уже закрыт"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /polls/{id}/close [post]
+func (h *Handler) ClosePoll(c *fiber.Ctx) error {
+	messageID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid messageID: %v", err))
+	}
+
+	results, err := h.messageService.ClosePoll(context.Background(), services.PollParams{
+		MessageID: messageID,
+		UserID:    currentUserID(c),
+	})
+	if err != nil {
+		return serviceError("h.messageService.ClosePoll", err)
+	}
+
+	return c.JSON(results)
+}
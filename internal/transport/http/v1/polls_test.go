@@ -0,0 +1,128 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+func TestHandler_polls(t *testing.T) {
+	results := &models.PollResults{
+		MessageID:   7,
+		Options:     []models.PollOptionResult{{Option: 0, Votes: 1}, {Option: 1}},
+		TotalVoters: 1,
+		MyVotes:     []int{0},
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+	}{
+		{
+			name:   "results",
+			method: "GET",
+			url:    "/v1/polls/7",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("GetPollResults", mock.Anything, services.PollParams{MessageID: 7, UserID: 1}).Return(results, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:   "vote",
+			method: "POST",
+			url:    "/v1/polls/7/votes",
+			body:   `{"options":[0]}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("Vote", mock.Anything, services.VoteParams{MessageID: 7, UserID: 1, Options: []int{0}}).Return(results, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:   "vote in closed poll",
+			method: "POST",
+			url:    "/v1/polls/7/votes",
+			body:   `{"options":[1]}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("Vote", mock.Anything, mock.Anything).Return(nil, services.ErrConflict)
+			},
+			expectedStatus: fiber.StatusConflict,
+		},
+		{
+			name:           "invalid body",
+			method:         "POST",
+			url:            "/v1/polls/7/votes",
+			body:           `{"options":`,
+			mockBehavior:   func(s *MockMessageService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:   "retract vote",
+			method: "DELETE",
+			url:    "/v1/polls/7/votes",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("RetractVote", mock.Anything, services.PollParams{MessageID: 7, UserID: 1}).Return(results, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:   "close without rights",
+			method: "POST",
+			url:    "/v1/polls/7/close",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("ClosePoll", mock.Anything, services.PollParams{MessageID: 7, UserID: 1}).Return(nil, services.ErrForbidden)
+			},
+			expectedStatus: fiber.StatusForbidden,
+		},
+		{
+			name:           "invalid message id",
+			method:         "POST",
+			url:            "/v1/polls/abc/close",
+			mockBehavior:   func(s *MockMessageService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus == fiber.StatusOK {
+				var got models.PollResults
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+				assert.Equal(t, []int{0}, got.MyVotes)
+			}
+
+			messageService.AssertExpectations(t)
+		})
+	}
+}
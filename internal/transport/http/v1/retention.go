@@ -0,0 +1,127 @@
+package v1
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/models"
+	"messanger/internal/services"
+)
+
+func (h *Handler) initRetentionRoutes(router fiber.Router) {
+	router.Get("/retention", h.requireUser, h.GetRetention)
+	router.Put("/retention", h.requireUser, h.SetRetention)
+}
+
+// GetRetention возвращает сроки хранения переписки
+// @Summary Сроки хранения переписки
+// @Tags retention
+// @Description Собственные настройки комнаты (room_id) или личной переписки (receiver_id).
+// @Description null в сроке означает, что действует глобальная настройка сервера, 0 — сообщения хранятся бессрочно
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param room_id query int false "ID комнаты"
+// @Param receiver_id query int false "ID собеседника"
+// @Success 200 {object} models.RetentionPolicy
+// @Failure 400 {object} HTTPError "Не указана переписка"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет доступа к комнате"
+// @Failure 404 {object} HTTPError "Комната не найдена"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /retention [get]
+func (h *Handler) GetRetention(c *fiber.Ctx) error {
+	policy, err := h.messageService.GetRetention(context.Background(), services.GetRetentionParams{
+		UserID:     currentUserID(c),
+		ReceiverID: int64(c.QueryInt("receiver_id")),
+		RoomID:     int64(c.QueryInt("room_id")),
+	})
+	if err != nil {
+		return serviceError("h.messageService.GetRetention", err)
+	}
+
+	return c.JSON(policy)
+}
+
+type SetRetentionRequest struct {
+	ReceiverID int64 `json:"receiver_id"`
+	RoomID     int64 `json:"room_id"`
+	// DeletedDays через сколько дней стирать удалённые сообщения; null — глобальная настройка, 0 — бессрочно
+	DeletedDays *int `json:"deleted_retention_days"`
+	// MessageDays через сколько дней стирать любые сообщения; null — глобальная настройка, 0 — бессрочно
+	MessageDays *int `json:"message_retention_days"`
+}
+
+// SetRetention задаёт сроки хранения переписки
+// @Summary Изменить сроки хранения
+// @Tags retention
+// @Description Переопределяет глобальные сроки хранения для переписки. В комнате настройку меняют администраторы,
+// @Description в личной переписке — любой участник. Переписки под legal hold не очищаются независимо от сроков
+// @Accept json
+// @Param Authorization header string true "Bearer {token}"
+// @Param settings body SetRetentionRequest true "Переписка и сроки"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Недостаточно прав"
+// @Failure 404 {object} HTTPError "Комната не найдена"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /retention [put]
+func (h *Handler) SetRetention(c *fiber.Ctx) error {
+	var req SetRetentionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	err := h.messageService.SetRetention(context.Background(), services.SetRetentionParams{
+		UserID:     currentUserID(c),
+		ReceiverID: req.ReceiverID,
+		RoomID:     req.RoomID,
+		Policy: models.RetentionPolicy{
+			DeletedDays: req.DeletedDays,
+			MessageDays: req.MessageDays,
+		},
+	})
+	if err != nil {
+		return serviceError("h.messageService.SetRetention", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type SetLegalHoldRequest struct {
+	RoomID int64 `json:"room_id"`
+	// UserIDs участники личной переписки; используется, если room_id не задан
+	UserIDs [2]int64 `json:"user_ids"`
+	Hold    bool     `json:"hold"`
+}
+
+// SetLegalHold ставит или снимает legal hold с переписки
+// @Summary Legal hold
+// @Tags admin
+// @Description Переписка под legal hold исключается из очистки по срокам хранения. Доступно только администраторам сервиса
+// @Accept json
+// @Param Authorization header string true "Bearer {token}"
+// @Param hold body SetLegalHoldRequest true "Переписка и признак"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Нет прав администратора"
+// @Failure 404 {object} HTTPError "Комната не найдена"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /admin/legal-hold [put]
+func (h *Handler) SetLegalHold(c *fiber.Ctx) error {
+	var req SetLegalHoldRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	err := h.messageService.SetLegalHold(context.Background(), services.SetLegalHoldParams{
+		RoomID:  req.RoomID,
+		UserIDs: req.UserIDs,
+		Hold:    req.Hold,
+	})
+	if err != nil {
+		return serviceError("h.messageService.SetLegalHold", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,134 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+func TestHandler_retention(t *testing.T) {
+	week := 7
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		userID         int64
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+	}{
+		{
+			name:   "set",
+			method: "PUT",
+			url:    "/v1/retention",
+			body:   `{"room_id":5,"deleted_retention_days":7,"message_retention_days":null}`,
+			userID: 1,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("SetRetention", mock.Anything, services.SetRetentionParams{
+					UserID: 1,
+					RoomID: 5,
+					Policy: models.RetentionPolicy{DeletedDays: &week},
+				}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:   "set without admin rights",
+			method: "PUT",
+			url:    "/v1/retention",
+			body:   `{"room_id":5,"deleted_retention_days":7}`,
+			userID: 3,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("SetRetention", mock.Anything, mock.Anything).Return(services.ErrForbidden)
+			},
+			expectedStatus: fiber.StatusForbidden,
+		},
+		{
+			name:   "legal hold by admin",
+			method: "PUT",
+			url:    "/v1/admin/legal-hold",
+			body:   `{"user_ids":[1,2],"hold":true}`,
+			userID: 100,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("SetLegalHold", mock.Anything, services.SetLegalHoldParams{UserIDs: [2]int64{1, 2}, Hold: true}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:           "legal hold by regular user",
+			method:         "PUT",
+			url:            "/v1/admin/legal-hold",
+			body:           `{"room_id":5,"hold":false}`,
+			userID:         1,
+			mockBehavior:   func(s *MockMessageService) {},
+			expectedStatus: fiber.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+				AdminIDs:       []int64{100},
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", testToken(t, tt.userID))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			messageService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_getRetention(t *testing.T) {
+	zero := 0
+	messageService := new(MockMessageService)
+	messageService.On("GetRetention", mock.Anything, services.GetRetentionParams{UserID: 1, ReceiverID: 2}).
+		Return(&models.RetentionPolicy{MessageDays: &zero, LegalHold: true}, nil)
+
+	app := fiber.New()
+	h := v1.NewHandler(v1.HandlerConfig{
+		MessageService: messageService,
+		TokenKey:       testTokenKey,
+	})
+	h.Init(app)
+
+	req := httptest.NewRequest("GET", "/v1/retention?receiver_id=2", nil)
+	req.Header.Set("Authorization", testToken(t, 1))
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Nil(t, body["deleted_retention_days"], "unset days fall back to the server default")
+	assert.Equal(t, float64(0), body["message_retention_days"])
+	assert.Equal(t, true, body["legal_hold"])
+
+	messageService.AssertExpectations(t)
+}
@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/services"
+	"strconv"
+)
+
+func (h *Handler) initRoomRoutes(router fiber.Router) {
+	router.Post("/rooms/:id/members", h.requireUser, h.AddRoomMember)
+	router.Delete("/rooms/:id/members/:userId", h.requireUser, h.RemoveRoomMember)
+}
+
+type AddRoomMemberRequest struct {
+	UserID int64 `json:"user_id"`
+}
+
+// AddRoomMember добавляет пользователя в комнату
+// @Summary Добавить участника
+// @Tags rooms
+// @Description Добавлять участников могут администраторы комнаты. Участники, включая нового,
+// @Description получают событие member.joined; повторное добавление ничего не меняет
+// @Accept json
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID комнаты"
+// @Param member body AddRoomMemberRequest true "Новый участник"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Недостаточно прав"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /rooms/{id}/members [post]
+func (h *Handler) AddRoomMember(c *fiber.Ctx) error {
+	roomID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid roomID: %v", err))
+	}
+
+	var req AddRoomMemberRequest
+	if err = c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	err = h.messageService.AddRoomMember(context.Background(), services.RoomMemberParams{
+		RoomID:   roomID,
+		UserID:   currentUserID(c),
+		MemberID: req.UserID,
+	})
+	if err != nil {
+		return serviceError("h.messageService.AddRoomMember", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RemoveRoomMember исключает участника из комнаты
+// @Summary Исключить участника или выйти из комнаты
+// @Tags rooms
+// @Description Свой ID в пути означает выход из комнаты, исключать других могут администраторы.
+// @Description Оставшиеся участники и сам исключённый получают событие member.left
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID комнаты"
+// @Param userId path int true "ID участника"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверный ID"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Недостаточно прав"
+// @Failure 404 {object} HTTPError "Пользователь не состоит в комнате"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /rooms/{id}/members/{userId} [delete]
+func (h *Handler) RemoveRoomMember(c *fiber.Ctx) error {
+	roomID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid roomID: %v", err))
+	}
+	memberID, err := strconv.ParseInt(c.Params("userId"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid userID: %v", err))
+	}
+
+	err = h.messageService.RemoveRoomMember(context.Background(), services.RoomMemberParams{
+		RoomID:   roomID,
+		UserID:   currentUserID(c),
+		MemberID: memberID,
+	})
+	if err != nil {
+		return serviceError("h.messageService.RemoveRoomMember", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,97 @@
+package v1_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+func TestHandler_roomMembers(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		mockBehavior   func(s *MockMessageService)
+		expectedStatus int
+	}{
+		{
+			name:   "add member",
+			method: "POST",
+			url:    "/v1/rooms/5/members",
+			body:   `{"user_id":4}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("AddRoomMember", mock.Anything, services.RoomMemberParams{RoomID: 5, UserID: 1, MemberID: 4}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:   "add member without admin rights",
+			method: "POST",
+			url:    "/v1/rooms/5/members",
+			body:   `{"user_id":4}`,
+			mockBehavior: func(s *MockMessageService) {
+				s.On("AddRoomMember", mock.Anything, mock.Anything).Return(services.ErrForbidden)
+			},
+			expectedStatus: fiber.StatusForbidden,
+		},
+		{
+			name:   "leave",
+			method: "DELETE",
+			url:    "/v1/rooms/5/members/1",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("RemoveRoomMember", mock.Anything, services.RoomMemberParams{RoomID: 5, UserID: 1, MemberID: 1}).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:   "kick missing member",
+			method: "DELETE",
+			url:    "/v1/rooms/5/members/3",
+			mockBehavior: func(s *MockMessageService) {
+				s.On("RemoveRoomMember", mock.Anything, mock.Anything).Return(services.ErrNotFound)
+			},
+			expectedStatus: fiber.StatusNotFound,
+		},
+		{
+			name:           "invalid room id",
+			method:         "DELETE",
+			url:            "/v1/rooms/abc/members/3",
+			mockBehavior:   func(s *MockMessageService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageService := new(MockMessageService)
+			tt.mockBehavior(messageService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				MessageService: messageService,
+				TokenKey:       testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", testToken(t, 1))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			messageService.AssertExpectations(t)
+		})
+	}
+}
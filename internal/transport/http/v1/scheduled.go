@@ -0,0 +1,165 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"strconv"
+	"time"
+)
+
+func (h *Handler) initScheduledRoutes(router fiber.Router) {
+	scheduled := router.Group("/scheduled-messages")
+	{
+		scheduled.Post("/", h.requireUser, h.ScheduleMessage)
+		scheduled.Get("/", h.requireUser, h.GetScheduledMessages)
+		scheduled.Patch("/:id", h.requireUser, h.EditScheduledMessage)
+		scheduled.Delete("/:id", h.requireUser, h.CancelScheduledMessage)
+	}
+}
+
+type ScheduleMessageRequest struct {
+	ReceiverID    int64               `json:"receiver_id"`
+	RoomID        int64               `json:"room_id"`
+	Content       string              `json:"content"`
+	RichContent   *models.RichContent `json:"rich_content"`
+	AttachmentIDs []int64             `json:"attachment_ids"`
+	// SendAt время отправки в формате RFC 3339
+	SendAt time.Time `json:"send_at"`
+}
+
+// ScheduleMessage планирует отправку сообщения
+// @Summary Запланировать сообщение
+// @Tags scheduled
+// @Description Сохраняет сообщение, которое будет отправлено от имени текущего пользователя в send_at (не позже чем через год).
+// @Description В момент отправки сообщение проходит те же проверки, что и обычное; если отправить его не удалось,
+// @Description оно остаётся в списке со статусом failed и причиной в поле error
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param message body ScheduleMessageRequest true "Сообщение и время отправки"
+// @Success 201 {object} models.ScheduledMessage
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 403 {object} HTTPError "Отправитель не состоит в комнате"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /scheduled-messages [post]
+func (h *Handler) ScheduleMessage(c *fiber.Ctx) error {
+	var req ScheduleMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	scheduled, err := h.scheduled.ScheduleMessage(context.Background(), services.ScheduleMessageParams{
+		SenderID:      currentUserID(c),
+		ReceiverID:    req.ReceiverID,
+		RoomID:        req.RoomID,
+		Content:       req.Content,
+		RichContent:   req.RichContent,
+		AttachmentIDs: req.AttachmentIDs,
+		SendAt:        req.SendAt,
+	})
+	if err != nil {
+		return serviceError("h.scheduled.ScheduleMessage", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(scheduled)
+}
+
+// GetScheduledMessages возвращает отложенные сообщения текущего пользователя
+// @Summary Отложенные сообщения
+// @Tags scheduled
+// @Description Неотправленные сообщения текущего пользователя в порядке времени отправки
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Success 200 {array} models.ScheduledMessage
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /scheduled-messages [get]
+func (h *Handler) GetScheduledMessages(c *fiber.Ctx) error {
+	scheduled, err := h.scheduled.GetScheduledMessages(context.Background(), currentUserID(c))
+	if err != nil {
+		return serviceError("h.scheduled.GetScheduledMessages", err)
+	}
+
+	return c.JSON(scheduled)
+}
+
+type EditScheduledMessageRequest struct {
+	Content     string              `json:"content"`
+	RichContent *models.RichContent `json:"rich_content"`
+	SendAt      *time.Time          `json:"send_at"`
+}
+
+// EditScheduledMessage изменяет отложенное сообщение
+// @Summary Изменить отложенное сообщение
+// @Tags scheduled
+// @Description Меняет текст и/или время отправки; незаполненные поля остаются прежними.
+// @Description Сообщение со статусом failed после правки снова ставится в очередь
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID отложенного сообщения"
+// @Param message body EditScheduledMessageRequest true "Новые значения"
+// @Success 200 {object} models.ScheduledMessage
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 404 {object} HTTPError "Сообщение не найдено или уже отправлено"
+// @Failure 409 {object} HTTPError "Сообщение прямо сейчас отправляется"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /scheduled-messages/{id} [patch]
+func (h *Handler) EditScheduledMessage(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid id: %v", err))
+	}
+
+	var req EditScheduledMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to parse request body")
+	}
+
+	scheduled, err := h.scheduled.EditScheduledMessage(context.Background(), services.EditScheduledMessageParams{
+		ID:          id,
+		UserID:      currentUserID(c),
+		Content:     req.Content,
+		RichContent: req.RichContent,
+		SendAt:      req.SendAt,
+	})
+	if err != nil {
+		return serviceError("h.scheduled.EditScheduledMessage", err)
+	}
+
+	return c.JSON(scheduled)
+}
+
+// CancelScheduledMessage отменяет отложенное сообщение
+// @Summary Отменить отложенное сообщение
+// @Tags scheduled
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "ID отложенного сообщения"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} HTTPError "Неверный ID"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 404 {object} HTTPError "Сообщение не найдено или уже отправлено"
+// @Failure 409 {object} HTTPError "Сообщение прямо сейчас отправляется"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /scheduled-messages/{id} [delete]
+func (h *Handler) CancelScheduledMessage(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid id: %v", err))
+	}
+
+	err = h.scheduled.CancelScheduledMessage(context.Background(), services.CancelScheduledMessageParams{
+		ID:     id,
+		UserID: currentUserID(c),
+	})
+	if err != nil {
+		return serviceError("h.scheduled.CancelScheduledMessage", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,163 @@
+package v1_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+// MockScheduledService реализует интерфейс services.ScheduledService для тестов
+type MockScheduledService struct {
+	mock.Mock
+}
+
+func (m *MockScheduledService) ScheduleMessage(ctx context.Context, params services.ScheduleMessageParams) (*models.ScheduledMessage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledService) GetScheduledMessages(ctx context.Context, userID int64) ([]models.ScheduledMessage, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledService) EditScheduledMessage(ctx context.Context, params services.EditScheduledMessageParams) (*models.ScheduledMessage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledService) CancelScheduledMessage(ctx context.Context, params services.CancelScheduledMessageParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func newScheduledApp(scheduled *MockScheduledService) *fiber.App {
+	app := fiber.New()
+	h := v1.NewHandler(v1.HandlerConfig{
+		Scheduled: scheduled,
+		TokenKey:  testTokenKey,
+	})
+	h.Init(app)
+	return app
+}
+
+func TestHandler_scheduleMessage(t *testing.T) {
+	sendAt := time.Date(2030, 1, 2, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		body           string
+		mockBehavior   func(s *MockScheduledService)
+		expectedStatus int
+	}{
+		{
+			name: "OK",
+			body: fmt.Sprintf(`{"receiver_id":2,"content":"good morning","send_at":%q}`, sendAt.Format(time.RFC3339)),
+			mockBehavior: func(s *MockScheduledService) {
+				s.On("ScheduleMessage", mock.Anything, services.ScheduleMessageParams{
+					SenderID:   1,
+					ReceiverID: 2,
+					Content:    "good morning",
+					SendAt:     sendAt,
+				}).Return(&models.ScheduledMessage{ID: 7, SenderID: 1, ReceiverID: 2, SendAt: sendAt}, nil)
+			},
+			expectedStatus: fiber.StatusCreated,
+		},
+		{
+			name: "send_at in the past",
+			body: `{"receiver_id":2,"content":"hi","send_at":"2001-01-01T00:00:00Z"}`,
+			mockBehavior: func(s *MockScheduledService) {
+				s.On("ScheduleMessage", mock.Anything, mock.Anything).Return(nil, services.ErrValidation)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "invalid send_at",
+			body:           `{"receiver_id":2,"content":"hi","send_at":"tomorrow"}`,
+			mockBehavior:   func(s *MockScheduledService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduled := new(MockScheduledService)
+			tt.mockBehavior(scheduled)
+			app := newScheduledApp(scheduled)
+
+			req := httptest.NewRequest("POST", "/v1/scheduled-messages", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			scheduled.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_editScheduledMessage(t *testing.T) {
+	scheduled := new(MockScheduledService)
+	scheduled.On("EditScheduledMessage", mock.Anything, services.EditScheduledMessageParams{
+		ID:      7,
+		UserID:  1,
+		Content: "updated",
+	}).Return(&models.ScheduledMessage{ID: 7, Content: "updated"}, nil)
+	app := newScheduledApp(scheduled)
+
+	req := httptest.NewRequest("PATCH", "/v1/scheduled-messages/7", strings.NewReader(`{"content":"updated"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", testToken(t, 1))
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	scheduled.AssertExpectations(t)
+}
+
+func TestHandler_cancelScheduledMessage(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "OK", expectedStatus: fiber.StatusNoContent},
+		{name: "already sent", err: services.ErrNotFound, expectedStatus: fiber.StatusNotFound},
+		{name: "being sent", err: services.ErrConflict, expectedStatus: fiber.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduled := new(MockScheduledService)
+			scheduled.On("CancelScheduledMessage", mock.Anything, services.CancelScheduledMessageParams{ID: 7, UserID: 1}).
+				Return(tt.err)
+			app := newScheduledApp(scheduled)
+
+			req := httptest.NewRequest("DELETE", "/v1/scheduled-messages/7", nil)
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+}
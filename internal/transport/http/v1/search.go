@@ -0,0 +1,79 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/services"
+	"time"
+)
+
+func (h *Handler) initSearchRoutes(router fiber.Router) {
+	search := router.Group("/search")
+	{
+		search.Get("/messages", h.requireUser, h.SearchMessages)
+	}
+}
+
+// SearchMessages выполняет полнотекстовый поиск по сообщениям
+// @Summary Поиск сообщений
+// @Tags search
+// @Description Ищет по переписке, доступной текущему пользователю. Фразы указываются в кавычках,
+// @Description поиск по префиксу — звёздочкой в конце слова. Фрагменты с совпадениями выделены тегом <mark>
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param q query string true "Поисковый запрос"
+// @Param room_id query int false "Искать только в комнате"
+// @Param peer_id query int false "Искать только в личном диалоге с пользователем"
+// @Param sender_id query int false "Искать только сообщения отправителя"
+// @Param from query string false "Начало периода (RFC3339)"
+// @Param to query string false "Конец периода (RFC3339), не включительно"
+// @Param order query string false "relevance или date" default(relevance)
+// @Param cursor query string false "Курсор следующей страницы"
+// @Param limit query int false "Размер страницы" default(20)
+// @Success 200 {object} models.SearchPage
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /search/messages [get]
+func (h *Handler) SearchMessages(c *fiber.Ctx) error {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return err
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return err
+	}
+
+	page, err := h.searchService.SearchMessages(context.Background(), services.SearchParams{
+		UserID:   currentUserID(c),
+		Query:    c.Query("q"),
+		RoomID:   int64(c.QueryInt("room_id")),
+		PeerID:   int64(c.QueryInt("peer_id")),
+		SenderID: int64(c.QueryInt("sender_id")),
+		From:     from,
+		To:       to,
+		Order:    c.Query("order"),
+		Cursor:   c.Query("cursor"),
+		Limit:    c.QueryInt("limit"),
+	})
+	if err != nil {
+		return serviceError("h.searchService.SearchMessages", err)
+	}
+
+	return c.JSON(page)
+}
+
+func parseTimeQuery(c *fiber.Ctx, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid %s: %v", key, err))
+	}
+	return &t, nil
+}
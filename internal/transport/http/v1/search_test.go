@@ -0,0 +1,95 @@
+package v1_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+// MockSearchService реализует интерфейс services.SearchService для тестов
+type MockSearchService struct {
+	mock.Mock
+}
+
+func (m *MockSearchService) SearchMessages(ctx context.Context, params services.SearchParams) (*models.SearchPage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SearchPage), args.Error(1)
+}
+
+func TestHandler_searchMessages(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		target         string
+		mockBehavior   func(s *MockSearchService)
+		expectedStatus int
+	}{
+		{
+			name:   "success",
+			target: "/v1/search/messages?q=hello&room_id=5&from=2024-01-01T00:00:00Z&order=date&limit=10",
+			mockBehavior: func(s *MockSearchService) {
+				s.On("SearchMessages", mock.Anything, services.SearchParams{
+					UserID: 1,
+					Query:  "hello",
+					RoomID: 5,
+					From:   &from,
+					Order:  "date",
+					Limit:  10,
+				}).Return(&models.SearchPage{}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "invalid date",
+			target:         "/v1/search/messages?q=hello&from=yesterday",
+			mockBehavior:   func(s *MockSearchService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:   "empty query",
+			target: "/v1/search/messages?q=",
+			mockBehavior: func(s *MockSearchService) {
+				s.On("SearchMessages", mock.Anything, mock.Anything).Return(nil, services.ErrValidation)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searchService := new(MockSearchService)
+			tt.mockBehavior(searchService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				SearchService: searchService,
+				TokenKey:      testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest("GET", tt.target, nil)
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			searchService.AssertExpectations(t)
+		})
+	}
+}
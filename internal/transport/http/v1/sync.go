@@ -0,0 +1,40 @@
+package v1
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"messanger/internal/services"
+)
+
+func (h *Handler) initSyncRoutes(router fiber.Router) {
+	router.Get("/sync", h.requireUser, h.Sync)
+}
+
+// Sync возвращает события, пропущенные клиентом
+// @Summary Синхронизация после переподключения
+// @Tags sync
+// @Description Новые, изменённые и удалённые сообщения, реакции, отметки о прочтении и остальные события пользователя
+// @Description с номером больше since, в порядке номеров. Номер события приходит в поле seq событий WebSocket.
+// @Description Если has_more, следующий запрос делается с since = seq последнего события. Если resync_required,
+// @Description часть событий уже удалена из журнала: клиент заново загружает переписки и продолжает с last_seq
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param since query int false "Номер последнего применённого события (0 — с начала журнала)"
+// @Param limit query int false "Количество событий (по умолчанию 200, не больше 1000)"
+// @Success 200 {object} models.SyncBatch
+// @Failure 400 {object} HTTPError "Неверные параметры"
+// @Failure 401 {object} HTTPError "Пользователь не авторизован"
+// @Failure 500 {object} HTTPError "Внутренняя ошибка сервера"
+// @Router /sync [get]
+func (h *Handler) Sync(c *fiber.Ctx) error {
+	batch, err := h.sync.GetEvents(context.Background(), services.SyncParams{
+		UserID: currentUserID(c),
+		Since:  int64(c.QueryInt("since")),
+		Limit:  c.QueryInt("limit"),
+	})
+	if err != nil {
+		return serviceError("h.sync.GetEvents", err)
+	}
+
+	return c.JSON(batch)
+}
@@ -0,0 +1,102 @@
+package v1_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"messanger/internal/models"
+	"messanger/internal/services"
+	"messanger/internal/transport/http/v1"
+)
+
+// MockSyncService реализует интерфейс services.SyncService для тестов
+type MockSyncService struct {
+	mock.Mock
+}
+
+func (m *MockSyncService) GetEvents(ctx context.Context, params services.SyncParams) (*models.SyncBatch, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SyncBatch), args.Error(1)
+}
+
+func TestHandler_sync(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		mockBehavior   func(s *MockSyncService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "missed events",
+			url:  "/v1/sync?since=10&limit=50",
+			mockBehavior: func(s *MockSyncService) {
+				s.On("GetEvents", mock.Anything, services.SyncParams{UserID: 1, Since: 10, Limit: 50}).Return(&models.SyncBatch{
+					Events:  []models.SyncEvent{{Seq: 11, Type: "message.new", Payload: json.RawMessage(`{"id":3}`)}},
+					LastSeq: 11,
+				}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"events":[{"seq":11,"type":"message.new","payload":{"id":3},"created_at":"0001-01-01T00:00:00Z"}],"last_seq":11,"has_more":false,"resync_required":false}`,
+		},
+		{
+			name: "full resync",
+			url:  "/v1/sync?since=2",
+			mockBehavior: func(s *MockSyncService) {
+				s.On("GetEvents", mock.Anything, services.SyncParams{UserID: 1, Since: 2}).Return(&models.SyncBatch{
+					Events:         []models.SyncEvent{},
+					LastSeq:        90,
+					ResyncRequired: true,
+				}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"events":[],"last_seq":90,"has_more":false,"resync_required":true}`,
+		},
+		{
+			name: "negative since",
+			url:  "/v1/sync?since=-1",
+			mockBehavior: func(s *MockSyncService) {
+				s.On("GetEvents", mock.Anything, mock.Anything).Return(nil, services.ErrValidation)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncService := new(MockSyncService)
+			tt.mockBehavior(syncService)
+
+			app := fiber.New()
+			h := v1.NewHandler(v1.HandlerConfig{
+				Sync:     syncService,
+				TokenKey: testTokenKey,
+			})
+			h.Init(app)
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			req.Header.Set("Authorization", testToken(t, 1))
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedBody != "" {
+				var body json.RawMessage
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+				assert.JSONEq(t, tt.expectedBody, string(body))
+			}
+			syncService.AssertExpectations(t)
+		})
+	}
+}
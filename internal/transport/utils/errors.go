@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"errors"
+	"messanger/internal/apperr"
+	"net/http"
+)
+
+// Коды ошибок, одинаковые в ответах HTTP API и в кадрах ошибок WebSocket
+const (
+	CodeValidation   = "validation_failed"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeRateLimited  = "rate_limited"
+	CodeInternal     = "internal_error"
+	// CodeMessageRejected сообщение отклонено фильтром; этап и причина передаются в details
+	CodeMessageRejected = "message_rejected"
+)
+
+// ServiceError определяет HTTP-статус и код ошибки сервисного слоя по ошибкам apperr
+func ServiceError(err error) (int, string) {
+	switch {
+	case errors.Is(err, apperr.ErrMessageRejected):
+		return http.StatusUnprocessableEntity, CodeMessageRejected
+	case errors.Is(err, apperr.ErrValidation):
+		return http.StatusBadRequest, CodeValidation
+	case errors.Is(err, apperr.ErrRateLimited):
+		return http.StatusTooManyRequests, CodeRateLimited
+	case errors.Is(err, apperr.ErrNotFound):
+		return http.StatusNotFound, CodeNotFound
+	case errors.Is(err, apperr.ErrForbidden):
+		return http.StatusForbidden, CodeForbidden
+	case errors.Is(err, apperr.ErrConflict):
+		return http.StatusConflict, CodeConflict
+	default:
+		return http.StatusInternalServerError, CodeInternal
+	}
+}
+
+// StatusCode код ошибки для HTTP-статуса, в том числе для ошибок, возникших до вызова сервиса
+func StatusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType:
+		return CodeValidation
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusUnprocessableEntity:
+		return CodeMessageRejected
+	default:
+		return CodeInternal
+	}
+}
+
+// ErrorDetails подробности ошибки для клиента: этап и причина отклонения сообщения; nil, если подробностей нет
+func ErrorDetails(err error) any {
+	var rejection *apperr.RejectionError
+	if errors.As(err, &rejection) {
+		return rejection
+	}
+	return nil
+}
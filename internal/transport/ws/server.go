@@ -2,19 +2,26 @@ package ws
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 	httpSwagger "github.com/swaggo/http-swagger"
 	_ "messanger/docs"
+	"messanger/internal/events"
+	"messanger/internal/models"
 	"messanger/internal/services"
 	"messanger/internal/transport/utils"
 	"net/http"
+	"slices"
 	"sync"
+	"time"
 )
 
 type WebSocketServer struct {
 	messageService services.MessageService
+	syncService    services.SyncService
+	presence       *events.Presence
 	clients        map[*websocket.Conn]int64
 	mu             sync.Mutex
 	log            *logrus.Logger
@@ -22,28 +29,228 @@ type WebSocketServer struct {
 	tokenKey       string
 }
 
-func NewWebSocketServer(messageService services.MessageService, log *logrus.Logger, tokenKey string) *WebSocketServer {
-	return &WebSocketServer{
+func NewWebSocketServer(messageService services.MessageService, syncService services.SyncService, bus *events.Bus, presence *events.Presence, log *logrus.Logger, tokenKey string) *WebSocketServer {
+	server := &WebSocketServer{
 		messageService: messageService,
+		syncService:    syncService,
+		presence:       presence,
 		clients:        make(map[*websocket.Conn]int64),
 		log:            log,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true // Разрешить все соединения
 			},
+			Subprotocols: []string{ProtocolV1},
 		},
 		tokenKey: tokenKey,
 	}
+
+	bus.Subscribe(server.deliverEvent)
+
+	return server
+}
+
+// ProtocolV1 подпротокол WebSocket (Sec-WebSocket-Protocol) с кадрами Envelope. Соединения без подпротокола
+// работают по протоколу без конверта: действие задаётся полем action, данные лежат в самом кадре
+const ProtocolV1 = "messanger.v1"
+
+// Envelope кадр протокола ProtocolV1. Запрос клиента содержит тип, ключ id и данные в payload;
+// на каждый запрос сервер отвечает кадром ack, history или error с тем же id.
+// События сервера приходят в том же формате без id и с номером seq, если событие записано в журнал
+type Envelope struct {
+	Type string `json:"type"`
+	// ID ключ запроса, выбранный клиентом; возвращается в ответе без изменений
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// reply ответ сервера на запрос клиента в формате Envelope
+type reply struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Payload any    `json:"payload,omitempty"`
+}
+
+const maxRequestIDLength = 64
+
+// Типы кадров ProtocolV1.
+//   - send: отправка сообщения (CreateMessageRequest), в ack — MessageAck с сохранённым сообщением;
+//   - history: страница истории личной переписки или комнаты (HistoryRequest), ответ — кадр history с массивом сообщений;
+//   - typing: индикатор набора (TypingRequest), остальные участники получают событие typing;
+//   - read, delivered: подтверждение прочтения и доставки сообщений (ReceiptRequest);
+//   - subscribe: повтор событий после номера since (ResumeRequest), в ack — Resumed;
+//   - ack и error: ответы сервера, error содержит ErrorFrame.
+//
+// Остальные запросы называются так же, как действия протокола без конверта: edit_message (в ack — сообщение),
+// delete_message, add_reaction, remove_reaction, pin_message, unpin_message, save_draft (в ack — черновик),
+// vote, retract_vote и close_poll (в ack — итоги опроса)
+const (
+	frameSend      = "send"
+	frameAck       = "ack"
+	frameError     = "error"
+	frameHistory   = "history"
+	frameTyping    = "typing"
+	frameRead      = "read"
+	frameDelivered = "delivered"
+	frameSubscribe = "subscribe"
+)
+
+// frameActions сопоставляет типы запросов ProtocolV1 с действиями
+var frameActions = map[string]string{
+	frameSend:            actionSendMessage,
+	frameHistory:         actionHistory,
+	frameTyping:          actionTyping,
+	frameRead:            actionRead,
+	frameDelivered:       actionAck,
+	frameSubscribe:       actionResume,
+	actionEditMessage:    actionEditMessage,
+	actionDeleteMessage:  actionDeleteMessage,
+	actionAddReaction:    actionAddReaction,
+	actionRemoveReaction: actionRemoveReaction,
+	actionPinMessage:     actionPinMessage,
+	actionUnpinMessage:   actionUnpinMessage,
+	actionSaveDraft:      actionSaveDraft,
+	actionVote:           actionVote,
+	actionRetractVote:    actionRetractVote,
+	actionClosePoll:      actionClosePoll,
+}
+
+// Действия, которые клиент может передать в поле action; пустое действие означает отправку сообщения.
+// Если действие не выполнено, клиент получает событие error с ErrorFrame
+const (
+	actionSendMessage    = "send_message"
+	actionEditMessage    = "edit_message"
+	actionDeleteMessage  = "delete_message"
+	actionAddReaction    = "add_reaction"
+	actionRemoveReaction = "remove_reaction"
+	actionPinMessage     = "pin_message"
+	actionUnpinMessage   = "unpin_message"
+	actionSaveDraft      = "save_draft"
+	actionVote           = "vote"
+	actionRetractVote    = "retract_vote"
+	actionClosePoll      = "close_poll"
+	actionAck            = "ack"
+	actionRead           = "read"
+	actionResume         = "resume"
+	actionHistory        = "history"
+	actionTyping         = "typing"
+)
+
+type frame struct {
+	Action string `json:"action"`
 }
 
 type CreateMessageRequest struct {
 	ReceiverID int64  `json:"receiver_id"`
+	RoomID     int64  `json:"room_id"`
 	Content    string `json:"content"`
+	// RichContent структурированное содержимое; Content в этом случае игнорируется
+	RichContent *models.RichContent `json:"rich_content"`
+	ClientMsgID string              `json:"client_msg_id"`
+	// AttachmentIDs вложения, предварительно загруженные через HTTP API
+	AttachmentIDs []int64 `json:"attachment_ids"`
+	// TTL время жизни сообщения в секундах; 0 — действует настройка переписки
+	TTL      int64 `json:"ttl"`
+	ViewOnce bool  `json:"view_once"`
+}
+
+// MessageAck подтверждение отправителю: сопоставляет ключ клиента с ID сохранённого сообщения
+type MessageAck struct {
+	ClientMsgID string          `json:"client_msg_id,omitempty"`
+	MessageID   int64           `json:"message_id"`
+	Message     *models.Message `json:"message,omitempty"`
+}
+
+const eventMessageAck = "message.ack"
+
+// ErrorFrame ошибка обработки кадра клиента; для сопоставления с запросом содержит действие и его ключ
+type ErrorFrame struct {
+	Action      string `json:"action,omitempty"`
+	ClientMsgID string `json:"client_msg_id,omitempty"`
+	MessageID   int64  `json:"message_id,omitempty"`
+	// Code тот же код, что и в ответах HTTP API: validation_failed, forbidden, not_found и т. д.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Details подробности ошибки; для message_rejected — этап фильтра и причина отклонения
+	Details any `json:"details,omitempty"`
+}
+
+type EditMessageRequest struct {
+	MessageID   int64               `json:"message_id"`
+	Content     string              `json:"content"`
+	RichContent *models.RichContent `json:"rich_content"`
+}
+
+type DeleteMessageRequest struct {
+	MessageID int64  `json:"message_id"`
+	Scope     string `json:"scope"`
+}
+
+type ReactionRequest struct {
+	MessageID int64  `json:"message_id"`
+	Emoji     string `json:"emoji"`
+}
+
+type PinRequest struct {
+	MessageID int64 `json:"message_id"`
+}
+
+// PollVoteRequest голос в опросе; options не используется при отзыве голоса и закрытии опроса
+type PollVoteRequest struct {
+	MessageID int64 `json:"message_id"`
+	Options   []int `json:"options"`
+}
+
+// DraftRequest черновик переписки; пустой content удаляет черновик
+type DraftRequest struct {
+	ReceiverID int64  `json:"receiver_id"`
+	RoomID     int64  `json:"room_id"`
+	Content    string `json:"content"`
+}
+
+// ReceiptRequest подтверждение доставки (ack) или прочтения (read) сообщений
+type ReceiptRequest struct {
+	MessageIDs []int64 `json:"message_ids"`
+}
+
+// HistoryRequest страница истории личной переписки или комнаты; параметры те же,
+// что у GET /messages/{id} и GET /rooms/{id}/messages
+type HistoryRequest struct {
+	ReceiverID int64 `json:"receiver_id"`
+	RoomID     int64 `json:"room_id"`
+	BeforeSeq  int64 `json:"before_seq"`
+	AfterSeq   int64 `json:"after_seq"`
+	Limit      int   `json:"limit"`
+}
+
+// TypingRequest индикатор набора в личной переписке или комнате; клиент повторяет его во время набора
+type TypingRequest struct {
+	ReceiverID int64 `json:"receiver_id"`
+	RoomID     int64 `json:"room_id"`
+}
+
+// ResumeRequest возобновление после переподключения: сервер повторяет события с номером больше since
+type ResumeRequest struct {
+	Since int64 `json:"since"`
+}
+
+// Resumed завершает повтор пропущенных событий. События, пришедшие во время повтора, могут опередить
+// повторяемые: клиент применяет события по порядку seq и пропускает уже применённые
+type Resumed struct {
+	LastSeq int64 `json:"last_seq"`
+	// ResyncRequired часть событий удалена из журнала: клиент заново загружает переписки и продолжает с LastSeq
+	ResyncRequired bool `json:"resync_required,omitempty"`
 }
 
+const eventResumed = "resumed"
+
 // HandleConnection обрабатывает WebSocket-соединение клиента.
 // @Summary      Подключение к WebSocket
-// @Description  Устанавливает соединение по WebSocket и обрабатывает входящие/исходящие сообщения
+// @Description  Устанавливает соединение по WebSocket и обрабатывает входящие/исходящие сообщения.
+// @Description  С подпротоколом messanger.v1 (заголовок Sec-WebSocket-Protocol) кадры имеют вид {type, id, payload},
+// @Description  и на каждый запрос приходит ответ ack, history или error с тем же id. Без подпротокола действие
+// @Description  задаётся полем action кадра
+// @Param        Sec-WebSocket-Protocol header string false "messanger.v1"
 // @Tags         websocket
 // @Accept       json
 // @Produce      json
@@ -75,52 +282,415 @@ func (s *WebSocketServer) HandleConnection(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Без регистрации пользователю нельзя написать в личные сообщения, но подключение от этого не страдает
+	if err := s.messageService.RegisterUser(context.Background(), userID); err != nil {
+		s.log.Errorf("s.messageService.RegisterUser: %v", err)
+	}
+
 	s.mu.Lock()
 	s.clients[conn] = userID
 	s.mu.Unlock()
+	s.presence.Connect(userID)
 
 	s.log.Infof("New client connected: userID=%d", userID)
 
 	for {
-		var req CreateMessageRequest
-		if err := conn.ReadJSON(&req); err != nil {
-			s.log.Infof("conn.ReadJSON: %v", err)
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			s.log.Infof("conn.ReadMessage: %v", err)
 			break
 		}
 
-		err := s.messageService.SaveMessage(context.Background(), services.SaveMessageParams{
-			SenderID:   userID,
+		if conn.Subprotocol() == ProtocolV1 {
+			s.handleEnvelope(conn, userID, data)
+		} else {
+			s.handleFrame(conn, userID, data)
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.clients, conn)
+	s.mu.Unlock()
+	s.presence.Disconnect(userID)
+
+	s.log.Infof("Client disconnected: userID=%d", userID)
+}
+
+// handleFrame обрабатывает кадр протокола без конверта: действие задаётся полем action,
+// ответ получают только отправка сообщения, resume и history
+func (s *WebSocketServer) handleFrame(conn *websocket.Conn, userID int64, data []byte) {
+	var f frame
+	if err := json.Unmarshal(data, &f); err != nil {
+		s.writeError(conn, "", ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		return
+	}
+	if f.Action == "" {
+		f.Action = actionSendMessage
+	}
+
+	result, rerr := s.handleRequest(conn, userID, f.Action, data)
+	if rerr != nil {
+		s.writeError(conn, "", rerr.frame, rerr.op, rerr.err)
+		return
+	}
+
+	switch result := result.(type) {
+	case MessageAck:
+		s.writeJSON(conn, events.Event{Type: eventMessageAck, Payload: result})
+	case Resumed:
+		s.writeJSON(conn, events.Event{Type: eventResumed, Payload: result})
+	case []models.Message:
+		s.writeJSON(conn, events.Event{Type: frameHistory, Payload: result})
+	}
+}
+
+// handleEnvelope обрабатывает кадр протокола ProtocolV1 и отвечает на него кадром ack, history или error
+func (s *WebSocketServer) handleEnvelope(conn *websocket.Conn, userID int64, data []byte) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		s.writeError(conn, "", ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		return
+	}
+	if len(env.ID) > maxRequestIDLength {
+		s.writeError(conn, "", ErrorFrame{Action: env.Type}, "handleEnvelope",
+			fmt.Errorf("%w: id is longer than %d characters", services.ErrValidation, maxRequestIDLength))
+		return
+	}
+
+	action, ok := frameActions[env.Type]
+	if !ok {
+		s.writeError(conn, env.ID, ErrorFrame{Action: env.Type}, "handleEnvelope",
+			fmt.Errorf("%w: unknown frame type %q", services.ErrValidation, env.Type))
+		return
+	}
+	if len(env.Payload) == 0 {
+		env.Payload = json.RawMessage(`{}`)
+	}
+
+	result, rerr := s.handleRequest(conn, userID, action, env.Payload)
+	if rerr != nil {
+		rerr.frame.Action = env.Type
+		s.writeError(conn, env.ID, rerr.frame, rerr.op, rerr.err)
+		return
+	}
+
+	replyType := frameAck
+	if action == actionHistory {
+		replyType = frameHistory
+	}
+	s.writeJSON(conn, reply{Type: replyType, ID: env.ID, Payload: result})
+}
+
+// requestError ошибка выполнения действия вместе с ключами запроса для ErrorFrame
+type requestError struct {
+	frame ErrorFrame
+	op    string
+	err   error
+}
+
+// handleRequest выполняет действие клиента с данными data и возвращает результат для подтверждения;
+// nil означает подтверждение без данных
+func (s *WebSocketServer) handleRequest(conn *websocket.Conn, userID int64, action string, data []byte) (any, *requestError) {
+	fail := func(frame ErrorFrame, op string, err error) (any, *requestError) {
+		frame.Action = action
+		return nil, &requestError{frame: frame, op: op, err: err}
+	}
+
+	switch action {
+	case actionSendMessage:
+		var req CreateMessageRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fail(ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		}
+
+		// Получатели получат событие message.new через шину событий и подтвердят доставку через ack
+		message, err := s.messageService.SaveMessage(context.Background(), services.SaveMessageParams{
+			SenderID:      userID,
+			ReceiverID:    req.ReceiverID,
+			RoomID:        req.RoomID,
+			Content:       req.Content,
+			RichContent:   req.RichContent,
+			ClientMsgID:   req.ClientMsgID,
+			AttachmentIDs: req.AttachmentIDs,
+			TTL:           time.Duration(req.TTL) * time.Second,
+			ViewOnce:      req.ViewOnce,
+		})
+		if err != nil {
+			return fail(ErrorFrame{ClientMsgID: req.ClientMsgID}, "s.messageService.SaveMessage", err)
+		}
+
+		return MessageAck{
+			ClientMsgID: req.ClientMsgID,
+			MessageID:   message.ID,
+			Message:     message,
+		}, nil
+	case actionEditMessage:
+		var req EditMessageRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fail(ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		}
+
+		// Участники переписки получат событие message.edited через шину событий
+		message, err := s.messageService.EditMessage(context.Background(), services.EditMessageParams{
+			MessageID:   req.MessageID,
+			EditorID:    userID,
+			Content:     req.Content,
+			RichContent: req.RichContent,
+		})
+		if err != nil {
+			return fail(ErrorFrame{MessageID: req.MessageID}, "s.messageService.EditMessage", err)
+		}
+		return message, nil
+	case actionDeleteMessage:
+		var req DeleteMessageRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fail(ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		}
+		if req.Scope == "" {
+			req.Scope = services.DeleteForMe
+		}
+
+		err := s.messageService.DeleteMessage(context.Background(), services.DeleteMessageParams{
+			MessageID: req.MessageID,
+			UserID:    userID,
+			Scope:     req.Scope,
+		})
+		if err != nil {
+			return fail(ErrorFrame{MessageID: req.MessageID}, "s.messageService.DeleteMessage", err)
+		}
+		return nil, nil
+	case actionAddReaction, actionRemoveReaction:
+		var req ReactionRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fail(ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		}
+
+		params := services.ReactionParams{
+			MessageID: req.MessageID,
+			UserID:    userID,
+			Emoji:     req.Emoji,
+		}
+		if action == actionAddReaction {
+			if err := s.messageService.AddReaction(context.Background(), params); err != nil {
+				return fail(ErrorFrame{MessageID: req.MessageID}, "s.messageService.AddReaction", err)
+			}
+		} else {
+			if err := s.messageService.RemoveReaction(context.Background(), params); err != nil {
+				return fail(ErrorFrame{MessageID: req.MessageID}, "s.messageService.RemoveReaction", err)
+			}
+		}
+		return nil, nil
+	case actionSaveDraft:
+		var req DraftRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fail(ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		}
+
+		draft, err := s.messageService.SaveDraft(context.Background(), services.SaveDraftParams{
+			UserID:     userID,
 			ReceiverID: req.ReceiverID,
+			RoomID:     req.RoomID,
 			Content:    req.Content,
 		})
 		if err != nil {
-			s.log.Infof("s.messageService.SaveMessage: %v", err)
-			continue
+			return fail(ErrorFrame{}, "s.messageService.SaveDraft", err)
+		}
+		return draft, nil
+	case actionPinMessage, actionUnpinMessage:
+		var req PinRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fail(ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		}
+
+		params := services.PinParams{
+			MessageID: req.MessageID,
+			UserID:    userID,
+		}
+		if action == actionPinMessage {
+			if err := s.messageService.PinMessage(context.Background(), params); err != nil {
+				return fail(ErrorFrame{MessageID: req.MessageID}, "s.messageService.PinMessage", err)
+			}
+		} else {
+			if err := s.messageService.UnpinMessage(context.Background(), params); err != nil {
+				return fail(ErrorFrame{MessageID: req.MessageID}, "s.messageService.UnpinMessage", err)
+			}
+		}
+		return nil, nil
+	case actionVote, actionRetractVote, actionClosePoll:
+		var req PollVoteRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fail(ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		}
+
+		// Итоги приходят всем участникам, включая автора действия, событиями poll.updated и poll.closed
+		params := services.PollParams{
+			MessageID: req.MessageID,
+			UserID:    userID,
+		}
+		var (
+			op      string
+			results *models.PollResults
+			err     error
+		)
+		switch action {
+		case actionVote:
+			op = "s.messageService.Vote"
+			results, err = s.messageService.Vote(context.Background(), services.VoteParams{
+				MessageID: req.MessageID,
+				UserID:    userID,
+				Options:   req.Options,
+			})
+		case actionRetractVote:
+			op = "s.messageService.RetractVote"
+			results, err = s.messageService.RetractVote(context.Background(), params)
+		default:
+			op = "s.messageService.ClosePoll"
+			results, err = s.messageService.ClosePoll(context.Background(), params)
+		}
+		if err != nil {
+			return fail(ErrorFrame{MessageID: req.MessageID}, op, err)
+		}
+		return results, nil
+	case actionAck, actionRead:
+		var req ReceiptRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fail(ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		}
+
+		params := services.ReceiptParams{
+			UserID:     userID,
+			MessageIDs: req.MessageIDs,
+		}
+		if action == actionAck {
+			if err := s.messageService.MarkDelivered(context.Background(), params); err != nil {
+				return fail(ErrorFrame{}, "s.messageService.MarkDelivered", err)
+			}
+		} else {
+			if err := s.messageService.MarkRead(context.Background(), params); err != nil {
+				return fail(ErrorFrame{}, "s.messageService.MarkRead", err)
+			}
+		}
+		return nil, nil
+	case actionHistory:
+		var req HistoryRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fail(ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		}
+
+		messages, err := s.messageService.GetHistory(context.Background(), services.GetHistoryParams{
+			SenderID:   userID,
+			ReceiverID: req.ReceiverID,
+			RoomID:     req.RoomID,
+			BeforeSeq:  req.BeforeSeq,
+			AfterSeq:   req.AfterSeq,
+			Limit:      req.Limit,
+		})
+		if err != nil {
+			return fail(ErrorFrame{}, "s.messageService.GetHistory", err)
+		}
+		if messages == nil {
+			messages = []models.Message{}
+		}
+		return messages, nil
+	case actionTyping:
+		var req TypingRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fail(ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		}
+
+		err := s.messageService.SendTyping(context.Background(), services.TypingParams{
+			UserID:     userID,
+			ReceiverID: req.ReceiverID,
+			RoomID:     req.RoomID,
+		})
+		if err != nil {
+			return fail(ErrorFrame{}, "s.messageService.SendTyping", err)
+		}
+		return nil, nil
+	case actionResume:
+		var req ResumeRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fail(ErrorFrame{}, "json.Unmarshal", invalidFrame(err))
+		}
+
+		resumed, err := s.resume(conn, userID, req.Since)
+		if err != nil {
+			return fail(ErrorFrame{}, "s.resume", err)
+		}
+		return resumed, nil
+	default:
+		return fail(ErrorFrame{}, "handleRequest", fmt.Errorf("%w: unknown action %q", services.ErrValidation, action))
+	}
+}
+
+// resume повторяет клиенту пропущенные события пачками и возвращает положение журнала после повтора
+func (s *WebSocketServer) resume(conn *websocket.Conn, userID, since int64) (Resumed, error) {
+	for {
+		batch, err := s.syncService.GetEvents(context.Background(), services.SyncParams{
+			UserID: userID,
+			Since:  since,
+		})
+		if err != nil {
+			return Resumed{}, fmt.Errorf("s.syncService.GetEvents: %w", err)
 		}
 
-		s.broadcastMessage(userID, req)
+		for _, event := range batch.Events {
+			s.writeJSON(conn, events.Event{Type: event.Type, Payload: event.Payload, Seq: event.Seq})
+			since = event.Seq
+		}
+		if !batch.HasMore {
+			return Resumed{
+				LastSeq:        batch.LastSeq,
+				ResyncRequired: batch.ResyncRequired,
+			}, nil
+		}
+	}
+}
+
+// invalidFrame ошибка разбора кадра клиента
+func invalidFrame(err error) error {
+	return fmt.Errorf("%w: invalid frame: %v", services.ErrValidation, err)
+}
+
+// writeError сообщает клиенту, что кадр не обработан; id — ключ запроса ProtocolV1. Код ошибки совпадает
+// с кодом в ответах HTTP API; текст внутренних ошибок клиенту не передаётся
+func (s *WebSocketServer) writeError(conn *websocket.Conn, id string, frame ErrorFrame, op string, err error) {
+	_, frame.Code = utils.ServiceError(err)
+	frame.Message = err.Error()
+	frame.Details = utils.ErrorDetails(err)
+	if frame.Code == utils.CodeInternal {
+		s.log.Errorf("%s: %v", op, err)
+		frame.Message = "internal error"
+	} else {
+		s.log.Infof("%s: %v", op, err)
 	}
 
+	s.writeJSON(conn, reply{Type: frameError, ID: id, Payload: frame})
+}
+
+// writeJSON отправляет кадр в конкретное соединение; запись сериализуется общим мьютексом
+func (s *WebSocketServer) writeJSON(conn *websocket.Conn, v any) {
 	s.mu.Lock()
-	delete(s.clients, conn)
-	s.mu.Unlock()
+	defer s.mu.Unlock()
 
-	s.log.Infof("Client disconnected: userID=%d", userID)
+	if err := conn.WriteJSON(v); err != nil {
+		s.log.Warnf("Error sending frame: %v", err)
+	}
 }
 
-func (s *WebSocketServer) broadcastMessage(userID int64, req CreateMessageRequest) {
+// deliverEvent отправляет событие всем подключённым клиентам указанных пользователей
+func (s *WebSocketServer) deliverEvent(userIDs []int64, event events.Event) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for client := range s.clients {
-		if s.clients[client] != userID {
-			if s.clients[client] == req.ReceiverID {
-				if err := client.WriteJSON(req.Content); err != nil {
-					s.log.Warnf("Error sending message: %v", err)
-					client.Close()
-					delete(s.clients, client)
-				}
-			}
+	for client, clientUserID := range s.clients {
+		if !slices.Contains(userIDs, clientUserID) {
+			continue
+		}
+		if err := client.WriteJSON(event); err != nil {
+			s.log.Warnf("Error sending event: %v", err)
+			client.Close()
+			delete(s.clients, client)
 		}
 	}
 }
@@ -0,0 +1,144 @@
+// Package chatimport разбирает выгрузки истории сторонних мессенджеров в общее представление
+package chatimport
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Источники выгрузок
+const (
+	SourceTelegram = "telegram"
+	SourceSlack    = "slack"
+)
+
+var ErrUnsupportedArchive = errors.New("unsupported archive")
+
+// Archive содержимое выгрузки: переписки с сообщениями в порядке отправки
+type Archive struct {
+	Source string
+	// FS каталог выгрузки, относительно которого заданы пути файлов
+	FS    fs.FS
+	Chats []Chat
+}
+
+type Chat struct {
+	// ID идентификатор переписки в исходном мессенджере
+	ID   string
+	Name string
+	// Direct личная переписка двух пользователей; остальные переписки импортируются как комнаты
+	Direct bool
+	// MemberIDs известные участники; авторы сообщений добавляются к ним при импорте
+	MemberIDs []string
+	CreatorID string
+	CreatedAt time.Time
+	Messages  []Message
+}
+
+type Message struct {
+	// ID идентификатор сообщения, уникальный в пределах переписки
+	ID       string
+	AuthorID string
+	Text     string
+	SentAt   time.Time
+	// ReplyToID сообщение той же переписки, на которое дан ответ
+	ReplyToID string
+	Files     []File
+}
+
+type File struct {
+	Name     string
+	MimeType string
+	// Path путь к содержимому внутри выгрузки; пустой, если файл в выгрузку не попал
+	Path string
+}
+
+// Parse разбирает выгрузку указанного источника
+func Parse(source string, fsys fs.FS) (*Archive, error) {
+	switch source {
+	case SourceTelegram:
+		return ParseTelegram(fsys)
+	case SourceSlack:
+		return ParseSlack(fsys)
+	}
+	return nil, fmt.Errorf("%w: unknown source %q", ErrUnsupportedArchive, source)
+}
+
+// Open открывает выгрузку: каталог, zip-архив или отдельный файл result.json.
+// Для файла выгрузкой считается его каталог — рядом с result.json Telegram кладёт медиафайлы
+func Open(name string) (fs.FS, io.Closer, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case info.IsDir():
+		return os.DirFS(name), io.NopCloser(nil), nil
+	case strings.EqualFold(filepath.Ext(name), ".zip"):
+		r, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrUnsupportedArchive, err)
+		}
+		return r, r, nil
+	case filepath.Base(name) == telegramResultFile:
+		return os.DirFS(filepath.Dir(name)), io.NopCloser(nil), nil
+	}
+	return nil, nil, fmt.Errorf("%w: expected a directory, a zip file or %s", ErrUnsupportedArchive, telegramResultFile)
+}
+
+// root находит каталог выгрузки по характерному файлу: архив часто содержит выгрузку
+// не в корне, а во вложенной папке
+func root(fsys fs.FS, marker string) (fs.FS, error) {
+	if _, err := fs.Stat(fsys, marker); err == nil {
+		return fsys, nil
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedArchive, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err = fs.Stat(fsys, path.Join(entry.Name(), marker)); err == nil {
+			return fs.Sub(fsys, entry.Name())
+		}
+	}
+	return nil, fmt.Errorf("%w: %s not found", ErrUnsupportedArchive, marker)
+}
+
+func readJSON(fsys fs.FS, name string, v any) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrUnsupportedArchive, name, err)
+	}
+	return nil
+}
+
+// ReadUserMap читает файл соответствия пользователей вида {"telegram": {"user123": 1}, "slack": {"U024BE7LH": 2}}
+// и возвращает соответствие для источника source
+func ReadUserMap(r io.Reader, source string) (map[string]int64, error) {
+	var mapping map[string]map[string]int64
+	if err := json.NewDecoder(r).Decode(&mapping); err != nil {
+		return nil, fmt.Errorf("invalid user mapping: %w", err)
+	}
+	users := mapping[source]
+	for externalID, userID := range users {
+		if userID <= 0 {
+			return nil, fmt.Errorf("invalid user mapping: %s/%s must map to a positive user id", source, externalID)
+		}
+	}
+	return users, nil
+}
@@ -0,0 +1,145 @@
+package chatimport
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const telegramResult = `{
+  "name": "Team",
+  "type": "private_supergroup",
+  "id": 1001,
+  "messages": [
+    {"id": 1, "type": "service", "date": "2023-01-15T09:59:00", "date_unixtime": "1673776740", "actor_id": "user1", "action": "create_group", "text": ""},
+    {"id": 2, "type": "message", "date": "2023-01-15T10:00:00", "date_unixtime": "1673776800", "from": "Alice", "from_id": "user1",
+     "text": ["Hello ", {"type": "bold", "text": "team"}, "!"]},
+    {"id": 3, "type": "message", "date": "2023-01-15T10:01:00", "date_unixtime": "1673776860", "from": "Bob", "from_id": "user2",
+     "text": "hi", "reply_to_message_id": 2, "photo": "photos/photo_1.jpg"},
+    {"id": 4, "type": "message", "date": "2023-01-15T10:02:00", "date_unixtime": "1673776920", "from": "Bob", "from_id": "user2",
+     "text": "", "file": "(File not included. Change data exporting settings to download.)", "file_name": "big.mp4", "mime_type": "video/mp4"}
+  ]
+}`
+
+func TestParseTelegram(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ChatExport_2023-01-20/result.json":        {Data: []byte(telegramResult)},
+		"ChatExport_2023-01-20/photos/photo_1.jpg": {Data: []byte("jpeg")},
+	}
+
+	archive, err := ParseTelegram(fsys)
+	require.NoError(t, err)
+	require.Len(t, archive.Chats, 1)
+
+	chat := archive.Chats[0]
+	assert.Equal(t, "1001", chat.ID)
+	assert.Equal(t, "Team", chat.Name)
+	assert.False(t, chat.Direct)
+	assert.Equal(t, "user1", chat.CreatorID)
+	require.Len(t, chat.Messages, 3, "service messages are skipped")
+
+	assert.Equal(t, Message{
+		ID:       "2",
+		AuthorID: "user1",
+		Text:     "Hello team!",
+		SentAt:   time.Date(2023, 1, 15, 10, 0, 0, 0, time.UTC),
+	}, chat.Messages[0])
+
+	reply := chat.Messages[1]
+	assert.Equal(t, "2", reply.ReplyToID)
+	assert.Equal(t, []File{{Name: "photo_1.jpg", MimeType: "image/jpeg", Path: "photos/photo_1.jpg"}}, reply.Files)
+
+	data, err := archive.FS.Open(reply.Files[0].Path)
+	require.NoError(t, err, "file paths are relative to the export root")
+	require.NoError(t, data.Close())
+
+	assert.Equal(t, []File{{Name: "big.mp4", MimeType: "video/mp4"}}, chat.Messages[2].Files, "files left out of the export have no path")
+}
+
+func TestParseTelegram_AccountExport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"result.json": {Data: []byte(`{"chats": {"list": [
+			{"name": "Saved Messages", "type": "saved_messages", "id": 1, "messages": []},
+			{"name": "Bob", "type": "personal_chat", "id": 2, "messages": [
+				{"id": 5, "type": "message", "date": "2023-01-15T10:00:00", "from_id": "user2", "text": "hey"}
+			]}
+		]}}`)},
+	}
+
+	archive, err := ParseTelegram(fsys)
+	require.NoError(t, err)
+	require.Len(t, archive.Chats, 1)
+	assert.True(t, archive.Chats[0].Direct)
+	assert.Equal(t, time.Date(2023, 1, 15, 10, 0, 0, 0, time.UTC), archive.Chats[0].Messages[0].SentAt)
+}
+
+func TestParseTelegram_NotAnExport(t *testing.T) {
+	_, err := ParseTelegram(fstest.MapFS{"notes.txt": {Data: []byte("hi")}})
+	assert.ErrorIs(t, err, ErrUnsupportedArchive)
+}
+
+func TestParseSlack(t *testing.T) {
+	fsys := fstest.MapFS{
+		"users.json": {Data: []byte(`[
+			{"id": "U1", "name": "alice", "profile": {"display_name": "Alice"}},
+			{"id": "U2", "name": "bob", "real_name": "Bob B"}
+		]`)},
+		"channels.json": {Data: []byte(`[{"id": "C1", "name": "general", "created": 1673776000, "creator": "U1", "members": ["U1", "U2"]}]`)},
+		"dms.json":      {Data: []byte(`[{"id": "D1", "created": 1673776000, "members": ["U1", "U2"]}]`)},
+		"general/2023-01-16.json": {Data: []byte(`[
+			{"type": "message", "user": "U2", "text": "reply", "ts": "1673863200.000200", "thread_ts": "1673776800.000100"}
+		]`)},
+		"general/2023-01-15.json": {Data: []byte(`[
+			{"type": "message", "subtype": "channel_join", "user": "U2", "text": "<@U2> has joined the channel", "ts": "1673776700.000100"},
+			{"type": "message", "user": "U1", "text": "Hi <@U2>, see <https://example.com|docs> &amp; <!here>", "ts": "1673776800.000100",
+			 "thread_ts": "1673776800.000100", "files": [{"id": "F1", "name": "plan.pdf", "mimetype": "application/pdf"}]},
+			{"type": "message", "subtype": "bot_message", "bot_id": "B1", "text": "deploy done", "ts": "1673776900.000100"}
+		]`)},
+		"__uploads/F1/plan.pdf": {Data: []byte("%PDF")},
+		"D1/2023-01-15.json": {Data: []byte(`[
+			{"type": "message", "user": "U1", "text": "psst", "ts": "1673776800.000100"}
+		]`)},
+	}
+
+	archive, err := ParseSlack(fsys)
+	require.NoError(t, err)
+	require.Len(t, archive.Chats, 2)
+
+	general := archive.Chats[0]
+	assert.Equal(t, "C1", general.ID)
+	assert.False(t, general.Direct)
+	assert.Equal(t, []string{"U1", "U2"}, general.MemberIDs)
+	assert.Equal(t, "U1", general.CreatorID)
+	assert.Equal(t, time.Unix(1673776000, 0).UTC(), general.CreatedAt)
+	require.Len(t, general.Messages, 2, "joins and bot messages are skipped")
+
+	first := general.Messages[0]
+	assert.Equal(t, "1673776800.000100", first.ID)
+	assert.Equal(t, "Hi @Bob B, see docs (https://example.com) & @here", first.Text)
+	assert.Equal(t, time.Unix(1673776800, 100000).UTC(), first.SentAt)
+	assert.Empty(t, first.ReplyToID, "thread parent is not a reply to itself")
+	assert.Equal(t, []File{{Name: "plan.pdf", MimeType: "application/pdf", Path: "__uploads/F1/plan.pdf"}}, first.Files)
+
+	assert.Equal(t, first.ID, general.Messages[1].ReplyToID)
+
+	dm := archive.Chats[1]
+	assert.True(t, dm.Direct)
+	require.Len(t, dm.Messages, 1)
+	assert.Equal(t, "psst", dm.Messages[0].Text)
+}
+
+func TestReadUserMap(t *testing.T) {
+	users, err := ReadUserMap(strings.NewReader(`{"telegram": {"user1": 10}, "slack": {"U1": 20}}`), SourceSlack)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"U1": 20}, users)
+
+	_, err = ReadUserMap(strings.NewReader(`{"slack": {"U1": 0}}`), SourceSlack)
+	assert.Error(t, err)
+
+	_, err = ReadUserMap(strings.NewReader(`not json`), SourceSlack)
+	assert.Error(t, err)
+}
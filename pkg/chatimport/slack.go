@@ -0,0 +1,231 @@
+package chatimport
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const slackUsersFile = "users.json"
+
+type slackUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RealName string `json:"real_name"`
+	Profile  struct {
+		DisplayName string `json:"display_name"`
+	} `json:"profile"`
+}
+
+type slackChannel struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Created int64    `json:"created"`
+	Creator string   `json:"creator"`
+	Members []string `json:"members"`
+}
+
+type slackMessage struct {
+	Type     string      `json:"type"`
+	Subtype  string      `json:"subtype"`
+	User     string      `json:"user"`
+	Text     string      `json:"text"`
+	TS       string      `json:"ts"`
+	ThreadTS string      `json:"thread_ts"`
+	Files    []slackFile `json:"files"`
+}
+
+type slackFile struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimetype"`
+}
+
+// slackMessageSubtypes подтипы сообщений, написанных пользователями; остальные — служебные
+// события канала (вступления, смена темы и т.п.) и сообщения ботов
+var slackMessageSubtypes = []string{"", "thread_broadcast", "file_share", "me_message"}
+
+// ParseSlack разбирает zip-выгрузку рабочего пространства Slack. Каналы, приватные каналы и групповые
+// личные переписки импортируются как комнаты, личные переписки — как личные.
+// Slack не кладёт в выгрузку содержимое файлов, только ссылки на них; файлы импортируются,
+// если архив дополнен ими по пути __uploads/<id файла>/<имя файла>
+func ParseSlack(fsys fs.FS) (*Archive, error) {
+	fsys, err := root(fsys, slackUsersFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []slackUser
+	if err = readJSON(fsys, slackUsersFile, &users); err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		names[u.ID] = slackName(u)
+	}
+
+	archive := &Archive{Source: SourceSlack, FS: fsys}
+	for _, list := range []struct {
+		file   string
+		direct bool
+	}{
+		{file: "channels.json"},
+		{file: "groups.json"},
+		{file: "mpims.json"},
+		{file: "dms.json", direct: true},
+	} {
+		var channels []slackChannel
+		if err = readJSON(fsys, list.file, &channels); err != nil {
+			// Состав выгрузки зависит от тарифа и прав: приватных каналов и личных переписок может не быть
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, sc := range channels {
+			chat := Chat{
+				ID:        sc.ID,
+				Name:      sc.Name,
+				Direct:    list.direct,
+				MemberIDs: sc.Members,
+				CreatorID: sc.Creator,
+			}
+			if sc.Created != 0 {
+				chat.CreatedAt = time.Unix(sc.Created, 0).UTC()
+			}
+			// Каталог личной переписки назван по её ID, остальных — по имени канала
+			dir := sc.Name
+			if list.direct {
+				dir = sc.ID
+			}
+			if chat.Messages, err = slackMessages(fsys, dir, names); err != nil {
+				return nil, fmt.Errorf("channel %s: %w", sc.ID, err)
+			}
+			archive.Chats = append(archive.Chats, chat)
+		}
+	}
+	return archive, nil
+}
+
+func slackName(u slackUser) string {
+	for _, name := range []string{u.Profile.DisplayName, u.RealName, u.Name} {
+		if name != "" {
+			return name
+		}
+	}
+	return u.ID
+}
+
+// slackMessages читает сообщения канала из файлов по дням (2006-01-02.json)
+func slackMessages(fsys fs.FS, dir string, names map[string]string) ([]Message, error) {
+	days, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(days)
+
+	var messages []Message
+	for _, day := range days {
+		var batch []slackMessage
+		if err = readJSON(fsys, day, &batch); err != nil {
+			return nil, err
+		}
+
+		for _, sm := range batch {
+			if sm.Type != "message" || sm.User == "" || !slices.Contains(slackMessageSubtypes, sm.Subtype) {
+				continue
+			}
+			sentAt, err := slackTime(sm.TS)
+			if err != nil {
+				return nil, fmt.Errorf("%w: message %s: %v", ErrUnsupportedArchive, sm.TS, err)
+			}
+
+			msg := Message{
+				ID:       sm.TS,
+				AuthorID: sm.User,
+				Text:     slackText(sm.Text, names),
+				SentAt:   sentAt,
+			}
+			// Ответы в треде ссылаются на его первое сообщение
+			if sm.ThreadTS != "" && sm.ThreadTS != sm.TS {
+				msg.ReplyToID = sm.ThreadTS
+			}
+			for _, f := range sm.Files {
+				file := File{Name: f.Name, MimeType: f.MimeType}
+				if filePath := path.Join("__uploads", f.ID, f.Name); f.ID != "" && fs.ValidPath(filePath) {
+					if _, err = fs.Stat(fsys, filePath); err == nil {
+						file.Path = filePath
+					}
+				}
+				msg.Files = append(msg.Files, file)
+			}
+			messages = append(messages, msg)
+		}
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].SentAt.Before(messages[j].SentAt) })
+	return messages, nil
+}
+
+// slackTime разбирает ts вида 1672567200.000100 — секунды и микросекунды
+func slackTime(ts string) (time.Time, error) {
+	secs, micros, _ := strings.Cut(ts, ".")
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var usec int64
+	if micros != "" {
+		if usec, err = strconv.ParseInt(micros, 10, 64); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(sec, usec*int64(time.Microsecond)).UTC(), nil
+}
+
+var slackMarkupRe = regexp.MustCompile(`<([^<>|]+)(?:\|([^<>]*))?>`)
+
+// slackText переводит разметку Slack в простой текст: <@U123> становится @именем, <#C123|general> — #general,
+// <!channel> и <!here> — @room и @here, ссылки <url|подпись> — подписью со ссылкой в скобках
+func slackText(text string, names map[string]string) string {
+	text = slackMarkupRe.ReplaceAllStringFunc(text, func(markup string) string {
+		match := slackMarkupRe.FindStringSubmatch(markup)
+		target, label := match[1], match[2]
+		switch {
+		case strings.HasPrefix(target, "@"):
+			if name, ok := names[target[1:]]; ok {
+				return "@" + name
+			}
+			if label != "" {
+				return "@" + label
+			}
+			return target
+		case strings.HasPrefix(target, "#"):
+			if label != "" {
+				return "#" + label
+			}
+			return target
+		case target == "!channel" || target == "!everyone":
+			return "@room"
+		case target == "!here":
+			return "@here"
+		case strings.HasPrefix(target, "!"):
+			return label
+		case strings.HasPrefix(target, "mailto:"):
+			return strings.TrimPrefix(target, "mailto:")
+		case label != "" && label != target:
+			return label + " (" + target + ")"
+		}
+		return target
+	})
+	return html.UnescapeString(text)
+}
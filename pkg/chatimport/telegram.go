@@ -0,0 +1,164 @@
+package chatimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const telegramResultFile = "result.json"
+
+// telegramExport result.json выгрузки одного чата или всего аккаунта (в этом случае чаты лежат в chats.list)
+type telegramExport struct {
+	telegramChat
+	Chats struct {
+		List []telegramChat `json:"list"`
+	} `json:"chats"`
+}
+
+type telegramChat struct {
+	ID       int64             `json:"id"`
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Messages []telegramMessage `json:"messages"`
+}
+
+type telegramMessage struct {
+	ID           int64        `json:"id"`
+	Type         string       `json:"type"`
+	Date         string       `json:"date"`
+	DateUnixtime string       `json:"date_unixtime"`
+	FromID       string       `json:"from_id"`
+	Text         telegramText `json:"text"`
+	ReplyTo      int64        `json:"reply_to_message_id"`
+	Photo        string       `json:"photo"`
+	File         string       `json:"file"`
+	FileName     string       `json:"file_name"`
+	MimeType     string       `json:"mime_type"`
+}
+
+// telegramText текст сообщения: строка либо список из строк и размеченных фрагментов {"type": "bold", "text": "..."}
+type telegramText string
+
+func (t *telegramText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = telegramText(s)
+		return nil
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		var entity struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(part, &s); err == nil {
+			b.WriteString(s)
+		} else if err = json.Unmarshal(part, &entity); err == nil {
+			b.WriteString(entity.Text)
+		}
+	}
+	*t = telegramText(b.String())
+	return nil
+}
+
+// ParseTelegram разбирает выгрузку Telegram Desktop в формате JSON. Служебные сообщения
+// (вступления, закрепления и т.п.) пропускаются; каналы импортируются как комнаты
+func ParseTelegram(fsys fs.FS) (*Archive, error) {
+	fsys, err := root(fsys, telegramResultFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var export telegramExport
+	if err = readJSON(fsys, telegramResultFile, &export); err != nil {
+		return nil, err
+	}
+
+	chats := export.Chats.List
+	if len(chats) == 0 && export.Type != "" {
+		chats = []telegramChat{export.telegramChat}
+	}
+
+	archive := &Archive{Source: SourceTelegram, FS: fsys}
+	for _, tc := range chats {
+		// Избранное — заметки владельца аккаунта, а не переписка
+		if tc.Type == "saved_messages" {
+			continue
+		}
+
+		chat := Chat{
+			ID:     strconv.FormatInt(tc.ID, 10),
+			Name:   tc.Name,
+			Direct: tc.Type == "personal_chat" || tc.Type == "bot_chat",
+		}
+		for _, tm := range tc.Messages {
+			if tm.Type != "message" || tm.FromID == "" {
+				continue
+			}
+			sentAt, err := telegramTime(tm)
+			if err != nil {
+				return nil, fmt.Errorf("%w: message %d in chat %d: %v", ErrUnsupportedArchive, tm.ID, tc.ID, err)
+			}
+
+			msg := Message{
+				ID:       strconv.FormatInt(tm.ID, 10),
+				AuthorID: tm.FromID,
+				Text:     string(tm.Text),
+				SentAt:   sentAt,
+			}
+			if tm.ReplyTo != 0 {
+				msg.ReplyToID = strconv.FormatInt(tm.ReplyTo, 10)
+			}
+			if tm.Photo != "" {
+				msg.Files = append(msg.Files, telegramFile(fsys, tm.Photo, "", "image/jpeg"))
+			}
+			if tm.File != "" {
+				msg.Files = append(msg.Files, telegramFile(fsys, tm.File, tm.FileName, tm.MimeType))
+			}
+			chat.Messages = append(chat.Messages, msg)
+
+			if chat.CreatedAt.IsZero() {
+				chat.CreatorID, chat.CreatedAt = msg.AuthorID, msg.SentAt
+			}
+		}
+		archive.Chats = append(archive.Chats, chat)
+	}
+	return archive, nil
+}
+
+// telegramTime предпочитает date_unixtime: date записан в часовом поясе компьютера, с которого сделана выгрузка
+func telegramTime(tm telegramMessage) (time.Time, error) {
+	if tm.DateUnixtime != "" {
+		sec, err := strconv.ParseInt(tm.DateUnixtime, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(sec, 0).UTC(), nil
+	}
+	return time.Parse("2006-01-02T15:04:05", tm.Date)
+}
+
+// telegramFile описывает медиафайл сообщения. Если файл не выгружался (например, из-за ограничения размера),
+// Telegram пишет вместо пути пояснение в скобках, и путь остаётся пустым
+func telegramFile(fsys fs.FS, filePath, name, mimeType string) File {
+	file := File{Name: name, MimeType: mimeType}
+	if strings.HasPrefix(filePath, "(") || !fs.ValidPath(filePath) {
+		return file
+	}
+	if file.Name == "" {
+		file.Name = path.Base(filePath)
+	}
+	if _, err := fs.Stat(fsys, filePath); err == nil {
+		file.Path = filePath
+	}
+	return file
+}
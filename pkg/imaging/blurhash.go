@@ -0,0 +1,103 @@
+package imaging
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+const base83Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Blurhash кодирует изображение в компактную строку-заглушку по алгоритму https://blurha.sh.
+// xComponents и yComponents задают детализацию (1–9); изображение стоит предварительно уменьшить,
+// так как сложность пропорциональна числу пикселей
+func Blurhash(img *image.RGBA, xComponents, yComponents int) string {
+	xComponents = min(max(xComponents, 1), 9)
+	yComponents = min(max(yComponents, 1), 9)
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			factors = append(factors, multiplyBasis(img, w, h, i, j))
+		}
+	}
+
+	var sb strings.Builder
+	encodeBase83(&sb, (xComponents-1)+(yComponents-1)*9, 1)
+
+	maxValue := 1.0
+	if len(factors) > 1 {
+		actualMax := 0.0
+		for _, f := range factors[1:] {
+			actualMax = max(actualMax, math.Abs(f[0]), math.Abs(f[1]), math.Abs(f[2]))
+		}
+		quantisedMax := min(max(int(math.Floor(actualMax*166-0.5)), 0), 82)
+		maxValue = float64(quantisedMax+1) / 166
+		encodeBase83(&sb, quantisedMax, 1)
+	} else {
+		encodeBase83(&sb, 0, 1)
+	}
+
+	dc := factors[0]
+	encodeBase83(&sb, linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4)
+
+	for _, f := range factors[1:] {
+		quant := func(v float64) int {
+			return min(max(int(math.Floor(signPow(v/maxValue, 0.5)*9+9.5)), 0), 18)
+		}
+		encodeBase83(&sb, quant(f[0])*19*19+quant(f[1])*19+quant(f[2]), 2)
+	}
+
+	return sb.String()
+}
+
+func multiplyBasis(img *image.RGBA, w, h, i, j int) [3]float64 {
+	normalisation := 2.0
+	if i == 0 && j == 0 {
+		normalisation = 1
+	}
+
+	var r, g, b float64
+	for y := 0; y < h; y++ {
+		basisY := math.Cos(math.Pi * float64(j) * float64(y) / float64(h))
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < w; x++ {
+			basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) * basisY
+			p := row[x*4:]
+			r += basis * sRGBToLinear(p[0])
+			g += basis * sRGBToLinear(p[1])
+			b += basis * sRGBToLinear(p[2])
+		}
+	}
+
+	scale := normalisation / float64(w*h)
+	return [3]float64{r * scale, g * scale, b * scale}
+}
+
+func sRGBToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := min(max(value, 0), 1)
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
+
+func encodeBase83(sb *strings.Builder, value, length int) {
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		sb.WriteByte(base83Alphabet[digit])
+	}
+}
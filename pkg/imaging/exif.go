@@ -0,0 +1,158 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrNotJPEG = errors.New("not a JPEG image")
+
+const (
+	markerSOI  = 0xD8
+	markerSOS  = 0xDA
+	markerAPP1 = 0xE1
+
+	tagOrientation = 0x0112
+	tagGPSInfo     = 0x8825
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// SanitizeJPEG удаляет из JPEG сведения о местоположении: обнуляет GPS-раздел EXIF
+// и выбрасывает XMP-блоки, в которых тоже может храниться геопозиция. Остальные теги EXIF
+// сохраняются. Вторым значением возвращается EXIF Orientation (1, если тег отсутствует)
+func SanitizeJPEG(data []byte) ([]byte, int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != markerSOI {
+		return nil, 0, ErrNotJPEG
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	orientation := 1
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, 0, errors.New("invalid JPEG marker")
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			// Заполняющий байт перед маркером
+			pos++
+			continue
+		}
+		if marker == markerSOS {
+			// Дальше идут сжатые данные, метаданных после них не бывает
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, 0, errors.New("truncated JPEG segment")
+		}
+		payload := data[pos+4 : end]
+
+		if marker == markerAPP1 {
+			switch {
+			case bytes.HasPrefix(payload, xmpHeader):
+				pos = end
+				continue
+			case bytes.HasPrefix(payload, exifHeader):
+				segment := bytes.Clone(data[pos:end])
+				if o := stripExifLocation(segment[4+len(exifHeader):]); o != 0 {
+					orientation = o
+				}
+				out.Write(segment)
+				pos = end
+				continue
+			}
+		}
+
+		out.Write(data[pos:end])
+		pos = end
+	}
+
+	out.Write(data[pos:])
+	return out.Bytes(), orientation, nil
+}
+
+// stripExifLocation обнуляет GPS IFD внутри TIFF-структуры EXIF на месте и возвращает Orientation.
+// Смещения не пересчитываются: раздел просто становится пустым, поэтому структура остаётся валидной.
+// Повреждённый EXIF не считается ошибкой — изображение при этом остаётся пригодным к показу
+func stripExifLocation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	orientation := 0
+	ifd0 := int(order.Uint32(tiff[4:]))
+	forEachIFDEntry(tiff, order, ifd0, func(entry []byte) {
+		switch order.Uint16(entry) {
+		case tagOrientation:
+			orientation = int(order.Uint16(entry[8:]))
+		case tagGPSInfo:
+			clearIFD(tiff, order, int(order.Uint32(entry[8:])))
+		}
+	})
+
+	return orientation
+}
+
+func forEachIFDEntry(tiff []byte, order binary.ByteOrder, offset int, fn func(entry []byte)) {
+	if offset <= 0 || offset+2 > len(tiff) {
+		return
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		start := offset + 2 + i*12
+		if start+12 > len(tiff) {
+			return
+		}
+		fn(tiff[start : start+12])
+	}
+}
+
+// Размеры значений TIFF по типам; 0 — неизвестный тип
+var tiffTypeSizes = [...]int{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+func clearIFD(tiff []byte, order binary.ByteOrder, offset int) {
+	if offset <= 0 || offset+2 > len(tiff) {
+		return
+	}
+
+	// Сначала стираются значения, вынесенные за пределы записей
+	forEachIFDEntry(tiff, order, offset, func(entry []byte) {
+		typ := int(order.Uint16(entry[2:]))
+		if typ >= len(tiffTypeSizes) {
+			return
+		}
+		size := tiffTypeSizes[typ] * int(order.Uint32(entry[4:]))
+		if size <= 4 {
+			return
+		}
+		valueOffset := int(order.Uint32(entry[8:]))
+		if valueOffset > 0 && valueOffset+size <= len(tiff) && size > 0 {
+			clear(tiff[valueOffset : valueOffset+size])
+		}
+	})
+
+	count := int(order.Uint16(tiff[offset:]))
+	end := min(offset+2+count*12+4, len(tiff))
+	// Нулевое число записей и нулевая ссылка на следующий IFD
+	clear(tiff[offset:end])
+}
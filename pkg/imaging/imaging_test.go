@@ -0,0 +1,179 @@
+package imaging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"messanger/pkg/imaging"
+)
+
+func solid(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestFit(t *testing.T) {
+	img := solid(400, 100, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+
+	thumb := imaging.Fit(img, 160)
+	assert.Equal(t, image.Rect(0, 0, 160, 40), thumb.Rect)
+	assert.Equal(t, color.RGBA{R: 200, G: 100, B: 50, A: 255}, thumb.RGBAAt(80, 20))
+
+	assert.Same(t, img, imaging.Fit(img, 1000), "small images must not be upscaled")
+
+	w, h := imaging.FitSize(100, 400, 160)
+	assert.Equal(t, 40, w)
+	assert.Equal(t, 160, h)
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 255, A: 255})
+
+	// Поворот на 90° по часовой: левый пиксель становится верхним
+	rotated := imaging.Orient(img, 6)
+	assert.Equal(t, image.Rect(0, 0, 1, 2), rotated.Rect)
+	assert.Equal(t, uint8(255), rotated.RGBAAt(0, 0).R)
+	assert.Equal(t, uint8(0), rotated.RGBAAt(0, 1).R)
+
+	assert.Same(t, img, imaging.Orient(img, 1))
+}
+
+func TestBlurhash(t *testing.T) {
+	white := imaging.Blurhash(solid(32, 32, color.RGBA{R: 255, G: 255, B: 255, A: 255}), 4, 3)
+	assert.Equal(t, "L", white[:1], "size flag for 4x3 components")
+	assert.Equal(t, "TSUA", white[2:6], "DC component encodes the average colour #FFFFFF")
+
+	gradient := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			gradient.SetRGBA(x, y, color.RGBA{R: uint8(x * 8), B: uint8(y * 8), A: 255})
+		}
+	}
+	hash := imaging.Blurhash(gradient, 4, 3)
+	assert.Len(t, hash, 4+2*4*3)
+	assert.NotEqual(t, white, hash)
+	assert.Equal(t, hash, imaging.Blurhash(gradient, 4, 3), "encoding is deterministic")
+}
+
+// exifSegment собирает APP1-сегмент с Orientation и GPS-широтой
+func exifSegment(orientation uint16, latitude uint32) []byte {
+	order := binary.BigEndian
+	tiff := make([]byte, 0, 128)
+	tiff = append(tiff, 'M', 'M', 0, 42)
+	tiff = order.AppendUint32(tiff, 8)
+
+	// IFD0: Orientation и ссылка на GPS IFD
+	tiff = order.AppendUint16(tiff, 2)
+	tiff = order.AppendUint16(tiff, 0x0112)
+	tiff = order.AppendUint16(tiff, 3)
+	tiff = order.AppendUint32(tiff, 1)
+	tiff = order.AppendUint16(tiff, orientation)
+	tiff = order.AppendUint16(tiff, 0)
+	tiff = order.AppendUint16(tiff, 0x8825)
+	tiff = order.AppendUint16(tiff, 4)
+	tiff = order.AppendUint32(tiff, 1)
+	tiff = order.AppendUint32(tiff, 38)
+	tiff = order.AppendUint32(tiff, 0)
+
+	// GPS IFD по смещению 38: GPSLatitude из трёх RATIONAL по смещению 56
+	tiff = order.AppendUint16(tiff, 1)
+	tiff = order.AppendUint16(tiff, 2)
+	tiff = order.AppendUint16(tiff, 5)
+	tiff = order.AppendUint32(tiff, 3)
+	tiff = order.AppendUint32(tiff, 56)
+	tiff = order.AppendUint32(tiff, 0)
+	for i := 0; i < 6; i++ {
+		tiff = order.AppendUint32(tiff, latitude)
+	}
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = order.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func xmpSegment(content string) []byte {
+	payload := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), content...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestSanitizeJPEG(t *testing.T) {
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, solid(8, 8, color.RGBA{G: 255, A: 255}), nil))
+
+	latitude := []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	var withMeta []byte
+	withMeta = append(withMeta, encoded.Bytes()[:2]...)
+	withMeta = append(withMeta, exifSegment(6, binary.BigEndian.Uint32(latitude))...)
+	withMeta = append(withMeta, xmpSegment(`<exif:GPSLatitude>55,45N</exif:GPSLatitude>`)...)
+	withMeta = append(withMeta, encoded.Bytes()[2:]...)
+
+	clean, orientation, err := imaging.SanitizeJPEG(withMeta)
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, orientation)
+	assert.False(t, bytes.Contains(clean, latitude), "GPS values must be wiped")
+	assert.False(t, bytes.Contains(clean, []byte("GPSLatitude")), "XMP must be dropped")
+	assert.True(t, bytes.Contains(clean, []byte("Exif\x00\x00")), "the rest of EXIF is kept")
+
+	_, err = jpeg.Decode(bytes.NewReader(clean))
+	require.NoError(t, err)
+
+	_, _, err = imaging.SanitizeJPEG([]byte("not an image"))
+	assert.ErrorIs(t, err, imaging.ErrNotJPEG)
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestSanitizePNG(t *testing.T) {
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, solid(8, 8, color.RGBA{G: 255, A: 255})))
+
+	// IHDR всегда идёт первым: сигнатура, длина, тип, 13 байт данных и CRC
+	ihdrEnd := 8 + 12 + 13
+	latitude := []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	var withMeta []byte
+	withMeta = append(withMeta, encoded.Bytes()[:ihdrEnd]...)
+	withMeta = append(withMeta, pngChunk("eXIf", exifSegment(6, binary.BigEndian.Uint32(latitude))[10:])...)
+	withMeta = append(withMeta, pngChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00<exif:GPSLatitude>55,45N</exif:GPSLatitude>"))...)
+	withMeta = append(withMeta, pngChunk("tEXt", []byte("Comment\x00taken at 55.75, 37.61"))...)
+	withMeta = append(withMeta, encoded.Bytes()[ihdrEnd:]...)
+
+	clean, orientation, err := imaging.SanitizePNG(withMeta)
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, orientation)
+	assert.False(t, bytes.Contains(clean, latitude), "GPS values must be wiped")
+	assert.False(t, bytes.Contains(clean, []byte("GPSLatitude")), "iTXt must be dropped")
+	assert.False(t, bytes.Contains(clean, []byte("taken at")), "tEXt must be dropped")
+	assert.True(t, bytes.Contains(clean, []byte("eXIf")), "the rest of EXIF is kept")
+
+	// Декодер проверяет CRC, поэтому пересчёт контрольной суммы eXIf тоже проверяется
+	_, err = png.Decode(bytes.NewReader(clean))
+	require.NoError(t, err)
+
+	_, _, err = imaging.SanitizePNG([]byte("not an image"))
+	assert.ErrorIs(t, err, imaging.ErrNotPNG)
+}
@@ -0,0 +1,45 @@
+package imaging
+
+import "image"
+
+// Orient применяет к изображению преобразование, заданное EXIF-тегом Orientation (1–8),
+// чтобы пиксели шли в порядке отображения
+func Orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	// Ориентации 5–8 меняют стороны местами
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var tx, ty int
+			switch orientation {
+			case 2: // отражение по горизонтали
+				tx, ty = w-1-x, y
+			case 3: // поворот на 180°
+				tx, ty = w-1-x, h-1-y
+			case 4: // отражение по вертикали
+				tx, ty = x, h-1-y
+			case 5: // транспонирование
+				tx, ty = y, x
+			case 6: // поворот на 90° по часовой
+				tx, ty = h-1-y, x
+			case 7: // поперечное транспонирование
+				tx, ty = h-1-y, w-1-x
+			case 8: // поворот на 90° против часовой
+				tx, ty = y, w-1-x
+			}
+			si := (y-src.Rect.Min.Y)*src.Stride + (x-src.Rect.Min.X)*4
+			di := ty*dst.Stride + tx*4
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
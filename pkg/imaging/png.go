@@ -0,0 +1,66 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+var ErrNotPNG = errors.New("not a PNG image")
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Текстовые чанки: в них хранятся XMP и произвольные подписи, в том числе с геопозицией
+var pngTextChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+}
+
+// SanitizePNG удаляет из PNG сведения о местоположении: обнуляет GPS-раздел в чанке eXIf
+// и выбрасывает текстовые чанки. Вторым значением возвращается EXIF Orientation
+// (1, если тег отсутствует)
+func SanitizePNG(data []byte) ([]byte, int, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, 0, ErrNotPNG
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+	orientation := 1
+
+	pos := len(pngSignature)
+	for pos < len(data) {
+		// Длина, тип, данные и CRC
+		if pos+12 > len(data) {
+			return nil, 0, errors.New("truncated PNG chunk")
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, 0, errors.New("truncated PNG chunk")
+		}
+		chunkType := string(data[pos+4 : pos+8])
+
+		switch {
+		case pngTextChunks[chunkType]:
+		case chunkType == "eXIf":
+			chunk := bytes.Clone(data[pos:end])
+			if o := stripExifLocation(chunk[8 : 8+length]); o != 0 {
+				orientation = o
+			}
+			binary.BigEndian.PutUint32(chunk[8+length:], crc32.ChecksumIEEE(chunk[4:8+length]))
+			out.Write(chunk)
+		default:
+			out.Write(data[pos:end])
+		}
+
+		pos = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+
+	return out.Bytes(), orientation, nil
+}
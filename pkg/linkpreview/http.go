@@ -0,0 +1,213 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	defaultTimeout      = 5 * time.Second
+	defaultMaxBytes     = 1 << 20
+	defaultMaxRedirects = 3
+	maxFieldLength      = 1024
+	userAgent           = "messanger-link-preview/1.0"
+)
+
+type HTTPFetcherConfig struct {
+	// Timeout ограничивает весь запрос, включая редиректы и чтение тела
+	Timeout time.Duration
+	// MaxBytes сколько байт страницы читается в поисках метаданных
+	MaxBytes     int64
+	MaxRedirects int
+	// AllowPrivateNetworks отключает защиту от SSRF; только для тестов
+	AllowPrivateNetworks bool
+}
+
+// HTTPFetcher загружает страницы по HTTP(S) и извлекает из них OpenGraph и Twitter Cards.
+// Соединения во внутренние сети запрещены, размер и время загрузки ограничены
+type HTTPFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func NewHTTPFetcher(cfg HTTPFetcherConfig) *HTTPFetcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultMaxBytes
+	}
+	if cfg.MaxRedirects <= 0 {
+		cfg.MaxRedirects = defaultMaxRedirects
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = guardControl
+	}
+
+	transport := &http.Transport{
+		// Прокси из окружения не используется: иначе проверялся бы адрес прокси, а не сайта
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.Timeout,
+		ResponseHeaderTimeout: cfg.Timeout,
+		MaxIdleConns:          16,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &HTTPFetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > cfg.MaxRedirects {
+					return fmt.Errorf("stopped after %d redirects", cfg.MaxRedirects)
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("%w: redirect to %s", ErrUnsupported, req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+		maxBytes: cfg.MaxBytes,
+	}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequest: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && errors.Is(urlErr.Err, ErrBlockedAddress) {
+			return nil, urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: status %d", rawURL, resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("%w: content type %q", ErrUnsupported, mediaType)
+	}
+
+	preview := parseHTML(io.LimitReader(resp.Body, f.maxBytes), resp.Request.URL)
+	if preview.Title == "" && preview.Description == "" {
+		return nil, fmt.Errorf("%w: no metadata at %s", ErrUnsupported, rawURL)
+	}
+	preview.URL = rawURL
+	return preview, nil
+}
+
+// parseHTML читает <head> страницы и собирает метаданные; OpenGraph имеет приоритет над Twitter Cards,
+// а те — над <title> и <meta name="description">
+func parseHTML(r io.Reader, base *url.URL) *Preview {
+	meta := make(map[string]string)
+	var title strings.Builder
+	inTitle := false
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Body:
+				break loop
+			case atom.Title:
+				inTitle = true
+			case atom.Meta:
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = string(v)
+					}
+				}
+				if _, ok := meta[key]; key != "" && !ok {
+					meta[key] = content
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Head:
+				break loop
+			case atom.Title:
+				inTitle = false
+			}
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		}
+	}
+
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if v := strings.TrimSpace(meta[key]); v != "" {
+				return truncate(v)
+			}
+		}
+		return ""
+	}
+
+	preview := &Preview{
+		Title:       first("og:title", "twitter:title"),
+		Description: first("og:description", "twitter:description", "description"),
+		SiteName:    first("og:site_name"),
+	}
+	if preview.Title == "" {
+		preview.Title = truncate(strings.Join(strings.Fields(title.String()), " "))
+	}
+	if image := first("og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != "" {
+		if u, err := base.Parse(image); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			preview.ImageURL = u.String()
+		}
+	}
+	return preview
+}
+
+func truncate(s string) string {
+	if len(s) <= maxFieldLength {
+		return s
+	}
+	// Обрезка по границе символа UTF-8
+	cut := maxFieldLength
+	for cut > 0 && s[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return s[:cut]
+}
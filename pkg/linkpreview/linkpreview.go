@@ -0,0 +1,28 @@
+// Package linkpreview получает метаданные страниц (OpenGraph, Twitter Cards) для превью ссылок
+package linkpreview
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrBlockedAddress адрес ведёт во внутреннюю сеть или на служебный диапазон
+	ErrBlockedAddress = errors.New("address is not allowed")
+	// ErrUnsupported страница не является HTML или ссылка имеет неподдерживаемую схему
+	ErrUnsupported = errors.New("unsupported link")
+)
+
+// Preview метаданные страницы
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// Fetcher получает превью страницы по ссылке
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (*Preview, error)
+}
@@ -0,0 +1,124 @@
+package linkpreview
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `<!doctype html><html><head>
+<title>  Fallback
+ title </title>
+<meta property="og:title" content="Open Graph title">
+<meta name="twitter:title" content="Twitter title">
+<meta name="twitter:description" content="Twitter description">
+<meta property="og:image" content="/images/cover.png">
+<meta property="og:site_name" content="Example">
+</head><body><meta property="og:description" content="ignored"></body></html>`
+
+func TestHTTPFetcher_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(testPage))
+		case "/redirect":
+			http.Redirect(w, r, "/page", http.StatusFound)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f := NewHTTPFetcher(HTTPFetcherConfig{AllowPrivateNetworks: true})
+
+	preview, err := f.Fetch(context.Background(), srv.URL+"/redirect")
+	require.NoError(t, err)
+	assert.Equal(t, &Preview{
+		URL:         srv.URL + "/redirect",
+		Title:       "Open Graph title",
+		Description: "Twitter description",
+		ImageURL:    srv.URL + "/images/cover.png",
+		SiteName:    "Example",
+	}, preview)
+
+	_, err = f.Fetch(context.Background(), srv.URL+"/image")
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	_, err = f.Fetch(context.Background(), srv.URL+"/missing")
+	assert.Error(t, err)
+
+	_, err = f.Fetch(context.Background(), "file:///etc/passwd")
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestHTTPFetcher_BlocksPrivateNetworks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(testPage))
+	}))
+	defer srv.Close()
+
+	f := NewHTTPFetcher(HTTPFetcherConfig{})
+	_, err := f.Fetch(context.Background(), srv.URL)
+	assert.ErrorIs(t, err, ErrBlockedAddress)
+
+	_, err = f.Fetch(context.Background(), strings.Replace(srv.URL, "127.0.0.1", "localhost", 1))
+	assert.ErrorIs(t, err, ErrBlockedAddress)
+}
+
+func TestHTTPFetcher_Limits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		// Метаданные находятся за пределами лимита чтения
+		_, _ = w.Write([]byte("<html><head>" + strings.Repeat("<!-- padding -->", 1024)))
+		_, _ = w.Write([]byte(`<meta property="og:title" content="too far"></head></html>`))
+	}))
+	defer srv.Close()
+
+	f := NewHTTPFetcher(HTTPFetcherConfig{
+		Timeout:              50 * time.Millisecond,
+		MaxBytes:             1024,
+		AllowPrivateNetworks: true,
+	})
+
+	_, err := f.Fetch(context.Background(), srv.URL+"/large")
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	_, err = f.Fetch(context.Background(), srv.URL+"/slow")
+	assert.Error(t, err)
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"8.8.8.8":            true,
+		"2606:4700::1111":    true,
+		"127.0.0.1":          false,
+		"10.1.2.3":           false,
+		"172.20.0.1":         false,
+		"192.168.1.1":        false,
+		"169.254.169.254":    false,
+		"100.64.0.1":         false,
+		"0.0.0.0":            false,
+		"::1":                false,
+		"fd00::1":            false,
+		"fe80::1":            false,
+		"::ffff:127.0.0.1":   false,
+		"::ffff:192.168.0.1": false,
+	} {
+		assert.Equal(t, public, IsPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
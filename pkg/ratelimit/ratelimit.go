@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// cleanupThreshold число ключей, после которого при очередной проверке удаляются устаревшие окна
+const cleanupThreshold = 10000
+
+// Limiter ограничивает число событий на ключ в фиксированном временном окне
+type Limiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[int64]*window
+	now     func() time.Time
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+func New(limit int, period time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  period,
+		windows: make(map[int64]*window),
+		now:     time.Now,
+	}
+}
+
+// Allow учитывает событие для ключа и сообщает, укладывается ли оно в лимит
+func (l *Limiter) Allow(key int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if len(l.windows) > cleanupThreshold {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		l.windows[key] = &window{start: now, count: 1}
+		return true
+	}
+
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	return true
+}
@@ -0,0 +1,22 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	assert.True(t, l.Allow(1))
+	assert.True(t, l.Allow(1))
+	assert.False(t, l.Allow(1))
+	assert.True(t, l.Allow(2), "limits are tracked per key")
+
+	now = now.Add(time.Minute)
+	assert.True(t, l.Allow(1), "a new window resets the counter")
+}